@@ -0,0 +1,121 @@
+// Command apikey mints, lists and revokes API keys. It uses the same DB_*
+// variables as the server.
+//
+//	apikey create -name reporting -role viewer -scopes accounts:read
+//	apikey list
+//	apikey revoke -id 3
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/InternalTransfer/internal/auth"
+	"github.com/InternalTransfer/internal/config"
+	"github.com/InternalTransfer/internal/database"
+	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/repository"
+	"github.com/InternalTransfer/internal/service"
+)
+
+const usage = `usage:
+  apikey create -name NAME -role ROLE -scopes SCOPE[,SCOPE...]
+  apikey list
+  apikey revoke -id ID
+
+roles:  viewer, operator, approver, admin
+scopes: accounts:read, accounts:write, transfers:write, admin
+`
+
+func main() {
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	if err := run(os.Args[1], os.Args[2:], logger); err != nil {
+		fmt.Fprintln(os.Stderr, "apikey:", err)
+		os.Exit(1)
+	}
+}
+
+func run(cmd string, args []string, logger *slog.Logger) error {
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	fs.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	name := fs.String("name", "", "name of the client the key is for")
+	role := fs.String("role", "", "role of the client: viewer, operator, approver or admin")
+	scopes := fs.String("scopes", "", "comma-separated scopes to grant")
+	id := fs.Int64("id", 0, "ID of the key to revoke")
+	fs.Parse(args)
+
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("loading config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pool, err := database.NewPool(ctx, cfg.DB)
+	if err != nil {
+		return fmt.Errorf("connecting to database: %w", err)
+	}
+	defer pool.Close()
+
+	apiKeySvc := service.NewAPIKeyService(repository.NewAPIKeyRepository(pool), logger)
+
+	switch cmd {
+	case "create":
+		var granted []auth.Scope
+		for _, sc := range strings.Split(*scopes, ",") {
+			if sc = strings.TrimSpace(sc); sc != "" {
+				granted = append(granted, auth.Scope(sc))
+			}
+		}
+		k, key, err := apiKeySvc.Create(ctx, *name, auth.Role(*role), granted)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("created API key %d (%s) with role %s and scopes %s\n", k.ID, k.Name, k.Role, strings.Join(k.Scopes, ","))
+		fmt.Println("store it now, it cannot be shown again:")
+		fmt.Println(key)
+	case "list":
+		keys, err := apiKeySvc.List(ctx)
+		if err != nil {
+			return err
+		}
+		printKeys(os.Stdout, keys)
+	case "revoke":
+		k, err := apiKeySvc.Revoke(ctx, *id)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("revoked API key %d (%s)\n", k.ID, k.Name)
+	default:
+		fs.Usage()
+		os.Exit(2)
+	}
+	return nil
+}
+
+func printKeys(w io.Writer, keys []model.APIKey) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tPREFIX\tROLE\tSCOPES\tCREATED\tREVOKED")
+	for _, k := range keys {
+		revoked := "-"
+		if k.RevokedAt != nil {
+			revoked = k.RevokedAt.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s…\t%s\t%s\t%s\t%s\n", k.ID, k.Name, k.Prefix, k.Role, strings.Join(k.Scopes, ","),
+			k.CreatedAt.UTC().Format(time.RFC3339), revoked)
+	}
+	tw.Flush()
+}
@@ -0,0 +1,68 @@
+// Command reconcile checks every account balance against the ledger and the
+// transactions, every transaction against its ledger entries, and the
+// per-currency totals against cross-currency flows. It prints a JSON report
+// to stdout and exits with status 1 when it finds discrepancies, or 2 when
+// the checks could not be run.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/InternalTransfer/internal/config"
+	"github.com/InternalTransfer/internal/database"
+	"github.com/InternalTransfer/internal/repository"
+	"github.com/InternalTransfer/internal/service"
+)
+
+const (
+	exitDiscrepancies = 1
+	exitError         = 2
+)
+
+func main() {
+	// logs go to stderr so that stdout carries only the report
+	logger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+
+	ok, err := run(logger)
+	if err != nil {
+		logger.Error("reconciliation failed", "error", err)
+		os.Exit(exitError)
+	}
+	if !ok {
+		os.Exit(exitDiscrepancies)
+	}
+}
+
+func run(logger *slog.Logger) (bool, error) {
+	dbConfig, err := config.LoadDB()
+	if err != nil {
+		return false, fmt.Errorf("loading config: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	pool, err := database.NewPool(ctx, dbConfig)
+	if err != nil {
+		return false, fmt.Errorf("connecting to database: %w", err)
+	}
+	defer pool.Close()
+
+	reconcileSvc := service.NewReconcileService(repository.NewReconcileRepository(pool), logger)
+	report, err := reconcileSvc.Reconcile(context.Background())
+	if err != nil {
+		return false, err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return false, fmt.Errorf("writing report: %w", err)
+	}
+	return report.OK, nil
+}
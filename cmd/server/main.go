@@ -7,10 +7,15 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"slices"
+	"sync"
 	"time"
 
+	"github.com/InternalTransfer/internal/auth"
 	"github.com/InternalTransfer/internal/config"
 	"github.com/InternalTransfer/internal/database"
+	"github.com/InternalTransfer/internal/eventsink"
+	"github.com/InternalTransfer/internal/fxrate"
 	"github.com/InternalTransfer/internal/handler"
 	"github.com/InternalTransfer/internal/repository"
 	"github.com/InternalTransfer/internal/service"
@@ -19,18 +24,23 @@ import (
 func main() {
 	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
 
-	if err := run(logger); err != nil {
-		logger.Error("application exited with error", "error", err)
+	cfg, err := config.Load()
+	if err != nil {
+		logger.Error("application exited with error", "error", fmt.Errorf("loading config: %w", err))
 		os.Exit(1)
 	}
-}
+	// logs go to stderr so that the stdout outbox sink carries only events
+	if slices.Contains(cfg.Outbox.Sinks, "stdout") {
+		logger = slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	}
 
-func run(logger *slog.Logger) error {
-	cfg, err := config.Load()
-	if err != nil {
-		return fmt.Errorf("loading config: %w", err)
+	if err := run(cfg, logger); err != nil {
+		logger.Error("application exited with error", "error", err)
+		os.Exit(1)
 	}
+}
 
+func run(cfg config.App, logger *slog.Logger) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
@@ -43,15 +53,88 @@ func run(logger *slog.Logger) error {
 
 	accountRepo := repository.NewAccountRepository(pool)
 	transactionRepo := repository.NewTransactionRepository(pool)
+	ledgerRepo := repository.NewLedgerRepository(pool)
+	idempotencyRepo := repository.NewIdempotencyRepository(pool)
+	holdRepo := repository.NewHoldRepository(pool)
+	fxQuoteRepo := repository.NewFXQuoteRepository(pool)
+	scheduleRepo := repository.NewScheduleRepository(pool)
+	feePolicyRepo := repository.NewFeePolicyRepository(pool)
+	limitRepo := repository.NewAccountLimitRepository(pool)
+	outboxRepo := repository.NewOutboxRepository(pool)
+	webhookRepo := repository.NewWebhookRepository(pool)
+	apiKeyRepo := repository.NewAPIKeyRepository(pool)
+	approvalRepo := repository.NewApprovalRepository(pool)
 	txManager := database.NewTxManager(pool)
+	listener := database.NewListener(pool)
 
-	accountSvc := service.NewAccountService(accountRepo, logger)
-	transferSvc := service.NewTransferService(accountRepo, transactionRepo, txManager, logger, cfg.MaxTransferAmount)
+	accountSvc := service.NewAccountService(accountRepo, transactionRepo, ledgerRepo, outboxRepo, limitRepo, txManager, logger,
+		cfg.AccountIDCheckDigit)
+	approvals := service.NewApprovalPolicy(cfg.Approvals.Threshold, cfg.Approvals.TTL, cfg.Approvals.HoldFunds)
+	transferSvc := service.NewTransferService(accountRepo, transactionRepo, ledgerRepo, outboxRepo, idempotencyRepo, fxQuoteRepo, feePolicyRepo, limitRepo, approvalRepo, txManager, logger,
+		cfg.MaxTransferAmount, cfg.FeeAccountID, cfg.AccountIDCheckDigit, approvals)
+	holdSvc := service.NewHoldService(holdRepo, accountRepo, transactionRepo, ledgerRepo, outboxRepo, limitRepo, txManager, logger,
+		cfg.MaxTransferAmount, cfg.Holds.DefaultTTL, cfg.Holds.MaxTTL, cfg.AccountIDCheckDigit, approvals)
+
+	rates, err := newRateProvider(cfg.FX)
+	if err != nil {
+		return fmt.Errorf("configuring FX rates: %w", err)
+	}
+	fxSvc := service.NewFXService(fxQuoteRepo, rates, logger, cfg.FX.QuoteTTL)
+	feeSvc := service.NewFeeService(feePolicyRepo, accountRepo, logger, cfg.FeeAccountID, cfg.AccountIDCheckDigit)
+	scheduleSvc := service.NewScheduleService(scheduleRepo, accountRepo, transferSvc, txManager, logger, cfg.MaxTransferAmount,
+		cfg.AccountIDCheckDigit)
+	accountEventSvc := service.NewAccountEventService(accountRepo, ledgerRepo, listener, logger, cfg.AccountIDCheckDigit)
+	webhookSvc := service.NewWebhookService(webhookRepo, txManager, &http.Client{Timeout: cfg.Webhooks.Timeout}, logger,
+		cfg.Webhooks.MaxAttempts, cfg.Webhooks.DisableAfter)
+
+	sinks, closeSinks, err := newEventSinks(cfg.Outbox, webhookSvc)
+	if err != nil {
+		return fmt.Errorf("configuring outbox sinks: %w", err)
+	}
+	defer closeSinks(logger)
+	relay := service.NewOutboxRelay(outboxRepo, sinks, txManager, logger, cfg.Outbox.MaxAttempts)
 
 	accountHandler := handler.NewAccountHandler(accountSvc, logger)
+	accountEventHandler := handler.NewAccountEventHandler(accountEventSvc, logger)
 	transactionHandler := handler.NewTransactionHandler(transferSvc, logger)
+	holdHandler := handler.NewHoldHandler(holdSvc, logger)
+	fxHandler := handler.NewFXHandler(fxSvc, logger)
+	scheduleHandler := handler.NewScheduleHandler(scheduleSvc, logger)
+	feeHandler := handler.NewFeeHandler(feeSvc, logger)
+	webhookHandler := handler.NewWebhookHandler(webhookSvc, logger)
+
+	var authn handler.Authenticator
+	var authenticator *service.Authenticator
+	if cfg.Auth.Enabled {
+		authenticator, err = newAuthenticator(ctx, cfg.Auth, service.NewAPIKeyService(apiKeyRepo, logger), logger)
+		if err != nil {
+			return fmt.Errorf("configuring authentication: %w", err)
+		}
+		authn = authenticator
+	} else {
+		logger.Warn("authentication is disabled; every request is served as an anonymous admin")
+	}
 
-	router := handler.NewRouter(accountHandler, transactionHandler, logger)
+	router := handler.NewRouter(accountHandler, accountEventHandler, transactionHandler, holdHandler, fxHandler, scheduleHandler, feeHandler, webhookHandler, authn, logger)
+
+	// background workers stop when the server shuts down, and are waited for
+	// before the sinks and the pool they use are closed
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	defer func() {
+		stopWorkers()
+		workers.Wait()
+	}()
+	workers.Go(func() { holdSvc.RunSweeper(workerCtx, cfg.Holds.SweepInterval) })
+	workers.Go(func() { transferSvc.RunApprovalExpiry(workerCtx, cfg.Approvals.SweepInterval) })
+	workers.Go(func() { scheduleSvc.RunScheduler(workerCtx, cfg.SchedulerInterval) })
+	workers.Go(func() { accountSvc.RunSnapshotter(workerCtx, cfg.SnapshotInterval) })
+	workers.Go(func() { relay.RunRelay(workerCtx, cfg.Outbox.RelayInterval) })
+	workers.Go(func() { webhookSvc.RunDeliveries(workerCtx, cfg.Webhooks.Interval) })
+	workers.Go(func() { accountEventSvc.Run(workerCtx) })
+	if authenticator != nil && cfg.Auth.JWKS != "" {
+		workers.Go(func() { authenticator.RunKeyRefresh(workerCtx, cfg.Auth.JWKSRefresh) })
+	}
 
 	addr := fmt.Sprintf(":%d", cfg.ServerPort)
 	srv := &http.Server{
@@ -65,6 +148,7 @@ func run(logger *slog.Logger) error {
 		<-sigCh
 
 		logger.Info("shutting down server...")
+		stopWorkers()
 		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer shutdownCancel()
 		srv.Shutdown(shutdownCtx)
@@ -78,3 +162,61 @@ func run(logger *slog.Logger) error {
 	logger.Info("server stopped")
 	return nil
 }
+
+func newRateProvider(cfg config.FX) (service.RateProvider, error) {
+	if cfg.Provider == "http" {
+		return fxrate.NewHTTP(cfg.HTTPURL, &http.Client{Timeout: cfg.HTTPTimeout}), nil
+	}
+	return fxrate.ParseStatic(cfg.StaticRates)
+}
+
+// newEventSinks builds the configured outbox sinks. The returned function
+// closes the ones holding a file open.
+func newEventSinks(cfg config.Outbox, webhooks *service.WebhookService) ([]service.EventSink, func(*slog.Logger), error) {
+	var sinks []service.EventSink
+	var files []*eventsink.Writer
+	for _, name := range cfg.Sinks {
+		switch name {
+		case "webhooks":
+			sinks = append(sinks, webhooks)
+		case "stdout":
+			sinks = append(sinks, eventsink.NewStdout())
+		case "file":
+			f, err := eventsink.NewFile(cfg.FilePath)
+			if err != nil {
+				return nil, nil, err
+			}
+			sinks = append(sinks, f)
+			files = append(files, f)
+		case "http":
+			sinks = append(sinks, eventsink.NewHTTP(cfg.HTTPURL, &http.Client{Timeout: 10 * time.Second}))
+		}
+	}
+	closeSinks := func(logger *slog.Logger) {
+		for _, f := range files {
+			if err := f.Close(); err != nil {
+				logger.Error("closing outbox sink", "sink", f.Name(), "error", err)
+			}
+		}
+	}
+	return sinks, closeSinks, nil
+}
+
+// newAuthenticator accepts API keys and, when a JWKS is configured, end-user
+// JWTs. The JWKS is loaded once up front so that a bad source fails startup.
+func newAuthenticator(ctx context.Context, cfg config.Auth, apiKeys *service.APIKeyService, logger *slog.Logger) (*service.Authenticator, error) {
+	if cfg.JWKS == "" {
+		return service.NewAuthenticator(apiKeys, nil, nil, logger, "", "", nil), nil
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	loadJWKS := func(ctx context.Context) ([]byte, error) {
+		return auth.ReadJWKS(ctx, cfg.JWKS, client)
+	}
+	a := service.NewAuthenticator(apiKeys, auth.NewJWTVerifier(cfg.JWTIssuer, cfg.JWTAudience), loadJWKS, logger,
+		cfg.JWTOwnerClaim, cfg.JWTRole, cfg.JWTScopes)
+	if err := a.RefreshKeys(ctx); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
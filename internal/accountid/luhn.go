@@ -0,0 +1,33 @@
+// Package accountid computes the Luhn check digit carried by server-generated
+// account numbers.
+package accountid
+
+// CheckDigit returns the Luhn digit that makes base followed by it a valid
+// number.
+func CheckDigit(base int64) int64 {
+	var sum int64
+	double := true
+	for n := base; n > 0; n /= 10 {
+		d := n % 10
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return (10 - sum%10) % 10
+}
+
+// Append returns base with its check digit appended, e.g. 7992739871 becomes
+// 79927398713.
+func Append(base int64) int64 {
+	return base*10 + CheckDigit(base)
+}
+
+// Valid reports whether the last digit of id is the check digit of the rest.
+func Valid(id int64) bool {
+	return id >= 10 && id%10 == CheckDigit(id/10)
+}
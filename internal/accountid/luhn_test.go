@@ -0,0 +1,65 @@
+package accountid
+
+import "testing"
+
+func TestCheckDigit(t *testing.T) {
+	tests := []struct {
+		base int64
+		want int64
+	}{
+		{7992739871, 3},
+		{4992739871, 6},
+		{411111111111111, 1},
+		{123456781234567, 0},
+		{1, 8},
+		{9, 1},
+		{0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := CheckDigit(tt.base); got != tt.want {
+			t.Errorf("CheckDigit(%d) = %d, want %d", tt.base, got, tt.want)
+		}
+	}
+}
+
+func TestAppend(t *testing.T) {
+	if got := Append(7992739871); got != 79927398713 {
+		t.Errorf("Append(7992739871) = %d, want 79927398713", got)
+	}
+	for base := int64(1); base <= 1000; base++ {
+		if id := Append(base); !Valid(id) {
+			t.Fatalf("Append(%d) = %d, which is not Valid", base, id)
+		}
+	}
+}
+
+func TestValid(t *testing.T) {
+	tests := []struct {
+		id   int64
+		want bool
+	}{
+		{79927398713, true},
+		{49927398716, true},
+		{4111111111111111, true},
+		{1234567812345670, true},
+		{18, true},
+		{79927398710, false},
+		{79927398712, false},
+		{79927398714, false},
+		{49927398717, false},
+		{1234567812345678, false},
+		// swapping adjacent digits changes the check digit
+		{97927398713, false},
+		{19, false},
+		{8, false},
+		{0, false},
+		{-18, false},
+	}
+
+	for _, tt := range tests {
+		if got := Valid(tt.id); got != tt.want {
+			t.Errorf("Valid(%d) = %v, want %v", tt.id, got, tt.want)
+		}
+	}
+}
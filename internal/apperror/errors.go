@@ -1,6 +1,10 @@
 package apperror
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
 const (
 	CodeNotFound            = "NOT_FOUND"
@@ -8,6 +12,18 @@ const (
 	CodeInsufficientBalance = "INSUFFICIENT_BALANCE"
 	CodeValidation          = "VALIDATION_ERROR"
 	CodeInternal            = "INTERNAL_ERROR"
+	CodeIdempotencyMismatch = "IDEMPOTENCY_KEY_REUSED"
+	CodeReversalNoFunds     = "REVERSAL_INSUFFICIENT_FUNDS"
+	CodeHoldNotActive       = "HOLD_NOT_ACTIVE"
+	CodeCurrencyMismatch    = "CURRENCY_MISMATCH"
+	CodeRateUnavailable     = "FX_RATE_UNAVAILABLE"
+	CodeQuoteInvalid        = "FX_QUOTE_INVALID"
+	CodeScheduleState       = "INVALID_SCHEDULE_STATE"
+	CodeApprovalState       = "INVALID_APPROVAL_STATE"
+	CodeLimitExceeded       = "LIMIT_EXCEEDED"
+	CodeAccountInactive     = "ACCOUNT_INACTIVE"
+	CodeUnauthenticated     = "UNAUTHENTICATED"
+	CodeForbidden           = "FORBIDDEN"
 )
 
 type AppError interface {
@@ -15,6 +31,12 @@ type AppError interface {
 	Code() string
 }
 
+// DetailedError is implemented by errors that carry machine-readable details
+// for the client in addition to their message.
+type DetailedError interface {
+	Details() map[string]any
+}
+
 type ErrNotFound struct {
 	Entity string
 	ID     int64
@@ -47,6 +69,112 @@ func (e *ErrInsufficientBalance) Error() string {
 
 func (e *ErrInsufficientBalance) Code() string { return CodeInsufficientBalance }
 
+type ErrCurrencyMismatch struct {
+	SourceCurrency      string
+	DestinationCurrency string
+}
+
+func (e *ErrCurrencyMismatch) Error() string {
+	return fmt.Sprintf("Cannot move funds from a %s account to a %s account without an FX rate or quote", e.SourceCurrency, e.DestinationCurrency)
+}
+
+func (e *ErrCurrencyMismatch) Code() string { return CodeCurrencyMismatch }
+
+type ErrRateUnavailable struct {
+	SourceCurrency      string
+	DestinationCurrency string
+}
+
+func (e *ErrRateUnavailable) Error() string {
+	return fmt.Sprintf("No exchange rate is available from %s to %s", e.SourceCurrency, e.DestinationCurrency)
+}
+
+func (e *ErrRateUnavailable) Code() string { return CodeRateUnavailable }
+
+type ErrQuoteInvalid struct {
+	QuoteID int64
+	Reason  string
+}
+
+func (e *ErrQuoteInvalid) Error() string {
+	return fmt.Sprintf("This FX quote cannot be used: %s. Please request a new quote", e.Reason)
+}
+
+func (e *ErrQuoteInvalid) Code() string { return CodeQuoteInvalid }
+
+type ErrHoldNotActive struct {
+	HoldID int64
+	Status string
+}
+
+func (e *ErrHoldNotActive) Error() string {
+	return fmt.Sprintf("This hold is %s and can no longer be captured or released", e.Status)
+}
+
+func (e *ErrHoldNotActive) Code() string { return CodeHoldNotActive }
+
+type ErrScheduleState struct {
+	ScheduleID int64
+	Status     string
+	Action     string
+}
+
+func (e *ErrScheduleState) Error() string {
+	return fmt.Sprintf("Cannot %s a scheduled transfer that is %s", e.Action, e.Status)
+}
+
+func (e *ErrScheduleState) Code() string { return CodeScheduleState }
+
+type ErrApprovalState struct {
+	ApprovalID int64
+	Status     string
+	Action     string
+}
+
+func (e *ErrApprovalState) Error() string {
+	return fmt.Sprintf("Cannot %s a transfer whose approval is %s", e.Action, e.Status)
+}
+
+func (e *ErrApprovalState) Code() string { return CodeApprovalState }
+
+type ErrAccountInactive struct {
+	AccountID int64
+	Status    string
+}
+
+func (e *ErrAccountInactive) Error() string {
+	return fmt.Sprintf("Account %d is %s and cannot send or receive funds", e.AccountID, e.Status)
+}
+
+func (e *ErrAccountInactive) Code() string { return CodeAccountInactive }
+
+// ErrLimitExceeded reports which per-account limit a transfer would break.
+// ResetsAt is when the limit's window rolls over; it is nil for the
+// single-transfer limit.
+type ErrLimitExceeded struct {
+	AccountID int64
+	Limit     string
+	ResetsAt  *time.Time
+}
+
+func (e *ErrLimitExceeded) Error() string {
+	if e.ResetsAt == nil {
+		return fmt.Sprintf("This transfer exceeds the %s limit on account %d", e.Limit, e.AccountID)
+	}
+	return fmt.Sprintf("This transfer exceeds the %s limit on account %d. The limit resets at %s",
+		e.Limit, e.AccountID, e.ResetsAt.UTC().Format(time.RFC3339))
+}
+
+func (e *ErrLimitExceeded) Code() string { return CodeLimitExceeded }
+
+func (e *ErrLimitExceeded) Details() map[string]any {
+	d := map[string]any{"account_id": e.AccountID, "limit": e.Limit}
+	if e.ResetsAt != nil {
+		d["resets_at"] = e.ResetsAt.UTC()
+	}
+	return d
+}
+
 type ErrValidation struct {
 	Message string
 }
@@ -56,3 +184,113 @@ func (e *ErrValidation) Error() string {
 }
 
 func (e *ErrValidation) Code() string { return CodeValidation }
+
+// ErrUnauthenticated is returned when a request carries no valid
+// credentials.
+type ErrUnauthenticated struct{}
+
+func (e *ErrUnauthenticated) Error() string {
+	return "Please provide a valid API key or bearer token in the Authorization header"
+}
+
+func (e *ErrUnauthenticated) Code() string { return CodeUnauthenticated }
+
+// ErrForbidden is returned when the caller lacks the scope an operation
+// needs.
+type ErrForbidden struct {
+	Scope string
+}
+
+func (e *ErrForbidden) Error() string {
+	return fmt.Sprintf("This operation requires the %q scope", e.Scope)
+}
+
+func (e *ErrForbidden) Code() string { return CodeForbidden }
+
+func (e *ErrForbidden) Details() map[string]any {
+	return map[string]any{"required_scope": e.Scope}
+}
+
+// ErrNotPermitted is returned when the caller's role does not allow an
+// action.
+type ErrNotPermitted struct {
+	Role   string
+	Action string
+}
+
+func (e *ErrNotPermitted) Error() string {
+	return fmt.Sprintf("The %s role is not allowed to perform %s", e.Role, e.Action)
+}
+
+func (e *ErrNotPermitted) Code() string { return CodeForbidden }
+
+func (e *ErrNotPermitted) Details() map[string]any {
+	return map[string]any{"role": e.Role, "action": e.Action}
+}
+
+// ErrNotOwner is returned when an end user acts on an account that belongs
+// to someone else.
+type ErrNotOwner struct {
+	AccountID int64
+}
+
+func (e *ErrNotOwner) Error() string {
+	return fmt.Sprintf("You do not have access to account %d", e.AccountID)
+}
+
+func (e *ErrNotOwner) Code() string { return CodeForbidden }
+
+// ErrSelfApproval is returned when the principal who submitted a transfer
+// tries to decide on it.
+type ErrSelfApproval struct {
+	ApprovalID int64
+	Action     string
+}
+
+func (e *ErrSelfApproval) Error() string {
+	return fmt.Sprintf("Transfer %d was submitted by you and must be %s by someone else", e.ApprovalID, e.Action)
+}
+
+func (e *ErrSelfApproval) Code() string { return CodeForbidden }
+
+type ErrIdempotencyMismatch struct {
+	Key string
+}
+
+func (e *ErrIdempotencyMismatch) Error() string {
+	return "This Idempotency-Key was already used with a different request. Please use a new key"
+}
+
+func (e *ErrIdempotencyMismatch) Code() string { return CodeIdempotencyMismatch }
+
+type ErrReversalInsufficientFunds struct {
+	TransactionID int64
+	AccountID     int64
+}
+
+func (e *ErrReversalInsufficientFunds) Error() string {
+	return "This transfer cannot be reversed because the destination account no longer holds enough funds"
+}
+
+func (e *ErrReversalInsufficientFunds) Code() string { return CodeReversalNoFunds }
+
+// ErrBatchLeg reports which leg of a batch transfer failed. It carries the
+// code of the underlying error so clients can handle it the same way.
+type ErrBatchLeg struct {
+	Leg int
+	Err error
+}
+
+func (e *ErrBatchLeg) Error() string {
+	return fmt.Sprintf("legs[%d]: %s", e.Leg, e.Err.Error())
+}
+
+func (e *ErrBatchLeg) Code() string {
+	var appErr AppError
+	if errors.As(e.Err, &appErr) {
+		return appErr.Code()
+	}
+	return CodeInternal
+}
+
+func (e *ErrBatchLeg) Unwrap() error { return e.Err }
@@ -0,0 +1,342 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+var b64 = base64.RawURLEncoding
+
+type testKeys struct {
+	rsa   *rsa.PrivateKey
+	ec    *ecdsa.PrivateKey
+	other *rsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) *testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testKeys{rsa: rsaKey, ec: ecKey, other: other}
+}
+
+func rsaJWK(kid string, pub *rsa.PublicKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA", "kid": kid, "use": "sig", "alg": "RS256",
+		"n": b64.EncodeToString(pub.N.Bytes()),
+		"e": b64.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+	}
+}
+
+func ecJWK(t *testing.T, kid string, pub *ecdsa.PublicKey) map[string]string {
+	t.Helper()
+	point, err := pub.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]string{
+		"kty": "EC", "kid": kid, "crv": "P-256",
+		"x": b64.EncodeToString(point[1:33]),
+		"y": b64.EncodeToString(point[33:]),
+	}
+}
+
+func jwks(t *testing.T, keys ...map[string]string) []byte {
+	t.Helper()
+	b, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// signJWT builds a token with the given header fields, signed with key as
+// alg. A nil key leaves the signature empty.
+func signJWT(t *testing.T, alg, kid string, claims map[string]any, key crypto.Signer) string {
+	t.Helper()
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	h, _ := json.Marshal(header)
+	c, _ := json.Marshal(claims)
+	signed := b64.EncodeToString(h) + "." + b64.EncodeToString(c)
+	digest := sha256.Sum256([]byte(signed))
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = make([]byte, 64)
+		r.FillBytes(sig[:32])
+		s.FillBytes(sig[32:])
+	}
+	return signed + "." + b64.EncodeToString(sig)
+}
+
+func TestJWTVerify(t *testing.T) {
+	keys := newTestKeys(t)
+	v := NewJWTVerifier("https://issuer.example", "transfers")
+	if err := v.SetKeys(jwks(t, rsaJWK("rsa-1", &keys.rsa.PublicKey), ecJWK(t, "ec-1", &keys.ec.PublicKey))); err != nil {
+		t.Fatalf("SetKeys: %v", err)
+	}
+
+	now := time.Unix(1_750_000_000, 0)
+	claims := func(overrides map[string]any) map[string]any {
+		c := map[string]any{
+			"sub": "customer-1",
+			"iss": "https://issuer.example",
+			"aud": "transfers",
+			"exp": now.Add(time.Hour).Unix(),
+		}
+		for k, v := range overrides {
+			if v == nil {
+				delete(c, k)
+			} else {
+				c[k] = v
+			}
+		}
+		return c
+	}
+	tampered := func(token string) string {
+		parts := strings.Split(token, ".")
+		c, _ := json.Marshal(claims(map[string]any{"sub": "customer-2"}))
+		return parts[0] + "." + b64.EncodeToString(c) + "." + parts[2]
+	}
+
+	truncated := func(token string) string {
+		return token[:len(token)-4]
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{"RS256", signJWT(t, "RS256", "rsa-1", claims(nil), keys.rsa), false},
+		{"ES256", signJWT(t, "ES256", "ec-1", claims(nil), keys.ec), false},
+		{"ES256 on the RSA key", signJWT(t, "ES256", "rsa-1", claims(nil), keys.ec), true},
+		{"RS256 on the EC key", signJWT(t, "RS256", "ec-1", claims(nil), keys.rsa), true},
+		{"HS256", signJWT(t, "HS256", "rsa-1", claims(nil), keys.rsa), true},
+		{"alg none", signJWT(t, "none", "rsa-1", claims(nil), nil), true},
+		{"unknown kid", signJWT(t, "RS256", "rsa-2", claims(nil), keys.rsa), true},
+		{"no kid with several keys", signJWT(t, "RS256", "", claims(nil), keys.rsa), true},
+		{"signed by another key", signJWT(t, "RS256", "rsa-1", claims(nil), keys.other), true},
+		{"tampered RS256 claims", tampered(signJWT(t, "RS256", "rsa-1", claims(nil), keys.rsa)), true},
+		{"tampered ES256 claims", tampered(signJWT(t, "ES256", "ec-1", claims(nil), keys.ec)), true},
+		{"truncated ES256 signature", truncated(signJWT(t, "ES256", "ec-1", claims(nil), keys.ec)), true},
+		{"expired within the leeway", signJWT(t, "RS256", "rsa-1", claims(map[string]any{"exp": now.Add(-30 * time.Second).Unix()}), keys.rsa), false},
+		{"expired beyond the leeway", signJWT(t, "RS256", "rsa-1", claims(map[string]any{"exp": now.Add(-2 * time.Minute).Unix()}), keys.rsa), true},
+		{"no expiry", signJWT(t, "RS256", "rsa-1", claims(map[string]any{"exp": nil}), keys.rsa), true},
+		{"not before within the leeway", signJWT(t, "RS256", "rsa-1", claims(map[string]any{"nbf": now.Add(30 * time.Second).Unix()}), keys.rsa), false},
+		{"not before beyond the leeway", signJWT(t, "RS256", "rsa-1", claims(map[string]any{"nbf": now.Add(2 * time.Minute).Unix()}), keys.rsa), true},
+		{"wrong issuer", signJWT(t, "ES256", "ec-1", claims(map[string]any{"iss": "https://other.example"}), keys.ec), true},
+		{"audience array", signJWT(t, "ES256", "ec-1", claims(map[string]any{"aud": []string{"reports", "transfers"}}), keys.ec), false},
+		{"wrong audience", signJWT(t, "ES256", "ec-1", claims(map[string]any{"aud": "reports"}), keys.ec), true},
+		{"two segments", "abc.def", true},
+		{"malformed header", "!!!." + strings.SplitN(signJWT(t, "RS256", "rsa-1", claims(nil), keys.rsa), ".", 2)[1], true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := v.Verify(tt.token, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Verify succeeded with claims %v, want an error", got)
+				}
+				if !errors.Is(err, errInvalidToken) {
+					t.Errorf("Verify error = %v, want errInvalidToken", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if got["sub"] != "customer-1" {
+				t.Errorf("sub = %v, want customer-1", got["sub"])
+			}
+		})
+	}
+}
+
+func TestJWTVerifyWithoutKid(t *testing.T) {
+	keys := newTestKeys(t)
+	v := NewJWTVerifier("", "")
+	if err := v.SetKeys(jwks(t, ecJWK(t, "only", &keys.ec.PublicKey))); err != nil {
+		t.Fatalf("SetKeys: %v", err)
+	}
+
+	now := time.Now()
+	token := signJWT(t, "ES256", "", map[string]any{"sub": "customer-1", "exp": now.Add(time.Hour).Unix()}, keys.ec)
+	if _, err := v.Verify(token, now); err != nil {
+		t.Errorf("Verify with the only key and no kid: %v", err)
+	}
+}
+
+func TestJWTSetKeys(t *testing.T) {
+	keys := newTestKeys(t)
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	weak, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey := rsaJWK("rsa-1", &keys.rsa.PublicKey)
+	with := func(k map[string]string, field, value string) map[string]string {
+		c := make(map[string]string, len(k))
+		for f, v := range k {
+			c[f] = v
+		}
+		c[field] = value
+		return c
+	}
+
+	tests := []struct {
+		name     string
+		doc      []byte
+		wantErr  bool
+		wantKids []string
+	}{
+		{"RSA and EC", jwks(t, rsaKey, ecJWK(t, "ec-1", &keys.ec.PublicKey)), false, []string{"rsa-1", "ec-1"}},
+		{"RSA without alg", jwks(t, with(rsaKey, "alg", "")), false, []string{"rsa-1"}},
+		{"encryption keys are skipped", jwks(t, rsaKey, with(rsaJWK("enc-1", &keys.other.PublicKey), "use", "enc")), false, []string{"rsa-1"}},
+		{"other algorithms are skipped", jwks(t, rsaKey, with(rsaJWK("ps-1", &keys.other.PublicKey), "alg", "PS256")), false, []string{"rsa-1"}},
+		{"other curves are skipped", jwks(t, rsaKey, map[string]string{
+			"kty": "EC", "kid": "p384", "crv": "P-384",
+			"x": b64.EncodeToString(p384.X.Bytes()), "y": b64.EncodeToString(p384.Y.Bytes()),
+		}), false, []string{"rsa-1"}},
+		{"symmetric keys are skipped", jwks(t, rsaKey, map[string]string{"kty": "oct", "kid": "hs", "k": "c2VjcmV0"}), false, []string{"rsa-1"}},
+		{"no usable keys", jwks(t, with(rsaKey, "use", "enc")), true, nil},
+		{"empty", []byte(`{"keys":[]}`), true, nil},
+		{"not JSON", []byte(`keys`), true, nil},
+		{"bad RSA modulus", jwks(t, with(rsaKey, "n", "***")), true, nil},
+		{"oversized RSA exponent", jwks(t, with(rsaKey, "e", b64.EncodeToString([]byte{1, 0, 0, 0, 1}))), true, nil},
+		{"RSA exponent above 2^31-1", jwks(t, with(rsaKey, "e", b64.EncodeToString([]byte{0x80, 0, 0, 1}))), true, nil},
+		{"even RSA exponent", jwks(t, with(rsaKey, "e", b64.EncodeToString([]byte{1, 0, 0}))), true, nil},
+		{"RSA exponent of 1", jwks(t, with(rsaKey, "e", b64.EncodeToString([]byte{1}))), true, nil},
+		{"short RSA modulus", jwks(t, rsaJWK("rsa-1", &weak.PublicKey)), true, nil},
+		{"short EC coordinate", jwks(t, with(ecJWK(t, "ec-1", &keys.ec.PublicKey), "x", b64.EncodeToString(make([]byte, 31)))), true, nil},
+		{"EC point off the curve", jwks(t, with(ecJWK(t, "ec-1", &keys.ec.PublicKey), "y", b64.EncodeToString(make([]byte, 32)))), true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewJWTVerifier("", "")
+			err := v.SetKeys(tt.doc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetKeys error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(v.keys) != len(tt.wantKids) {
+				t.Errorf("loaded %d keys, want %v", len(v.keys), tt.wantKids)
+			}
+			for _, kid := range tt.wantKids {
+				if _, ok := v.keys[kid]; !ok {
+					t.Errorf("key %q not loaded", kid)
+				}
+			}
+		})
+	}
+}
+
+func TestJWTSetKeysRotates(t *testing.T) {
+	keys := newTestKeys(t)
+	v := NewJWTVerifier("", "")
+	now := time.Now()
+	claims := map[string]any{"sub": "customer-1", "exp": now.Add(time.Hour).Unix()}
+
+	if err := v.SetKeys(jwks(t, rsaJWK("old", &keys.rsa.PublicKey))); err != nil {
+		t.Fatalf("SetKeys: %v", err)
+	}
+	oldToken := signJWT(t, "RS256", "old", claims, keys.rsa)
+	if _, err := v.Verify(oldToken, now); err != nil {
+		t.Fatalf("Verify before rotation: %v", err)
+	}
+
+	if err := v.SetKeys(jwks(t, rsaJWK("new", &keys.other.PublicKey))); err != nil {
+		t.Fatalf("SetKeys: %v", err)
+	}
+	if _, err := v.Verify(oldToken, now); err == nil {
+		t.Error("token signed with a rotated-out key still verifies")
+	}
+	if _, err := v.Verify(signJWT(t, "RS256", "new", claims, keys.other), now); err != nil {
+		t.Errorf("Verify after rotation: %v", err)
+	}
+
+	// a bad document leaves the current keys in place
+	if err := v.SetKeys([]byte(`{"keys":[]}`)); err == nil {
+		t.Fatal("SetKeys accepted a JWKS without keys")
+	}
+	if _, err := v.Verify(signJWT(t, "RS256", "new", claims, keys.other), now); err != nil {
+		t.Errorf("Verify after a rejected JWKS: %v", err)
+	}
+}
+
+func TestJWTCheckClaims(t *testing.T) {
+	now := time.Unix(1_750_000_000, 0)
+	exp := float64(now.Add(time.Hour).Unix())
+
+	tests := []struct {
+		name     string
+		issuer   string
+		audience string
+		claims   map[string]any
+		wantErr  bool
+	}{
+		{"nothing configured", "", "", map[string]any{"exp": exp}, false},
+		{"audience string", "", "transfers", map[string]any{"exp": exp, "aud": "transfers"}, false},
+		{"audience array", "", "transfers", map[string]any{"exp": exp, "aud": []any{"reports", "transfers"}}, false},
+		{"audience string mismatch", "", "transfers", map[string]any{"exp": exp, "aud": "reports"}, true},
+		{"audience array mismatch", "", "transfers", map[string]any{"exp": exp, "aud": []any{"reports"}}, true},
+		{"audience missing", "", "transfers", map[string]any{"exp": exp}, true},
+		{"audience of the wrong type", "", "transfers", map[string]any{"exp": exp, "aud": 7.0}, true},
+		{"audience not required", "", "", map[string]any{"exp": exp, "aud": "reports"}, false},
+		{"issuer", "https://issuer.example", "", map[string]any{"exp": exp, "iss": "https://issuer.example"}, false},
+		{"issuer missing", "https://issuer.example", "", map[string]any{"exp": exp}, true},
+		{"expiry at the leeway", "", "", map[string]any{"exp": float64(now.Add(-jwtLeeway).Unix())}, false},
+		{"expiry past the leeway", "", "", map[string]any{"exp": float64(now.Add(-jwtLeeway - time.Second).Unix())}, true},
+		{"expiry of the wrong type", "", "", map[string]any{"exp": "tomorrow"}, true},
+		{"not before at the leeway", "", "", map[string]any{"exp": exp, "nbf": float64(now.Add(jwtLeeway).Unix())}, false},
+		{"not before past the leeway", "", "", map[string]any{"exp": exp, "nbf": float64(now.Add(jwtLeeway + time.Second).Unix())}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewJWTVerifier(tt.issuer, tt.audience)
+			err := v.checkClaims(tt.claims, now)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkClaims error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
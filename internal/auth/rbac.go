@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"slices"
+
+	"github.com/InternalTransfer/internal/apperror"
+)
+
+// Role says what kind of staff or client a principal is. Roles grant
+// actions through policy; scopes can only narrow them further.
+type Role string
+
+const (
+	// RoleViewer can read accounts and transactions.
+	RoleViewer Role = "viewer"
+	// RoleOperator can also open accounts and move money.
+	RoleOperator Role = "operator"
+	// RoleApprover can also freeze and close accounts, change limits,
+	// reverse transfers and approve transfers submitted by others.
+	RoleApprover Role = "approver"
+	// RoleAdmin can do everything, including managing fees and webhooks.
+	RoleAdmin Role = "admin"
+)
+
+var Roles = []Role{RoleViewer, RoleOperator, RoleApprover, RoleAdmin}
+
+func ValidRole(r Role) bool {
+	return slices.Contains(Roles, r)
+}
+
+// Action is an operation that is subject to access control.
+type Action string
+
+const (
+	ActionReadAccounts   Action = "accounts.read"
+	ActionWriteAccounts  Action = "accounts.write"
+	ActionChangeStatus   Action = "accounts.change_status"
+	ActionSetLimits      Action = "accounts.set_limits"
+	ActionTransfer       Action = "transfers.create"
+	ActionReverse        Action = "transfers.reverse"
+	ActionApprove        Action = "transfers.approve"
+	ActionManageFees     Action = "fees.manage"
+	ActionManageWebhooks Action = "webhooks.manage"
+)
+
+// policy lists the actions each role may perform.
+var policy = map[Role][]Action{
+	RoleViewer: {
+		ActionReadAccounts,
+	},
+	RoleOperator: {
+		ActionReadAccounts, ActionWriteAccounts, ActionTransfer,
+	},
+	RoleApprover: {
+		ActionReadAccounts, ActionWriteAccounts, ActionTransfer,
+		ActionChangeStatus, ActionSetLimits, ActionReverse, ActionApprove,
+	},
+	RoleAdmin: {
+		ActionReadAccounts, ActionWriteAccounts, ActionTransfer,
+		ActionChangeStatus, ActionSetLimits, ActionReverse, ActionApprove,
+		ActionManageFees, ActionManageWebhooks,
+	},
+}
+
+// actionScopes is the scope a credential needs for each action.
+var actionScopes = map[Action]Scope{
+	ActionReadAccounts:   ScopeAccountsRead,
+	ActionWriteAccounts:  ScopeAccountsWrite,
+	ActionChangeStatus:   ScopeAccountsWrite,
+	ActionSetLimits:      ScopeAccountsWrite,
+	ActionTransfer:       ScopeTransfersWrite,
+	ActionReverse:        ScopeTransfersWrite,
+	ActionApprove:        ScopeTransfersWrite,
+	ActionManageFees:     ScopeAdmin,
+	ActionManageWebhooks: ScopeAdmin,
+}
+
+// RoleAllows reports whether role may perform action.
+func RoleAllows(role Role, action Action) bool {
+	return slices.Contains(policy[role], action)
+}
+
+// ScopeFor returns the scope a credential needs for action.
+func ScopeFor(action Action) Scope {
+	return actionScopes[action]
+}
+
+// Can reports whether p may perform action: its role must allow the action
+// and its scopes must cover it.
+func (p *Principal) Can(action Action) bool {
+	return RoleAllows(p.Role, action) && p.Has(ScopeFor(action))
+}
+
+// Authorize returns why p may not perform action, or nil if it may.
+func Authorize(p *Principal, action Action) error {
+	if p == nil {
+		return &apperror.ErrUnauthenticated{}
+	}
+	if !p.Has(ScopeFor(action)) {
+		return &apperror.ErrForbidden{Scope: string(ScopeFor(action))}
+	}
+	if !RoleAllows(p.Role, action) {
+		return &apperror.ErrNotPermitted{Role: string(p.Role), Action: string(action)}
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/InternalTransfer/internal/apperror"
+)
+
+func TestRoleAllows(t *testing.T) {
+	// the policy table documented in the README
+	allowed := map[Action][]Role{
+		ActionReadAccounts:   {RoleViewer, RoleOperator, RoleApprover, RoleAdmin},
+		ActionWriteAccounts:  {RoleOperator, RoleApprover, RoleAdmin},
+		ActionTransfer:       {RoleOperator, RoleApprover, RoleAdmin},
+		ActionChangeStatus:   {RoleApprover, RoleAdmin},
+		ActionSetLimits:      {RoleApprover, RoleAdmin},
+		ActionReverse:        {RoleApprover, RoleAdmin},
+		ActionApprove:        {RoleApprover, RoleAdmin},
+		ActionManageFees:     {RoleAdmin},
+		ActionManageWebhooks: {RoleAdmin},
+	}
+
+	for action, roles := range allowed {
+		for _, role := range slices.Concat(Roles, []Role{"auditor", ""}) {
+			if got, want := RoleAllows(role, action), slices.Contains(roles, role); got != want {
+				t.Errorf("RoleAllows(%q, %s) = %v, want %v", role, action, got, want)
+			}
+		}
+		if ScopeFor(action) == "" {
+			t.Errorf("action %s has no scope", action)
+		}
+	}
+	for role, actions := range policy {
+		for _, action := range actions {
+			if _, ok := allowed[action]; !ok {
+				t.Errorf("role %s allows %s, which is missing from the table", role, action)
+			}
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	tests := []struct {
+		name      string
+		principal *Principal
+		action    Action
+		want      error // nil, or the type of error expected
+	}{
+		{"no principal", nil, ActionReadAccounts, &apperror.ErrUnauthenticated{}},
+		{"role and scope", &Principal{Role: RoleOperator, Scopes: []Scope{ScopeTransfersWrite}}, ActionTransfer, nil},
+		{"missing scope", &Principal{Role: RoleAdmin, Scopes: []Scope{ScopeAccountsRead}}, ActionTransfer, &apperror.ErrForbidden{}},
+		{"role without the action", &Principal{Role: RoleOperator, Scopes: []Scope{ScopeTransfersWrite}}, ActionReverse, &apperror.ErrNotPermitted{}},
+		{"admin scope covers the others", &Principal{Role: RoleApprover, Scopes: []Scope{ScopeAdmin}}, ActionChangeStatus, nil},
+		{"admin scope does not raise the role", &Principal{Role: RoleViewer, Scopes: []Scope{ScopeAdmin}}, ActionTransfer, &apperror.ErrNotPermitted{}},
+		{"admin-only action", &Principal{Role: RoleAdmin, Scopes: []Scope{ScopeAccountsWrite, ScopeTransfersWrite}}, ActionManageFees, &apperror.ErrForbidden{}},
+		{"unknown role", &Principal{Role: "auditor", Scopes: []Scope{ScopeAccountsRead}}, ActionReadAccounts, &apperror.ErrNotPermitted{}},
+		{"anonymous", Anonymous, ActionManageWebhooks, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Authorize(tt.principal, tt.action)
+			switch want := tt.want.(type) {
+			case nil:
+				if err != nil {
+					t.Errorf("Authorize = %v, want nil", err)
+				}
+			case *apperror.ErrUnauthenticated:
+				if !errors.As(err, &want) {
+					t.Errorf("Authorize = %v, want unauthenticated", err)
+				}
+			case *apperror.ErrForbidden:
+				if !errors.As(err, &want) || want.Scope != string(ScopeFor(tt.action)) {
+					t.Errorf("Authorize = %v, want forbidden for lack of %s", err, ScopeFor(tt.action))
+				}
+			case *apperror.ErrNotPermitted:
+				if !errors.As(err, &want) || want.Role != string(tt.principal.Role) || want.Action != string(tt.action) {
+					t.Errorf("Authorize = %v, want %s not permitted to %s", err, tt.principal.Role, tt.action)
+				}
+			}
+		})
+	}
+}
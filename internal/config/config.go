@@ -3,8 +3,12 @@ package config
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/InternalTransfer/internal/auth"
 	"github.com/InternalTransfer/internal/database"
 )
 
@@ -15,8 +19,87 @@ type App struct {
 	ServerPort        int
 	DB                database.Config
 	MaxTransferAmount int64
+	Holds             Holds
+	Approvals         Approvals
+	FX                FX
+	Outbox            Outbox
+	Webhooks          Webhooks
+	Auth              Auth
+	SchedulerInterval time.Duration
+	SnapshotInterval  time.Duration
+	// FeeAccountID collects transfer fees; zero disables fees.
+	FeeAccountID int64
+	// AccountIDCheckDigit appends a Luhn check digit to generated account IDs
+	// and rejects account IDs without a valid one.
+	AccountIDCheckDigit bool
 }
 
+type Holds struct {
+	DefaultTTL    time.Duration
+	MaxTTL        time.Duration
+	SweepInterval time.Duration
+}
+
+// Approvals configures maker-checker approval. Transfers above Threshold
+// wait for a second principal and expire after TTL; HoldFunds reserves their
+// amount meanwhile. A zero Threshold disables approvals.
+type Approvals struct {
+	Threshold     int64
+	TTL           time.Duration
+	HoldFunds     bool
+	SweepInterval time.Duration
+}
+
+// FX configures the rate provider used for quotes. Provider is "static",
+// which serves StaticRates ("USD:EUR=0.92,EUR:USD=1.087"), or "http", which
+// queries HTTPURL.
+type FX struct {
+	Provider    string
+	StaticRates string
+	HTTPURL     string
+	HTTPTimeout time.Duration
+	QuoteTTL    time.Duration
+}
+
+// Outbox configures the relay publishing outbox events. Sinks names where
+// each event goes: "webhooks" (the subscriptions managed through the API),
+// "stdout", "file" (NDJSON appended to FilePath) or "http" (POSTed to
+// HTTPURL). Events failing MaxAttempts times are dead-lettered.
+type Outbox struct {
+	Sinks         []string
+	FilePath      string
+	HTTPURL       string
+	RelayInterval time.Duration
+	MaxAttempts   int
+}
+
+// Webhooks configures delivery to webhook subscriptions. A delivery is given
+// up after MaxAttempts, and a subscription failing DisableAfter deliveries
+// in a row is disabled.
+type Webhooks struct {
+	Interval     time.Duration
+	Timeout      time.Duration
+	MaxAttempts  int
+	DisableAfter int
+}
+
+// Auth configures request authentication. With Enabled unset every request
+// is served as an anonymous admin, which is only meant for local development.
+type Auth struct {
+	Enabled bool
+	// JWKS is the file path or URL of the keys that sign end-user tokens;
+	// empty accepts API keys only.
+	JWKS          string
+	JWKSRefresh   time.Duration
+	JWTIssuer     string
+	JWTAudience   string
+	JWTOwnerClaim string
+	JWTRole       auth.Role
+	JWTScopes     []auth.Scope
+}
+
+var outboxSinks = []string{"webhooks", "stdout", "file", "http"}
+
 func Load() (App, error) {
 	env := getEnv("APP_ENV", "development")
 
@@ -25,22 +108,238 @@ func Load() (App, error) {
 		return App{}, fmt.Errorf("invalid SERVER_PORT: %w", err)
 	}
 
-	dbPort, err := getEnvInt("DB_PORT", 5432)
+	db, err := LoadDB()
+	if err != nil {
+		return App{}, err
+	}
+
+	holdTTL, err := getEnvDuration("HOLD_DEFAULT_TTL", 24*time.Hour)
+	if err != nil || holdTTL < time.Second {
+		return App{}, fmt.Errorf("invalid HOLD_DEFAULT_TTL %q: must be at least 1s", os.Getenv("HOLD_DEFAULT_TTL"))
+	}
+
+	holdMaxTTL, err := getEnvDuration("HOLD_MAX_TTL", 30*24*time.Hour)
+	if err != nil || holdMaxTTL < time.Second {
+		return App{}, fmt.Errorf("invalid HOLD_MAX_TTL %q: must be at least 1s", os.Getenv("HOLD_MAX_TTL"))
+	}
+	if holdTTL > holdMaxTTL {
+		return App{}, fmt.Errorf("HOLD_DEFAULT_TTL %s exceeds HOLD_MAX_TTL %s", holdTTL, holdMaxTTL)
+	}
+
+	holdSweep, err := getEnvDuration("HOLD_SWEEP_INTERVAL", time.Minute)
+	if err != nil || holdSweep <= 0 {
+		return App{}, fmt.Errorf("invalid HOLD_SWEEP_INTERVAL %q", os.Getenv("HOLD_SWEEP_INTERVAL"))
+	}
+
+	approvalThreshold, err := strconv.ParseInt(getEnv("APPROVAL_THRESHOLD", "0"), 10, 64)
+	if err != nil || approvalThreshold < 0 || approvalThreshold >= DefaultMaxTransferAmount {
+		return App{}, fmt.Errorf("invalid APPROVAL_THRESHOLD %q: must be between 0 and %d", os.Getenv("APPROVAL_THRESHOLD"), DefaultMaxTransferAmount-1)
+	}
+
+	approvalTTL, err := getEnvDuration("APPROVAL_TTL", 24*time.Hour)
+	if err != nil || approvalTTL <= 0 {
+		return App{}, fmt.Errorf("invalid APPROVAL_TTL %q", os.Getenv("APPROVAL_TTL"))
+	}
+
+	approvalHoldFunds, err := strconv.ParseBool(getEnv("APPROVAL_HOLD_FUNDS", "true"))
 	if err != nil {
-		return App{}, fmt.Errorf("invalid DB_PORT: %w", err)
+		return App{}, fmt.Errorf("invalid APPROVAL_HOLD_FUNDS: %w", err)
+	}
+
+	approvalSweep, err := getEnvDuration("APPROVAL_SWEEP_INTERVAL", time.Minute)
+	if err != nil || approvalSweep <= 0 {
+		return App{}, fmt.Errorf("invalid APPROVAL_SWEEP_INTERVAL %q", os.Getenv("APPROVAL_SWEEP_INTERVAL"))
+	}
+
+	fxProvider := getEnv("FX_PROVIDER", "static")
+	if fxProvider != "static" && fxProvider != "http" {
+		return App{}, fmt.Errorf("invalid FX_PROVIDER %q: must be static or http", fxProvider)
+	}
+	fxURL := getEnv("FX_HTTP_URL", "")
+	if fxProvider == "http" && fxURL == "" {
+		return App{}, fmt.Errorf("FX_HTTP_URL is required when FX_PROVIDER is http")
+	}
+
+	fxTimeout, err := getEnvDuration("FX_HTTP_TIMEOUT", 5*time.Second)
+	if err != nil || fxTimeout <= 0 {
+		return App{}, fmt.Errorf("invalid FX_HTTP_TIMEOUT %q", os.Getenv("FX_HTTP_TIMEOUT"))
+	}
+
+	quoteTTL, err := getEnvDuration("FX_QUOTE_TTL", 30*time.Second)
+	if err != nil || quoteTTL <= 0 {
+		return App{}, fmt.Errorf("invalid FX_QUOTE_TTL %q", os.Getenv("FX_QUOTE_TTL"))
+	}
+
+	schedulerInterval, err := getEnvDuration("SCHEDULER_INTERVAL", 30*time.Second)
+	if err != nil || schedulerInterval <= 0 {
+		return App{}, fmt.Errorf("invalid SCHEDULER_INTERVAL %q", os.Getenv("SCHEDULER_INTERVAL"))
+	}
+
+	snapshotInterval, err := getEnvDuration("BALANCE_SNAPSHOT_INTERVAL", time.Hour)
+	if err != nil || snapshotInterval <= 0 {
+		return App{}, fmt.Errorf("invalid BALANCE_SNAPSHOT_INTERVAL %q", os.Getenv("BALANCE_SNAPSHOT_INTERVAL"))
+	}
+
+	var sinks []string
+	for _, name := range strings.Split(getEnv("OUTBOX_SINKS", "webhooks"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(outboxSinks, name) {
+			return App{}, fmt.Errorf("invalid OUTBOX_SINKS entry %q: must be one of %s", name, strings.Join(outboxSinks, ", "))
+		}
+		sinks = append(sinks, name)
+	}
+	outboxFile := getEnv("OUTBOX_FILE_PATH", "")
+	if slices.Contains(sinks, "file") && outboxFile == "" {
+		return App{}, fmt.Errorf("OUTBOX_FILE_PATH is required when OUTBOX_SINKS includes file")
+	}
+	outboxURL := getEnv("OUTBOX_HTTP_URL", "")
+	if slices.Contains(sinks, "http") && outboxURL == "" {
+		return App{}, fmt.Errorf("OUTBOX_HTTP_URL is required when OUTBOX_SINKS includes http")
+	}
+
+	relayInterval, err := getEnvDuration("OUTBOX_RELAY_INTERVAL", time.Second)
+	if err != nil || relayInterval <= 0 {
+		return App{}, fmt.Errorf("invalid OUTBOX_RELAY_INTERVAL %q", os.Getenv("OUTBOX_RELAY_INTERVAL"))
+	}
+
+	outboxAttempts, err := getEnvInt("OUTBOX_MAX_ATTEMPTS", 10)
+	if err != nil || outboxAttempts < 1 {
+		return App{}, fmt.Errorf("invalid OUTBOX_MAX_ATTEMPTS %q", os.Getenv("OUTBOX_MAX_ATTEMPTS"))
+	}
+
+	webhookInterval, err := getEnvDuration("WEBHOOK_DELIVERY_INTERVAL", time.Second)
+	if err != nil || webhookInterval <= 0 {
+		return App{}, fmt.Errorf("invalid WEBHOOK_DELIVERY_INTERVAL %q", os.Getenv("WEBHOOK_DELIVERY_INTERVAL"))
+	}
+
+	webhookTimeout, err := getEnvDuration("WEBHOOK_TIMEOUT", 10*time.Second)
+	if err != nil || webhookTimeout <= 0 {
+		return App{}, fmt.Errorf("invalid WEBHOOK_TIMEOUT %q", os.Getenv("WEBHOOK_TIMEOUT"))
+	}
+
+	webhookAttempts, err := getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8)
+	if err != nil || webhookAttempts < 1 {
+		return App{}, fmt.Errorf("invalid WEBHOOK_MAX_ATTEMPTS %q", os.Getenv("WEBHOOK_MAX_ATTEMPTS"))
+	}
+
+	webhookDisableAfter, err := getEnvInt("WEBHOOK_DISABLE_AFTER", 20)
+	if err != nil || webhookDisableAfter < 1 {
+		return App{}, fmt.Errorf("invalid WEBHOOK_DISABLE_AFTER %q", os.Getenv("WEBHOOK_DISABLE_AFTER"))
+	}
+
+	// authentication is opt-in so that upgrading does not lock out clients
+	// of releases without it before they have been given keys
+	authEnabled, err := strconv.ParseBool(getEnv("AUTH_ENABLED", "false"))
+	if err != nil {
+		return App{}, fmt.Errorf("invalid AUTH_ENABLED: %w", err)
+	}
+	// without authentication every request is the same principal, so
+	// nobody could approve a transfer
+	if approvalThreshold > 0 && !authEnabled {
+		return App{}, fmt.Errorf("APPROVAL_THRESHOLD requires AUTH_ENABLED")
+	}
+
+	jwksRefresh, err := getEnvDuration("JWT_JWKS_REFRESH_INTERVAL", time.Hour)
+	if err != nil || jwksRefresh <= 0 {
+		return App{}, fmt.Errorf("invalid JWT_JWKS_REFRESH_INTERVAL %q", os.Getenv("JWT_JWKS_REFRESH_INTERVAL"))
+	}
+
+	jwtRole := auth.Role(getEnv("JWT_ROLE", string(auth.RoleOperator)))
+	if !auth.ValidRole(jwtRole) {
+		return App{}, fmt.Errorf("invalid JWT_ROLE %q", jwtRole)
+	}
+
+	var jwtScopes []auth.Scope
+	for _, name := range strings.Split(getEnv("JWT_SCOPES", "accounts:read,transfers:write"), ",") {
+		scope := auth.Scope(strings.TrimSpace(name))
+		if scope == "" {
+			continue
+		}
+		if !auth.ValidScope(scope) || scope == auth.ScopeAdmin {
+			return App{}, fmt.Errorf("invalid JWT_SCOPES entry %q", scope)
+		}
+		jwtScopes = append(jwtScopes, scope)
+	}
+
+	feeAccountID, err := strconv.ParseInt(getEnv("FEE_COLLECTION_ACCOUNT_ID", "0"), 10, 64)
+	if err != nil || feeAccountID < 0 {
+		return App{}, fmt.Errorf("invalid FEE_COLLECTION_ACCOUNT_ID %q", os.Getenv("FEE_COLLECTION_ACCOUNT_ID"))
+	}
+
+	checkDigit, err := strconv.ParseBool(getEnv("ACCOUNT_ID_CHECK_DIGIT", "false"))
+	if err != nil {
+		return App{}, fmt.Errorf("invalid ACCOUNT_ID_CHECK_DIGIT: %w", err)
 	}
 
 	return App{
 		Env:               env,
 		ServerPort:        port,
 		MaxTransferAmount: DefaultMaxTransferAmount,
-		DB: database.Config{
-			Host:     getEnv("DB_HOST", "localhost"),
-			Port:     dbPort,
-			User:     getEnv("DB_USER", "postgres"),
-			Password: getEnv("DB_PASSWORD", "postgres"),
-			DBName:   getEnv("DB_NAME", "transaction_manager"),
+		Holds: Holds{
+			DefaultTTL:    holdTTL,
+			MaxTTL:        holdMaxTTL,
+			SweepInterval: holdSweep,
+		},
+		Approvals: Approvals{
+			Threshold:     approvalThreshold,
+			TTL:           approvalTTL,
+			HoldFunds:     approvalHoldFunds,
+			SweepInterval: approvalSweep,
 		},
+		FX: FX{
+			Provider:    fxProvider,
+			StaticRates: getEnv("FX_STATIC_RATES", ""),
+			HTTPURL:     fxURL,
+			HTTPTimeout: fxTimeout,
+			QuoteTTL:    quoteTTL,
+		},
+		Outbox: Outbox{
+			Sinks:         sinks,
+			FilePath:      outboxFile,
+			HTTPURL:       outboxURL,
+			RelayInterval: relayInterval,
+			MaxAttempts:   outboxAttempts,
+		},
+		Webhooks: Webhooks{
+			Interval:     webhookInterval,
+			Timeout:      webhookTimeout,
+			MaxAttempts:  webhookAttempts,
+			DisableAfter: webhookDisableAfter,
+		},
+		Auth: Auth{
+			Enabled:       authEnabled,
+			JWKS:          getEnv("JWT_JWKS", ""),
+			JWKSRefresh:   jwksRefresh,
+			JWTIssuer:     getEnv("JWT_ISSUER", ""),
+			JWTAudience:   getEnv("JWT_AUDIENCE", ""),
+			JWTOwnerClaim: getEnv("JWT_OWNER_CLAIM", "sub"),
+			JWTRole:       jwtRole,
+			JWTScopes:     jwtScopes,
+		},
+		SchedulerInterval:   schedulerInterval,
+		SnapshotInterval:    snapshotInterval,
+		FeeAccountID:        feeAccountID,
+		AccountIDCheckDigit: checkDigit,
+		DB:                  db,
+	}, nil
+}
+
+// LoadDB reads only the database settings, for commands that need nothing
+// else and should not fail on the server's configuration.
+func LoadDB() (database.Config, error) {
+	port, err := getEnvInt("DB_PORT", 5432)
+	if err != nil {
+		return database.Config{}, fmt.Errorf("invalid DB_PORT: %w", err)
+	}
+	return database.Config{
+		Host:     getEnv("DB_HOST", "localhost"),
+		Port:     port,
+		User:     getEnv("DB_USER", "postgres"),
+		Password: getEnv("DB_PASSWORD", "postgres"),
+		DBName:   getEnv("DB_NAME", "transaction_manager"),
 	}, nil
 }
 
@@ -58,3 +357,11 @@ func getEnvInt(key string, fallback int) (int, error) {
 	}
 	return strconv.Atoi(v)
 }
+
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	return time.ParseDuration(v)
+}
@@ -0,0 +1,47 @@
+// Package currency holds the ISO 4217 currencies the ledger accepts and the
+// number of minor units (decimal places) each one uses.
+package currency
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Default is used for accounts created without an explicit currency.
+const Default = "USD"
+
+var minorUnits = map[string]int32{
+	"AED": 2, "AUD": 2, "BHD": 3, "BRL": 2, "CAD": 2, "CHF": 2, "CLP": 0,
+	"CNY": 2, "CZK": 2, "DKK": 2, "EUR": 2, "GBP": 2, "HKD": 2, "HUF": 2,
+	"IDR": 2, "ILS": 2, "INR": 2, "ISK": 0, "JOD": 3, "JPY": 0, "KRW": 0,
+	"KWD": 3, "MXN": 2, "MYR": 2, "NOK": 2, "NZD": 2, "OMR": 3, "PHP": 2,
+	"PLN": 2, "SAR": 2, "SEK": 2, "SGD": 2, "THB": 2, "TND": 3, "TRY": 2,
+	"TWD": 2, "UGX": 0, "USD": 2, "VND": 0, "ZAR": 2,
+}
+
+// Normalize upper-cases a currency code.
+func Normalize(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+func IsSupported(code string) bool {
+	_, ok := minorUnits[code]
+	return ok
+}
+
+// MinorUnits returns the number of decimal places used by code.
+func MinorUnits(code string) int32 {
+	return minorUnits[code]
+}
+
+// HasValidPrecision reports whether amount uses no more decimal places than
+// the currency allows.
+func HasValidPrecision(amount decimal.Decimal, code string) bool {
+	return amount.Exponent() >= -MinorUnits(code)
+}
+
+// Round rounds amount to the currency's minor unit.
+func Round(amount decimal.Decimal, code string) decimal.Decimal {
+	return amount.Round(MinorUnits(code))
+}
@@ -0,0 +1,46 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Listener receives Postgres notifications. Each Listen call takes a
+// connection out of the pool for as long as it runs.
+type Listener struct {
+	pool *pgxpool.Pool
+}
+
+func NewListener(pool *pgxpool.Pool) *Listener {
+	return &Listener{pool: pool}
+}
+
+// Listen subscribes to channel, calls ready once notifications are being
+// received and then fn for each payload. It returns when ctx is cancelled
+// or the connection fails; notifications sent while no one listens are lost.
+func (l *Listener) Listen(ctx context.Context, channel string, ready func(), fn func(payload string)) error {
+	pooled, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("acquiring listener connection: %w", err)
+	}
+	// the connection keeps listening until closed, so it must not go back to
+	// the pool
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return fmt.Errorf("listening on %s: %w", channel, err)
+	}
+	ready()
+
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("waiting for notification: %w", err)
+		}
+		fn(n.Payload)
+	}
+}
@@ -1,24 +1,400 @@
 package dto
 
-import "github.com/shopspring/decimal"
+import (
+	"time"
 
+	"github.com/shopspring/decimal"
+)
+
+// CreateAccountRequest opens an account. Omit AccountID to have the server
+// allocate one.
 type CreateAccountRequest struct {
-	AccountID      int64           `json:"account_id"`
+	AccountID      int64           `json:"account_id,omitempty"`
 	InitialBalance decimal.Decimal `json:"initial_balance"`
+	Currency       string          `json:"currency,omitempty"`
+	AccountType    string          `json:"account_type,omitempty"`
+	OwnerID        *string         `json:"owner_id,omitempty"`
+	Name           *string         `json:"name,omitempty"`
+	Metadata       map[string]any  `json:"metadata,omitempty"`
+}
+
+// UpdateAccountRequest changes the fields that are present. An empty
+// owner_id or name clears it; metadata is merged into the stored map, and a
+// key set to null removes it.
+type UpdateAccountRequest struct {
+	OwnerID     *string        `json:"owner_id,omitempty"`
+	Name        *string        `json:"name,omitempty"`
+	AccountType *string        `json:"account_type,omitempty"`
+	Metadata    map[string]any `json:"metadata,omitempty"`
 }
 
 type AccountResponse struct {
+	AccountID        int64           `json:"account_id"`
+	Currency         string          `json:"currency"`
+	AccountType      string          `json:"account_type"`
+	Status           string          `json:"status"`
+	OwnerID          *string         `json:"owner_id,omitempty"`
+	Name             *string         `json:"name,omitempty"`
+	Metadata         map[string]any  `json:"metadata"`
+	Balance          decimal.Decimal `json:"balance"`
+	AvailableBalance decimal.Decimal `json:"available_balance"`
+}
+
+type BalanceResponse struct {
+	AccountID int64           `json:"account_id"`
+	Currency  string          `json:"currency"`
+	Balance   decimal.Decimal `json:"balance"`
+	AsOf      time.Time       `json:"as_of"`
+}
+
+// AccountTransactionEvent is the data of a "transaction" event on an account
+// event stream: one ledger entry of the account and its balance after it.
+type AccountTransactionEvent struct {
+	TransactionID         int64           `json:"transaction_id"`
+	Kind                  string          `json:"kind"`
+	Direction             string          `json:"direction"`
+	Amount                decimal.Decimal `json:"amount"`
+	Currency              string          `json:"currency"`
+	CounterpartyAccountID int64           `json:"counterparty_account_id"`
+	Balance               decimal.Decimal `json:"balance"`
+	CreatedAt             time.Time       `json:"created_at"`
+}
+
+// StatementBalanceResponse is the opening or closing balance record of an
+// ndjson statement.
+type StatementBalanceResponse struct {
+	Type      string          `json:"type"`
 	AccountID int64           `json:"account_id"`
+	Currency  string          `json:"currency"`
+	AsOf      time.Time       `json:"as_of"`
 	Balance   decimal.Decimal `json:"balance"`
 }
 
+type StatementLineResponse struct {
+	TransactionID         int64           `json:"transaction_id"`
+	Kind                  string          `json:"kind"`
+	Direction             string          `json:"direction"`
+	Amount                decimal.Decimal `json:"amount"`
+	CounterpartyAccountID int64           `json:"counterparty_account_id"`
+	Balance               decimal.Decimal `json:"balance"`
+	CreatedAt             time.Time       `json:"created_at"`
+}
+
+type AccountListResponse struct {
+	Accounts   []AccountResponse `json:"accounts"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// UpdateAccountStatusRequest moves an account to Status. Closing an account
+// that still has a balance requires SweepToAccountID.
+type UpdateAccountStatusRequest struct {
+	Status           string `json:"status"`
+	Reason           string `json:"reason"`
+	ChangedBy        string `json:"changed_by"`
+	SweepToAccountID *int64 `json:"sweep_to_account_id,omitempty"`
+}
+
+type AccountStatusChangeResponse struct {
+	ID                 int64     `json:"id"`
+	AccountID          int64     `json:"account_id"`
+	FromStatus         string    `json:"from_status"`
+	ToStatus           string    `json:"to_status"`
+	ChangedBy          string    `json:"changed_by"`
+	Reason             string    `json:"reason"`
+	SweepTransactionID *int64    `json:"sweep_transaction_id,omitempty"`
+	CreatedAt          time.Time `json:"created_at"`
+}
+
+type UpdateAccountStatusResponse struct {
+	Account AccountResponse             `json:"account"`
+	Change  AccountStatusChangeResponse `json:"change"`
+}
+
+// CreateTransactionRequest moves Amount, in Currency, out of the source
+// account. When the destination holds another currency exactly one of FXRate
+// or FXQuoteID is required.
 type CreateTransactionRequest struct {
+	SourceAccountID      int64            `json:"source_account_id"`
+	DestinationAccountID int64            `json:"destination_account_id"`
+	Amount               decimal.Decimal  `json:"amount"`
+	Currency             string           `json:"currency"`
+	FXRate               *decimal.Decimal `json:"fx_rate,omitempty"`
+	FXQuoteID            *int64           `json:"fx_quote_id,omitempty"`
+}
+
+type BatchTransferRequest struct {
+	Legs []CreateTransactionRequest `json:"legs"`
+}
+
+// BatchTransferResponse lists one transaction per leg, in request order.
+type BatchTransferResponse struct {
+	Transactions []TransactionResponse `json:"transactions"`
+}
+
+type TransactionResponse struct {
+	ID                   int64                `json:"id"`
+	Kind                 string               `json:"kind"`
+	SourceAccountID      int64                `json:"source_account_id"`
+	DestinationAccountID int64                `json:"destination_account_id"`
+	Amount               decimal.Decimal      `json:"amount"`
+	Currency             string               `json:"currency"`
+	DestinationAmount    decimal.Decimal      `json:"destination_amount"`
+	DestinationCurrency  string               `json:"destination_currency"`
+	FXRate               decimal.NullDecimal  `json:"fx_rate"`
+	FXQuoteID            *int64               `json:"fx_quote_id,omitempty"`
+	FeeOf                *int64               `json:"fee_of,omitempty"`
+	SourceBalanceAfter   decimal.NullDecimal  `json:"source_balance_after"`
+	ReversalOf           *int64               `json:"reversal_of,omitempty"`
+	CreatedAt            time.Time            `json:"created_at"`
+	Fee                  *TransactionResponse `json:"fee,omitempty"`
+}
+
+// ReverseTransactionRequest reverses the remaining amount when Amount is omitted.
+type ReverseTransactionRequest struct {
+	Amount *decimal.Decimal `json:"amount,omitempty"`
+}
+
+// ApprovalResponse is a transfer awaiting approval, or the record of the
+// decision on one. An approved transfer is the transaction with the same ID.
+type ApprovalResponse struct {
+	ID                   int64               `json:"id"`
+	SourceAccountID      int64               `json:"source_account_id"`
+	DestinationAccountID int64               `json:"destination_account_id"`
+	Amount               decimal.Decimal     `json:"amount"`
+	Currency             string              `json:"currency"`
+	FXRate               decimal.NullDecimal `json:"fx_rate"`
+	FundsHeld            bool                `json:"funds_held"`
+	Status               string              `json:"status"`
+	InitiatedBy          string              `json:"initiated_by"`
+	DecidedBy            *string             `json:"decided_by,omitempty"`
+	Reason               *string             `json:"reason,omitempty"`
+	ExpiresAt            time.Time           `json:"expires_at"`
+	DecidedAt            *time.Time          `json:"decided_at,omitempty"`
+	CreatedAt            time.Time           `json:"created_at"`
+}
+
+type RejectTransferRequest struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+type ApprovalListResponse struct {
+	Approvals  []ApprovalResponse `json:"approvals"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+type TransactionListResponse struct {
+	Transactions []TransactionResponse `json:"transactions"`
+	NextCursor   string                `json:"next_cursor,omitempty"`
+}
+
+type CreateHoldRequest struct {
+	AccountID        int64           `json:"account_id"`
+	Amount           decimal.Decimal `json:"amount"`
+	ExpiresInSeconds int64           `json:"expires_in_seconds,omitempty"`
+}
+
+// CaptureHoldRequest captures the full hold when Amount is omitted.
+type CaptureHoldRequest struct {
+	DestinationAccountID int64            `json:"destination_account_id"`
+	Amount               *decimal.Decimal `json:"amount,omitempty"`
+}
+
+type HoldResponse struct {
+	ID             int64               `json:"id"`
+	AccountID      int64               `json:"account_id"`
+	Amount         decimal.Decimal     `json:"amount"`
+	Status         string              `json:"status"`
+	CapturedAmount decimal.NullDecimal `json:"captured_amount"`
+	TransactionID  *int64              `json:"transaction_id,omitempty"`
+	ExpiresAt      time.Time           `json:"expires_at"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+type CaptureHoldResponse struct {
+	Hold        HoldResponse        `json:"hold"`
+	Transaction TransactionResponse `json:"transaction"`
+}
+
+type CreateFXQuoteRequest struct {
+	SourceCurrency      string `json:"source_currency"`
+	DestinationCurrency string `json:"destination_currency"`
+}
+
+type FXQuoteResponse struct {
+	ID                  int64           `json:"id"`
+	SourceCurrency      string          `json:"source_currency"`
+	DestinationCurrency string          `json:"destination_currency"`
+	Rate                decimal.Decimal `json:"rate"`
+	ExpiresAt           time.Time       `json:"expires_at"`
+	TransactionID       *int64          `json:"transaction_id,omitempty"`
+	CreatedAt           time.Time       `json:"created_at"`
+}
+
+// CreateScheduledTransferRequest takes exactly one of Cron or
+// IntervalSeconds. StartsAt defaults to now.
+type CreateScheduledTransferRequest struct {
+	SourceAccountID      int64           `json:"source_account_id"`
+	DestinationAccountID int64           `json:"destination_account_id"`
+	Amount               decimal.Decimal `json:"amount"`
+	Currency             string          `json:"currency"`
+	Cron                 *string         `json:"cron,omitempty"`
+	IntervalSeconds      *int64          `json:"interval_seconds,omitempty"`
+	StartsAt             *time.Time      `json:"starts_at,omitempty"`
+	EndsAt               *time.Time      `json:"ends_at,omitempty"`
+}
+
+// UpdateScheduledTransferRequest changes only the fields that are present.
+type UpdateScheduledTransferRequest struct {
+	Amount          *decimal.Decimal `json:"amount,omitempty"`
+	Cron            *string          `json:"cron,omitempty"`
+	IntervalSeconds *int64           `json:"interval_seconds,omitempty"`
+	EndsAt          *time.Time       `json:"ends_at,omitempty"`
+}
+
+type ScheduledTransferResponse struct {
+	ID                   int64           `json:"id"`
 	SourceAccountID      int64           `json:"source_account_id"`
 	DestinationAccountID int64           `json:"destination_account_id"`
 	Amount               decimal.Decimal `json:"amount"`
+	Currency             string          `json:"currency"`
+	Cron                 *string         `json:"cron,omitempty"`
+	IntervalSeconds      *int64          `json:"interval_seconds,omitempty"`
+	StartsAt             time.Time       `json:"starts_at"`
+	EndsAt               *time.Time      `json:"ends_at,omitempty"`
+	Status               string          `json:"status"`
+	NextRunAt            *time.Time      `json:"next_run_at"`
+	LastRunAt            *time.Time      `json:"last_run_at"`
+	CreatedAt            time.Time       `json:"created_at"`
+	UpdatedAt            time.Time       `json:"updated_at"`
+}
+
+type ScheduledTransferListResponse struct {
+	ScheduledTransfers []ScheduledTransferResponse `json:"scheduled_transfers"`
+	NextCursor         string                      `json:"next_cursor,omitempty"`
+}
+
+type ScheduledTransferRunResponse struct {
+	ID            int64     `json:"id"`
+	ScheduledFor  time.Time `json:"scheduled_for"`
+	Status        string    `json:"status"`
+	TransactionID *int64    `json:"transaction_id,omitempty"`
+	ErrorCode     *string   `json:"error_code,omitempty"`
+	ErrorMessage  *string   `json:"error_message,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type ScheduledTransferRunListResponse struct {
+	Runs       []ScheduledTransferRunResponse `json:"runs"`
+	NextCursor string                         `json:"next_cursor,omitempty"`
+}
+
+// AccountLimitsRequest replaces all of an account's limits; omitted limits
+// are removed.
+type AccountLimitsRequest struct {
+	MaxSingleAmount decimal.NullDecimal `json:"max_single_amount"`
+	DailyAmount     decimal.NullDecimal `json:"daily_amount"`
+	MonthlyAmount   decimal.NullDecimal `json:"monthly_amount"`
+	HourlyCount     *int                `json:"hourly_count"`
+}
+
+type AccountLimitsResponse struct {
+	AccountID       int64               `json:"account_id"`
+	MaxSingleAmount decimal.NullDecimal `json:"max_single_amount"`
+	DailyAmount     decimal.NullDecimal `json:"daily_amount"`
+	MonthlyAmount   decimal.NullDecimal `json:"monthly_amount"`
+	HourlyCount     *int                `json:"hourly_count"`
+	UpdatedAt       *time.Time          `json:"updated_at,omitempty"`
+}
+
+// FeePolicyRequest scopes a policy with either AccountType or both account
+// IDs. Percentages are in percent: "1.5" charges 1.5% of the amount.
+type FeePolicyRequest struct {
+	AccountType          *string             `json:"account_type,omitempty"`
+	SourceAccountID      *int64              `json:"source_account_id,omitempty"`
+	DestinationAccountID *int64              `json:"destination_account_id,omitempty"`
+	Currency             string              `json:"currency"`
+	FeeType              string              `json:"fee_type"`
+	FlatAmount           decimal.NullDecimal `json:"flat_amount"`
+	Percentage           decimal.NullDecimal `json:"percentage"`
+	Tiers                []FeeTier           `json:"tiers,omitempty"`
+	MinFee               decimal.NullDecimal `json:"min_fee"`
+	MaxFee               decimal.NullDecimal `json:"max_fee"`
+}
+
+type FeeTier struct {
+	UpTo       *decimal.Decimal `json:"up_to,omitempty"`
+	FlatAmount decimal.Decimal  `json:"flat_amount"`
+	Percentage decimal.Decimal  `json:"percentage"`
+}
+
+type FeePolicyResponse struct {
+	ID                   int64               `json:"id"`
+	AccountType          *string             `json:"account_type,omitempty"`
+	SourceAccountID      *int64              `json:"source_account_id,omitempty"`
+	DestinationAccountID *int64              `json:"destination_account_id,omitempty"`
+	Currency             string              `json:"currency"`
+	FeeType              string              `json:"fee_type"`
+	FlatAmount           decimal.NullDecimal `json:"flat_amount"`
+	Percentage           decimal.NullDecimal `json:"percentage"`
+	Tiers                []FeeTier           `json:"tiers,omitempty"`
+	MinFee               decimal.NullDecimal `json:"min_fee"`
+	MaxFee               decimal.NullDecimal `json:"max_fee"`
+	CreatedAt            time.Time           `json:"created_at"`
+	UpdatedAt            time.Time           `json:"updated_at"`
+}
+
+type FeePolicyListResponse struct {
+	FeePolicies []FeePolicyResponse `json:"fee_policies"`
+}
+
+// CreateWebhookRequest subscribes URL to events. Empty event types or
+// account IDs match every event or account.
+type CreateWebhookRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	AccountIDs []int64  `json:"account_ids"`
+}
+
+type WebhookResponse struct {
+	ID                  int64      `json:"id"`
+	URL                 string     `json:"url"`
+	EventTypes          []string   `json:"event_types"`
+	AccountIDs          []int64    `json:"account_ids"`
+	Status              string     `json:"status"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	// Secret signs deliveries. It is only returned when the subscription is
+	// created.
+	Secret string `json:"secret,omitempty"`
+}
+
+type WebhookListResponse struct {
+	Webhooks []WebhookResponse `json:"webhooks"`
+}
+
+type WebhookDeliveryResponse struct {
+	ID             int64      `json:"id"`
+	EventID        int64      `json:"event_id"`
+	EventType      string     `json:"event_type"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	ResponseStatus *int       `json:"response_status,omitempty"`
+	LastError      *string    `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+	NextCursor string                    `json:"next_cursor,omitempty"`
 }
 
 type ErrorResponse struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
+	Code    string         `json:"code"`
+	Message string         `json:"message"`
+	Details map[string]any `json:"details,omitempty"`
 }
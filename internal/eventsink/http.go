@@ -0,0 +1,52 @@
+package eventsink
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/InternalTransfer/internal/model"
+)
+
+// HTTP POSTs each event as JSON to a fixed URL. Any 2xx response counts as
+// delivered; everything else is retried by the relay.
+type HTTP struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTP(url string, client *http.Client) *HTTP {
+	return &HTTP{url: url, client: client}
+}
+
+func (h *HTTP) Name() string {
+	return "http"
+}
+
+func (h *HTTP) Publish(ctx context.Context, e *model.OutboxEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.url, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("building event request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("posting event: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("event endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package eventsink
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Webhook deliveries carry these headers. The signature is "v1=" followed by
+// the hex HMAC-SHA256, keyed with the subscription secret, of the timestamp
+// header, a '.', and the raw request body.
+const (
+	HeaderEventID    = "X-Webhook-Event-Id"
+	HeaderDeliveryID = "X-Webhook-Delivery-Id"
+	HeaderEventType  = "X-Webhook-Event-Type"
+	HeaderTimestamp  = "X-Webhook-Timestamp"
+	HeaderSignature  = "X-Webhook-Signature"
+)
+
+const signatureVersion = "v1="
+
+// Sign returns the signature header value for body sent at ts.
+func Sign(secret string, ts time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(ts.Unix(), 10)))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return signatureVersion + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the timestamp and signature headers of a received delivery
+// against its body. Deliveries whose timestamp is more than tolerance away
+// from now are rejected so that captured requests cannot be replayed later.
+func Verify(secret, timestamp, signature string, body []byte, tolerance time.Duration) bool {
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	ts := time.Unix(sec, 0)
+	if d := time.Since(ts); d > tolerance || d < -tolerance {
+		return false
+	}
+	if !strings.HasPrefix(signature, signatureVersion) {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, ts, body)))
+}
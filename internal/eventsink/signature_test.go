@@ -0,0 +1,69 @@
+package eventsink
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"id":1,"type":"transaction.posted"}`)
+	now := time.Now()
+	ts := strconv.FormatInt(now.Unix(), 10)
+	sig := Sign(secret, now, body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      []byte
+		want      bool
+	}{
+		{"valid", secret, ts, sig, body, true},
+		{"wrong secret", "whsec_other", ts, sig, body, false},
+		{"tampered body", secret, ts, sig, []byte(`{"id":2,"type":"transaction.posted"}`), false},
+		{"timestamp not signed", secret, strconv.FormatInt(now.Unix()-1, 10), sig, body, false},
+		{"malformed timestamp", secret, "yesterday", sig, body, false},
+		{"empty timestamp", secret, "", sig, body, false},
+		{"missing version", secret, ts, strings.TrimPrefix(sig, "v1="), body, false},
+		{"unknown version", secret, ts, "v2=" + strings.TrimPrefix(sig, "v1="), body, false},
+		{"empty signature", secret, ts, "", body, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.secret, tt.timestamp, tt.signature, tt.body, 5*time.Minute); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyRejectsStaleTimestamps(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{}`)
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		want   bool
+	}{
+		{"just inside the past tolerance", -4 * time.Minute, true},
+		{"past the tolerance", -6 * time.Minute, false},
+		{"just inside the future tolerance", 4 * time.Minute, true},
+		{"beyond the future tolerance", 6 * time.Minute, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := time.Now().Add(tt.offset)
+			got := Verify(secret, strconv.FormatInt(ts.Unix(), 10), Sign(secret, ts, body), body, 5*time.Minute)
+			if got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+// Package eventsink publishes outbox events to destinations outside the
+// database. Every sink receives the JSON form of model.OutboxEvent.
+package eventsink
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/InternalTransfer/internal/model"
+)
+
+// Writer appends each event as one line of JSON (NDJSON) to w.
+type Writer struct {
+	name string
+	mu   sync.Mutex
+	w    io.Writer
+	// closer is the file opened by NewFile, closed by Close.
+	closer io.Closer
+}
+
+func NewWriter(name string, w io.Writer) *Writer {
+	return &Writer{name: name, w: w}
+}
+
+// NewStdout writes events to standard output.
+func NewStdout() *Writer {
+	return NewWriter("stdout", os.Stdout)
+}
+
+// NewFile appends events to the file at path, creating it if needed. The
+// file stays open until Close.
+func NewFile(path string) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("opening event file: %w", err)
+	}
+	w := NewWriter("file", f)
+	w.closer = f
+	return w, nil
+}
+
+func (w *Writer) Name() string {
+	return w.name
+}
+
+// Close closes the file opened by NewFile. Other writers are left open, as
+// they belong to the caller.
+func (w *Writer) Close() error {
+	if w.closer == nil {
+		return nil
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closer.Close()
+}
+
+func (w *Writer) Publish(_ context.Context, e *model.OutboxEvent) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("encoding event: %w", err)
+	}
+	b = append(b, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.w.Write(b); err != nil {
+		return fmt.Errorf("writing event: %w", err)
+	}
+	return nil
+}
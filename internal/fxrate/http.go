@@ -0,0 +1,73 @@
+package fxrate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/apperror"
+)
+
+// HTTP fetches rates from a JSON endpoint:
+//
+//	GET {baseURL}?from=USD&to=EUR  ->  {"rate": "0.92"}
+//
+// A 404 response means the pair is not quoted.
+type HTTP struct {
+	baseURL string
+	client  *http.Client
+}
+
+func NewHTTP(baseURL string, client *http.Client) *HTTP {
+	return &HTTP{baseURL: baseURL, client: client}
+}
+
+type rateResponse struct {
+	Rate decimal.Decimal `json:"rate"`
+}
+
+func (h *HTTP) Rate(ctx context.Context, from, to string) (decimal.Decimal, error) {
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+
+	u, err := url.Parse(h.baseURL)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("parsing rate provider URL: %w", err)
+	}
+	q := u.Query()
+	q.Set("from", from)
+	q.Set("to", to)
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("building rate request: %w", err)
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("requesting rate: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return decimal.Zero, &apperror.ErrRateUnavailable{SourceCurrency: from, DestinationCurrency: to}
+	}
+	if resp.StatusCode != http.StatusOK {
+		return decimal.Zero, fmt.Errorf("rate provider returned status %d", resp.StatusCode)
+	}
+
+	var body rateResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return decimal.Zero, fmt.Errorf("decoding rate response: %w", err)
+	}
+	if !body.Rate.IsPositive() {
+		return decimal.Zero, fmt.Errorf("rate provider returned non-positive rate %s", body.Rate)
+	}
+	return body.Rate, nil
+}
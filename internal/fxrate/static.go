@@ -0,0 +1,65 @@
+// Package fxrate contains implementations of service.RateProvider.
+package fxrate
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/currency"
+)
+
+// Static serves rates from a fixed table, typically loaded from config.
+type Static struct {
+	rates map[string]decimal.Decimal
+}
+
+// ParseStatic builds a Static provider from a spec such as
+// "USD:EUR=0.92,EUR:USD=1.087". Each entry gives the number of units of the
+// second currency bought by one unit of the first. Inverse rates are not
+// derived: EUR:USD is only served if it is listed too.
+func ParseStatic(spec string) (*Static, error) {
+	rates := make(map[string]decimal.Decimal)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pair, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("rate %q: expected FROM:TO=RATE", entry)
+		}
+		from, to, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, fmt.Errorf("rate %q: expected FROM:TO=RATE", entry)
+		}
+		from, to = currency.Normalize(from), currency.Normalize(to)
+		if !currency.IsSupported(from) || !currency.IsSupported(to) || from == to {
+			return nil, fmt.Errorf("rate %q: expected two different supported currencies", entry)
+		}
+		rate, err := decimal.NewFromString(strings.TrimSpace(value))
+		if err != nil || !rate.IsPositive() {
+			return nil, fmt.Errorf("rate %q: invalid rate", entry)
+		}
+		rates[pairKey(from, to)] = rate
+	}
+	return &Static{rates: rates}, nil
+}
+
+func (s *Static) Rate(_ context.Context, from, to string) (decimal.Decimal, error) {
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+	rate, ok := s.rates[pairKey(from, to)]
+	if !ok {
+		return decimal.Zero, &apperror.ErrRateUnavailable{SourceCurrency: from, DestinationCurrency: to}
+	}
+	return rate, nil
+}
+
+func pairKey(from, to string) string {
+	return strings.ToUpper(strings.TrimSpace(from)) + ":" + strings.ToUpper(strings.TrimSpace(to))
+}
@@ -0,0 +1,87 @@
+package fxrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/apperror"
+)
+
+func TestParseStaticRejectsMalformedSpecs(t *testing.T) {
+	tests := []struct {
+		name string
+		spec string
+	}{
+		{"missing rate", "USD:EUR"},
+		{"missing separator", "USDEUR=0.92"},
+		{"empty rate", "USD:EUR="},
+		{"non-numeric rate", "USD:EUR=abc"},
+		{"zero rate", "USD:EUR=0"},
+		{"negative rate", "USD:EUR=-0.92"},
+		{"missing source currency", ":EUR=0.92"},
+		{"missing destination currency", "USD:=0.92"},
+		{"unsupported currency", "USD:XYZ=1.5"},
+		{"same currency", "USD:USD=1"},
+		{"one bad entry among good ones", "USD:EUR=0.92,EUR:USD"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ParseStatic(tt.spec); err == nil {
+				t.Errorf("ParseStatic(%q) succeeded, want an error", tt.spec)
+			}
+		})
+	}
+}
+
+func TestStaticRate(t *testing.T) {
+	s, err := ParseStatic(" usd:eur = 0.92 , EUR:USD=1.087,, GBP:USD=1.27")
+	if err != nil {
+		t.Fatalf("ParseStatic: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     string // empty when the rate is unavailable
+	}{
+		{"listed pair", "USD", "EUR", "0.92"},
+		{"inverse listed separately", "EUR", "USD", "1.087"},
+		{"inverse not derived", "USD", "GBP", ""},
+		{"lower-case codes", "gbp", "usd", "1.27"},
+		{"same currency", "JPY", "JPY", "1"},
+		{"unlisted pair", "EUR", "GBP", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Rate(context.Background(), tt.from, tt.to)
+			if tt.want == "" {
+				var unavailable *apperror.ErrRateUnavailable
+				if !errors.As(err, &unavailable) {
+					t.Fatalf("Rate(%s, %s) = %s, %v; want ErrRateUnavailable", tt.from, tt.to, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Rate(%s, %s): %v", tt.from, tt.to, err)
+			}
+			if !got.Equal(decimal.RequireFromString(tt.want)) {
+				t.Errorf("Rate(%s, %s) = %s, want %s", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseStaticEmptySpec(t *testing.T) {
+	s, err := ParseStatic("")
+	if err != nil {
+		t.Fatalf("ParseStatic: %v", err)
+	}
+	if _, err := s.Rate(context.Background(), "USD", "EUR"); err == nil {
+		t.Error("empty spec served a rate")
+	}
+}
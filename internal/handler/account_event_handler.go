@@ -0,0 +1,137 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/dto"
+	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/service"
+)
+
+// sseKeepAlive is how often an idle stream sends a comment, so that proxies
+// do not time it out.
+const sseKeepAlive = 15 * time.Second
+
+type AccountEventHandler struct {
+	eventSvc *service.AccountEventService
+	logger   *slog.Logger
+}
+
+func NewAccountEventHandler(eventSvc *service.AccountEventService, logger *slog.Logger) *AccountEventHandler {
+	return &AccountEventHandler{
+		eventSvc: eventSvc,
+		logger:   logger,
+	}
+}
+
+// Stream sends an account's activity as Server-Sent Events. A new stream
+// starts with a "balance" event holding the current balance. Each ledger
+// entry then produces a "transaction" event followed by a "balance" event
+// carrying the entry ID, so a client reconnecting with Last-Event-ID resumes
+// after the last entry it fully received.
+func (h *AccountEventHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(r.PathValue("account_id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid account ID. Please provide a valid account number"})
+		return
+	}
+	var lastEventID int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		lastEventID, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid Last-Event-ID. Please provide the id of a previous event"})
+			return
+		}
+	}
+
+	ctx := r.Context()
+	account, events, err := h.eventSvc.Subscribe(ctx, accountID, lastEventID)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	if lastEventID == 0 {
+		err = writeSSE(w, "", "balance", dto.BalanceResponse{
+			AccountID: account.AccountID,
+			Currency:  account.Currency,
+			Balance:   account.Balance,
+			AsOf:      time.Now(),
+		})
+	}
+	if err == nil {
+		err = rc.Flush()
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for err == nil {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			err = writeAccountEvent(w, &e)
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		case <-ctx.Done():
+			return
+		}
+		if err == nil {
+			err = rc.Flush()
+		}
+	}
+	if ctx.Err() == nil {
+		h.logger.Error("streaming account events", "account_id", accountID, "error", err)
+	}
+}
+
+func writeAccountEvent(w http.ResponseWriter, e *model.AccountEvent) error {
+	err := writeSSE(w, "", "transaction", dto.AccountTransactionEvent{
+		TransactionID:         e.TransactionID,
+		Kind:                  string(e.Kind),
+		Direction:             string(e.Direction),
+		Amount:                e.Amount,
+		Currency:              e.Currency,
+		CounterpartyAccountID: e.CounterpartyAccountID,
+		Balance:               e.Balance,
+		CreatedAt:             e.CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	return writeSSE(w, strconv.FormatInt(e.ID, 10), "balance", dto.BalanceResponse{
+		AccountID: e.AccountID,
+		Currency:  e.Currency,
+		Balance:   e.Balance,
+		AsOf:      e.CreatedAt,
+	})
+}
+
+// writeSSE writes one event. JSON never contains a raw newline, so data fits
+// on a single line.
+func writeSSE(w http.ResponseWriter, id, event string, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		if _, err = fmt.Fprintf(w, "id: %s\n", id); err != nil {
+			return err
+		}
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, b)
+	return err
+}
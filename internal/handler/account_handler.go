@@ -2,12 +2,20 @@ package handler
 
 import (
 	"encoding/json"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
 
 	"github.com/InternalTransfer/internal/apperror"
 	"github.com/InternalTransfer/internal/dto"
+	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/pagination"
 	"github.com/InternalTransfer/internal/service"
 )
 
@@ -30,12 +38,22 @@ func (h *AccountHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.accountSvc.Create(r.Context(), req.AccountID, req.InitialBalance); err != nil {
+	account := model.Account{
+		AccountID:   req.AccountID,
+		Currency:    req.Currency,
+		AccountType: req.AccountType,
+		OwnerID:     req.OwnerID,
+		Name:        req.Name,
+		Metadata:    req.Metadata,
+	}
+	created, err := h.accountSvc.Create(r.Context(), account, req.InitialBalance)
+	if err != nil {
 		mapErrorToResponse(w, err, h.logger)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	w.Header().Set("Location", fmt.Sprintf("/accounts/%d", created.AccountID))
+	writeJSON(w, http.StatusCreated, toAccountResponse(created))
 }
 
 func (h *AccountHandler) GetByID(w http.ResponseWriter, r *http.Request) {
@@ -52,8 +70,343 @@ func (h *AccountHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeJSON(w, http.StatusOK, dto.AccountResponse{
-		AccountID: account.AccountID,
-		Balance:   account.Balance,
+	writeJSON(w, http.StatusOK, toAccountResponse(account))
+}
+
+func (h *AccountHandler) GetBalance(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(r.PathValue("account_id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid account ID. Please provide a valid account number"})
+		return
+	}
+
+	asOf, err := queryTime(r.URL.Query(), "as_of")
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+	var at time.Time
+	if asOf != nil {
+		at = *asOf
+	}
+
+	balance, err := h.accountSvc.BalanceAt(r.Context(), accountID, at)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dto.BalanceResponse{
+		AccountID: balance.AccountID,
+		Currency:  balance.Currency,
+		Balance:   balance.Balance,
+		AsOf:      balance.AsOf,
 	})
 }
+
+// Statement streams an account statement. Once the first byte is written
+// the status can no longer change, so later failures end the response early
+// and are only logged.
+func (h *AccountHandler) Statement(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(r.PathValue("account_id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid account ID. Please provide a valid account number"})
+		return
+	}
+
+	q := r.URL.Query()
+	format := q.Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	f, ok := statementFormats[format]
+	if !ok {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Format must be one of: csv, json, ndjson"})
+		return
+	}
+	from, err := queryTime(q, "from")
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+	to, err := queryTime(q, "to")
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+	if from == nil || to == nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Please provide both 'from' and 'to' for the statement period"})
+		return
+	}
+
+	sw := &statusWriter{ResponseWriter: w}
+	err = h.accountSvc.Statement(r.Context(), accountID, *from, *to, f.newWriter(sw))
+	if err == nil {
+		return
+	}
+	if !sw.wroteHeader {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+	h.logger.Error("streaming statement", "account_id", accountID, "error", err)
+}
+
+func (h *AccountHandler) Update(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(r.PathValue("account_id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid account ID. Please provide a valid account number"})
+		return
+	}
+
+	var req dto.UpdateAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid request format. Please check your input and try again"})
+		return
+	}
+
+	account, err := h.accountSvc.Update(r.Context(), accountID, model.AccountPatch{
+		OwnerID:     req.OwnerID,
+		Name:        req.Name,
+		AccountType: req.AccountType,
+		Metadata:    req.Metadata,
+	})
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toAccountResponse(account))
+}
+
+func (h *AccountHandler) List(w http.ResponseWriter, r *http.Request) {
+	filter, err := parseAccountFilter(r.URL.Query())
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	accounts, next, err := h.accountSvc.List(r.Context(), filter)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	resp := dto.AccountListResponse{Accounts: make([]dto.AccountResponse, 0, len(accounts))}
+	for i := range accounts {
+		resp.Accounts = append(resp.Accounts, toAccountResponse(&accounts[i]))
+	}
+	if next != nil {
+		cur := pagination.Cursor{ID: next.AccountID}
+		switch filter.Sort {
+		case model.AccountSortBalance:
+			cur.Key = next.Balance.String()
+		case model.AccountSortCreatedAt:
+			cur.Key = next.CreatedAt.Format(time.RFC3339Nano)
+		}
+		resp.NextCursor = pagination.Encode(cur)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parseAccountFilter reads the GET /accounts query. sort names a column,
+// prefixed with '-' for descending order; a cursor is only valid with the
+// sort it was issued for. Parameters named metadata.<key> filter on metadata.
+func parseAccountFilter(q url.Values) (model.AccountFilter, error) {
+	var f model.AccountFilter
+	var err error
+
+	f.Status = model.AccountStatus(q.Get("status"))
+	f.OwnerID = q.Get("owner_id")
+	f.AccountType = q.Get("account_type")
+	f.Name = q.Get("name")
+	for name, values := range q {
+		if key, ok := strings.CutPrefix(name, "metadata."); ok && key != "" {
+			if f.Metadata == nil {
+				f.Metadata = make(map[string]string)
+			}
+			f.Metadata[key] = values[0]
+		}
+	}
+	sort := q.Get("sort")
+	if rest, ok := strings.CutPrefix(sort, "-"); ok {
+		sort, f.Desc = rest, true
+	}
+	f.Sort = model.AccountSort(sort)
+	if f.MinBalance, err = queryDecimal(q, "min_balance"); err != nil {
+		return f, err
+	}
+	if f.MaxBalance, err = queryDecimal(q, "max_balance"); err != nil {
+		return f, err
+	}
+	if f.CreatedFrom, err = queryTime(q, "created_from"); err != nil {
+		return f, err
+	}
+	if f.CreatedTo, err = queryTime(q, "created_to"); err != nil {
+		return f, err
+	}
+	if f.Limit, err = queryInt(q, "limit"); err != nil {
+		return f, err
+	}
+
+	if c := q.Get("cursor"); c != "" {
+		invalid := &apperror.ErrValidation{Message: "Invalid cursor. Please use the next_cursor value from a previous response"}
+		cur, err := pagination.Decode(c)
+		if err != nil || cur.ID == 0 {
+			return f, invalid
+		}
+		after := &model.AccountCursor{AccountID: cur.ID}
+		switch f.Sort {
+		case model.AccountSortBalance:
+			if after.Balance, err = decimal.NewFromString(cur.Key); err != nil {
+				return f, invalid
+			}
+		case model.AccountSortCreatedAt:
+			if after.CreatedAt, err = time.Parse(time.RFC3339Nano, cur.Key); err != nil {
+				return f, invalid
+			}
+		default:
+			if cur.Key != "" {
+				return f, invalid
+			}
+		}
+		f.After = after
+	}
+
+	return f, nil
+}
+
+func (h *AccountHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(r.PathValue("account_id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid account ID. Please provide a valid account number"})
+		return
+	}
+
+	var req dto.UpdateAccountStatusRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid request format. Please check your input and try again"})
+		return
+	}
+
+	account, change, err := h.accountSvc.ChangeStatus(r.Context(), accountID, model.AccountStatusUpdate{
+		Status:           model.AccountStatus(req.Status),
+		ChangedBy:        req.ChangedBy,
+		Reason:           req.Reason,
+		SweepToAccountID: req.SweepToAccountID,
+	})
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, dto.UpdateAccountStatusResponse{
+		Account: toAccountResponse(account),
+		Change:  toAccountStatusChangeResponse(change),
+	})
+}
+
+func (h *AccountHandler) StatusHistory(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(r.PathValue("account_id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid account ID. Please provide a valid account number"})
+		return
+	}
+
+	history, err := h.accountSvc.StatusHistory(r.Context(), accountID)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	resp := make([]dto.AccountStatusChangeResponse, len(history))
+	for i := range history {
+		resp[i] = toAccountStatusChangeResponse(&history[i])
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *AccountHandler) GetLimits(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(r.PathValue("account_id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid account ID. Please provide a valid account number"})
+		return
+	}
+
+	limits, err := h.accountSvc.GetLimits(r.Context(), accountID)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toAccountLimitsResponse(limits))
+}
+
+func (h *AccountHandler) SetLimits(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(r.PathValue("account_id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid account ID. Please provide a valid account number"})
+		return
+	}
+
+	var req dto.AccountLimitsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid request format. Please check your input and try again"})
+		return
+	}
+
+	limits, err := h.accountSvc.SetLimits(r.Context(), model.AccountLimits{
+		AccountID:       accountID,
+		MaxSingleAmount: req.MaxSingleAmount,
+		DailyAmount:     req.DailyAmount,
+		MonthlyAmount:   req.MonthlyAmount,
+		HourlyCount:     req.HourlyCount,
+	})
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toAccountLimitsResponse(limits))
+}
+
+func toAccountResponse(a *model.Account) dto.AccountResponse {
+	return dto.AccountResponse{
+		AccountID:        a.AccountID,
+		Currency:         a.Currency,
+		AccountType:      a.AccountType,
+		Status:           string(a.Status),
+		OwnerID:          a.OwnerID,
+		Name:             a.Name,
+		Metadata:         a.Metadata,
+		Balance:          a.Balance,
+		AvailableBalance: a.AvailableBalance(),
+	}
+}
+
+func toAccountStatusChangeResponse(c *model.AccountStatusChange) dto.AccountStatusChangeResponse {
+	return dto.AccountStatusChangeResponse{
+		ID:                 c.ID,
+		AccountID:          c.AccountID,
+		FromStatus:         string(c.FromStatus),
+		ToStatus:           string(c.ToStatus),
+		ChangedBy:          c.ChangedBy,
+		Reason:             c.Reason,
+		SweepTransactionID: c.SweepTransactionID,
+		CreatedAt:          c.CreatedAt,
+	}
+}
+
+func toAccountLimitsResponse(l *model.AccountLimits) dto.AccountLimitsResponse {
+	resp := dto.AccountLimitsResponse{
+		AccountID:       l.AccountID,
+		MaxSingleAmount: l.MaxSingleAmount,
+		DailyAmount:     l.DailyAmount,
+		MonthlyAmount:   l.MonthlyAmount,
+		HourlyCount:     l.HourlyCount,
+	}
+	if !l.UpdatedAt.IsZero() {
+		resp.UpdatedAt = &l.UpdatedAt
+	}
+	return resp
+}
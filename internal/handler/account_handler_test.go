@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/pagination"
+)
+
+func TestParseAccountFilter(t *testing.T) {
+	createdAt := time.Date(2025, 1, 1, 12, 0, 0, 123, time.UTC)
+	byID := pagination.Encode(pagination.Cursor{ID: 7})
+	byBalance := pagination.Encode(pagination.Cursor{Key: "250.50", ID: 7})
+	byCreatedAt := pagination.Encode(pagination.Cursor{Key: createdAt.Format(time.RFC3339Nano), ID: 7})
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+		check   func(t *testing.T, f model.AccountFilter)
+	}{
+		{"empty", "", false, func(t *testing.T, f model.AccountFilter) {
+			if f.After != nil || f.Sort != "" || f.Desc || f.Limit != 0 || f.Metadata != nil {
+				t.Errorf("filter = %+v, want zero", f)
+			}
+		}},
+		{"filters", "status=frozen&owner_id=cust-1&account_type=savings&name=main&metadata.region=eu&metadata.=x" +
+			"&min_balance=5&max_balance=10&created_from=2025-01-01T00:00:00Z&limit=20", false, func(t *testing.T, f model.AccountFilter) {
+			if f.Status != model.AccountFrozen || f.OwnerID != "cust-1" || f.AccountType != "savings" || f.Name != "main" ||
+				len(f.Metadata) != 1 || f.Metadata["region"] != "eu" || f.MinBalance.String() != "5" ||
+				f.MaxBalance.String() != "10" || f.CreatedFrom == nil || f.CreatedTo != nil || f.Limit != 20 {
+				t.Errorf("filter = %+v", f)
+			}
+		}},
+		{"descending sort", "sort=-balance", false, func(t *testing.T, f model.AccountFilter) {
+			if f.Sort != model.AccountSortBalance || !f.Desc {
+				t.Errorf("sort = %q desc=%v, want balance descending", f.Sort, f.Desc)
+			}
+		}},
+		{"cursor by account ID", "cursor=" + byID, false, func(t *testing.T, f model.AccountFilter) {
+			if f.After == nil || f.After.AccountID != 7 {
+				t.Errorf("After = %+v, want account 7", f.After)
+			}
+		}},
+		{"cursor by balance", "sort=balance&cursor=" + byBalance, false, func(t *testing.T, f model.AccountFilter) {
+			if f.After == nil || f.After.Balance.String() != "250.5" || f.After.AccountID != 7 {
+				t.Errorf("After = %+v, want 250.50/7", f.After)
+			}
+		}},
+		{"cursor by creation time", "sort=-created_at&cursor=" + byCreatedAt, false, func(t *testing.T, f model.AccountFilter) {
+			if f.After == nil || !f.After.CreatedAt.Equal(createdAt) || f.After.AccountID != 7 || !f.Desc {
+				t.Errorf("After = %+v, want %s/7 descending", f.After, createdAt)
+			}
+		}},
+		{"cursor not base64", "cursor=***", true, nil},
+		{"cursor without an account ID", "cursor=" + pagination.Encode(pagination.Cursor{}), true, nil},
+		{"balance cursor used with the default sort", "cursor=" + byBalance, true, nil},
+		{"ID cursor used with the balance sort", "sort=balance&cursor=" + byID, true, nil},
+		{"balance cursor used with the creation time sort", "sort=created_at&cursor=" + byBalance, true, nil},
+		{"bad balance", "min_balance=lots", true, nil},
+		{"bad time", "created_to=2025-01-01", true, nil},
+		{"negative limit", "limit=-1", true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			f, err := parseAccountFilter(q)
+			if tt.wantErr {
+				var validation *apperror.ErrValidation
+				if !errors.As(err, &validation) {
+					t.Errorf("error = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAccountFilter: %v", err)
+			}
+			tt.check(t, f)
+		})
+	}
+}
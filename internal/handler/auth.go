@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/InternalTransfer/internal/auth"
+)
+
+// Authenticator resolves the API key or bearer token presented with a
+// request.
+type Authenticator interface {
+	Authenticate(ctx context.Context, credential string) (*auth.Principal, error)
+}
+
+// authMiddleware attaches the caller's principal to the request context. The
+// credential is read from "Authorization: Bearer <credential>" or from
+// X-API-Key. With a nil authn every request runs as auth.Anonymous. /health
+// is always open so that probes need no credentials.
+func authMiddleware(authn Authenticator, logger *slog.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authn == nil {
+			logPrincipal(w, auth.Anonymous)
+			next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), auth.Anonymous)))
+			return
+		}
+		if r.URL.Path == "/health" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		p, err := authn.Authenticate(r.Context(), credentials(r))
+		if err != nil {
+			mapErrorToResponse(w, err, logger)
+			return
+		}
+		logPrincipal(w, p)
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+	})
+}
+
+// logPrincipal records who made the request in the request log.
+func logPrincipal(w http.ResponseWriter, p *auth.Principal) {
+	if sw, ok := w.(*statusWriter); ok {
+		sw.principal = p.Name
+	}
+}
+
+func credentials(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(token)
+}
+
+// authorize rejects requests whose principal may not perform action.
+func authorize(action auth.Action, logger *slog.Logger, next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := auth.Authorize(auth.FromContext(r.Context()), action); err != nil {
+			mapErrorToResponse(w, err, logger)
+			return
+		}
+		next(w, r)
+	})
+}
@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/dto"
+	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/service"
+)
+
+type FeeHandler struct {
+	feeSvc *service.FeeService
+	logger *slog.Logger
+}
+
+func NewFeeHandler(feeSvc *service.FeeService, logger *slog.Logger) *FeeHandler {
+	return &FeeHandler{
+		feeSvc: feeSvc,
+		logger: logger,
+	}
+}
+
+func (h *FeeHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req dto.FeePolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid request format. Please check your input and try again"})
+		return
+	}
+
+	p, err := h.feeSvc.Create(r.Context(), toFeePolicy(req))
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/fee-policies/%d", p.ID))
+	writeJSON(w, http.StatusCreated, toFeePolicyResponse(p))
+}
+
+func (h *FeeHandler) List(w http.ResponseWriter, r *http.Request) {
+	policies, err := h.feeSvc.List(r.Context())
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	resp := dto.FeePolicyListResponse{FeePolicies: make([]dto.FeePolicyResponse, 0, len(policies))}
+	for i := range policies {
+		resp.FeePolicies = append(resp.FeePolicies, toFeePolicyResponse(&policies[i]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *FeeHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := feePolicyIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	p, err := h.feeSvc.GetByID(r.Context(), id)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toFeePolicyResponse(p))
+}
+
+func (h *FeeHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := feePolicyIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.FeePolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid request format. Please check your input and try again"})
+		return
+	}
+
+	p, err := h.feeSvc.Update(r.Context(), id, toFeePolicy(req))
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toFeePolicyResponse(p))
+}
+
+func (h *FeeHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := feePolicyIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.feeSvc.Delete(r.Context(), id); err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func feePolicyIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid fee policy ID. Please provide a valid fee policy number"})
+		return 0, false
+	}
+	return id, true
+}
+
+func toFeePolicy(req dto.FeePolicyRequest) model.FeePolicy {
+	p := model.FeePolicy{
+		AccountType:          req.AccountType,
+		SourceAccountID:      req.SourceAccountID,
+		DestinationAccountID: req.DestinationAccountID,
+		Currency:             req.Currency,
+		FeeType:              model.FeeType(req.FeeType),
+		FlatAmount:           req.FlatAmount,
+		Percentage:           req.Percentage,
+		MinFee:               req.MinFee,
+		MaxFee:               req.MaxFee,
+	}
+	for _, t := range req.Tiers {
+		p.Tiers = append(p.Tiers, model.FeeTier{UpTo: t.UpTo, FlatAmount: t.FlatAmount, Percentage: t.Percentage})
+	}
+	return p
+}
+
+func toFeePolicyResponse(p *model.FeePolicy) dto.FeePolicyResponse {
+	resp := dto.FeePolicyResponse{
+		ID:                   p.ID,
+		AccountType:          p.AccountType,
+		SourceAccountID:      p.SourceAccountID,
+		DestinationAccountID: p.DestinationAccountID,
+		Currency:             p.Currency,
+		FeeType:              string(p.FeeType),
+		FlatAmount:           p.FlatAmount,
+		Percentage:           p.Percentage,
+		MinFee:               p.MinFee,
+		MaxFee:               p.MaxFee,
+		CreatedAt:            p.CreatedAt,
+		UpdatedAt:            p.UpdatedAt,
+	}
+	for _, t := range p.Tiers {
+		resp.Tiers = append(resp.Tiers, dto.FeeTier{UpTo: t.UpTo, FlatAmount: t.FlatAmount, Percentage: t.Percentage})
+	}
+	return resp
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/dto"
+	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/service"
+)
+
+type FXHandler struct {
+	fxSvc  *service.FXService
+	logger *slog.Logger
+}
+
+func NewFXHandler(fxSvc *service.FXService, logger *slog.Logger) *FXHandler {
+	return &FXHandler{
+		fxSvc:  fxSvc,
+		logger: logger,
+	}
+}
+
+func (h *FXHandler) CreateQuote(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateFXQuoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid request format. Please check your input and try again"})
+		return
+	}
+
+	quote, err := h.fxSvc.CreateQuote(r.Context(), req.SourceCurrency, req.DestinationCurrency)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/fx/quotes/%d", quote.ID))
+	writeJSON(w, http.StatusCreated, toFXQuoteResponse(quote))
+}
+
+func (h *FXHandler) GetQuote(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid quote ID. Please provide a valid quote number"})
+		return
+	}
+
+	quote, err := h.fxSvc.GetQuote(r.Context(), id)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toFXQuoteResponse(quote))
+}
+
+func toFXQuoteResponse(q *model.FXQuote) dto.FXQuoteResponse {
+	return dto.FXQuoteResponse{
+		ID:                  q.ID,
+		SourceCurrency:      q.SourceCurrency,
+		DestinationCurrency: q.DestinationCurrency,
+		Rate:                q.Rate,
+		ExpiresAt:           q.ExpiresAt,
+		TransactionID:       q.TransactionID,
+		CreatedAt:           q.CreatedAt,
+	}
+}
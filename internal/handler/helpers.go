@@ -3,11 +3,18 @@ package handler
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
 
 	"github.com/InternalTransfer/internal/apperror"
 	"github.com/InternalTransfer/internal/dto"
+	"github.com/InternalTransfer/internal/pagination"
 )
 
 func writeJSON(w http.ResponseWriter, status int, v any) {
@@ -24,9 +31,15 @@ func httpStatusForError(code string) int {
 		return http.StatusBadRequest
 	case apperror.CodeNotFound:
 		return http.StatusNotFound
-	case apperror.CodeConflict:
+	case apperror.CodeUnauthenticated:
+		return http.StatusUnauthorized
+	case apperror.CodeForbidden:
+		return http.StatusForbidden
+	case apperror.CodeConflict, apperror.CodeHoldNotActive, apperror.CodeScheduleState, apperror.CodeApprovalState:
 		return http.StatusConflict
-	case apperror.CodeInsufficientBalance:
+	case apperror.CodeInsufficientBalance, apperror.CodeIdempotencyMismatch, apperror.CodeReversalNoFunds,
+		apperror.CodeCurrencyMismatch, apperror.CodeQuoteInvalid, apperror.CodeRateUnavailable,
+		apperror.CodeLimitExceeded, apperror.CodeAccountInactive:
 		return http.StatusUnprocessableEntity
 	default:
 		return http.StatusInternalServerError
@@ -37,10 +50,68 @@ func mapErrorToResponse(w http.ResponseWriter, err error, logger *slog.Logger) {
 	var appErr apperror.AppError
 	if errors.As(err, &appErr) {
 		status := httpStatusForError(appErr.Code())
-		writeJSON(w, status, dto.ErrorResponse{Code: appErr.Code(), Message: appErr.Error()})
+		resp := dto.ErrorResponse{Code: appErr.Code(), Message: appErr.Error()}
+		var detailed apperror.DetailedError
+		if errors.As(err, &detailed) {
+			resp.Details = detailed.Details()
+		}
+		writeJSON(w, status, resp)
 		return
 	}
 
 	logger.Error("unhandled error", "error", err)
 	writeJSON(w, http.StatusInternalServerError, dto.ErrorResponse{Code: apperror.CodeInternal, Message: "internal server error"})
 }
+
+// The query helpers below return nil for absent parameters and an
+// *apperror.ErrValidation for malformed ones.
+
+func queryTime(q url.Values, name string) (*time.Time, error) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Invalid '%s' value. Please use RFC 3339 format (e.g., 2025-01-31T00:00:00Z)", name)}
+	}
+	return &t, nil
+}
+
+func queryDecimal(q url.Values, name string) (*decimal.Decimal, error) {
+	v := q.Get(name)
+	if v == "" {
+		return nil, nil
+	}
+	d, err := decimal.NewFromString(v)
+	if err != nil {
+		return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Invalid '%s' value. Please provide a valid amount", name)}
+	}
+	return &d, nil
+}
+
+func queryInt(q url.Values, name string) (int, error) {
+	v := q.Get(name)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, &apperror.ErrValidation{Message: fmt.Sprintf("Invalid '%s' value. Please provide a positive number", name)}
+	}
+	return n, nil
+}
+
+// queryCursorID decodes a cursor that only carries a row ID, returning zero
+// when the parameter is absent.
+func queryCursorID(q url.Values) (int64, error) {
+	v := q.Get("cursor")
+	if v == "" {
+		return 0, nil
+	}
+	cur, err := pagination.Decode(v)
+	if err != nil || cur.ID <= 0 {
+		return 0, &apperror.ErrValidation{Message: "Invalid cursor. Please use the next_cursor value from a previous response"}
+	}
+	return cur.ID, nil
+}
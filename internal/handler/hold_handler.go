@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/dto"
+	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/service"
+)
+
+type HoldHandler struct {
+	holdSvc *service.HoldService
+	logger  *slog.Logger
+}
+
+func NewHoldHandler(holdSvc *service.HoldService, logger *slog.Logger) *HoldHandler {
+	return &HoldHandler{
+		holdSvc: holdSvc,
+		logger:  logger,
+	}
+}
+
+func (h *HoldHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid request format. Please check your input and try again"})
+		return
+	}
+
+	hold, err := h.holdSvc.Create(r.Context(), req.AccountID, req.Amount, time.Duration(req.ExpiresInSeconds)*time.Second)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/holds/%d", hold.ID))
+	writeJSON(w, http.StatusCreated, toHoldResponse(hold))
+}
+
+func (h *HoldHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := holdIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	hold, err := h.holdSvc.GetByID(r.Context(), id)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toHoldResponse(hold))
+}
+
+func (h *HoldHandler) Capture(w http.ResponseWriter, r *http.Request) {
+	id, ok := holdIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.CaptureHoldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid request format. Please check your input and try again"})
+		return
+	}
+
+	hold, txn, err := h.holdSvc.Capture(r.Context(), id, req.DestinationAccountID, req.Amount)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/transactions/%d", txn.ID))
+	writeJSON(w, http.StatusCreated, dto.CaptureHoldResponse{
+		Hold:        toHoldResponse(hold),
+		Transaction: toTransactionResponse(txn),
+	})
+}
+
+func (h *HoldHandler) Release(w http.ResponseWriter, r *http.Request) {
+	id, ok := holdIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	hold, err := h.holdSvc.Release(r.Context(), id)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toHoldResponse(hold))
+}
+
+func holdIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid hold ID. Please provide a valid hold number"})
+		return 0, false
+	}
+	return id, true
+}
+
+func toHoldResponse(h *model.Hold) dto.HoldResponse {
+	return dto.HoldResponse{
+		ID:             h.ID,
+		AccountID:      h.AccountID,
+		Amount:         h.Amount,
+		Status:         string(h.Status),
+		CapturedAmount: h.CapturedAmount,
+		TransactionID:  h.TransactionID,
+		ExpiresAt:      h.ExpiresAt,
+		CreatedAt:      h.CreatedAt,
+		UpdatedAt:      h.UpdatedAt,
+	}
+}
@@ -3,18 +3,77 @@ package handler
 import (
 	"log/slog"
 	"net/http"
+
+	"github.com/InternalTransfer/internal/auth"
 )
 
 func NewRouter(
 	accountHandler *AccountHandler,
+	accountEventHandler *AccountEventHandler,
 	transactionHandler *TransactionHandler,
+	holdHandler *HoldHandler,
+	fxHandler *FXHandler,
+	scheduleHandler *ScheduleHandler,
+	feeHandler *FeeHandler,
+	webhookHandler *WebhookHandler,
+	authn Authenticator,
 	logger *slog.Logger,
 ) http.Handler {
 	mux := http.NewServeMux()
+	route := func(pattern string, action auth.Action, h http.HandlerFunc) {
+		mux.Handle(pattern, authorize(action, logger, h))
+	}
+
+	route("POST /accounts", auth.ActionWriteAccounts, accountHandler.Create)
+	route("GET /accounts", auth.ActionReadAccounts, accountHandler.List)
+	route("GET /accounts/{account_id}", auth.ActionReadAccounts, accountHandler.GetByID)
+	route("PATCH /accounts/{account_id}", auth.ActionWriteAccounts, accountHandler.Update)
+	route("GET /accounts/{account_id}/balance", auth.ActionReadAccounts, accountHandler.GetBalance)
+	route("GET /accounts/{account_id}/statement", auth.ActionReadAccounts, accountHandler.Statement)
+	route("GET /accounts/{account_id}/events", auth.ActionReadAccounts, accountEventHandler.Stream)
+	route("GET /accounts/{account_id}/transactions", auth.ActionReadAccounts, transactionHandler.ListByAccount)
+	route("GET /accounts/{account_id}/limits", auth.ActionReadAccounts, accountHandler.GetLimits)
+	route("PUT /accounts/{account_id}/limits", auth.ActionSetLimits, accountHandler.SetLimits)
+	route("PATCH /accounts/{account_id}/status", auth.ActionChangeStatus, accountHandler.UpdateStatus)
+	route("GET /accounts/{account_id}/status-history", auth.ActionReadAccounts, accountHandler.StatusHistory)
+	route("POST /transactions", auth.ActionTransfer, transactionHandler.Create)
+	route("POST /transactions/batch", auth.ActionTransfer, transactionHandler.CreateBatch)
+	route("GET /transactions/{id}", auth.ActionReadAccounts, transactionHandler.GetByID)
+	route("POST /transactions/{id}/reverse", auth.ActionReverse, transactionHandler.Reverse)
+	route("GET /transactions/approvals", auth.ActionReadAccounts, transactionHandler.ListApprovals)
+	route("GET /transactions/{id}/approval", auth.ActionReadAccounts, transactionHandler.GetApproval)
+	route("POST /transactions/{id}/approve", auth.ActionApprove, transactionHandler.Approve)
+	route("POST /transactions/{id}/reject", auth.ActionApprove, transactionHandler.Reject)
+
+	route("POST /holds", auth.ActionTransfer, holdHandler.Create)
+	route("GET /holds/{id}", auth.ActionReadAccounts, holdHandler.GetByID)
+	route("POST /holds/{id}/capture", auth.ActionTransfer, holdHandler.Capture)
+	route("POST /holds/{id}/release", auth.ActionTransfer, holdHandler.Release)
+
+	route("POST /fx/quotes", auth.ActionTransfer, fxHandler.CreateQuote)
+	route("GET /fx/quotes/{id}", auth.ActionReadAccounts, fxHandler.GetQuote)
 
-	mux.HandleFunc("POST /accounts", accountHandler.Create)
-	mux.HandleFunc("GET /accounts/{account_id}", accountHandler.GetByID)
-	mux.HandleFunc("POST /transactions", transactionHandler.Create)
+	route("POST /scheduled-transfers", auth.ActionTransfer, scheduleHandler.Create)
+	route("GET /scheduled-transfers", auth.ActionReadAccounts, scheduleHandler.List)
+	route("GET /scheduled-transfers/{id}", auth.ActionReadAccounts, scheduleHandler.GetByID)
+	route("PATCH /scheduled-transfers/{id}", auth.ActionTransfer, scheduleHandler.Update)
+	route("DELETE /scheduled-transfers/{id}", auth.ActionTransfer, scheduleHandler.Cancel)
+	route("POST /scheduled-transfers/{id}/pause", auth.ActionTransfer, scheduleHandler.Pause)
+	route("POST /scheduled-transfers/{id}/resume", auth.ActionTransfer, scheduleHandler.Resume)
+	route("GET /scheduled-transfers/{id}/runs", auth.ActionReadAccounts, scheduleHandler.ListRuns)
+
+	route("POST /fee-policies", auth.ActionManageFees, feeHandler.Create)
+	route("GET /fee-policies", auth.ActionManageFees, feeHandler.List)
+	route("GET /fee-policies/{id}", auth.ActionManageFees, feeHandler.GetByID)
+	route("PUT /fee-policies/{id}", auth.ActionManageFees, feeHandler.Update)
+	route("DELETE /fee-policies/{id}", auth.ActionManageFees, feeHandler.Delete)
+
+	route("POST /webhooks", auth.ActionManageWebhooks, webhookHandler.Create)
+	route("GET /webhooks", auth.ActionManageWebhooks, webhookHandler.List)
+	route("GET /webhooks/{id}", auth.ActionManageWebhooks, webhookHandler.GetByID)
+	route("DELETE /webhooks/{id}", auth.ActionManageWebhooks, webhookHandler.Delete)
+	route("POST /webhooks/{id}/enable", auth.ActionManageWebhooks, webhookHandler.Enable)
+	route("GET /webhooks/{id}/deliveries", auth.ActionManageWebhooks, webhookHandler.ListDeliveries)
 
 	mux.HandleFunc("GET /health", func(w http.ResponseWriter, _ *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
@@ -23,6 +82,7 @@ func NewRouter(
 	})
 
 	var h http.Handler = mux
+	h = authMiddleware(authn, logger, h)
 	h = loggingMiddleware(logger, h)
 	return h
 }
@@ -35,6 +95,7 @@ func loggingMiddleware(logger *slog.Logger, next http.Handler) http.Handler {
 			"method", r.Method,
 			"path", r.URL.Path,
 			"status", sw.status,
+			"principal", sw.principal,
 		)
 	})
 }
@@ -43,6 +104,14 @@ type statusWriter struct {
 	http.ResponseWriter
 	status      int
 	wroteHeader bool
+	// principal is filled in by authMiddleware for the request log.
+	principal string
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to
+// flush streamed responses.
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
 }
 
 func (sw *statusWriter) WriteHeader(code int) {
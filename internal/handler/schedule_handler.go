@@ -0,0 +1,234 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/dto"
+	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/pagination"
+	"github.com/InternalTransfer/internal/service"
+)
+
+type ScheduleHandler struct {
+	scheduleSvc *service.ScheduleService
+	logger      *slog.Logger
+}
+
+func NewScheduleHandler(scheduleSvc *service.ScheduleService, logger *slog.Logger) *ScheduleHandler {
+	return &ScheduleHandler{
+		scheduleSvc: scheduleSvc,
+		logger:      logger,
+	}
+}
+
+func (h *ScheduleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateScheduledTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid request format. Please check your input and try again"})
+		return
+	}
+
+	in := model.ScheduledTransfer{
+		SourceAccountID:      req.SourceAccountID,
+		DestinationAccountID: req.DestinationAccountID,
+		Amount:               req.Amount,
+		Currency:             req.Currency,
+		CronExpr:             req.Cron,
+		IntervalSeconds:      req.IntervalSeconds,
+		EndsAt:               req.EndsAt,
+	}
+	if req.StartsAt != nil {
+		in.StartsAt = *req.StartsAt
+	}
+
+	st, err := h.scheduleSvc.Create(r.Context(), in)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/scheduled-transfers/%d", st.ID))
+	writeJSON(w, http.StatusCreated, toScheduledTransferResponse(st))
+}
+
+func (h *ScheduleHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := scheduleIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	st, err := h.scheduleSvc.GetByID(r.Context(), id)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toScheduledTransferResponse(st))
+}
+
+func (h *ScheduleHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	var f model.ScheduleFilter
+	var err error
+
+	if v := q.Get("account_id"); v != "" {
+		if f.AccountID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid account ID. Please provide a valid account number"})
+			return
+		}
+	}
+	if f.Limit, err = queryInt(q, "limit"); err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+	if f.After, err = queryCursorID(q); err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	list, next, err := h.scheduleSvc.List(r.Context(), f)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	resp := dto.ScheduledTransferListResponse{ScheduledTransfers: make([]dto.ScheduledTransferResponse, 0, len(list))}
+	for i := range list {
+		resp.ScheduledTransfers = append(resp.ScheduledTransfers, toScheduledTransferResponse(&list[i]))
+	}
+	if next != 0 {
+		resp.NextCursor = pagination.Encode(pagination.Cursor{ID: next})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *ScheduleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := scheduleIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.UpdateScheduledTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid request format. Please check your input and try again"})
+		return
+	}
+
+	st, err := h.scheduleSvc.Update(r.Context(), id, model.SchedulePatch{
+		Amount:          req.Amount,
+		CronExpr:        req.Cron,
+		IntervalSeconds: req.IntervalSeconds,
+		EndsAt:          req.EndsAt,
+	})
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toScheduledTransferResponse(st))
+}
+
+func (h *ScheduleHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, h.scheduleSvc.Cancel)
+}
+
+func (h *ScheduleHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, h.scheduleSvc.Pause)
+}
+
+func (h *ScheduleHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	h.transition(w, r, h.scheduleSvc.Resume)
+}
+
+func (h *ScheduleHandler) transition(w http.ResponseWriter, r *http.Request, fn func(ctx context.Context, id int64) (*model.ScheduledTransfer, error)) {
+	id, ok := scheduleIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	st, err := fn(r.Context(), id)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toScheduledTransferResponse(st))
+}
+
+func (h *ScheduleHandler) ListRuns(w http.ResponseWriter, r *http.Request) {
+	id, ok := scheduleIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	limit, err := queryInt(q, "limit")
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+	before, err := queryCursorID(q)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	runs, next, err := h.scheduleSvc.ListRuns(r.Context(), id, before, limit)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	resp := dto.ScheduledTransferRunListResponse{Runs: make([]dto.ScheduledTransferRunResponse, 0, len(runs))}
+	for _, run := range runs {
+		resp.Runs = append(resp.Runs, dto.ScheduledTransferRunResponse{
+			ID:            run.ID,
+			ScheduledFor:  run.ScheduledFor,
+			Status:        string(run.Status),
+			TransactionID: run.TransactionID,
+			ErrorCode:     run.ErrorCode,
+			ErrorMessage:  run.ErrorMessage,
+			CreatedAt:     run.CreatedAt,
+		})
+	}
+	if next != 0 {
+		resp.NextCursor = pagination.Encode(pagination.Cursor{ID: next})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func scheduleIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid scheduled transfer ID. Please provide a valid scheduled transfer number"})
+		return 0, false
+	}
+	return id, true
+}
+
+func toScheduledTransferResponse(st *model.ScheduledTransfer) dto.ScheduledTransferResponse {
+	return dto.ScheduledTransferResponse{
+		ID:                   st.ID,
+		SourceAccountID:      st.SourceAccountID,
+		DestinationAccountID: st.DestinationAccountID,
+		Amount:               st.Amount,
+		Currency:             st.Currency,
+		Cron:                 st.CronExpr,
+		IntervalSeconds:      st.IntervalSeconds,
+		StartsAt:             st.StartsAt,
+		EndsAt:               st.EndsAt,
+		Status:               string(st.Status),
+		NextRunAt:            st.NextRunAt,
+		LastRunAt:            st.LastRunAt,
+		CreatedAt:            st.CreatedAt,
+		UpdatedAt:            st.UpdatedAt,
+	}
+}
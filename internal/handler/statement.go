@@ -0,0 +1,146 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/InternalTransfer/internal/dto"
+	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/service"
+)
+
+// statementFormats maps the format query parameter to a writer constructor
+// and the file extension used in Content-Disposition.
+var statementFormats = map[string]struct {
+	ext         string
+	contentType string
+	newWriter   func(w http.ResponseWriter) service.StatementWriter
+}{
+	"csv":    {"csv", "text/csv; charset=utf-8", func(w http.ResponseWriter) service.StatementWriter { return &csvStatement{w: w} }},
+	"json":   {"json", "application/json", func(w http.ResponseWriter) service.StatementWriter { return &jsonStatement{w: w} }},
+	"ndjson": {"ndjson", "application/x-ndjson", func(w http.ResponseWriter) service.StatementWriter { return &ndjsonStatement{w: w} }},
+}
+
+// startStatement sends the response headers once the statement is known to
+// exist; errors before this point still get a regular error response.
+func startStatement(w http.ResponseWriter, s *model.Statement, format string) {
+	f := statementFormats[format]
+	filename := fmt.Sprintf("statement-%d-%s-%s.%s", s.AccountID, s.From.UTC().Format("20060102"), s.To.UTC().Format("20060102"), f.ext)
+	w.Header().Set("Content-Type", f.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+}
+
+func toStatementLineResponse(l *model.StatementLine) dto.StatementLineResponse {
+	return dto.StatementLineResponse{
+		TransactionID:         l.TransactionID,
+		Kind:                  string(l.Kind),
+		Direction:             string(l.Direction),
+		Amount:                l.Amount,
+		CounterpartyAccountID: l.CounterpartyAccountID,
+		Balance:               l.Balance,
+		CreatedAt:             l.CreatedAt,
+	}
+}
+
+// csvStatement writes one record per row, tagged with its record type so the
+// opening and closing balances can share the file with the transactions.
+type csvStatement struct {
+	w        http.ResponseWriter
+	csv      *csv.Writer
+	currency string
+}
+
+var csvStatementHeader = []string{"record_type", "date", "transaction_id", "kind", "direction", "counterparty_account_id", "amount", "balance", "currency"}
+
+func (c *csvStatement) Opening(s *model.Statement) error {
+	startStatement(c.w, s, "csv")
+	c.csv = csv.NewWriter(c.w)
+	c.currency = s.Currency
+	if err := c.csv.Write(csvStatementHeader); err != nil {
+		return err
+	}
+	return c.csv.Write([]string{"opening_balance", s.From.UTC().Format(time.RFC3339), "", "", "", "", "", s.OpeningBalance.String(), s.Currency})
+}
+
+func (c *csvStatement) Line(l *model.StatementLine) error {
+	return c.csv.Write([]string{
+		"transaction",
+		l.CreatedAt.UTC().Format(time.RFC3339Nano),
+		strconv.FormatInt(l.TransactionID, 10),
+		string(l.Kind),
+		string(l.Direction),
+		strconv.FormatInt(l.CounterpartyAccountID, 10),
+		l.Amount.String(),
+		l.Balance.String(),
+		c.currency,
+	})
+}
+
+func (c *csvStatement) Closing(s *model.Statement) error {
+	if err := c.csv.Write([]string{"closing_balance", s.To.UTC().Format(time.RFC3339), "", "", "", "", "", s.ClosingBalance.String(), s.Currency}); err != nil {
+		return err
+	}
+	c.csv.Flush()
+	return c.csv.Error()
+}
+
+// jsonStatement writes a single object whose transactions array is streamed
+// element by element.
+type jsonStatement struct {
+	w     http.ResponseWriter
+	lines int
+}
+
+func (j *jsonStatement) Opening(s *model.Statement) error {
+	startStatement(j.w, s, "json")
+	_, err := fmt.Fprintf(j.w, `{"account_id":%d,"currency":%q,"from":%q,"to":%q,"opening_balance":%q,"transactions":[`,
+		s.AccountID, s.Currency, s.From.Format(time.RFC3339Nano), s.To.Format(time.RFC3339Nano), s.OpeningBalance.String())
+	return err
+}
+
+func (j *jsonStatement) Line(l *model.StatementLine) error {
+	b, err := json.Marshal(toStatementLineResponse(l))
+	if err != nil {
+		return err
+	}
+	if j.lines > 0 {
+		b = append([]byte{','}, b...)
+	}
+	j.lines++
+	_, err = j.w.Write(b)
+	return err
+}
+
+func (j *jsonStatement) Closing(s *model.Statement) error {
+	_, err := fmt.Fprintf(j.w, `],"closing_balance":%q}`+"\n", s.ClosingBalance.String())
+	return err
+}
+
+// ndjsonStatement writes one JSON object per line: the opening balance, each
+// transaction, then the closing balance, told apart by their type field.
+type ndjsonStatement struct {
+	w   http.ResponseWriter
+	enc *json.Encoder
+}
+
+func (n *ndjsonStatement) Opening(s *model.Statement) error {
+	startStatement(n.w, s, "ndjson")
+	n.enc = json.NewEncoder(n.w)
+	return n.enc.Encode(dto.StatementBalanceResponse{Type: "opening_balance", AccountID: s.AccountID, Currency: s.Currency, AsOf: s.From, Balance: s.OpeningBalance})
+}
+
+func (n *ndjsonStatement) Line(l *model.StatementLine) error {
+	return n.enc.Encode(struct {
+		Type string `json:"type"`
+		dto.StatementLineResponse
+	}{"transaction", toStatementLineResponse(l)})
+}
+
+func (n *ndjsonStatement) Closing(s *model.Statement) error {
+	return n.enc.Encode(dto.StatementBalanceResponse{Type: "closing_balance", AccountID: s.AccountID, Currency: s.Currency, AsOf: s.To, Balance: s.ClosingBalance})
+}
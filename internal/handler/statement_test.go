@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/dto"
+	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/service"
+)
+
+var (
+	testStatement = model.Statement{
+		AccountID:      1000000009,
+		Currency:       "USD",
+		From:           time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:             time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC),
+		OpeningBalance: decimal.RequireFromString("100"),
+		ClosingBalance: decimal.RequireFromString("125.5"),
+	}
+	testStatementLines = []model.StatementLine{
+		{TransactionID: 7, Kind: model.TransactionKindTransfer, Direction: model.EntryCredit, Amount: decimal.RequireFromString("50.5"),
+			CounterpartyAccountID: 1000000017, Balance: decimal.RequireFromString("150.5"), CreatedAt: time.Date(2025, 1, 3, 9, 30, 0, 500, time.UTC)},
+		{TransactionID: 9, Kind: model.TransactionKindFee, Direction: model.EntryDebit, Amount: decimal.RequireFromString("25"),
+			CounterpartyAccountID: 900, Balance: decimal.RequireFromString("125.5"), CreatedAt: time.Date(2025, 1, 20, 18, 0, 0, 0, time.UTC)},
+	}
+)
+
+// writeStatement drives w the way AccountService.Statement does.
+func writeStatement(t *testing.T, newWriter func(rec *httptest.ResponseRecorder) service.StatementWriter, lines []model.StatementLine) *httptest.ResponseRecorder {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	w := newWriter(rec)
+	if err := w.Opening(&testStatement); err != nil {
+		t.Fatalf("Opening: %v", err)
+	}
+	for i := range lines {
+		if err := w.Line(&lines[i]); err != nil {
+			t.Fatalf("Line: %v", err)
+		}
+	}
+	if err := w.Closing(&testStatement); err != nil {
+		t.Fatalf("Closing: %v", err)
+	}
+	return rec
+}
+
+func checkStatementHeaders(t *testing.T, rec *httptest.ResponseRecorder, contentType, filename string) {
+	t.Helper()
+	if got := rec.Header().Get("Content-Type"); got != contentType {
+		t.Errorf("Content-Type = %q, want %q", got, contentType)
+	}
+	if got, want := rec.Header().Get("Content-Disposition"), `attachment; filename="`+filename+`"`; got != want {
+		t.Errorf("Content-Disposition = %q, want %q", got, want)
+	}
+}
+
+func TestCSVStatement(t *testing.T) {
+	newWriter := func(rec *httptest.ResponseRecorder) service.StatementWriter { return &csvStatement{w: rec} }
+
+	for _, tt := range []struct {
+		name  string
+		lines []model.StatementLine
+		want  [][]string
+	}{
+		{"empty", nil, [][]string{
+			csvStatementHeader,
+			{"opening_balance", "2025-01-01T00:00:00Z", "", "", "", "", "", "100", "USD"},
+			{"closing_balance", "2025-02-01T00:00:00Z", "", "", "", "", "", "125.5", "USD"},
+		}},
+		{"transactions", testStatementLines, [][]string{
+			csvStatementHeader,
+			{"opening_balance", "2025-01-01T00:00:00Z", "", "", "", "", "", "100", "USD"},
+			{"transaction", "2025-01-03T09:30:00.0000005Z", "7", "transfer", "credit", "1000000017", "50.5", "150.5", "USD"},
+			{"transaction", "2025-01-20T18:00:00Z", "9", "fee", "debit", "900", "25", "125.5", "USD"},
+			{"closing_balance", "2025-02-01T00:00:00Z", "", "", "", "", "", "125.5", "USD"},
+		}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := writeStatement(t, newWriter, tt.lines)
+			checkStatementHeaders(t, rec, "text/csv; charset=utf-8", "statement-1000000009-20250101-20250201.csv")
+
+			records, err := csv.NewReader(rec.Body).ReadAll()
+			if err != nil {
+				t.Fatalf("reading CSV: %v", err)
+			}
+			if !reflect.DeepEqual(records, tt.want) {
+				t.Errorf("records = %q, want %q", records, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONStatement(t *testing.T) {
+	newWriter := func(rec *httptest.ResponseRecorder) service.StatementWriter { return &jsonStatement{w: rec} }
+
+	for _, tt := range []struct {
+		name  string
+		lines []model.StatementLine
+	}{
+		{"empty", nil},
+		{"transactions", testStatementLines},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := writeStatement(t, newWriter, tt.lines)
+			checkStatementHeaders(t, rec, "application/json", "statement-1000000009-20250101-20250201.json")
+
+			var got struct {
+				AccountID      int64                       `json:"account_id"`
+				Currency       string                      `json:"currency"`
+				From           time.Time                   `json:"from"`
+				To             time.Time                   `json:"to"`
+				OpeningBalance decimal.Decimal             `json:"opening_balance"`
+				Transactions   []dto.StatementLineResponse `json:"transactions"`
+				ClosingBalance decimal.Decimal             `json:"closing_balance"`
+			}
+			dec := json.NewDecoder(rec.Body)
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&got); err != nil {
+				t.Fatalf("decoding statement: %v", err)
+			}
+			if got.AccountID != testStatement.AccountID || got.Currency != "USD" || !got.From.Equal(testStatement.From) ||
+				!got.To.Equal(testStatement.To) || got.OpeningBalance.String() != "100" || got.ClosingBalance.String() != "125.5" {
+				t.Errorf("statement = %+v", got)
+			}
+			if got.Transactions == nil {
+				t.Fatal("transactions missing, want an array")
+			}
+			if len(got.Transactions) != len(tt.lines) {
+				t.Fatalf("got %d transactions, want %d", len(got.Transactions), len(tt.lines))
+			}
+			for i, l := range tt.lines {
+				if want := toStatementLineResponse(&l); !reflect.DeepEqual(got.Transactions[i], want) {
+					t.Errorf("transaction %d = %+v, want %+v", i, got.Transactions[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestNDJSONStatement(t *testing.T) {
+	newWriter := func(rec *httptest.ResponseRecorder) service.StatementWriter { return &ndjsonStatement{w: rec} }
+	rec := writeStatement(t, newWriter, testStatementLines)
+	checkStatementHeaders(t, rec, "application/x-ndjson", "statement-1000000009-20250101-20250201.ndjson")
+
+	body := rec.Body.String()
+	if !strings.HasSuffix(body, "\n") {
+		t.Error("last line is not terminated")
+	}
+	var objects []map[string]any
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		var o map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &o); err != nil {
+			t.Fatalf("line %q: %v", scanner.Text(), err)
+		}
+		objects = append(objects, o)
+	}
+	want := []map[string]any{
+		{"type": "opening_balance", "account_id": float64(1000000009), "currency": "USD", "as_of": "2025-01-01T00:00:00Z", "balance": "100"},
+		{"type": "transaction", "transaction_id": float64(7), "kind": "transfer", "direction": "credit", "amount": "50.5",
+			"counterparty_account_id": float64(1000000017), "balance": "150.5", "created_at": "2025-01-03T09:30:00.0000005Z"},
+		{"type": "transaction", "transaction_id": float64(9), "kind": "fee", "direction": "debit", "amount": "25",
+			"counterparty_account_id": float64(900), "balance": "125.5", "created_at": "2025-01-20T18:00:00Z"},
+		{"type": "closing_balance", "account_id": float64(1000000009), "currency": "USD", "as_of": "2025-02-01T00:00:00Z", "balance": "125.5"},
+	}
+	if !reflect.DeepEqual(objects, want) {
+		t.Errorf("lines = %v\nwant %v", objects, want)
+	}
+}
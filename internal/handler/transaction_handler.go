@@ -2,11 +2,19 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/InternalTransfer/internal/apperror"
 	"github.com/InternalTransfer/internal/dto"
+	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/pagination"
 	"github.com/InternalTransfer/internal/service"
 )
 
@@ -29,10 +37,304 @@ func (h *TransactionHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.transferSvc.Transfer(r.Context(), req.SourceAccountID, req.DestinationAccountID, req.Amount); err != nil {
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	txn, approval, err := h.transferSvc.Submit(r.Context(), toTransferRequest(req), idempotencyKey)
+	if err != nil {
 		mapErrorToResponse(w, err, h.logger)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
+	// transfers above the approval threshold are accepted but not yet posted
+	if approval != nil {
+		w.Header().Set("Location", fmt.Sprintf("/transactions/%d/approval", approval.ID))
+		writeJSON(w, http.StatusAccepted, toApprovalResponse(approval))
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/transactions/%d", txn.ID))
+	writeJSON(w, http.StatusCreated, toTransactionResponse(txn))
+}
+
+func toTransferRequest(req dto.CreateTransactionRequest) model.TransferRequest {
+	return model.TransferRequest{
+		SourceAccountID:      req.SourceAccountID,
+		DestinationAccountID: req.DestinationAccountID,
+		Amount:               req.Amount,
+		Currency:             req.Currency,
+		FXRate:               req.FXRate,
+		FXQuoteID:            req.FXQuoteID,
+	}
+}
+
+func toTransactionResponse(t *model.Transaction) dto.TransactionResponse {
+	resp := dto.TransactionResponse{
+		ID:                   t.ID,
+		Kind:                 string(t.Kind),
+		SourceAccountID:      t.SourceAccountID,
+		DestinationAccountID: t.DestinationAccountID,
+		Amount:               t.Amount,
+		Currency:             t.Currency,
+		DestinationAmount:    t.DestinationAmount,
+		DestinationCurrency:  t.DestinationCurrency,
+		FXRate:               t.FXRate,
+		FXQuoteID:            t.FXQuoteID,
+		FeeOf:                t.FeeOf,
+		SourceBalanceAfter:   t.SourceBalanceAfter,
+		ReversalOf:           t.ReversalOf,
+		CreatedAt:            t.CreatedAt,
+	}
+	if t.Fee != nil {
+		fee := toTransactionResponse(t.Fee)
+		resp.Fee = &fee
+	}
+	return resp
+}
+
+func (h *TransactionHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := transactionIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	txn, err := h.transferSvc.GetTransaction(r.Context(), id)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toTransactionResponse(txn))
+}
+
+func (h *TransactionHandler) ListByAccount(w http.ResponseWriter, r *http.Request) {
+	accountID, err := strconv.ParseInt(r.PathValue("account_id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid account ID. Please provide a valid account number"})
+		return
+	}
+
+	filter, err := parseTransactionFilter(r.URL.Query())
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+	filter.AccountID = accountID
+
+	txns, next, err := h.transferSvc.ListAccountTransactions(r.Context(), filter)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	resp := dto.TransactionListResponse{Transactions: make([]dto.TransactionResponse, 0, len(txns))}
+	for i := range txns {
+		resp.Transactions = append(resp.Transactions, toTransactionResponse(&txns[i]))
+	}
+	if next != nil {
+		resp.NextCursor = pagination.Encode(pagination.Cursor{Key: next.CreatedAt.Format(time.RFC3339Nano), ID: next.ID})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func parseTransactionFilter(q url.Values) (model.TransactionFilter, error) {
+	var f model.TransactionFilter
+	var err error
+
+	f.Direction = model.TransactionDirection(q.Get("direction"))
+	if f.From, err = queryTime(q, "from"); err != nil {
+		return f, err
+	}
+	if f.To, err = queryTime(q, "to"); err != nil {
+		return f, err
+	}
+	if f.MinAmount, err = queryDecimal(q, "min_amount"); err != nil {
+		return f, err
+	}
+	if f.MaxAmount, err = queryDecimal(q, "max_amount"); err != nil {
+		return f, err
+	}
+	if f.Limit, err = queryInt(q, "limit"); err != nil {
+		return f, err
+	}
+
+	if c := q.Get("cursor"); c != "" {
+		invalid := &apperror.ErrValidation{Message: "Invalid cursor. Please use the next_cursor value from a previous response"}
+		cur, err := pagination.Decode(c)
+		if err != nil {
+			return f, invalid
+		}
+		createdAt, err := time.Parse(time.RFC3339Nano, cur.Key)
+		if err != nil {
+			return f, invalid
+		}
+		f.After = &model.TransactionCursor{CreatedAt: createdAt, ID: cur.ID}
+	}
+
+	return f, nil
+}
+
+func (h *TransactionHandler) Reverse(w http.ResponseWriter, r *http.Request) {
+	id, ok := transactionIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// an empty body reverses the full remaining amount
+	var req dto.ReverseTransactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid request format. Please check your input and try again"})
+		return
+	}
+
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	txn, err := h.transferSvc.Reverse(r.Context(), id, req.Amount, idempotencyKey)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/transactions/%d", txn.ID))
+	writeJSON(w, http.StatusCreated, toTransactionResponse(txn))
+}
+
+func (h *TransactionHandler) CreateBatch(w http.ResponseWriter, r *http.Request) {
+	var req dto.BatchTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid request format. Please check your input and try again"})
+		return
+	}
+
+	legs := make([]model.TransferRequest, len(req.Legs))
+	for i, leg := range req.Legs {
+		legs[i] = toTransferRequest(leg)
+	}
+
+	txns, err := h.transferSvc.TransferBatch(r.Context(), legs)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	resp := dto.BatchTransferResponse{Transactions: make([]dto.TransactionResponse, 0, len(txns))}
+	for i := range txns {
+		resp.Transactions = append(resp.Transactions, toTransactionResponse(&txns[i]))
+	}
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (h *TransactionHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	id, ok := transactionIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	txn, err := h.transferSvc.Approve(r.Context(), id)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/transactions/%d", txn.ID))
+	writeJSON(w, http.StatusCreated, toTransactionResponse(txn))
+}
+
+func (h *TransactionHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	id, ok := transactionIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	// the reason is optional, so is the body
+	var req dto.RejectTransferRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid request format. Please check your input and try again"})
+		return
+	}
+
+	approval, err := h.transferSvc.Reject(r.Context(), id, req.Reason)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toApprovalResponse(approval))
+}
+
+func (h *TransactionHandler) GetApproval(w http.ResponseWriter, r *http.Request) {
+	id, ok := transactionIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	approval, err := h.transferSvc.GetApproval(r.Context(), id)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toApprovalResponse(approval))
+}
+
+func (h *TransactionHandler) ListApprovals(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := model.ApprovalFilter{Status: model.ApprovalStatus(q.Get("status"))}
+	var err error
+
+	if v := q.Get("account_id"); v != "" {
+		if f.AccountID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid account ID. Please provide a valid account number"})
+			return
+		}
+	}
+	if f.Limit, err = queryInt(q, "limit"); err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+	if f.After, err = queryCursorID(q); err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	list, next, err := h.transferSvc.ListApprovals(r.Context(), f)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	resp := dto.ApprovalListResponse{Approvals: make([]dto.ApprovalResponse, 0, len(list))}
+	for i := range list {
+		resp.Approvals = append(resp.Approvals, toApprovalResponse(&list[i]))
+	}
+	if next != 0 {
+		resp.NextCursor = pagination.Encode(pagination.Cursor{ID: next})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func transactionIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid transaction ID. Please provide a valid transaction number"})
+		return 0, false
+	}
+	return id, true
+}
+
+func toApprovalResponse(a *model.PendingApproval) dto.ApprovalResponse {
+	return dto.ApprovalResponse{
+		ID:                   a.ID,
+		SourceAccountID:      a.SourceAccountID,
+		DestinationAccountID: a.DestinationAccountID,
+		Amount:               a.Amount,
+		Currency:             a.Currency,
+		FXRate:               a.FXRate,
+		FundsHeld:            a.FundsHeld,
+		Status:               string(a.Status),
+		InitiatedBy:          a.InitiatedBy,
+		DecidedBy:            a.DecidedBy,
+		Reason:               a.Reason,
+		ExpiresAt:            a.ExpiresAt,
+		DecidedAt:            a.DecidedAt,
+		CreatedAt:            a.CreatedAt,
+	}
 }
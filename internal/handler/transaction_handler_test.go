@@ -0,0 +1,371 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/dto"
+	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/pagination"
+	"github.com/InternalTransfer/internal/service"
+)
+
+// The fakes below embed the repository interfaces and implement only the
+// methods the handlers under test reach; anything else panics.
+
+type fakeTx struct{ pgx.Tx }
+
+func (fakeTx) Commit(context.Context) error   { return nil }
+func (fakeTx) Rollback(context.Context) error { return nil }
+
+type fakeTxBeginner struct{}
+
+func (fakeTxBeginner) BeginTx(context.Context) (pgx.Tx, error) { return fakeTx{}, nil }
+
+type fakeAccountRepo struct {
+	service.AccountRepo
+	accounts map[int64]*model.Account
+}
+
+func (r *fakeAccountRepo) GetByID(_ context.Context, id int64) (*model.Account, error) {
+	a, ok := r.accounts[id]
+	if !ok {
+		return nil, &apperror.ErrNotFound{Entity: "account", ID: id}
+	}
+	c := *a
+	return &c, nil
+}
+
+func (r *fakeAccountRepo) GetByIDForUpdate(ctx context.Context, _ pgx.Tx, id int64) (*model.Account, error) {
+	return r.GetByID(ctx, id)
+}
+
+func (r *fakeAccountRepo) UpdateBalance(_ context.Context, _ pgx.Tx, id int64, balance decimal.Decimal) error {
+	r.accounts[id].Balance = balance
+	return nil
+}
+
+type fakeTransactionRepo struct {
+	service.TransactionRepo
+	txns []model.Transaction
+}
+
+func (r *fakeTransactionRepo) Create(_ context.Context, _ pgx.Tx, t *model.Transaction) error {
+	t.ID = int64(len(r.txns) + 1)
+	t.CreatedAt = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC).Add(time.Duration(t.ID) * time.Minute)
+	r.txns = append(r.txns, *t)
+	return nil
+}
+
+func (r *fakeTransactionRepo) GetByID(_ context.Context, id int64) (*model.Transaction, error) {
+	for _, t := range r.txns {
+		if t.ID == id {
+			return &t, nil
+		}
+	}
+	return nil, &apperror.ErrNotFound{Entity: "transaction", ID: id}
+}
+
+func (r *fakeTransactionRepo) GetFee(context.Context, int64) (*model.Transaction, error) {
+	return nil, nil
+}
+
+func (r *fakeTransactionRepo) OutgoingUsage(context.Context, pgx.Tx, int64, time.Time, time.Time, time.Time) (model.LimitUsage, error) {
+	return model.LimitUsage{}, nil
+}
+
+func (r *fakeTransactionRepo) ListByAccount(_ context.Context, f model.TransactionFilter) ([]model.Transaction, error) {
+	var out []model.Transaction
+	for i := len(r.txns) - 1; i >= 0 && len(out) < f.Limit; i-- {
+		if t := r.txns[i]; f.After == nil || t.ID < f.After.ID {
+			out = append(out, t)
+		}
+	}
+	return out, nil
+}
+
+type fakeLedgerRepo struct{ service.LedgerRepo }
+
+func (fakeLedgerRepo) CreateEntries(context.Context, pgx.Tx, []model.LedgerEntry) error { return nil }
+
+type fakeOutboxRepo struct{ service.OutboxRepo }
+
+func (fakeOutboxRepo) Create(context.Context, pgx.Tx, *model.OutboxEvent) error { return nil }
+
+type fakeLimitRepo struct{ service.AccountLimitRepo }
+
+func (fakeLimitRepo) GetForTransfer(context.Context, pgx.Tx, int64) (*model.AccountLimits, error) {
+	return nil, nil
+}
+
+type fakeIdempotencyRepo struct {
+	service.IdempotencyRepo
+	keys map[string]*model.IdempotencyKey
+}
+
+func (r *fakeIdempotencyRepo) Claim(_ context.Context, _ pgx.Tx, principal, key, requestHash string) (*model.IdempotencyKey, error) {
+	if k, ok := r.keys[principal+"/"+key]; ok {
+		return k, nil
+	}
+	r.keys[principal+"/"+key] = &model.IdempotencyKey{Principal: principal, Key: key, RequestHash: requestHash}
+	return nil, nil
+}
+
+func (r *fakeIdempotencyRepo) Complete(_ context.Context, _ pgx.Tx, principal, key string, transactionID int64) error {
+	r.keys[principal+"/"+key].TransactionID = &transactionID
+	return nil
+}
+
+var discardLogger = slog.New(slog.NewTextHandler(io.Discard, nil))
+
+// newTransactionMux serves the transaction routes over two USD accounts:
+// 1 holding 100 and 2 holding nothing.
+func newTransactionMux(t *testing.T) (*http.ServeMux, *fakeTransactionRepo) {
+	t.Helper()
+	accounts := &fakeAccountRepo{accounts: map[int64]*model.Account{
+		1: {AccountID: 1, Currency: "USD", Status: model.AccountActive, Balance: decimal.NewFromInt(100)},
+		2: {AccountID: 2, Currency: "USD", Status: model.AccountActive},
+	}}
+	txns := &fakeTransactionRepo{}
+	svc := service.NewTransferService(accounts, txns, fakeLedgerRepo{}, fakeOutboxRepo{},
+		&fakeIdempotencyRepo{keys: map[string]*model.IdempotencyKey{}}, nil, nil, fakeLimitRepo{}, nil, fakeTxBeginner{},
+		discardLogger, 1_000_000, 0, false, service.NewApprovalPolicy(0, 0, false))
+	h := NewTransactionHandler(svc, discardLogger)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /transactions", h.Create)
+	mux.HandleFunc("GET /transactions/{id}", h.GetByID)
+	mux.HandleFunc("GET /accounts/{account_id}/transactions", h.ListByAccount)
+	return mux, txns
+}
+
+func serve(mux http.Handler, method, target, body string, header map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestTransactionHandlerCreate(t *testing.T) {
+	tests := []struct {
+		name         string
+		body         string
+		wantStatus   int
+		wantCode     string
+		wantLocation string
+		wantAmount   string
+		wantBalance  string
+	}{
+		{
+			name:       "created transaction is returned",
+			body:       `{"source_account_id": 1, "destination_account_id": 2, "amount": "10.50", "currency": "USD"}`,
+			wantStatus: http.StatusCreated, wantLocation: "/transactions/1", wantAmount: "10.5", wantBalance: "89.5",
+		},
+		{
+			name:       "malformed body",
+			body:       `{"source_account_id": "one"}`,
+			wantStatus: http.StatusBadRequest, wantCode: apperror.CodeValidation,
+		},
+		{
+			name:       "invalid amount",
+			body:       `{"source_account_id": 1, "destination_account_id": 2, "amount": "0", "currency": "USD"}`,
+			wantStatus: http.StatusBadRequest, wantCode: apperror.CodeValidation,
+		},
+		{
+			name:       "insufficient balance",
+			body:       `{"source_account_id": 1, "destination_account_id": 2, "amount": "500", "currency": "USD"}`,
+			wantStatus: http.StatusUnprocessableEntity, wantCode: apperror.CodeInsufficientBalance,
+		},
+		{
+			name:       "unknown destination",
+			body:       `{"source_account_id": 1, "destination_account_id": 3, "amount": "10", "currency": "USD"}`,
+			wantStatus: http.StatusNotFound, wantCode: apperror.CodeNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mux, _ := newTransactionMux(t)
+			rec := serve(mux, http.MethodPost, "/transactions", tt.body, nil)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+			if tt.wantCode != "" {
+				var resp dto.ErrorResponse
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Code != tt.wantCode {
+					t.Errorf("error response = %+v, %v; want code %s", resp, err, tt.wantCode)
+				}
+				return
+			}
+
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("Location = %q, want %q", got, tt.wantLocation)
+			}
+			var resp dto.TransactionResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatalf("decoding response: %v", err)
+			}
+			if resp.ID != 1 || resp.Kind != "transfer" || resp.SourceAccountID != 1 || resp.DestinationAccountID != 2 {
+				t.Errorf("response = %+v, want transfer 1 from 1 to 2", resp)
+			}
+			if resp.Amount.String() != tt.wantAmount || resp.Currency != "USD" || resp.DestinationAmount.String() != tt.wantAmount {
+				t.Errorf("amounts = %s %s -> %s, want %s USD", resp.Amount, resp.Currency, resp.DestinationAmount, tt.wantAmount)
+			}
+			if !resp.SourceBalanceAfter.Valid || resp.SourceBalanceAfter.Decimal.String() != tt.wantBalance {
+				t.Errorf("source_balance_after = %v, want %s", resp.SourceBalanceAfter, tt.wantBalance)
+			}
+			if resp.CreatedAt.IsZero() {
+				t.Error("created_at is not set")
+			}
+		})
+	}
+}
+
+func TestTransactionHandlerCreateReplay(t *testing.T) {
+	mux, txns := newTransactionMux(t)
+	body := `{"source_account_id": 1, "destination_account_id": 2, "amount": "10", "currency": "USD"}`
+	header := map[string]string{"Idempotency-Key": "order-17"}
+
+	first := serve(mux, http.MethodPost, "/transactions", body, header)
+	second := serve(mux, http.MethodPost, "/transactions", body, header)
+	if first.Code != http.StatusCreated || second.Code != http.StatusCreated {
+		t.Fatalf("statuses = %d, %d; want 201 twice", first.Code, second.Code)
+	}
+	if first.Body.String() != second.Body.String() {
+		t.Errorf("replay returned %s, want %s", second.Body, first.Body)
+	}
+	if len(txns.txns) != 1 {
+		t.Errorf("posted %d transactions, want 1", len(txns.txns))
+	}
+
+	reused := serve(mux, http.MethodPost, "/transactions",
+		`{"source_account_id": 1, "destination_account_id": 2, "amount": "20", "currency": "USD"}`, header)
+	if reused.Code != http.StatusUnprocessableEntity || !strings.Contains(reused.Body.String(), apperror.CodeIdempotencyMismatch) {
+		t.Errorf("reused key = %d %s, want 422 %s", reused.Code, reused.Body, apperror.CodeIdempotencyMismatch)
+	}
+}
+
+func TestTransactionHandlerGetByID(t *testing.T) {
+	mux, _ := newTransactionMux(t)
+	serve(mux, http.MethodPost, "/transactions", `{"source_account_id": 1, "destination_account_id": 2, "amount": "10", "currency": "USD"}`, nil)
+
+	tests := []struct {
+		path       string
+		wantStatus int
+	}{
+		{"/transactions/1", http.StatusOK},
+		{"/transactions/2", http.StatusNotFound},
+		{"/transactions/abc", http.StatusBadRequest},
+		{"/transactions/0", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if rec := serve(mux, http.MethodGet, tt.path, "", nil); rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+		})
+	}
+}
+
+func TestTransactionHandlerListByAccountCursor(t *testing.T) {
+	mux, _ := newTransactionMux(t)
+	for range 3 {
+		serve(mux, http.MethodPost, "/transactions", `{"source_account_id": 1, "destination_account_id": 2, "amount": "10", "currency": "USD"}`, nil)
+	}
+
+	var ids []int64
+	target := "/accounts/1/transactions?limit=2"
+	for page := 0; ; page++ {
+		rec := serve(mux, http.MethodGet, target, "", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("page %d: status = %d: %s", page, rec.Code, rec.Body)
+		}
+		var resp dto.TransactionListResponse
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+		for _, txn := range resp.Transactions {
+			ids = append(ids, txn.ID)
+		}
+		if resp.NextCursor == "" {
+			break
+		}
+		if page > 2 {
+			t.Fatalf("still paging after %v", ids)
+		}
+		target = "/accounts/1/transactions?limit=2&cursor=" + resp.NextCursor
+	}
+	if want := []int64{3, 2, 1}; !slices.Equal(ids, want) {
+		t.Errorf("transactions = %v, want %v", ids, want)
+	}
+}
+
+func TestParseTransactionFilter(t *testing.T) {
+	createdAt := time.Date(2025, 1, 1, 12, 0, 0, 123, time.UTC)
+	cursor := pagination.Encode(pagination.Cursor{Key: createdAt.Format(time.RFC3339Nano), ID: 7})
+
+	tests := []struct {
+		name    string
+		query   string
+		wantErr bool
+		check   func(t *testing.T, f model.TransactionFilter)
+	}{
+		{"empty", "", false, func(t *testing.T, f model.TransactionFilter) {
+			if f.After != nil || f.Limit != 0 || f.Direction != "" {
+				t.Errorf("filter = %+v, want zero", f)
+			}
+		}},
+		{"cursor", "cursor=" + cursor, false, func(t *testing.T, f model.TransactionFilter) {
+			if f.After == nil || !f.After.CreatedAt.Equal(createdAt) || f.After.ID != 7 {
+				t.Errorf("After = %+v, want %s/7", f.After, createdAt)
+			}
+		}},
+		{"filters", "direction=incoming&from=2025-01-01T00:00:00Z&min_amount=5&limit=10", false, func(t *testing.T, f model.TransactionFilter) {
+			if f.Direction != model.DirectionIncoming || f.From == nil || f.MinAmount.String() != "5" || f.Limit != 10 {
+				t.Errorf("filter = %+v", f)
+			}
+		}},
+		{"cursor not base64", "cursor=***", true, nil},
+		{"cursor without a time", "cursor=" + pagination.Encode(pagination.Cursor{Key: "yesterday", ID: 7}), true, nil},
+		{"bad from", "from=2025-01-01", true, nil},
+		{"bad amount", "max_amount=lots", true, nil},
+		{"negative limit", "limit=-1", true, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := url.ParseQuery(tt.query)
+			f, err := parseTransactionFilter(q)
+			if tt.wantErr {
+				var validation *apperror.ErrValidation
+				if !errors.As(err, &validation) {
+					t.Errorf("error = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseTransactionFilter: %v", err)
+			}
+			tt.check(t, f)
+		})
+	}
+}
@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/dto"
+	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/pagination"
+	"github.com/InternalTransfer/internal/service"
+)
+
+type WebhookHandler struct {
+	webhookSvc *service.WebhookService
+	logger     *slog.Logger
+}
+
+func NewWebhookHandler(webhookSvc *service.WebhookService, logger *slog.Logger) *WebhookHandler {
+	return &WebhookHandler{
+		webhookSvc: webhookSvc,
+		logger:     logger,
+	}
+}
+
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid request format. Please check your input and try again"})
+		return
+	}
+
+	sub, err := h.webhookSvc.Create(r.Context(), model.WebhookSubscription{
+		URL:        req.URL,
+		EventTypes: req.EventTypes,
+		AccountIDs: req.AccountIDs,
+	})
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	resp := toWebhookResponse(sub)
+	resp.Secret = sub.Secret
+	w.Header().Set("Location", fmt.Sprintf("/webhooks/%d", sub.ID))
+	writeJSON(w, http.StatusCreated, resp)
+}
+
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.webhookSvc.List(r.Context())
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	resp := dto.WebhookListResponse{Webhooks: make([]dto.WebhookResponse, 0, len(subs))}
+	for i := range subs {
+		resp.Webhooks = append(resp.Webhooks, toWebhookResponse(&subs[i]))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *WebhookHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := h.webhookSvc.GetByID(r.Context(), id)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toWebhookResponse(sub))
+}
+
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookSvc.Delete(r.Context(), id); err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *WebhookHandler) Enable(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	sub, err := h.webhookSvc.Enable(r.Context(), id)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, toWebhookResponse(sub))
+}
+
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	id, ok := webhookIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	limit, err := queryInt(q, "limit")
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+	before, err := queryCursorID(q)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	deliveries, next, err := h.webhookSvc.ListDeliveries(r.Context(), id, before, limit)
+	if err != nil {
+		mapErrorToResponse(w, err, h.logger)
+		return
+	}
+
+	resp := dto.WebhookDeliveryListResponse{Deliveries: make([]dto.WebhookDeliveryResponse, 0, len(deliveries))}
+	for _, d := range deliveries {
+		item := dto.WebhookDeliveryResponse{
+			ID:             d.ID,
+			EventID:        d.EventID,
+			EventType:      d.EventType,
+			Status:         string(d.Status),
+			Attempts:       d.Attempts,
+			ResponseStatus: d.ResponseStatus,
+			LastError:      d.LastError,
+			CreatedAt:      d.CreatedAt,
+			DeliveredAt:    d.DeliveredAt,
+		}
+		if d.Status == model.DeliveryPending {
+			item.NextAttemptAt = &d.NextAttemptAt
+		}
+		resp.Deliveries = append(resp.Deliveries, item)
+	}
+	if next != 0 {
+		resp.NextCursor = pagination.Encode(pagination.Cursor{ID: next})
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func webhookIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, dto.ErrorResponse{Code: apperror.CodeValidation, Message: "Invalid webhook ID. Please provide a valid webhook number"})
+		return 0, false
+	}
+	return id, true
+}
+
+func toWebhookResponse(s *model.WebhookSubscription) dto.WebhookResponse {
+	return dto.WebhookResponse{
+		ID:                  s.ID,
+		URL:                 s.URL,
+		EventTypes:          s.EventTypes,
+		AccountIDs:          s.AccountIDs,
+		Status:              string(s.Status),
+		ConsecutiveFailures: s.ConsecutiveFailures,
+		DisabledAt:          s.DisabledAt,
+		CreatedAt:           s.CreatedAt,
+		UpdatedAt:           s.UpdatedAt,
+	}
+}
@@ -1,22 +1,600 @@
 package model
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/shopspring/decimal"
 )
 
 type Account struct {
-	AccountID int64           `json:"account_id"`
-	Balance   decimal.Decimal `json:"balance"`
-	CreatedAt time.Time       `json:"created_at"`
-	UpdatedAt time.Time       `json:"updated_at"`
+	AccountID   int64           `json:"account_id"`
+	Currency    string          `json:"currency"`
+	AccountType string          `json:"account_type"`
+	Status      AccountStatus   `json:"status"`
+	OwnerID     *string         `json:"owner_id,omitempty"`
+	Name        *string         `json:"name,omitempty"`
+	Metadata    map[string]any  `json:"metadata"`
+	Balance     decimal.Decimal `json:"balance"`
+	HeldBalance decimal.Decimal `json:"held_balance"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
 }
 
+type AccountStatus string
+
+const (
+	AccountActive AccountStatus = "active"
+	AccountFrozen AccountStatus = "frozen"
+	AccountClosed AccountStatus = "closed"
+)
+
+// AccountStatusChange records who moved an account between statuses and why.
+// SweepTransactionID is the transaction that emptied the account on close.
+type AccountStatusChange struct {
+	ID                 int64         `json:"id"`
+	AccountID          int64         `json:"account_id"`
+	FromStatus         AccountStatus `json:"from_status"`
+	ToStatus           AccountStatus `json:"to_status"`
+	ChangedBy          string        `json:"changed_by"`
+	Reason             string        `json:"reason"`
+	SweepTransactionID *int64        `json:"sweep_transaction_id,omitempty"`
+	CreatedAt          time.Time     `json:"created_at"`
+}
+
+// AccountStatusUpdate asks to move an account to Status. Closing an account
+// with a balance requires SweepToAccountID, which receives the balance.
+type AccountStatusUpdate struct {
+	Status           AccountStatus
+	ChangedBy        string
+	Reason           string
+	SweepToAccountID *int64
+}
+
+// AccountBalance is an account's ledger balance at a point in time.
+type AccountBalance struct {
+	AccountID int64
+	Currency  string
+	Balance   decimal.Decimal
+	AsOf      time.Time
+}
+
+// AccountPatch changes the descriptive fields of an account that are set.
+// An empty OwnerID or Name clears it; Metadata is merged into the stored map,
+// with a nil value removing its key.
+type AccountPatch struct {
+	OwnerID     *string
+	Name        *string
+	AccountType *string
+	Metadata    map[string]any
+}
+
+// AccountSort is the column accounts are listed by.
+type AccountSort string
+
+const (
+	AccountSortID        AccountSort = "account_id"
+	AccountSortBalance   AccountSort = "balance"
+	AccountSortCreatedAt AccountSort = "created_at"
+)
+
+// AccountCursor marks the last row of a page of accounts. Only the field
+// matching the sort column is meaningful; AccountID breaks ties.
+type AccountCursor struct {
+	Balance   decimal.Decimal
+	CreatedAt time.Time
+	AccountID int64
+}
+
+// AccountFilter selects customer accounts, leaving out system accounts.
+// Name matches names containing it, ignoring case, and Metadata matches
+// accounts whose metadata sets every given key to the given string. Created
+// bounds are half-open: CreatedFrom <= created_at < CreatedTo.
+type AccountFilter struct {
+	Status      AccountStatus
+	OwnerID     string
+	AccountType string
+	Name        string
+	Metadata    map[string]string
+	MinBalance  *decimal.Decimal
+	MaxBalance  *decimal.Decimal
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Sort        AccountSort
+	Desc        bool
+	After       *AccountCursor
+	Limit       int
+}
+
+// DefaultAccountType is used when an account is created without a type.
+const DefaultAccountType = "standard"
+
+// AvailableBalance is the ledger balance minus funds reserved by active holds
+// and by transfers awaiting approval.
+func (a *Account) AvailableBalance() decimal.Decimal {
+	return a.Balance.Sub(a.HeldBalance)
+}
+
+// SystemRoleEquity identifies the system account that funds opening balances.
+const SystemRoleEquity = "equity"
+
+type TransactionKind string
+
+const (
+	TransactionKindTransfer TransactionKind = "transfer"
+	TransactionKindOpening  TransactionKind = "opening"
+	TransactionKindReversal TransactionKind = "reversal"
+	TransactionKindCapture  TransactionKind = "capture"
+	TransactionKindFee      TransactionKind = "fee"
+	TransactionKindSweep    TransactionKind = "sweep"
+)
+
 type Transaction struct {
+	ID                   int64               `json:"id"`
+	Kind                 TransactionKind     `json:"kind"`
+	SourceAccountID      int64               `json:"source_account_id"`
+	DestinationAccountID int64               `json:"destination_account_id"`
+	Amount               decimal.Decimal     `json:"amount"`
+	Currency             string              `json:"currency"`
+	DestinationAmount    decimal.Decimal     `json:"destination_amount"`
+	DestinationCurrency  string              `json:"destination_currency"`
+	FXRate               decimal.NullDecimal `json:"fx_rate"`
+	FXQuoteID            *int64              `json:"fx_quote_id,omitempty"`
+	FeeOf                *int64              `json:"fee_of,omitempty"`
+	SourceBalanceAfter   decimal.NullDecimal `json:"source_balance_after"`
+	ReversalOf           *int64              `json:"reversal_of,omitempty"`
+	CreatedAt            time.Time           `json:"created_at"`
+
+	// Fee is the fee leg charged on this transaction, if any. It is loaded
+	// alongside the transaction rather than stored on it.
+	Fee *Transaction `json:"fee,omitempty"`
+}
+
+// TransferRequest is a requested movement of Amount, in Currency, from the
+// source to the destination account. When the destination account holds a
+// different currency the amount is converted with either an explicit FXRate
+// or the rate locked by FXQuoteID.
+type TransferRequest struct {
+	SourceAccountID      int64
+	DestinationAccountID int64
+	Amount               decimal.Decimal
+	Currency             string
+	FXRate               *decimal.Decimal
+	FXQuoteID            *int64
+}
+
+type EntryDirection string
+
+const (
+	EntryDebit  EntryDirection = "debit"
+	EntryCredit EntryDirection = "credit"
+)
+
+// LedgerEntry is one side of a transaction as seen by a single account.
+// A debit takes money out of the account and a credit puts money in.
+type LedgerEntry struct {
+	ID            int64           `json:"id"`
+	AccountID     int64           `json:"account_id"`
+	TransactionID int64           `json:"transaction_id"`
+	Direction     EntryDirection  `json:"direction"`
+	Amount        decimal.Decimal `json:"amount"`
+	BalanceAfter  decimal.Decimal `json:"balance_after"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+// Statement summarises an account over [From, To). ClosingBalance is only
+// known once every line has been read.
+type Statement struct {
+	AccountID      int64
+	Currency       string
+	From           time.Time
+	To             time.Time
+	OpeningBalance decimal.Decimal
+	ClosingBalance decimal.Decimal
+}
+
+// StatementLine is one ledger entry on a statement, with the account's
+// balance after it.
+type StatementLine struct {
+	TransactionID         int64
+	Kind                  TransactionKind
+	Direction             EntryDirection
+	Amount                decimal.Decimal
+	CounterpartyAccountID int64
+	Balance               decimal.Decimal
+	CreatedAt             time.Time
+}
+
+// AccountEvent is one ledger entry of an account as pushed to activity
+// streams, identified by the entry ID. Balance is the account's balance after
+// the entry.
+type AccountEvent struct {
+	ID                    int64           `json:"id"`
+	AccountID             int64           `json:"account_id"`
+	TransactionID         int64           `json:"transaction_id"`
+	Kind                  TransactionKind `json:"kind"`
+	Direction             EntryDirection  `json:"direction"`
+	Amount                decimal.Decimal `json:"amount"`
+	Currency              string          `json:"currency"`
+	CounterpartyAccountID int64           `json:"counterparty_account_id"`
+	Balance               decimal.Decimal `json:"balance"`
+	CreatedAt             time.Time       `json:"created_at"`
+}
+
+type TransactionDirection string
+
+const (
+	DirectionIncoming TransactionDirection = "incoming"
+	DirectionOutgoing TransactionDirection = "outgoing"
+	DirectionBoth     TransactionDirection = "both"
+)
+
+// TransactionCursor marks the last row of a page ordered by (created_at, id) descending.
+type TransactionCursor struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+type TransactionFilter struct {
+	AccountID int64
+	Direction TransactionDirection
+	From      *time.Time
+	To        *time.Time
+	MinAmount *decimal.Decimal
+	MaxAmount *decimal.Decimal
+	After     *TransactionCursor
+	Limit     int
+}
+
+type HoldStatus string
+
+const (
+	HoldActive   HoldStatus = "active"
+	HoldCaptured HoldStatus = "captured"
+	HoldReleased HoldStatus = "released"
+	HoldExpired  HoldStatus = "expired"
+)
+
+// Hold reserves funds on an account until it is captured, released or expires.
+type Hold struct {
+	ID             int64               `json:"id"`
+	AccountID      int64               `json:"account_id"`
+	Amount         decimal.Decimal     `json:"amount"`
+	Status         HoldStatus          `json:"status"`
+	CapturedAmount decimal.NullDecimal `json:"captured_amount"`
+	TransactionID  *int64              `json:"transaction_id,omitempty"`
+	ExpiresAt      time.Time           `json:"expires_at"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalRejected ApprovalStatus = "rejected"
+	ApprovalExpired  ApprovalStatus = "expired"
+)
+
+// PendingApproval is a transfer above the approval threshold waiting for a
+// second principal to approve or reject it. An approved transfer is posted
+// with the approval's ID. FundsHeld says whether Amount was reserved on the
+// source account while pending.
+type PendingApproval struct {
+	ID                   int64               `json:"id"`
+	SourceAccountID      int64               `json:"source_account_id"`
+	DestinationAccountID int64               `json:"destination_account_id"`
+	Amount               decimal.Decimal     `json:"amount"`
+	Currency             string              `json:"currency"`
+	FXRate               decimal.NullDecimal `json:"fx_rate"`
+	FundsHeld            bool                `json:"funds_held"`
+	Status               ApprovalStatus      `json:"status"`
+	InitiatedBy          string              `json:"initiated_by"`
+	InitiatedByID        *string             `json:"-"`
+	DecidedBy            *string             `json:"decided_by,omitempty"`
+	Reason               *string             `json:"reason,omitempty"`
+	ExpiresAt            time.Time           `json:"expires_at"`
+	DecidedAt            *time.Time          `json:"decided_at,omitempty"`
+	CreatedAt            time.Time           `json:"created_at"`
+}
+
+// ApprovalFilter selects approvals in Status (any when empty) touching
+// AccountID (any account when zero), ordered by ID. After is the last ID of
+// the previous page.
+type ApprovalFilter struct {
+	Status    ApprovalStatus
+	AccountID int64
+	After     int64
+	Limit     int
+}
+
+// FXQuote locks an exchange rate until ExpiresAt for a single transfer.
+type FXQuote struct {
+	ID                  int64           `json:"id"`
+	SourceCurrency      string          `json:"source_currency"`
+	DestinationCurrency string          `json:"destination_currency"`
+	Rate                decimal.Decimal `json:"rate"`
+	ExpiresAt           time.Time       `json:"expires_at"`
+	TransactionID       *int64          `json:"transaction_id,omitempty"`
+	CreatedAt           time.Time       `json:"created_at"`
+}
+
+type ScheduleStatus string
+
+const (
+	ScheduleActive    ScheduleStatus = "active"
+	SchedulePaused    ScheduleStatus = "paused"
+	ScheduleCompleted ScheduleStatus = "completed"
+	ScheduleCancelled ScheduleStatus = "cancelled"
+)
+
+// ScheduledTransfer repeats a transfer on either CronExpr or IntervalSeconds,
+// never both. NextRunAt is nil once the schedule is completed or cancelled.
+type ScheduledTransfer struct {
 	ID                   int64           `json:"id"`
 	SourceAccountID      int64           `json:"source_account_id"`
 	DestinationAccountID int64           `json:"destination_account_id"`
 	Amount               decimal.Decimal `json:"amount"`
+	Currency             string          `json:"currency"`
+	CronExpr             *string         `json:"cron,omitempty"`
+	IntervalSeconds      *int64          `json:"interval_seconds,omitempty"`
+	StartsAt             time.Time       `json:"starts_at"`
+	EndsAt               *time.Time      `json:"ends_at,omitempty"`
+	Status               ScheduleStatus  `json:"status"`
+	NextRunAt            *time.Time      `json:"next_run_at,omitempty"`
+	LastRunAt            *time.Time      `json:"last_run_at,omitempty"`
 	CreatedAt            time.Time       `json:"created_at"`
+	UpdatedAt            time.Time       `json:"updated_at"`
+}
+
+// SchedulePatch changes the fields of a schedule that are set. Setting
+// CronExpr or IntervalSeconds replaces the schedule's current rule.
+type SchedulePatch struct {
+	Amount          *decimal.Decimal
+	CronExpr        *string
+	IntervalSeconds *int64
+	EndsAt          *time.Time
+}
+
+// ScheduleFilter selects schedules touching AccountID (any account when
+// zero), ordered by ID. After is the last ID of the previous page.
+type ScheduleFilter struct {
+	AccountID int64
+	After     int64
+	Limit     int
+}
+
+type ScheduleRunStatus string
+
+const (
+	RunSucceeded ScheduleRunStatus = "succeeded"
+	RunFailed    ScheduleRunStatus = "failed"
+)
+
+// ScheduledTransferRun records the outcome of one execution of a schedule.
+type ScheduledTransferRun struct {
+	ID            int64             `json:"id"`
+	ScheduleID    int64             `json:"schedule_id"`
+	ScheduledFor  time.Time         `json:"scheduled_for"`
+	Status        ScheduleRunStatus `json:"status"`
+	TransactionID *int64            `json:"transaction_id,omitempty"`
+	ErrorCode     *string           `json:"error_code,omitempty"`
+	ErrorMessage  *string           `json:"error_message,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+type FeeType string
+
+const (
+	FeeFlat       FeeType = "flat"
+	FeePercentage FeeType = "percentage"
+	FeeTiered     FeeType = "tiered"
+)
+
+// FeeTier applies to amounts up to and including UpTo; the last tier has no
+// UpTo. Its fee is FlatAmount plus Percentage percent of the amount.
+type FeeTier struct {
+	UpTo       *decimal.Decimal `json:"up_to,omitempty"`
+	FlatAmount decimal.Decimal  `json:"flat_amount"`
+	Percentage decimal.Decimal  `json:"percentage"`
+}
+
+// FeePolicy prices transfers out of accounts of AccountType, or between
+// SourceAccountID and DestinationAccountID. Percentage is in percent, so
+// 1.5 charges 1.5% of the amount.
+type FeePolicy struct {
+	ID                   int64               `json:"id"`
+	AccountType          *string             `json:"account_type,omitempty"`
+	SourceAccountID      *int64              `json:"source_account_id,omitempty"`
+	DestinationAccountID *int64              `json:"destination_account_id,omitempty"`
+	Currency             string              `json:"currency"`
+	FeeType              FeeType             `json:"fee_type"`
+	FlatAmount           decimal.NullDecimal `json:"flat_amount"`
+	Percentage           decimal.NullDecimal `json:"percentage"`
+	Tiers                []FeeTier           `json:"tiers,omitempty"`
+	MinFee               decimal.NullDecimal `json:"min_fee"`
+	MaxFee               decimal.NullDecimal `json:"max_fee"`
+	CreatedAt            time.Time           `json:"created_at"`
+	UpdatedAt            time.Time           `json:"updated_at"`
+}
+
+// AccountLimits caps an account's outgoing transfers. Amounts are in the
+// account's currency; an unset field is not enforced.
+type AccountLimits struct {
+	AccountID       int64               `json:"account_id"`
+	MaxSingleAmount decimal.NullDecimal `json:"max_single_amount"`
+	DailyAmount     decimal.NullDecimal `json:"daily_amount"`
+	MonthlyAmount   decimal.NullDecimal `json:"monthly_amount"`
+	HourlyCount     *int                `json:"hourly_count,omitempty"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+}
+
+// LimitUsage is what an account has already sent in the current UTC day,
+// month and hour.
+type LimitUsage struct {
+	DailyAmount   decimal.Decimal
+	MonthlyAmount decimal.Decimal
+	HourlyCount   int
+}
+
+// IdempotencyKey records the result of a request: the transaction it posted
+// or, for a transfer awaiting approval, the approval it created.
+type IdempotencyKey struct {
+	// Principal is the stable ID of the caller that used Key; each caller
+	// has its own keys.
+	Principal     string    `json:"principal"`
+	Key           string    `json:"key"`
+	RequestHash   string    `json:"request_hash"`
+	TransactionID *int64    `json:"transaction_id,omitempty"`
+	ApprovalID    *int64    `json:"approval_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type OutboxStatus string
+
+const (
+	OutboxPending   OutboxStatus = "pending"
+	OutboxDelivered OutboxStatus = "delivered"
+	OutboxDead      OutboxStatus = "dead"
+)
+
+// OutboxEvent is a change recorded alongside the database transaction that
+// made it. Its JSON form is what sinks and webhooks receive; delivery state
+// is kept out of it.
+type OutboxEvent struct {
+	ID            int64           `json:"id"`
+	Type          string          `json:"type"`
+	AccountIDs    []int64         `json:"account_ids"`
+	Data          json.RawMessage `json:"data"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Status        OutboxStatus    `json:"-"`
+	Attempts      int             `json:"-"`
+	NextAttemptAt time.Time       `json:"-"`
+	LastError     *string         `json:"-"`
+	DeliveredAt   *time.Time      `json:"-"`
+}
+
+type WebhookStatus string
+
+const (
+	WebhookActive   WebhookStatus = "active"
+	WebhookDisabled WebhookStatus = "disabled"
+)
+
+// WebhookSubscription pushes events to URL, signed with Secret. Empty
+// EventTypes or AccountIDs match every event or account.
+type WebhookSubscription struct {
+	ID                  int64
+	URL                 string
+	Secret              string
+	EventTypes          []string
+	AccountIDs          []int64
+	Status              WebhookStatus
+	ConsecutiveFailures int
+	DisabledAt          *time.Time
+	CreatedAt           time.Time
+	UpdatedAt           time.Time
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryDead      DeliveryStatus = "dead"
+)
+
+// WebhookDelivery tracks one event sent to one subscription across retries.
+// Event and Subscription are loaded when a delivery is claimed for sending.
+type WebhookDelivery struct {
+	ID             int64
+	SubscriptionID int64
+	EventID        int64
+	EventType      string
+	Status         DeliveryStatus
+	Attempts       int
+	NextAttemptAt  time.Time
+	ResponseStatus *int
+	LastError      *string
+	CreatedAt      time.Time
+	DeliveredAt    *time.Time
+
+	Event        *OutboxEvent
+	Subscription *WebhookSubscription
+}
+
+// AccountTotals is what reconciliation compares for one account: its stored
+// balances against the sum of its ledger entries, the sum of its transactions
+// (the opening balance plus incoming minus outgoing amounts) and the funds
+// reserved by its active holds and pending approvals.
+type AccountTotals struct {
+	AccountID          int64
+	Currency           string
+	Balance            decimal.Decimal
+	HeldBalance        decimal.Decimal
+	LedgerBalance      decimal.Decimal
+	TransactionBalance decimal.Decimal
+	ActiveHolds        decimal.Decimal
+}
+
+// TransactionEntries is what reconciliation compares for one transaction: its
+// amounts against the ledger entries posted for it. DebitAmount and
+// CreditAmount only count entries on the transaction's own source and
+// destination accounts.
+type TransactionEntries struct {
+	TransactionID       int64
+	Currency            string
+	DestinationCurrency string
+	Amount              decimal.Decimal
+	DestinationAmount   decimal.Decimal
+	Debits              int
+	Credits             int
+	DebitAmount         decimal.Decimal
+	CreditAmount        decimal.Decimal
+}
+
+// CurrencyTotal is the sum of all balances in a currency, system accounts
+// included, and the net amount cross-currency transfers moved into it.
+// Without FX the two are equal: every movement debits as much as it credits.
+type CurrencyTotal struct {
+	Currency string
+	Balance  decimal.Decimal
+	NetFX    decimal.Decimal
+}
+
+// Discrepancy is one failed reconciliation check. AccountID is set for
+// account checks and TransactionID, with the number of Entries found, for
+// ledger entry checks.
+type Discrepancy struct {
+	Check         string          `json:"check"`
+	AccountID     *int64          `json:"account_id,omitempty"`
+	TransactionID *int64          `json:"transaction_id,omitempty"`
+	Entries       *int            `json:"entries,omitempty"`
+	Currency      string          `json:"currency"`
+	Actual        decimal.Decimal `json:"actual"`
+	Expected      decimal.Decimal `json:"expected"`
+}
+
+type ReconciliationReport struct {
+	OK                  bool          `json:"ok"`
+	StartedAt           time.Time     `json:"started_at"`
+	FinishedAt          time.Time     `json:"finished_at"`
+	AccountsChecked     int           `json:"accounts_checked"`
+	TransactionsChecked int           `json:"transactions_checked"`
+	CurrenciesChecked   int           `json:"currencies_checked"`
+	Discrepancies       []Discrepancy `json:"discrepancies"`
+}
+
+// APIKey is a credential for a service client. The key itself is only known
+// when it is created; afterwards only its hash is stored.
+type APIKey struct {
+	ID        int64
+	Name      string
+	Prefix    string
+	KeyHash   string
+	Role      string
+	Scopes    []string
+	CreatedAt time.Time
+	RevokedAt *time.Time
 }
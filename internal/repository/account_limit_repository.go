@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/InternalTransfer/internal/model"
+)
+
+type AccountLimitRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAccountLimitRepository(pool *pgxpool.Pool) *AccountLimitRepository {
+	return &AccountLimitRepository{pool: pool}
+}
+
+// Get returns the limits of an account, or nil when none are set.
+func (r *AccountLimitRepository) Get(ctx context.Context, accountID int64) (*model.AccountLimits, error) {
+	l, err := scanAccountLimits(r.pool.QueryRow(ctx, `SELECT `+accountLimitColumns+` FROM account_limits WHERE account_id = $1`, accountID))
+	if err != nil {
+		return nil, fmt.Errorf("querying account limits: %w", err)
+	}
+	return l, nil
+}
+
+// GetForTransfer reads the limits of an account inside tx. The caller is
+// expected to hold the account's row lock.
+func (r *AccountLimitRepository) GetForTransfer(ctx context.Context, tx pgx.Tx, accountID int64) (*model.AccountLimits, error) {
+	l, err := scanAccountLimits(tx.QueryRow(ctx, `SELECT `+accountLimitColumns+` FROM account_limits WHERE account_id = $1`, accountID))
+	if err != nil {
+		return nil, fmt.Errorf("querying account limits: %w", err)
+	}
+	return l, nil
+}
+
+// Upsert replaces the limits of l.AccountID and fills in UpdatedAt.
+func (r *AccountLimitRepository) Upsert(ctx context.Context, l *model.AccountLimits) error {
+	err := r.pool.QueryRow(ctx,
+		`INSERT INTO account_limits (account_id, max_single_amount, daily_amount, monthly_amount, hourly_count)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (account_id) DO UPDATE
+		 SET max_single_amount = EXCLUDED.max_single_amount, daily_amount = EXCLUDED.daily_amount,
+		     monthly_amount = EXCLUDED.monthly_amount, hourly_count = EXCLUDED.hourly_count, updated_at = NOW()
+		 RETURNING updated_at`,
+		l.AccountID, l.MaxSingleAmount, l.DailyAmount, l.MonthlyAmount, l.HourlyCount,
+	).Scan(&l.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("saving account limits: %w", err)
+	}
+	return nil
+}
+
+const accountLimitColumns = `account_id, max_single_amount, daily_amount, monthly_amount, hourly_count, updated_at`
+
+func scanAccountLimits(row pgx.Row) (*model.AccountLimits, error) {
+	var l model.AccountLimits
+	err := row.Scan(&l.AccountID, &l.MaxSingleAmount, &l.DailyAmount, &l.MonthlyAmount, &l.HourlyCount, &l.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &l, nil
+}
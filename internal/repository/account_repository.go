@@ -2,11 +2,12 @@ package repository
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	"github.com/InternalTransfer/internal/apperror"
@@ -22,12 +23,34 @@ func NewAccountRepository(pool *pgxpool.Pool) *AccountRepository {
 	return &AccountRepository{pool: pool}
 }
 
-func (r *AccountRepository) Create(ctx context.Context, accountID int64, initialBalance decimal.Decimal) error {
-	_, err := r.pool.Exec(ctx, `INSERT INTO accounts (account_id, balance) VALUES ($1, $2)`, accountID, initialBalance)
+// NextID allocates an unused value from the account ID sequence.
+func (r *AccountRepository) NextID(ctx context.Context, tx pgx.Tx) (int64, error) {
+	var id int64
+	if err := tx.QueryRow(ctx, `SELECT nextval('account_id_seq')`).Scan(&id); err != nil {
+		return 0, fmt.Errorf("allocating account ID: %w", err)
+	}
+	return id, nil
+}
+
+// Create inserts a with a zero balance, filling in its generated fields.
+// Opening balances are posted through the ledger afterwards. A taken ID is
+// reported as ErrConflict without aborting tx, so the caller may try another.
+func (r *AccountRepository) Create(ctx context.Context, tx pgx.Tx, a *model.Account) error {
+	metadata, err := marshalMetadata(a.Metadata)
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
-			return &apperror.ErrConflict{Entity: "account", ID: accountID}
+		return err
+	}
+
+	err = tx.QueryRow(ctx,
+		`INSERT INTO accounts (account_id, currency, account_type, owner_id, name, metadata, balance)
+		 VALUES ($1, $2, $3, $4, $5, $6, 0)
+		 ON CONFLICT (account_id) DO NOTHING
+		 RETURNING status, created_at, updated_at`,
+		a.AccountID, a.Currency, a.AccountType, a.OwnerID, a.Name, metadata,
+	).Scan(&a.Status, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &apperror.ErrConflict{Entity: "account", ID: a.AccountID}
 		}
 		return fmt.Errorf("inserting account: %w", err)
 	}
@@ -35,33 +58,150 @@ func (r *AccountRepository) Create(ctx context.Context, accountID int64, initial
 }
 
 func (r *AccountRepository) GetByID(ctx context.Context, accountID int64) (*model.Account, error) {
-	var a model.Account
-	err := r.pool.QueryRow(ctx,
-		`SELECT account_id, balance, created_at, updated_at FROM accounts WHERE account_id = $1`,
+	a, err := scanAccount(r.pool.QueryRow(ctx,
+		`SELECT `+accountColumns+` FROM accounts WHERE account_id = $1`,
 		accountID,
-	).Scan(&a.AccountID, &a.Balance, &a.CreatedAt, &a.UpdatedAt)
+	))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, &apperror.ErrNotFound{Entity: "account", ID: accountID}
 		}
 		return nil, fmt.Errorf("querying account: %w", err)
 	}
-	return &a, nil
+	return a, nil
 }
 
 func (r *AccountRepository) GetByIDForUpdate(ctx context.Context, tx pgx.Tx, accountID int64) (*model.Account, error) {
-	var a model.Account
-	err := tx.QueryRow(ctx,
-		`SELECT account_id, balance, created_at, updated_at FROM accounts WHERE account_id = $1 FOR UPDATE`,
+	a, err := scanAccount(tx.QueryRow(ctx,
+		`SELECT `+accountColumns+` FROM accounts WHERE account_id = $1 FOR UPDATE`,
 		accountID,
-	).Scan(&a.AccountID, &a.Balance, &a.CreatedAt, &a.UpdatedAt)
+	))
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, &apperror.ErrNotFound{Entity: "account", ID: accountID}
 		}
 		return nil, fmt.Errorf("locking account: %w", err)
 	}
-	return &a, nil
+	return a, nil
+}
+
+// List returns customer accounts matching f, ordered by f.Sort and then by
+// account ID, starting strictly after f.After when set.
+func (r *AccountRepository) List(ctx context.Context, f model.AccountFilter) ([]model.Account, error) {
+	query, args, err := accountListQuery(f)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing accounts: %w", err)
+	}
+	defer rows.Close()
+
+	var out []model.Account
+	for rows.Next() {
+		a, err := scanAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning account: %w", err)
+		}
+		out = append(out, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing accounts: %w", err)
+	}
+	return out, nil
+}
+
+// accountListQuery builds the keyset query behind List: filters first, then
+// the position after f.After in the requested sort order.
+func accountListQuery(f model.AccountFilter) (string, []any, error) {
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	where := "system_role IS NULL"
+	if f.Status != "" {
+		where += " AND status = " + arg(f.Status)
+	}
+	if f.OwnerID != "" {
+		where += " AND owner_id = " + arg(f.OwnerID)
+	}
+	if f.AccountType != "" {
+		where += " AND account_type = " + arg(f.AccountType)
+	}
+	if f.Name != "" {
+		where += ` AND name ILIKE '%' || ` + arg(likeEscaper.Replace(f.Name)) + ` || '%'`
+	}
+	if len(f.Metadata) > 0 {
+		metadata, err := json.Marshal(f.Metadata)
+		if err != nil {
+			return "", nil, fmt.Errorf("encoding metadata filter: %w", err)
+		}
+		where += " AND metadata @> " + arg(metadata)
+	}
+	if f.MinBalance != nil {
+		where += " AND balance >= " + arg(*f.MinBalance)
+	}
+	if f.MaxBalance != nil {
+		where += " AND balance <= " + arg(*f.MaxBalance)
+	}
+	if f.CreatedFrom != nil {
+		where += " AND created_at >= " + arg(*f.CreatedFrom)
+	}
+	if f.CreatedTo != nil {
+		where += " AND created_at < " + arg(*f.CreatedTo)
+	}
+
+	dir, cmp := "ASC", ">"
+	if f.Desc {
+		dir, cmp = "DESC", "<"
+	}
+	order := "account_id " + dir
+	if f.After != nil {
+		switch f.Sort {
+		case model.AccountSortBalance:
+			where += fmt.Sprintf(" AND (balance, account_id) %s (%s, %s)", cmp, arg(f.After.Balance), arg(f.After.AccountID))
+		case model.AccountSortCreatedAt:
+			where += fmt.Sprintf(" AND (created_at, account_id) %s (%s, %s)", cmp, arg(f.After.CreatedAt), arg(f.After.AccountID))
+		default:
+			where += fmt.Sprintf(" AND account_id %s %s", cmp, arg(f.After.AccountID))
+		}
+	}
+	switch f.Sort {
+	case model.AccountSortBalance:
+		order = "balance " + dir + ", " + order
+	case model.AccountSortCreatedAt:
+		order = "created_at " + dir + ", " + order
+	}
+
+	query := `SELECT ` + accountColumns + ` FROM accounts WHERE ` + where +
+		` ORDER BY ` + order + ` LIMIT ` + arg(f.Limit)
+	return query, args, nil
+}
+
+// GetSystemAccountForUpdate locks the system account with the given role and
+// currency, creating it on first use.
+func (r *AccountRepository) GetSystemAccountForUpdate(ctx context.Context, tx pgx.Tx, role, currency string) (*model.Account, error) {
+	_, err := tx.Exec(ctx,
+		`INSERT INTO accounts (account_id, currency, balance, system_role)
+		 VALUES (nextval('system_account_id_seq'), $2, 0, $1)
+		 ON CONFLICT (system_role, currency) WHERE system_role IS NOT NULL DO NOTHING`,
+		role, currency,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("creating %s %s system account: %w", currency, role, err)
+	}
+
+	a, err := scanAccount(tx.QueryRow(ctx,
+		`SELECT `+accountColumns+` FROM accounts WHERE system_role = $1 AND currency = $2 FOR UPDATE`,
+		role, currency,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("locking %s %s system account: %w", currency, role, err)
+	}
+	return a, nil
 }
 
 func (r *AccountRepository) UpdateBalance(ctx context.Context, tx pgx.Tx, accountID int64, newBalance decimal.Decimal) error {
@@ -74,3 +214,122 @@ func (r *AccountRepository) UpdateBalance(ctx context.Context, tx pgx.Tx, accoun
 	}
 	return nil
 }
+
+func (r *AccountRepository) UpdateHeldBalance(ctx context.Context, tx pgx.Tx, accountID int64, newHeld decimal.Decimal) error {
+	tag, err := tx.Exec(ctx, `UPDATE accounts SET held_balance = $1, updated_at = NOW() WHERE account_id = $2`, newHeld, accountID)
+	if err != nil {
+		return fmt.Errorf("updating held balance: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return &apperror.ErrNotFound{Entity: "account", ID: accountID}
+	}
+	return nil
+}
+
+// UpdateDetails saves the owner, name, type and metadata of a.
+func (r *AccountRepository) UpdateDetails(ctx context.Context, tx pgx.Tx, a *model.Account) error {
+	metadata, err := marshalMetadata(a.Metadata)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRow(ctx,
+		`UPDATE accounts SET owner_id = $1, name = $2, account_type = $3, metadata = $4, updated_at = NOW()
+		 WHERE account_id = $5
+		 RETURNING updated_at`,
+		a.OwnerID, a.Name, a.AccountType, metadata, a.AccountID,
+	).Scan(&a.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &apperror.ErrNotFound{Entity: "account", ID: a.AccountID}
+		}
+		return fmt.Errorf("updating account: %w", err)
+	}
+	return nil
+}
+
+func (r *AccountRepository) UpdateStatus(ctx context.Context, tx pgx.Tx, accountID int64, status model.AccountStatus) error {
+	tag, err := tx.Exec(ctx, `UPDATE accounts SET status = $1, updated_at = NOW() WHERE account_id = $2`, status, accountID)
+	if err != nil {
+		return fmt.Errorf("updating account status: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return &apperror.ErrNotFound{Entity: "account", ID: accountID}
+	}
+	return nil
+}
+
+// CreateStatusChange inserts c and fills in the generated ID and CreatedAt.
+func (r *AccountRepository) CreateStatusChange(ctx context.Context, tx pgx.Tx, c *model.AccountStatusChange) error {
+	err := tx.QueryRow(ctx,
+		`INSERT INTO account_status_history (account_id, from_status, to_status, changed_by, reason, sweep_transaction_id)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, created_at`,
+		c.AccountID, c.FromStatus, c.ToStatus, c.ChangedBy, c.Reason, c.SweepTransactionID,
+	).Scan(&c.ID, &c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting account status change: %w", err)
+	}
+	return nil
+}
+
+// ListStatusChanges returns the status history of an account, oldest first.
+func (r *AccountRepository) ListStatusChanges(ctx context.Context, accountID int64) ([]model.AccountStatusChange, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT id, account_id, from_status, to_status, changed_by, reason, sweep_transaction_id, created_at
+		 FROM account_status_history
+		 WHERE account_id = $1
+		 ORDER BY id`,
+		accountID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing account status history: %w", err)
+	}
+	defer rows.Close()
+
+	var out []model.AccountStatusChange
+	for rows.Next() {
+		var c model.AccountStatusChange
+		if err := rows.Scan(&c.ID, &c.AccountID, &c.FromStatus, &c.ToStatus, &c.ChangedBy, &c.Reason, &c.SweepTransactionID, &c.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scanning account status change: %w", err)
+		}
+		out = append(out, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing account status history: %w", err)
+	}
+	return out, nil
+}
+
+// likeEscaper escapes the ILIKE wildcards in user input.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+const accountColumns = `account_id, currency, account_type, status, owner_id, name, metadata,
+	balance, held_balance, created_at, updated_at`
+
+func scanAccount(row pgx.Row) (*model.Account, error) {
+	var a model.Account
+	var metadata []byte
+	err := row.Scan(&a.AccountID, &a.Currency, &a.AccountType, &a.Status, &a.OwnerID, &a.Name, &metadata,
+		&a.Balance, &a.HeldBalance, &a.CreatedAt, &a.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(metadata, &a.Metadata); err != nil {
+		return nil, fmt.Errorf("decoding account metadata: %w", err)
+	}
+	return &a, nil
+}
+
+// marshalMetadata encodes metadata for the JSONB column, using an empty
+// object for none.
+func marshalMetadata(metadata map[string]any) ([]byte, error) {
+	if len(metadata) == 0 {
+		return []byte("{}"), nil
+	}
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("encoding account metadata: %w", err)
+	}
+	return b, nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/model"
+)
+
+func TestAccountListQuery(t *testing.T) {
+	created := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	balance := decimal.RequireFromString("250.50")
+	after := &model.AccountCursor{Balance: balance, CreatedAt: created, AccountID: 42}
+
+	tests := []struct {
+		name      string
+		filter    model.AccountFilter
+		wantWhere string // everything after "FROM accounts"
+		wantArgs  []any
+	}{
+		{
+			name:      "defaults",
+			filter:    model.AccountFilter{Limit: 51},
+			wantWhere: " WHERE system_role IS NULL ORDER BY account_id ASC LIMIT $1",
+			wantArgs:  []any{51},
+		},
+		{
+			name: "filters",
+			filter: model.AccountFilter{
+				Status: model.AccountFrozen, OwnerID: "cust-1", AccountType: "savings", Name: "50%_off",
+				Metadata: map[string]string{"region": "eu"}, MinBalance: &balance, MaxBalance: &balance,
+				CreatedFrom: &created, CreatedTo: &created, Limit: 11,
+			},
+			wantWhere: " WHERE system_role IS NULL AND status = $1 AND owner_id = $2 AND account_type = $3" +
+				` AND name ILIKE '%' || $4 || '%' AND metadata @> $5 AND balance >= $6 AND balance <= $7` +
+				" AND created_at >= $8 AND created_at < $9 ORDER BY account_id ASC LIMIT $10",
+			wantArgs: []any{model.AccountFrozen, "cust-1", "savings", `50\%\_off`, []byte(`{"region":"eu"}`),
+				balance, balance, created, created, 11},
+		},
+		{
+			name:      "after an account ID",
+			filter:    model.AccountFilter{Sort: model.AccountSortID, After: after, Limit: 51},
+			wantWhere: " WHERE system_role IS NULL AND account_id > $1 ORDER BY account_id ASC LIMIT $2",
+			wantArgs:  []any{int64(42), 51},
+		},
+		{
+			name:      "after an account ID, descending",
+			filter:    model.AccountFilter{Sort: model.AccountSortID, Desc: true, After: after, Limit: 51},
+			wantWhere: " WHERE system_role IS NULL AND account_id < $1 ORDER BY account_id DESC LIMIT $2",
+			wantArgs:  []any{int64(42), 51},
+		},
+		{
+			name:      "by balance",
+			filter:    model.AccountFilter{Sort: model.AccountSortBalance, Limit: 51},
+			wantWhere: " WHERE system_role IS NULL ORDER BY balance ASC, account_id ASC LIMIT $1",
+			wantArgs:  []any{51},
+		},
+		{
+			name:   "after a balance, descending",
+			filter: model.AccountFilter{Status: model.AccountActive, Sort: model.AccountSortBalance, Desc: true, After: after, Limit: 51},
+			wantWhere: " WHERE system_role IS NULL AND status = $1 AND (balance, account_id) < ($2, $3)" +
+				" ORDER BY balance DESC, account_id DESC LIMIT $4",
+			wantArgs: []any{model.AccountActive, balance, int64(42), 51},
+		},
+		{
+			name:   "after a creation time",
+			filter: model.AccountFilter{Sort: model.AccountSortCreatedAt, After: after, Limit: 51},
+			wantWhere: " WHERE system_role IS NULL AND (created_at, account_id) > ($1, $2)" +
+				" ORDER BY created_at ASC, account_id ASC LIMIT $3",
+			wantArgs: []any{created, int64(42), 51},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := accountListQuery(tt.filter)
+			if err != nil {
+				t.Fatalf("accountListQuery: %v", err)
+			}
+			_, where, ok := strings.Cut(query, "FROM accounts")
+			if !ok {
+				t.Fatalf("query = %q, want a select from accounts", query)
+			}
+			if where != tt.wantWhere {
+				t.Errorf("query ends with\n%q\nwant\n%q", where, tt.wantWhere)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
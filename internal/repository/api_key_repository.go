@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/model"
+)
+
+type APIKeyRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewAPIKeyRepository(pool *pgxpool.Pool) *APIKeyRepository {
+	return &APIKeyRepository{pool: pool}
+}
+
+func (r *APIKeyRepository) Create(ctx context.Context, k *model.APIKey) error {
+	err := r.pool.QueryRow(ctx,
+		`INSERT INTO api_keys (name, prefix, key_hash, role, scopes)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id, created_at`,
+		k.Name, k.Prefix, k.KeyHash, k.Role, k.Scopes,
+	).Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.ConstraintName == "api_keys_name_key" {
+			return &apperror.ErrConflict{Entity: "API key name"}
+		}
+		return fmt.Errorf("inserting API key: %w", err)
+	}
+	return nil
+}
+
+// GetActiveByHash returns the unrevoked key with the given hash, or nil when
+// there is none.
+func (r *APIKeyRepository) GetActiveByHash(ctx context.Context, hash string) (*model.APIKey, error) {
+	k, err := scanAPIKey(r.pool.QueryRow(ctx,
+		`SELECT `+apiKeyColumns+` FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`, hash))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("querying API key: %w", err)
+	}
+	return k, nil
+}
+
+func (r *APIKeyRepository) List(ctx context.Context) ([]model.APIKey, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+apiKeyColumns+` FROM api_keys ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("listing API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var out []model.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning API key: %w", err)
+		}
+		out = append(out, *k)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing API keys: %w", err)
+	}
+	return out, nil
+}
+
+// Revoke marks a key revoked. Revoking a revoked key keeps the original
+// revocation time.
+func (r *APIKeyRepository) Revoke(ctx context.Context, id int64) (*model.APIKey, error) {
+	k, err := scanAPIKey(r.pool.QueryRow(ctx,
+		`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW())
+		 WHERE id = $1
+		 RETURNING `+apiKeyColumns,
+		id,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &apperror.ErrNotFound{Entity: "API key", ID: id}
+		}
+		return nil, fmt.Errorf("revoking API key: %w", err)
+	}
+	return k, nil
+}
+
+const apiKeyColumns = `id, name, prefix, key_hash, role, scopes, created_at, revoked_at`
+
+func scanAPIKey(row pgx.Row) (*model.APIKey, error) {
+	var k model.APIKey
+	if err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.KeyHash, &k.Role, &k.Scopes, &k.CreatedAt, &k.RevokedAt); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
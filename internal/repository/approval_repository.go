@@ -0,0 +1,149 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/model"
+)
+
+type ApprovalRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewApprovalRepository(pool *pgxpool.Pool) *ApprovalRepository {
+	return &ApprovalRepository{pool: pool}
+}
+
+// Create inserts a and fills in the generated ID, status and CreatedAt.
+func (r *ApprovalRepository) Create(ctx context.Context, tx pgx.Tx, a *model.PendingApproval) error {
+	err := tx.QueryRow(ctx,
+		`INSERT INTO pending_approvals (source_account_id, destination_account_id, amount, currency, fx_rate,
+		                                funds_held, initiated_by, initiated_by_id, expires_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 RETURNING id, status, created_at`,
+		a.SourceAccountID, a.DestinationAccountID, a.Amount, a.Currency, a.FXRate,
+		a.FundsHeld, a.InitiatedBy, a.InitiatedByID, a.ExpiresAt,
+	).Scan(&a.ID, &a.Status, &a.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting pending approval: %w", err)
+	}
+	return nil
+}
+
+func (r *ApprovalRepository) GetByID(ctx context.Context, id int64) (*model.PendingApproval, error) {
+	a, err := scanApproval(r.pool.QueryRow(ctx, `SELECT `+approvalColumns+` FROM pending_approvals WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &apperror.ErrNotFound{Entity: "approval", ID: id}
+		}
+		return nil, fmt.Errorf("querying pending approval: %w", err)
+	}
+	return a, nil
+}
+
+func (r *ApprovalRepository) GetByIDForUpdate(ctx context.Context, tx pgx.Tx, id int64) (*model.PendingApproval, error) {
+	a, err := scanApproval(tx.QueryRow(ctx, `SELECT `+approvalColumns+` FROM pending_approvals WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &apperror.ErrNotFound{Entity: "approval", ID: id}
+		}
+		return nil, fmt.Errorf("locking pending approval: %w", err)
+	}
+	return a, nil
+}
+
+func (r *ApprovalRepository) List(ctx context.Context, f model.ApprovalFilter) ([]model.PendingApproval, error) {
+	args := []any{f.After}
+	where := "id > $1"
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if f.Status != "" {
+		where += " AND status = " + arg(f.Status)
+	}
+	if f.AccountID != 0 {
+		p := arg(f.AccountID)
+		where += fmt.Sprintf(" AND (source_account_id = %s OR destination_account_id = %s)", p, p)
+	}
+
+	query := `SELECT ` + approvalColumns + ` FROM pending_approvals WHERE ` + where +
+		` ORDER BY id LIMIT ` + arg(f.Limit)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing pending approvals: %w", err)
+	}
+	return collectApprovals(rows)
+}
+
+// ClaimExpired locks up to limit pending approvals whose expiry has passed,
+// skipping any that another worker or a decision is already processing.
+func (r *ApprovalRepository) ClaimExpired(ctx context.Context, tx pgx.Tx, limit int) ([]model.PendingApproval, error) {
+	rows, err := tx.Query(ctx,
+		`SELECT `+approvalColumns+` FROM pending_approvals
+		 WHERE status = 'pending' AND expires_at <= NOW()
+		 ORDER BY expires_at
+		 LIMIT $1
+		 FOR UPDATE SKIP LOCKED`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claiming expired approvals: %w", err)
+	}
+	return collectApprovals(rows)
+}
+
+// Decide persists the status, decider and reason of a and stamps DecidedAt.
+func (r *ApprovalRepository) Decide(ctx context.Context, tx pgx.Tx, a *model.PendingApproval) error {
+	err := tx.QueryRow(ctx,
+		`UPDATE pending_approvals SET status = $1, decided_by = $2, reason = $3, decided_at = NOW()
+		 WHERE id = $4
+		 RETURNING decided_at`,
+		a.Status, a.DecidedBy, a.Reason, a.ID,
+	).Scan(&a.DecidedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &apperror.ErrNotFound{Entity: "approval", ID: a.ID}
+		}
+		return fmt.Errorf("updating pending approval: %w", err)
+	}
+	return nil
+}
+
+const approvalColumns = `id, source_account_id, destination_account_id, amount, currency, fx_rate, funds_held,
+	status, initiated_by, initiated_by_id, decided_by, reason, expires_at, decided_at, created_at`
+
+func scanApproval(row pgx.Row) (*model.PendingApproval, error) {
+	var a model.PendingApproval
+	err := row.Scan(&a.ID, &a.SourceAccountID, &a.DestinationAccountID, &a.Amount, &a.Currency, &a.FXRate, &a.FundsHeld,
+		&a.Status, &a.InitiatedBy, &a.InitiatedByID, &a.DecidedBy, &a.Reason, &a.ExpiresAt, &a.DecidedAt, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func collectApprovals(rows pgx.Rows) ([]model.PendingApproval, error) {
+	defer rows.Close()
+
+	var out []model.PendingApproval
+	for rows.Next() {
+		a, err := scanApproval(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning pending approval: %w", err)
+		}
+		out = append(out, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing pending approvals: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/model"
+)
+
+type FeePolicyRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewFeePolicyRepository(pool *pgxpool.Pool) *FeePolicyRepository {
+	return &FeePolicyRepository{pool: pool}
+}
+
+// Create inserts p and fills in the generated ID and timestamps.
+func (r *FeePolicyRepository) Create(ctx context.Context, p *model.FeePolicy) error {
+	tiers, err := marshalTiers(p.Tiers)
+	if err != nil {
+		return err
+	}
+	err = r.pool.QueryRow(ctx,
+		`INSERT INTO fee_policies (account_type, source_account_id, destination_account_id, currency, fee_type,
+		                           flat_amount, percentage, tiers, min_fee, max_fee)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 RETURNING id, created_at, updated_at`,
+		p.AccountType, p.SourceAccountID, p.DestinationAccountID, p.Currency, p.FeeType,
+		p.FlatAmount, p.Percentage, tiers, p.MinFee, p.MaxFee,
+	).Scan(&p.ID, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return &apperror.ErrConflict{Entity: "fee policy"}
+		}
+		return fmt.Errorf("inserting fee policy: %w", err)
+	}
+	return nil
+}
+
+func (r *FeePolicyRepository) GetByID(ctx context.Context, id int64) (*model.FeePolicy, error) {
+	p, err := scanFeePolicy(r.pool.QueryRow(ctx, `SELECT `+feePolicyColumns+` FROM fee_policies WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &apperror.ErrNotFound{Entity: "fee policy", ID: id}
+		}
+		return nil, fmt.Errorf("querying fee policy: %w", err)
+	}
+	return p, nil
+}
+
+func (r *FeePolicyRepository) List(ctx context.Context) ([]model.FeePolicy, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+feePolicyColumns+` FROM fee_policies ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("listing fee policies: %w", err)
+	}
+	defer rows.Close()
+
+	var out []model.FeePolicy
+	for rows.Next() {
+		p, err := scanFeePolicy(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning fee policy: %w", err)
+		}
+		out = append(out, *p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing fee policies: %w", err)
+	}
+	return out, nil
+}
+
+// Update replaces the pricing of p; its scope and currency cannot change.
+func (r *FeePolicyRepository) Update(ctx context.Context, p *model.FeePolicy) error {
+	tiers, err := marshalTiers(p.Tiers)
+	if err != nil {
+		return err
+	}
+	err = r.pool.QueryRow(ctx,
+		`UPDATE fee_policies
+		 SET fee_type = $1, flat_amount = $2, percentage = $3, tiers = $4, min_fee = $5, max_fee = $6, updated_at = NOW()
+		 WHERE id = $7
+		 RETURNING updated_at`,
+		p.FeeType, p.FlatAmount, p.Percentage, tiers, p.MinFee, p.MaxFee, p.ID,
+	).Scan(&p.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &apperror.ErrNotFound{Entity: "fee policy", ID: p.ID}
+		}
+		return fmt.Errorf("updating fee policy: %w", err)
+	}
+	return nil
+}
+
+func (r *FeePolicyRepository) Delete(ctx context.Context, id int64) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM fee_policies WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("deleting fee policy: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return &apperror.ErrNotFound{Entity: "fee policy", ID: id}
+	}
+	return nil
+}
+
+// FindForTransfer returns the policy pricing a transfer of currency from
+// sourceID to destID, preferring a policy for the exact account pair over one
+// for the source's account type. It returns nil when no policy applies.
+func (r *FeePolicyRepository) FindForTransfer(ctx context.Context, tx pgx.Tx, sourceID, destID int64, currency string) (*model.FeePolicy, error) {
+	p, err := scanFeePolicy(tx.QueryRow(ctx,
+		`SELECT `+feePolicyColumns+` FROM fee_policies
+		 WHERE currency = $1
+		   AND ((source_account_id = $2 AND destination_account_id = $3)
+		        OR account_type = (SELECT account_type FROM accounts WHERE account_id = $2))
+		 ORDER BY source_account_id IS NULL
+		 LIMIT 1`,
+		currency, sourceID, destID,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("finding fee policy: %w", err)
+	}
+	return p, nil
+}
+
+const feePolicyColumns = `id, account_type, source_account_id, destination_account_id, currency, fee_type,
+	flat_amount, percentage, tiers, min_fee, max_fee, created_at, updated_at`
+
+func scanFeePolicy(row pgx.Row) (*model.FeePolicy, error) {
+	var p model.FeePolicy
+	var tiers []byte
+	err := row.Scan(&p.ID, &p.AccountType, &p.SourceAccountID, &p.DestinationAccountID, &p.Currency, &p.FeeType,
+		&p.FlatAmount, &p.Percentage, &tiers, &p.MinFee, &p.MaxFee, &p.CreatedAt, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if len(tiers) > 0 {
+		if err := json.Unmarshal(tiers, &p.Tiers); err != nil {
+			return nil, fmt.Errorf("decoding fee tiers: %w", err)
+		}
+	}
+	return &p, nil
+}
+
+// marshalTiers encodes tiers for the JSONB column, using NULL for none.
+func marshalTiers(tiers []model.FeeTier) ([]byte, error) {
+	if len(tiers) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(tiers)
+	if err != nil {
+		return nil, fmt.Errorf("encoding fee tiers: %w", err)
+	}
+	return b, nil
+}
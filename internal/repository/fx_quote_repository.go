@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/model"
+)
+
+type FXQuoteRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewFXQuoteRepository(pool *pgxpool.Pool) *FXQuoteRepository {
+	return &FXQuoteRepository{pool: pool}
+}
+
+// Create inserts q and fills in the generated ID and CreatedAt.
+func (r *FXQuoteRepository) Create(ctx context.Context, q *model.FXQuote) error {
+	err := r.pool.QueryRow(ctx,
+		`INSERT INTO fx_quotes (source_currency, destination_currency, rate, expires_at)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING id, created_at`,
+		q.SourceCurrency, q.DestinationCurrency, q.Rate, q.ExpiresAt,
+	).Scan(&q.ID, &q.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting fx quote: %w", err)
+	}
+	return nil
+}
+
+func (r *FXQuoteRepository) GetByID(ctx context.Context, id int64) (*model.FXQuote, error) {
+	q, err := scanFXQuote(r.pool.QueryRow(ctx, `SELECT `+fxQuoteColumns+` FROM fx_quotes WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &apperror.ErrNotFound{Entity: "fx quote", ID: id}
+		}
+		return nil, fmt.Errorf("querying fx quote: %w", err)
+	}
+	return q, nil
+}
+
+func (r *FXQuoteRepository) GetByIDForUpdate(ctx context.Context, tx pgx.Tx, id int64) (*model.FXQuote, error) {
+	q, err := scanFXQuote(tx.QueryRow(ctx, `SELECT `+fxQuoteColumns+` FROM fx_quotes WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &apperror.ErrNotFound{Entity: "fx quote", ID: id}
+		}
+		return nil, fmt.Errorf("locking fx quote: %w", err)
+	}
+	return q, nil
+}
+
+func (r *FXQuoteRepository) MarkUsed(ctx context.Context, tx pgx.Tx, id, transactionID int64) error {
+	_, err := tx.Exec(ctx, `UPDATE fx_quotes SET transaction_id = $1 WHERE id = $2`, transactionID, id)
+	if err != nil {
+		return fmt.Errorf("marking fx quote used: %w", err)
+	}
+	return nil
+}
+
+const fxQuoteColumns = `id, source_currency, destination_currency, rate, expires_at, transaction_id, created_at`
+
+func scanFXQuote(row pgx.Row) (*model.FXQuote, error) {
+	var q model.FXQuote
+	err := row.Scan(&q.ID, &q.SourceCurrency, &q.DestinationCurrency, &q.Rate, &q.ExpiresAt, &q.TransactionID, &q.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
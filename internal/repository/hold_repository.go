@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/model"
+)
+
+type HoldRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewHoldRepository(pool *pgxpool.Pool) *HoldRepository {
+	return &HoldRepository{pool: pool}
+}
+
+// Create inserts h and fills in the generated ID, status and timestamps.
+func (r *HoldRepository) Create(ctx context.Context, tx pgx.Tx, h *model.Hold) error {
+	err := tx.QueryRow(ctx,
+		`INSERT INTO holds (account_id, amount, expires_at)
+		 VALUES ($1, $2, $3)
+		 RETURNING id, status, created_at, updated_at`,
+		h.AccountID, h.Amount, h.ExpiresAt,
+	).Scan(&h.ID, &h.Status, &h.CreatedAt, &h.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting hold: %w", err)
+	}
+	return nil
+}
+
+func (r *HoldRepository) GetByID(ctx context.Context, id int64) (*model.Hold, error) {
+	h, err := scanHold(r.pool.QueryRow(ctx, `SELECT `+holdColumns+` FROM holds WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &apperror.ErrNotFound{Entity: "hold", ID: id}
+		}
+		return nil, fmt.Errorf("querying hold: %w", err)
+	}
+	return h, nil
+}
+
+func (r *HoldRepository) GetByIDForUpdate(ctx context.Context, tx pgx.Tx, id int64) (*model.Hold, error) {
+	h, err := scanHold(tx.QueryRow(ctx, `SELECT `+holdColumns+` FROM holds WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &apperror.ErrNotFound{Entity: "hold", ID: id}
+		}
+		return nil, fmt.Errorf("locking hold: %w", err)
+	}
+	return h, nil
+}
+
+// ClaimExpired locks up to limit active holds whose expiry has passed,
+// skipping any that another worker or a capture is already processing.
+func (r *HoldRepository) ClaimExpired(ctx context.Context, tx pgx.Tx, limit int) ([]model.Hold, error) {
+	rows, err := tx.Query(ctx,
+		`SELECT `+holdColumns+` FROM holds
+		 WHERE status = 'active' AND expires_at <= NOW()
+		 ORDER BY expires_at
+		 LIMIT $1
+		 FOR UPDATE SKIP LOCKED`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claiming expired holds: %w", err)
+	}
+	defer rows.Close()
+
+	var out []model.Hold
+	for rows.Next() {
+		h, err := scanHold(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning hold: %w", err)
+		}
+		out = append(out, *h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("claiming expired holds: %w", err)
+	}
+	return out, nil
+}
+
+// UpdateStatus persists the status, captured amount and transaction of h.
+func (r *HoldRepository) UpdateStatus(ctx context.Context, tx pgx.Tx, h *model.Hold) error {
+	err := tx.QueryRow(ctx,
+		`UPDATE holds SET status = $1, captured_amount = $2, transaction_id = $3, updated_at = NOW()
+		 WHERE id = $4
+		 RETURNING updated_at`,
+		h.Status, h.CapturedAmount, h.TransactionID, h.ID,
+	).Scan(&h.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return &apperror.ErrNotFound{Entity: "hold", ID: h.ID}
+		}
+		return fmt.Errorf("updating hold: %w", err)
+	}
+	return nil
+}
+
+const holdColumns = `id, account_id, amount, status, captured_amount, transaction_id, expires_at, created_at, updated_at`
+
+func scanHold(row pgx.Row) (*model.Hold, error) {
+	var h model.Hold
+	err := row.Scan(&h.ID, &h.AccountID, &h.Amount, &h.Status, &h.CapturedAmount, &h.TransactionID, &h.ExpiresAt, &h.CreatedAt, &h.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
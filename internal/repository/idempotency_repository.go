@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/InternalTransfer/internal/model"
+)
+
+type IdempotencyRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewIdempotencyRepository(pool *pgxpool.Pool) *IdempotencyRepository {
+	return &IdempotencyRepository{pool: pool}
+}
+
+// Claim reserves principal's key for the current transaction. If the key was
+// already used the stored record is returned instead. A concurrent claim of
+// the same key blocks on the unique index until the other transaction
+// finishes.
+func (r *IdempotencyRepository) Claim(ctx context.Context, tx pgx.Tx, principal, key, requestHash string) (*model.IdempotencyKey, error) {
+	tag, err := tx.Exec(ctx,
+		`INSERT INTO idempotency_keys (principal, key, request_hash) VALUES ($1, $2, $3)
+		 ON CONFLICT (principal, key) DO NOTHING`,
+		principal, key, requestHash,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claiming idempotency key: %w", err)
+	}
+	if tag.RowsAffected() == 1 {
+		return nil, nil
+	}
+
+	var k model.IdempotencyKey
+	err = tx.QueryRow(ctx,
+		`SELECT principal, key, request_hash, transaction_id, approval_id, created_at FROM idempotency_keys
+		 WHERE principal = $1 AND key = $2`,
+		principal, key,
+	).Scan(&k.Principal, &k.Key, &k.RequestHash, &k.TransactionID, &k.ApprovalID, &k.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("querying idempotency key: %w", err)
+	}
+	return &k, nil
+}
+
+func (r *IdempotencyRepository) Complete(ctx context.Context, tx pgx.Tx, principal, key string, transactionID int64) error {
+	_, err := tx.Exec(ctx, `UPDATE idempotency_keys SET transaction_id = $1 WHERE principal = $2 AND key = $3`,
+		transactionID, principal, key)
+	if err != nil {
+		return fmt.Errorf("completing idempotency key: %w", err)
+	}
+	return nil
+}
+
+// CompleteApproval records that the request under principal's key was
+// submitted for approval as approvalID.
+func (r *IdempotencyRepository) CompleteApproval(ctx context.Context, tx pgx.Tx, principal, key string, approvalID int64) error {
+	_, err := tx.Exec(ctx, `UPDATE idempotency_keys SET approval_id = $1 WHERE principal = $2 AND key = $3`,
+		approvalID, principal, key)
+	if err != nil {
+		return fmt.Errorf("completing idempotency key: %w", err)
+	}
+	return nil
+}
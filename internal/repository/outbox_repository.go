@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/InternalTransfer/internal/model"
+)
+
+type OutboxRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewOutboxRepository(pool *pgxpool.Pool) *OutboxRepository {
+	return &OutboxRepository{pool: pool}
+}
+
+// Create records e in tx, so that it is published only if tx commits.
+func (r *OutboxRepository) Create(ctx context.Context, tx pgx.Tx, e *model.OutboxEvent) error {
+	err := tx.QueryRow(ctx,
+		`INSERT INTO outbox_events (event_type, account_ids, payload)
+		 VALUES ($1, $2, $3)
+		 RETURNING id, status, next_attempt_at, created_at`,
+		e.Type, e.AccountIDs, []byte(e.Data),
+	).Scan(&e.ID, &e.Status, &e.NextAttemptAt, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting outbox event: %w", err)
+	}
+	return nil
+}
+
+// ClaimDue leases up to limit pending events whose next attempt is due,
+// oldest first, by pushing their next attempt lease into the future. Other
+// relays skip them until then, so the lease must outlast publishing them.
+// The returned events carry the lease expiry in NextAttemptAt.
+func (r *OutboxRepository) ClaimDue(ctx context.Context, tx pgx.Tx, limit int, lease time.Duration) ([]model.OutboxEvent, error) {
+	rows, err := tx.Query(ctx,
+		`WITH claimed AS (
+		     UPDATE outbox_events SET next_attempt_at = NOW() + $2::INTERVAL
+		     WHERE id IN (
+		         SELECT id FROM outbox_events
+		         WHERE status = 'pending' AND next_attempt_at <= NOW()
+		         ORDER BY id
+		         LIMIT $1
+		         FOR UPDATE SKIP LOCKED
+		     )
+		     RETURNING id, event_type, account_ids, payload, status, attempts, next_attempt_at, last_error, created_at, delivered_at
+		 )
+		 SELECT * FROM claimed ORDER BY id`,
+		limit, lease,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claiming outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var out []model.OutboxEvent
+	for rows.Next() {
+		var e model.OutboxEvent
+		var payload []byte
+		err := rows.Scan(&e.ID, &e.Type, &e.AccountIDs, &payload, &e.Status, &e.Attempts, &e.NextAttemptAt,
+			&e.LastError, &e.CreatedAt, &e.DeliveredAt)
+		if err != nil {
+			return nil, fmt.Errorf("scanning outbox event: %w", err)
+		}
+		e.Data = payload
+		out = append(out, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("claiming outbox events: %w", err)
+	}
+	return out, nil
+}
+
+// MarkDelivered records that e, claimed by ClaimDue, was published. It
+// reports false, changing nothing, when the lease on e has expired and the
+// event was claimed again meanwhile.
+func (r *OutboxRepository) MarkDelivered(ctx context.Context, tx pgx.Tx, e *model.OutboxEvent) (bool, error) {
+	tag, err := tx.Exec(ctx,
+		`UPDATE outbox_events SET status = 'delivered', attempts = attempts + 1, last_error = NULL, delivered_at = NOW()
+		 WHERE id = $1 AND status = 'pending' AND next_attempt_at = $2`,
+		e.ID, e.NextAttemptAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("marking outbox event delivered: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// MarkFailed records a failed attempt on e, claimed by ClaimDue. The event is
+// retried at nextAttemptAt unless dead is set, which parks it for manual
+// inspection. Like MarkDelivered it reports false if the lease was lost.
+func (r *OutboxRepository) MarkFailed(ctx context.Context, tx pgx.Tx, e *model.OutboxEvent, lastError string, nextAttemptAt time.Time, dead bool) (bool, error) {
+	status := model.OutboxPending
+	if dead {
+		status = model.OutboxDead
+	}
+	tag, err := tx.Exec(ctx,
+		`UPDATE outbox_events SET status = $1, attempts = attempts + 1, last_error = $2, next_attempt_at = $3
+		 WHERE id = $4 AND status = 'pending' AND next_attempt_at = $5`,
+		status, lastError, nextAttemptAt, e.ID, e.NextAttemptAt,
+	)
+	if err != nil {
+		return false, fmt.Errorf("marking outbox event failed: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/InternalTransfer/internal/model"
+)
+
+// ReconcileRepository reads the totals that reconciliation compares. Each
+// method is a single statement, so its figures come from one consistent
+// snapshot even while transfers are running.
+type ReconcileRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewReconcileRepository(pool *pgxpool.Pool) *ReconcileRepository {
+	return &ReconcileRepository{pool: pool}
+}
+
+// ledgerStart selects the first transaction posted with ledger entries.
+// Transfers made before the ledger existed have none; the opening
+// transactions backfilled with the ledger account for them instead.
+const ledgerStart = `(SELECT COALESCE(MIN(transaction_id), 0) FROM ledger_entries)`
+
+// StreamAccountTotals calls fn for every account, system accounts included,
+// in account ID order.
+func (r *ReconcileRepository) StreamAccountTotals(ctx context.Context, fn func(*model.AccountTotals) error) error {
+	rows, err := r.pool.Query(ctx,
+		`SELECT a.account_id, a.currency, a.balance, a.held_balance,
+		        COALESCE(e.total, 0), COALESCE(t.total, 0), COALESCE(h.total, 0)
+		 FROM accounts a
+		 LEFT JOIN (
+		     SELECT account_id, SUM(CASE direction WHEN 'credit' THEN amount ELSE -amount END) AS total
+		     FROM ledger_entries
+		     GROUP BY account_id
+		 ) e ON e.account_id = a.account_id
+		 LEFT JOIN (
+		     SELECT account_id, SUM(amount) AS total
+		     FROM (
+		         SELECT destination_account_id AS account_id, destination_amount AS amount
+		         FROM transactions WHERE id >= `+ledgerStart+`
+		         UNION ALL
+		         SELECT source_account_id, -amount
+		         FROM transactions WHERE id >= `+ledgerStart+`
+		     ) moved
+		     GROUP BY account_id
+		 ) t ON t.account_id = a.account_id
+		 LEFT JOIN (
+		     SELECT account_id, SUM(amount) AS total
+		     FROM (
+		         SELECT account_id, amount FROM holds WHERE status = 'active'
+		         UNION ALL
+		         SELECT source_account_id, amount FROM pending_approvals WHERE status = 'pending' AND funds_held
+		     ) reserved
+		     GROUP BY account_id
+		 ) h ON h.account_id = a.account_id
+		 ORDER BY a.account_id`,
+	)
+	if err != nil {
+		return fmt.Errorf("querying account totals: %w", err)
+	}
+	defer rows.Close()
+
+	var t model.AccountTotals
+	for rows.Next() {
+		if err := rows.Scan(&t.AccountID, &t.Currency, &t.Balance, &t.HeldBalance, &t.LedgerBalance, &t.TransactionBalance, &t.ActiveHolds); err != nil {
+			return fmt.Errorf("scanning account totals: %w", err)
+		}
+		if err := fn(&t); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading account totals: %w", err)
+	}
+	return nil
+}
+
+// StreamTransactionEntries calls fn for every transaction posted since the
+// ledger was introduced, in ID order, with the ledger entries recorded for it.
+func (r *ReconcileRepository) StreamTransactionEntries(ctx context.Context, fn func(*model.TransactionEntries) error) error {
+	rows, err := r.pool.Query(ctx,
+		`SELECT t.id, t.currency, t.destination_currency, t.amount, t.destination_amount,
+		        COUNT(e.id) FILTER (WHERE e.direction = 'debit'),
+		        COUNT(e.id) FILTER (WHERE e.direction = 'credit'),
+		        COALESCE(SUM(e.amount) FILTER (WHERE e.direction = 'debit' AND e.account_id = t.source_account_id), 0),
+		        COALESCE(SUM(e.amount) FILTER (WHERE e.direction = 'credit' AND e.account_id = t.destination_account_id), 0)
+		 FROM transactions t
+		 LEFT JOIN ledger_entries e ON e.transaction_id = t.id
+		 WHERE t.id >= `+ledgerStart+`
+		 GROUP BY t.id
+		 ORDER BY t.id`,
+	)
+	if err != nil {
+		return fmt.Errorf("querying transaction entries: %w", err)
+	}
+	defer rows.Close()
+
+	var t model.TransactionEntries
+	for rows.Next() {
+		err := rows.Scan(&t.TransactionID, &t.Currency, &t.DestinationCurrency, &t.Amount, &t.DestinationAmount,
+			&t.Debits, &t.Credits, &t.DebitAmount, &t.CreditAmount)
+		if err != nil {
+			return fmt.Errorf("scanning transaction entries: %w", err)
+		}
+		if err := fn(&t); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading transaction entries: %w", err)
+	}
+	return nil
+}
+
+// CurrencyTotals sums balances per currency alongside the net amount that
+// cross-currency transfers moved into each currency.
+func (r *ReconcileRepository) CurrencyTotals(ctx context.Context) ([]model.CurrencyTotal, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT COALESCE(b.currency, f.currency), COALESCE(b.total, 0), COALESCE(f.net, 0)
+		 FROM (
+		     SELECT currency, SUM(balance) AS total FROM accounts GROUP BY currency
+		 ) b
+		 FULL JOIN (
+		     SELECT currency, SUM(amount) AS net
+		     FROM (
+		         SELECT destination_currency AS currency, destination_amount AS amount
+		         FROM transactions WHERE destination_currency <> currency
+		         UNION ALL
+		         SELECT currency, -amount
+		         FROM transactions WHERE destination_currency <> currency
+		     ) fx
+		     GROUP BY currency
+		 ) f ON f.currency = b.currency
+		 ORDER BY 1`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("querying currency totals: %w", err)
+	}
+	defer rows.Close()
+
+	var out []model.CurrencyTotal
+	for rows.Next() {
+		var t model.CurrencyTotal
+		if err := rows.Scan(&t.Currency, &t.Balance, &t.NetFX); err != nil {
+			return nil, fmt.Errorf("scanning currency totals: %w", err)
+		}
+		out = append(out, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading currency totals: %w", err)
+	}
+	return out, nil
+}
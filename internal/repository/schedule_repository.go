@@ -0,0 +1,191 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/model"
+)
+
+type ScheduleRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewScheduleRepository(pool *pgxpool.Pool) *ScheduleRepository {
+	return &ScheduleRepository{pool: pool}
+}
+
+// Create inserts s and fills in the generated ID, status and timestamps.
+func (r *ScheduleRepository) Create(ctx context.Context, s *model.ScheduledTransfer) error {
+	err := r.pool.QueryRow(ctx,
+		`INSERT INTO scheduled_transfers (source_account_id, destination_account_id, amount, currency,
+		                                  cron_expr, interval_seconds, starts_at, ends_at, next_run_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		 RETURNING id, status, created_at, updated_at`,
+		s.SourceAccountID, s.DestinationAccountID, s.Amount, s.Currency,
+		s.CronExpr, s.IntervalSeconds, s.StartsAt, s.EndsAt, s.NextRunAt,
+	).Scan(&s.ID, &s.Status, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting scheduled transfer: %w", err)
+	}
+	return nil
+}
+
+func (r *ScheduleRepository) GetByID(ctx context.Context, id int64) (*model.ScheduledTransfer, error) {
+	s, err := scanSchedule(r.pool.QueryRow(ctx, `SELECT `+scheduleColumns+` FROM scheduled_transfers WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &apperror.ErrNotFound{Entity: "scheduled transfer", ID: id}
+		}
+		return nil, fmt.Errorf("querying scheduled transfer: %w", err)
+	}
+	return s, nil
+}
+
+func (r *ScheduleRepository) GetByIDForUpdate(ctx context.Context, tx pgx.Tx, id int64) (*model.ScheduledTransfer, error) {
+	s, err := scanSchedule(tx.QueryRow(ctx, `SELECT `+scheduleColumns+` FROM scheduled_transfers WHERE id = $1 FOR UPDATE`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &apperror.ErrNotFound{Entity: "scheduled transfer", ID: id}
+		}
+		return nil, fmt.Errorf("locking scheduled transfer: %w", err)
+	}
+	return s, nil
+}
+
+func (r *ScheduleRepository) List(ctx context.Context, f model.ScheduleFilter) ([]model.ScheduledTransfer, error) {
+	args := []any{f.After}
+	where := "id > $1"
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if f.AccountID != 0 {
+		p := arg(f.AccountID)
+		where += fmt.Sprintf(" AND (source_account_id = %s OR destination_account_id = %s)", p, p)
+	}
+
+	query := `SELECT ` + scheduleColumns + ` FROM scheduled_transfers WHERE ` + where +
+		` ORDER BY id LIMIT ` + arg(f.Limit)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing scheduled transfers: %w", err)
+	}
+	return collectSchedules(rows)
+}
+
+// ClaimDue locks up to limit active schedules whose next run is due,
+// skipping any that another scheduler instance is already running.
+func (r *ScheduleRepository) ClaimDue(ctx context.Context, tx pgx.Tx, limit int) ([]model.ScheduledTransfer, error) {
+	rows, err := tx.Query(ctx,
+		`SELECT `+scheduleColumns+` FROM scheduled_transfers
+		 WHERE status = 'active' AND next_run_at <= NOW()
+		 ORDER BY next_run_at
+		 LIMIT $1
+		 FOR UPDATE SKIP LOCKED`,
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claiming due schedules: %w", err)
+	}
+	return collectSchedules(rows)
+}
+
+// Update persists the mutable fields of s and refreshes UpdatedAt.
+func (r *ScheduleRepository) Update(ctx context.Context, tx pgx.Tx, s *model.ScheduledTransfer) error {
+	err := tx.QueryRow(ctx,
+		`UPDATE scheduled_transfers
+		 SET amount = $1, cron_expr = $2, interval_seconds = $3, starts_at = $4, ends_at = $5,
+		     status = $6, next_run_at = $7, last_run_at = $8, updated_at = NOW()
+		 WHERE id = $9
+		 RETURNING updated_at`,
+		s.Amount, s.CronExpr, s.IntervalSeconds, s.StartsAt, s.EndsAt,
+		s.Status, s.NextRunAt, s.LastRunAt, s.ID,
+	).Scan(&s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("updating scheduled transfer: %w", err)
+	}
+	return nil
+}
+
+// CreateRun inserts run and fills in the generated ID and CreatedAt.
+func (r *ScheduleRepository) CreateRun(ctx context.Context, tx pgx.Tx, run *model.ScheduledTransferRun) error {
+	err := tx.QueryRow(ctx,
+		`INSERT INTO scheduled_transfer_runs (schedule_id, scheduled_for, status, transaction_id, error_code, error_message)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 RETURNING id, created_at`,
+		run.ScheduleID, run.ScheduledFor, run.Status, run.TransactionID, run.ErrorCode, run.ErrorMessage,
+	).Scan(&run.ID, &run.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting scheduled transfer run: %w", err)
+	}
+	return nil
+}
+
+// ListRuns returns a schedule's runs, newest first. beforeID is the last ID of
+// the previous page, or zero for the first page.
+func (r *ScheduleRepository) ListRuns(ctx context.Context, scheduleID, beforeID int64, limit int) ([]model.ScheduledTransferRun, error) {
+	query := `SELECT id, schedule_id, scheduled_for, status, transaction_id, error_code, error_message, created_at
+		 FROM scheduled_transfer_runs
+		 WHERE schedule_id = $1 AND ($2::BIGINT = 0 OR id < $2)
+		 ORDER BY id DESC
+		 LIMIT $3`
+
+	rows, err := r.pool.Query(ctx, query, scheduleID, beforeID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("listing scheduled transfer runs: %w", err)
+	}
+	defer rows.Close()
+
+	var out []model.ScheduledTransferRun
+	for rows.Next() {
+		var run model.ScheduledTransferRun
+		err := rows.Scan(&run.ID, &run.ScheduleID, &run.ScheduledFor, &run.Status, &run.TransactionID,
+			&run.ErrorCode, &run.ErrorMessage, &run.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("scanning scheduled transfer run: %w", err)
+		}
+		out = append(out, run)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing scheduled transfer runs: %w", err)
+	}
+	return out, nil
+}
+
+const scheduleColumns = `id, source_account_id, destination_account_id, amount, currency, cron_expr, interval_seconds,
+	starts_at, ends_at, status, next_run_at, last_run_at, created_at, updated_at`
+
+func scanSchedule(row pgx.Row) (*model.ScheduledTransfer, error) {
+	var s model.ScheduledTransfer
+	err := row.Scan(&s.ID, &s.SourceAccountID, &s.DestinationAccountID, &s.Amount, &s.Currency, &s.CronExpr, &s.IntervalSeconds,
+		&s.StartsAt, &s.EndsAt, &s.Status, &s.NextRunAt, &s.LastRunAt, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func collectSchedules(rows pgx.Rows) ([]model.ScheduledTransfer, error) {
+	defer rows.Close()
+
+	var out []model.ScheduledTransfer
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning scheduled transfer: %w", err)
+		}
+		out = append(out, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("reading scheduled transfers: %w", err)
+	}
+	return out, nil
+}
@@ -2,11 +2,16 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/model"
 )
 
 type TransactionRepository struct {
@@ -17,10 +22,202 @@ func NewTransactionRepository(pool *pgxpool.Pool) *TransactionRepository {
 	return &TransactionRepository{pool: pool}
 }
 
-func (r *TransactionRepository) Create(ctx context.Context, tx pgx.Tx, sourceID, destID int64, amount decimal.Decimal) error {
-	_, err := tx.Exec(ctx, `INSERT INTO transactions (source_account_id, destination_account_id, amount) VALUES ($1, $2, $3)`, sourceID, destID, amount)
+// Create inserts t and fills in CreatedAt. A zero ID is generated; a set one
+// must have been drawn from the transaction sequence, as approvals are.
+func (r *TransactionRepository) Create(ctx context.Context, tx pgx.Tx, t *model.Transaction) error {
+	err := tx.QueryRow(ctx,
+		`INSERT INTO transactions (id, kind, source_account_id, destination_account_id, amount, currency,
+		                           destination_amount, destination_currency, fx_rate, fx_quote_id, fee_of, source_balance_after, reversal_of)
+		 VALUES (COALESCE(NULLIF($1::BIGINT, 0), nextval('transactions_id_seq')), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		 RETURNING id, created_at`,
+		t.ID, t.Kind, t.SourceAccountID, t.DestinationAccountID, t.Amount, t.Currency,
+		t.DestinationAmount, t.DestinationCurrency, t.FXRate, t.FXQuoteID, t.FeeOf, t.SourceBalanceAfter, t.ReversalOf,
+	).Scan(&t.ID, &t.CreatedAt)
 	if err != nil {
 		return fmt.Errorf("inserting transaction: %w", err)
 	}
 	return nil
 }
+
+func (r *TransactionRepository) GetByID(ctx context.Context, id int64) (*model.Transaction, error) {
+	t, err := scanTransaction(r.pool.QueryRow(ctx,
+		`SELECT `+transactionColumns+` FROM transactions WHERE id = $1`,
+		id,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &apperror.ErrNotFound{Entity: "transaction", ID: id}
+		}
+		return nil, fmt.Errorf("querying transaction: %w", err)
+	}
+	return t, nil
+}
+
+// GetFee returns the fee leg charged on transaction id, or nil if none was.
+func (r *TransactionRepository) GetFee(ctx context.Context, id int64) (*model.Transaction, error) {
+	t, err := scanTransaction(r.pool.QueryRow(ctx,
+		`SELECT `+transactionColumns+` FROM transactions WHERE fee_of = $1`,
+		id,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("querying fee: %w", err)
+	}
+	return t, nil
+}
+
+// GetByIDForUpdate locks a transaction row so that concurrent reversals of the
+// same transaction are serialized.
+func (r *TransactionRepository) GetByIDForUpdate(ctx context.Context, tx pgx.Tx, id int64) (*model.Transaction, error) {
+	t, err := scanTransaction(tx.QueryRow(ctx,
+		`SELECT `+transactionColumns+` FROM transactions WHERE id = $1 FOR UPDATE`,
+		id,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &apperror.ErrNotFound{Entity: "transaction", ID: id}
+		}
+		return nil, fmt.Errorf("locking transaction: %w", err)
+	}
+	return t, nil
+}
+
+// SumReversals totals the reversals of a transaction. Because a reversal runs
+// in the opposite direction, debited is in the original destination currency
+// and credited in the original source currency.
+func (r *TransactionRepository) SumReversals(ctx context.Context, tx pgx.Tx, id int64) (debited, credited decimal.Decimal, err error) {
+	err = tx.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount), 0), COALESCE(SUM(destination_amount), 0) FROM transactions WHERE reversal_of = $1`,
+		id,
+	).Scan(&debited, &credited)
+	if err != nil {
+		return decimal.Zero, decimal.Zero, fmt.Errorf("summing reversals: %w", err)
+	}
+	return debited, credited, nil
+}
+
+// OutgoingUsage sums the transfers and hold captures an account has sent
+// since each of the given window starts.
+func (r *TransactionRepository) OutgoingUsage(ctx context.Context, tx pgx.Tx, accountID int64, dayStart, monthStart, hourStart time.Time) (model.LimitUsage, error) {
+	var u model.LimitUsage
+	err := tx.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount) FILTER (WHERE created_at >= $2), 0),
+		        COALESCE(SUM(amount) FILTER (WHERE created_at >= $3), 0),
+		        COUNT(*) FILTER (WHERE created_at >= $4)
+		 FROM transactions
+		 WHERE source_account_id = $1 AND kind IN ('transfer', 'capture') AND created_at >= LEAST($2, $3, $4)`,
+		accountID, dayStart, monthStart, hourStart,
+	).Scan(&u.DailyAmount, &u.MonthlyAmount, &u.HourlyCount)
+	if err != nil {
+		return u, fmt.Errorf("summing outgoing transfers: %w", err)
+	}
+	return u, nil
+}
+
+// ListByAccount returns transactions touching an account, newest first,
+// starting strictly after f.After when set.
+func (r *TransactionRepository) ListByAccount(ctx context.Context, f model.TransactionFilter) ([]model.Transaction, error) {
+	args := []any{f.AccountID}
+	var where string
+	switch f.Direction {
+	case model.DirectionIncoming:
+		where = "destination_account_id = $1"
+	case model.DirectionOutgoing:
+		where = "source_account_id = $1"
+	default:
+		where = "(source_account_id = $1 OR destination_account_id = $1)"
+	}
+
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	if f.From != nil {
+		where += " AND created_at >= " + arg(*f.From)
+	}
+	if f.To != nil {
+		where += " AND created_at < " + arg(*f.To)
+	}
+	if f.MinAmount != nil {
+		where += " AND amount >= " + arg(*f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		where += " AND amount <= " + arg(*f.MaxAmount)
+	}
+	if f.After != nil {
+		where += fmt.Sprintf(" AND (created_at, id) < (%s, %s)", arg(f.After.CreatedAt), arg(f.After.ID))
+	}
+
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE ` + where +
+		` ORDER BY created_at DESC, id DESC LIMIT ` + arg(f.Limit)
+
+	rows, err := r.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("listing transactions: %w", err)
+	}
+	defer rows.Close()
+
+	var out []model.Transaction
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning transaction: %w", err)
+		}
+		out = append(out, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing transactions: %w", err)
+	}
+	return out, nil
+}
+
+// StreamStatement calls fn for each ledger entry of an account created in
+// [from, to), oldest first. Rows are read from the database as fn consumes
+// them, so long statements are never held in memory; Balance is left for the
+// caller to fill in.
+func (r *TransactionRepository) StreamStatement(ctx context.Context, accountID int64, from, to time.Time, fn func(*model.StatementLine) error) error {
+	rows, err := r.pool.Query(ctx,
+		`SELECT t.id, t.kind, e.direction, e.amount,
+		        CASE WHEN e.direction = 'debit' THEN t.destination_account_id ELSE t.source_account_id END,
+		        e.created_at
+		 FROM ledger_entries e
+		 JOIN transactions t ON t.id = e.transaction_id
+		 WHERE e.account_id = $1 AND e.created_at >= $2 AND e.created_at < $3
+		 ORDER BY e.created_at, e.id`,
+		accountID, from, to,
+	)
+	if err != nil {
+		return fmt.Errorf("querying statement: %w", err)
+	}
+	defer rows.Close()
+
+	var line model.StatementLine
+	for rows.Next() {
+		err := rows.Scan(&line.TransactionID, &line.Kind, &line.Direction, &line.Amount, &line.CounterpartyAccountID, &line.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("scanning statement line: %w", err)
+		}
+		if err := fn(&line); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("reading statement: %w", err)
+	}
+	return nil
+}
+
+const transactionColumns = `id, kind, source_account_id, destination_account_id, amount, currency,
+	destination_amount, destination_currency, fx_rate, fx_quote_id, fee_of, source_balance_after, reversal_of, created_at`
+
+func scanTransaction(row pgx.Row) (*model.Transaction, error) {
+	var t model.Transaction
+	err := row.Scan(&t.ID, &t.Kind, &t.SourceAccountID, &t.DestinationAccountID, &t.Amount, &t.Currency,
+		&t.DestinationAmount, &t.DestinationCurrency, &t.FXRate, &t.FXQuoteID, &t.FeeOf, &t.SourceBalanceAfter, &t.ReversalOf, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
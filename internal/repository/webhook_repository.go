@@ -0,0 +1,260 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/model"
+)
+
+type WebhookRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewWebhookRepository(pool *pgxpool.Pool) *WebhookRepository {
+	return &WebhookRepository{pool: pool}
+}
+
+func (r *WebhookRepository) Create(ctx context.Context, s *model.WebhookSubscription) error {
+	err := r.pool.QueryRow(ctx,
+		`INSERT INTO webhook_subscriptions (url, secret, event_types, account_ids)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING id, status, consecutive_failures, created_at, updated_at`,
+		s.URL, s.Secret, s.EventTypes, s.AccountIDs,
+	).Scan(&s.ID, &s.Status, &s.ConsecutiveFailures, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("inserting webhook subscription: %w", err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) GetByID(ctx context.Context, id int64) (*model.WebhookSubscription, error) {
+	s, err := scanWebhook(r.pool.QueryRow(ctx, `SELECT `+webhookColumns+` FROM webhook_subscriptions WHERE id = $1`, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &apperror.ErrNotFound{Entity: "webhook", ID: id}
+		}
+		return nil, fmt.Errorf("querying webhook subscription: %w", err)
+	}
+	return s, nil
+}
+
+func (r *WebhookRepository) List(ctx context.Context) ([]model.WebhookSubscription, error) {
+	rows, err := r.pool.Query(ctx, `SELECT `+webhookColumns+` FROM webhook_subscriptions ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook subscriptions: %w", err)
+	}
+	defer rows.Close()
+
+	var out []model.WebhookSubscription
+	for rows.Next() {
+		s, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scanning webhook subscription: %w", err)
+		}
+		out = append(out, *s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing webhook subscriptions: %w", err)
+	}
+	return out, nil
+}
+
+// Delete removes a subscription together with its delivery log.
+func (r *WebhookRepository) Delete(ctx context.Context, id int64) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM webhook_subscriptions WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("deleting webhook subscription: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return &apperror.ErrNotFound{Entity: "webhook", ID: id}
+	}
+	return nil
+}
+
+// Enable reactivates a subscription and clears its failure count.
+func (r *WebhookRepository) Enable(ctx context.Context, id int64) (*model.WebhookSubscription, error) {
+	s, err := scanWebhook(r.pool.QueryRow(ctx,
+		`UPDATE webhook_subscriptions
+		 SET status = 'active', consecutive_failures = 0, disabled_at = NULL, updated_at = NOW()
+		 WHERE id = $1
+		 RETURNING `+webhookColumns,
+		id,
+	))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, &apperror.ErrNotFound{Entity: "webhook", ID: id}
+		}
+		return nil, fmt.Errorf("enabling webhook subscription: %w", err)
+	}
+	return s, nil
+}
+
+// EnqueueDeliveries creates a pending delivery of e for every active
+// subscription that matches it and returns how many were created. Repeating
+// it for the same event creates nothing new.
+func (r *WebhookRepository) EnqueueDeliveries(ctx context.Context, e *model.OutboxEvent) (int64, error) {
+	tag, err := r.pool.Exec(ctx,
+		`INSERT INTO webhook_deliveries (subscription_id, event_id)
+		 SELECT id, $1 FROM webhook_subscriptions
+		 WHERE status = 'active'
+		   AND (event_types = '{}' OR $2 = ANY(event_types))
+		   AND (account_ids = '{}' OR account_ids && $3)
+		 ON CONFLICT (subscription_id, event_id) DO NOTHING`,
+		e.ID, e.Type, e.AccountIDs,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("enqueueing webhook deliveries: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// ClaimDueDeliveries leases up to limit pending deliveries to active
+// subscriptions that are due, loading their event and subscription. Like
+// OutboxRepository.ClaimDue it pushes their next attempt lease into the
+// future, and the returned deliveries carry the lease expiry in
+// NextAttemptAt.
+func (r *WebhookRepository) ClaimDueDeliveries(ctx context.Context, tx pgx.Tx, limit int, lease time.Duration) ([]model.WebhookDelivery, error) {
+	rows, err := tx.Query(ctx,
+		`WITH claimed AS (
+		     UPDATE webhook_deliveries SET next_attempt_at = NOW() + $2::INTERVAL
+		     WHERE id IN (
+		         SELECT d.id
+		         FROM webhook_deliveries d
+		         JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		         WHERE d.status = 'pending' AND d.next_attempt_at <= NOW() AND s.status = 'active'
+		         ORDER BY d.next_attempt_at, d.id
+		         LIMIT $1
+		         FOR UPDATE OF d SKIP LOCKED
+		     )
+		     RETURNING id, subscription_id, event_id, status, attempts, next_attempt_at,
+		               response_status, last_error, created_at, delivered_at
+		 )
+		 SELECT d.id, d.subscription_id, d.event_id, e.event_type, d.status, d.attempts, d.next_attempt_at,
+		        d.response_status, d.last_error, d.created_at, d.delivered_at,
+		        e.account_ids, e.payload, e.created_at,
+		        s.url, s.secret, s.consecutive_failures
+		 FROM claimed d
+		 JOIN webhook_subscriptions s ON s.id = d.subscription_id
+		 JOIN outbox_events e ON e.id = d.event_id
+		 ORDER BY d.id`,
+		limit, lease,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claiming webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var out []model.WebhookDelivery
+	for rows.Next() {
+		var d model.WebhookDelivery
+		e := &model.OutboxEvent{}
+		s := &model.WebhookSubscription{Status: model.WebhookActive}
+		var payload []byte
+		err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.ResponseStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt,
+			&e.AccountIDs, &payload, &e.CreatedAt,
+			&s.URL, &s.Secret, &s.ConsecutiveFailures)
+		if err != nil {
+			return nil, fmt.Errorf("scanning webhook delivery: %w", err)
+		}
+		e.ID, e.Type, e.Data = d.EventID, d.EventType, payload
+		s.ID = d.SubscriptionID
+		d.Event, d.Subscription = e, s
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("claiming webhook deliveries: %w", err)
+	}
+	return out, nil
+}
+
+// UpdateDelivery saves the outcome of an attempt on d, which was claimed with
+// a lease until leasedUntil. It reports false, changing nothing, when that
+// lease has expired and the delivery was claimed again meanwhile.
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, tx pgx.Tx, d *model.WebhookDelivery, leasedUntil time.Time) (bool, error) {
+	tag, err := tx.Exec(ctx,
+		`UPDATE webhook_deliveries
+		 SET status = $1, attempts = $2, next_attempt_at = $3, response_status = $4, last_error = $5, delivered_at = $6
+		 WHERE id = $7 AND status = 'pending' AND next_attempt_at = $8`,
+		d.Status, d.Attempts, d.NextAttemptAt, d.ResponseStatus, d.LastError, d.DeliveredAt, d.ID, leasedUntil,
+	)
+	if err != nil {
+		return false, fmt.Errorf("updating webhook delivery: %w", err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// RecordResult resets the failure count of a subscription after a successful
+// attempt, or increments it after a failed one. A subscription reaching
+// disableAfter consecutive failures is disabled; the returned status says
+// whether that happened.
+func (r *WebhookRepository) RecordResult(ctx context.Context, tx pgx.Tx, id int64, success bool, disableAfter int) (model.WebhookStatus, error) {
+	var status model.WebhookStatus
+	err := tx.QueryRow(ctx,
+		`UPDATE webhook_subscriptions
+		 SET consecutive_failures = CASE WHEN $2 THEN 0 ELSE consecutive_failures + 1 END,
+		     status = CASE WHEN NOT $2 AND consecutive_failures + 1 >= $3 THEN 'disabled' ELSE status END,
+		     disabled_at = CASE WHEN NOT $2 AND consecutive_failures + 1 >= $3 THEN NOW() ELSE disabled_at END,
+		     updated_at = NOW()
+		 WHERE id = $1
+		 RETURNING status`,
+		id, success, disableAfter,
+	).Scan(&status)
+	if err != nil {
+		return "", fmt.Errorf("recording webhook result: %w", err)
+	}
+	return status, nil
+}
+
+// ListDeliveries returns the delivery log of a subscription, newest first,
+// starting strictly before beforeID when it is non-zero.
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, subscriptionID, beforeID int64, limit int) ([]model.WebhookDelivery, error) {
+	rows, err := r.pool.Query(ctx,
+		`SELECT d.id, d.subscription_id, d.event_id, e.event_type, d.status, d.attempts, d.next_attempt_at,
+		        d.response_status, d.last_error, d.created_at, d.delivered_at
+		 FROM webhook_deliveries d
+		 JOIN outbox_events e ON e.id = d.event_id
+		 WHERE d.subscription_id = $1 AND ($2::BIGINT = 0 OR d.id < $2)
+		 ORDER BY d.id DESC
+		 LIMIT $3`,
+		subscriptionID, beforeID, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var out []model.WebhookDelivery
+	for rows.Next() {
+		var d model.WebhookDelivery
+		err := rows.Scan(&d.ID, &d.SubscriptionID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.ResponseStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt)
+		if err != nil {
+			return nil, fmt.Errorf("scanning webhook delivery: %w", err)
+		}
+		out = append(out, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("listing webhook deliveries: %w", err)
+	}
+	return out, nil
+}
+
+const webhookColumns = `id, url, secret, event_types, account_ids, status, consecutive_failures, disabled_at, created_at, updated_at`
+
+func scanWebhook(row pgx.Row) (*model.WebhookSubscription, error) {
+	var s model.WebhookSubscription
+	err := row.Scan(&s.ID, &s.URL, &s.Secret, &s.EventTypes, &s.AccountIDs, &s.Status, &s.ConsecutiveFailures,
+		&s.DisabledAt, &s.CreatedAt, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
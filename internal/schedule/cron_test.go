@@ -0,0 +1,105 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func utc(year int, month time.Month, day, hour, minute int) time.Time {
+	return time.Date(year, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParseCronRejectsInvalidExpressions(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * 32 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"5-1 * * * *",
+		"1-x * * * *",
+		"a * * * *",
+		"@never",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := ParseCron(expr); err == nil {
+				t.Errorf("ParseCron(%q) succeeded, want an error", expr)
+			}
+		})
+	}
+}
+
+// 2025-01-01 is a Wednesday.
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  string
+		after time.Time
+		want  time.Time
+	}{
+		{"next month", "0 9 1 * *", utc(2025, 1, 1, 9, 0), utc(2025, 2, 1, 9, 0)},
+		{"strictly after", "30 10 * * *", utc(2025, 1, 1, 10, 30), utc(2025, 1, 2, 10, 30)},
+		{"seconds truncated", "30 10 * * *", utc(2025, 1, 1, 10, 29).Add(59 * time.Second), utc(2025, 1, 1, 10, 30)},
+		{"list", "0 9 1,15 * *", utc(2025, 1, 2, 0, 0), utc(2025, 1, 15, 9, 0)},
+		{"weekdays skip the weekend", "*/15 8-18 * * 1-5", utc(2025, 1, 3, 18, 50), utc(2025, 1, 6, 8, 0)},
+		{"step from a value", "5/15 * * * *", utc(2025, 1, 1, 10, 20), utc(2025, 1, 1, 10, 35)},
+		{"step from a value wraps the hour", "5/15 * * * *", utc(2025, 1, 1, 10, 50), utc(2025, 1, 1, 11, 5)},
+		{"sunday as 0", "0 0 * * 0", utc(2025, 1, 1, 0, 0), utc(2025, 1, 5, 0, 0)},
+		{"sunday as 7", "0 0 * * 7", utc(2025, 1, 1, 0, 0), utc(2025, 1, 5, 0, 0)},
+		{"sunday as 7 in a range", "0 0 * * 6-7", utc(2025, 1, 1, 0, 0), utc(2025, 1, 4, 0, 0)},
+		{"both day fields restricted match either", "0 0 13 * 5", utc(2025, 1, 1, 0, 0), utc(2025, 1, 3, 0, 0)},
+		{"day of month matches alone", "0 0 13 * 5", utc(2025, 1, 10, 0, 0), utc(2025, 1, 13, 0, 0)},
+		{"day of month step is unrestricted", "0 0 */2 * 1", utc(2025, 1, 1, 0, 0), utc(2025, 1, 13, 0, 0)},
+		{"day of week step is unrestricted", "0 0 13 * */2", utc(2025, 1, 1, 0, 0), utc(2025, 2, 13, 0, 0)},
+		{"leap day", "0 0 29 2 *", utc(2025, 1, 1, 0, 0), utc(2028, 2, 29, 0, 0)},
+		{"end of year", "@yearly", utc(2025, 6, 1, 0, 0), utc(2026, 1, 1, 0, 0)},
+		{"hourly macro", "@hourly", utc(2025, 1, 1, 10, 30), utc(2025, 1, 1, 11, 0)},
+		{"weekly macro", " @WEEKLY ", utc(2025, 1, 1, 0, 0), utc(2025, 1, 5, 0, 0)},
+		{"non-UTC input", "0 9 * * *", time.Date(2025, 1, 1, 9, 30, 0, 0, time.FixedZone("CET", 3600)), utc(2025, 1, 1, 9, 0)},
+		{"never matches", "0 0 31 2 *", utc(2025, 1, 1, 0, 0), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron(%q): %v", tt.expr, err)
+			}
+			if got := c.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIntervalNext(t *testing.T) {
+	start := utc(2025, 1, 1, 0, 0)
+	i := Interval{Start: start, Every: time.Hour}
+
+	tests := []struct {
+		name  string
+		after time.Time
+		want  time.Time
+	}{
+		{"before start", start.Add(-time.Minute), start},
+		{"at start", start, start.Add(time.Hour)},
+		{"between runs", start.Add(90 * time.Minute), start.Add(2 * time.Hour)},
+		{"on a run", start.Add(3 * time.Hour), start.Add(4 * time.Hour)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := i.Next(tt.after); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.after, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/auth"
+	"github.com/InternalTransfer/internal/model"
+)
+
+const (
+	accountActivityChannel = "account_activity"
+	// streamBufferSize is how many events a stream may fall behind before it
+	// is closed. The client then reconnects and catches up from the ledger.
+	streamBufferSize = 256
+	replayBatchSize  = 500
+	listenRetryDelay = 5 * time.Second
+)
+
+// AccountEventService streams account activity. A single listener receives
+// a notification for every committed ledger entry and fans it out to the
+// streams of that account; streams resuming after a given entry first
+// replay the entries since then from the ledger.
+type AccountEventService struct {
+	accountRepo AccountRepo
+	ledgerRepo  LedgerRepo
+	listener    Listener
+	logger      *slog.Logger
+	// checkDigit requires account IDs to end in a Luhn check digit.
+	checkDigit bool
+
+	mu      sync.Mutex
+	streams map[int64]map[*accountStream]struct{}
+}
+
+type accountStream struct {
+	live chan model.AccountEvent
+	// closed is set once live has been closed, under the service mutex.
+	closed bool
+}
+
+func NewAccountEventService(accountRepo AccountRepo, ledgerRepo LedgerRepo, listener Listener, logger *slog.Logger, checkDigit bool) *AccountEventService {
+	return &AccountEventService{
+		accountRepo: accountRepo,
+		ledgerRepo:  ledgerRepo,
+		listener:    listener,
+		logger:      logger,
+		checkDigit:  checkDigit,
+		streams:     make(map[int64]map[*accountStream]struct{}),
+	}
+}
+
+// Subscribe returns the account and a channel of its ledger entries, starting
+// after entry lastEventID when it is non-zero and otherwise with the next
+// one. An entry committed while subscribing may be both included in the
+// returned account's balance and sent. The channel is closed when ctx is
+// cancelled or when events may have been missed, in which case the caller
+// should resume from the last event it received.
+func (s *AccountEventService) Subscribe(ctx context.Context, accountID, lastEventID int64) (*model.Account, <-chan model.AccountEvent, error) {
+	if err := authorize(ctx, auth.ActionReadAccounts); err != nil {
+		return nil, nil, err
+	}
+	if !validAccountID(accountID, s.checkDigit) {
+		return nil, nil, &apperror.ErrValidation{Message: "Please provide a valid account number"}
+	}
+	if lastEventID < 0 {
+		return nil, nil, &apperror.ErrValidation{Message: "Invalid Last-Event-ID. Please provide the id of a previous event"}
+	}
+
+	// register before reading the account or replaying, so that entries
+	// committed in between are buffered rather than lost
+	st := &accountStream{live: make(chan model.AccountEvent, streamBufferSize)}
+	s.mu.Lock()
+	if s.streams[accountID] == nil {
+		s.streams[accountID] = make(map[*accountStream]struct{})
+	}
+	s.streams[accountID][st] = struct{}{}
+	s.mu.Unlock()
+
+	account, err := s.accountRepo.GetByID(ctx, accountID)
+	if err != nil {
+		s.remove(accountID, st)
+		return nil, nil, fmt.Errorf("fetching account: %w", err)
+	}
+	if err = checkOwner(ctx, account); err != nil {
+		s.remove(accountID, st)
+		return nil, nil, err
+	}
+
+	out := make(chan model.AccountEvent)
+	go func() {
+		defer close(out)
+		defer s.remove(accountID, st)
+
+		send := func(e model.AccountEvent) bool {
+			select {
+			case out <- e:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		last := lastEventID
+		if last > 0 {
+			for {
+				events, err := s.ledgerRepo.ListEventsAfter(ctx, accountID, last, replayBatchSize)
+				if err != nil {
+					if ctx.Err() == nil {
+						s.logger.Error("replaying account events", "account_id", accountID, "error", err)
+					}
+					return
+				}
+				for _, e := range events {
+					if !send(e) {
+						return
+					}
+					last = e.ID
+				}
+				if len(events) < replayBatchSize {
+					break
+				}
+			}
+		}
+
+		for {
+			select {
+			case e, ok := <-st.live:
+				if !ok {
+					return
+				}
+				// replayed already
+				if e.ID <= last {
+					continue
+				}
+				if !send(e) {
+					return
+				}
+				last = e.ID
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return account, out, nil
+}
+
+// remove unregisters st and closes its channel unless that already happened.
+func (s *AccountEventService) remove(accountID int64, st *accountStream) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeLocked(accountID, st)
+}
+
+func (s *AccountEventService) closeLocked(accountID int64, st *accountStream) {
+	if st.closed {
+		return
+	}
+	st.closed = true
+	close(st.live)
+	delete(s.streams[accountID], st)
+	if len(s.streams[accountID]) == 0 {
+		delete(s.streams, accountID)
+	}
+}
+
+// closeAll ends every stream. It is called whenever the listener
+// (re)connects, since notifications sent while it was away are lost.
+func (s *AccountEventService) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for accountID, streams := range s.streams {
+		for st := range streams {
+			s.closeLocked(accountID, st)
+		}
+	}
+}
+
+func (s *AccountEventService) dispatch(payload string) {
+	var e model.AccountEvent
+	if err := json.Unmarshal([]byte(payload), &e); err != nil {
+		s.logger.Error("decoding account activity notification", "error", err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for st := range s.streams[e.AccountID] {
+		select {
+		case st.live <- e:
+		default:
+			s.logger.Warn("account event stream fell behind, closing it", "account_id", e.AccountID)
+			s.closeLocked(e.AccountID, st)
+		}
+	}
+}
+
+// Run listens for ledger entry notifications until ctx is cancelled,
+// reconnecting after failures.
+func (s *AccountEventService) Run(ctx context.Context) {
+	for {
+		err := s.listener.Listen(ctx, accountActivityChannel, s.closeAll, s.dispatch)
+		if ctx.Err() != nil {
+			s.closeAll()
+			return
+		}
+		s.logger.Error("listening for account activity", "error", err)
+		s.closeAll()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/InternalTransfer/internal/model"
+)
+
+func newTestAccountEventService(bank *fakeBank) *AccountEventService {
+	return NewAccountEventService(fakeAccountRepo{bank}, fakeLedgerRepo{bank}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)), false)
+}
+
+// notify delivers e as the listener would.
+func notify(t *testing.T, s *AccountEventService, e model.AccountEvent) {
+	t.Helper()
+	payload, err := json.Marshal(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.dispatch(string(payload))
+}
+
+// receive returns the IDs of the next n events on events.
+func receive(t *testing.T, events <-chan model.AccountEvent, n int) []int64 {
+	t.Helper()
+	var ids []int64
+	for len(ids) < n {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				t.Fatalf("stream closed after %v, want %d events", ids, n)
+			}
+			ids = append(ids, e.ID)
+		case <-time.After(time.Second):
+			t.Fatalf("received %v, want %d events", ids, n)
+		}
+	}
+	return ids
+}
+
+// waitClosed drains events until the stream is closed, returning how many
+// were left.
+func waitClosed(t *testing.T, events <-chan model.AccountEvent) int {
+	t.Helper()
+	n := 0
+	timeout := time.After(time.Second)
+	for {
+		select {
+		case _, ok := <-events:
+			if !ok {
+				return n
+			}
+			n++
+		case <-timeout:
+			t.Fatal("stream still open")
+		}
+	}
+}
+
+func TestSubscribeResumesFromLastEventID(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "1000")
+	bank.addAccount(2, "USD", "0")
+	transfers := newTestTransferService(bank, ApprovalPolicy{})
+	for _, amount := range []string{"10", "20", "30"} {
+		if _, err := transfers.Transfer(context.Background(), transfer(1, 2, amount), ""); err != nil {
+			t.Fatalf("Transfer: %v", err)
+		}
+	}
+	// entries alternate between the source and the destination
+	if len(bank.entries) != 6 || bank.entries[4].AccountID != 1 {
+		t.Fatalf("entries = %+v, want a debit and a credit per transfer", bank.entries)
+	}
+	s := newTestAccountEventService(bank)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	account, events, err := s.Subscribe(ctx, 2, 2)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if account.Balance.String() != "60" {
+		t.Errorf("balance = %s, want 60", account.Balance)
+	}
+	// the last replayed entry was committed while subscribing, so it is
+	// notified as well; entries of other accounts go to their own streams
+	notify(t, s, model.AccountEvent{ID: 6, AccountID: 2})
+	notify(t, s, model.AccountEvent{ID: 7, AccountID: 1})
+	notify(t, s, model.AccountEvent{ID: 8, AccountID: 2})
+
+	if got := receive(t, events, 3); got[0] != 4 || got[1] != 6 || got[2] != 8 {
+		t.Errorf("events = %v, want [4 6 8]", got)
+	}
+	select {
+	case e := <-events:
+		t.Errorf("unexpected event %+v", e)
+	default:
+	}
+
+	cancel()
+	waitClosed(t, events)
+	if len(s.streams) != 0 {
+		t.Errorf("%d accounts still have streams after cancelling", len(s.streams))
+	}
+}
+
+func TestSubscribeWithoutLastEventID(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "1000")
+	bank.addAccount(2, "USD", "0")
+	if _, err := newTestTransferService(bank, ApprovalPolicy{}).Transfer(context.Background(), transfer(1, 2, "10"), ""); err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+	s := newTestAccountEventService(bank)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, events, err := s.Subscribe(ctx, 2, 0)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	notify(t, s, model.AccountEvent{ID: 3, AccountID: 2})
+	if got := receive(t, events, 1); got[0] != 3 {
+		t.Errorf("events = %v, want only the new entry 3", got)
+	}
+}
+
+func TestSubscribeClosesLaggingStream(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "0")
+	s := newTestAccountEventService(bank)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, slow, err := s.Subscribe(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	_, other, err := s.Subscribe(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	// nobody reads either stream, so both fall behind once the buffer and
+	// the event being sent are full
+	total := streamBufferSize + 2
+	for id := 1; id <= total; id++ {
+		notify(t, s, model.AccountEvent{ID: int64(id), AccountID: 1})
+	}
+	for _, events := range []<-chan model.AccountEvent{slow, other} {
+		if n := waitClosed(t, events); n >= total {
+			t.Errorf("stream delivered %d of %d events, want it closed early", n, total)
+		}
+	}
+	if len(s.streams) != 0 {
+		t.Errorf("%d accounts still have streams", len(s.streams))
+	}
+
+	// a new stream is unaffected
+	_, events, err := s.Subscribe(ctx, 1, 0)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	notify(t, s, model.AccountEvent{ID: int64(total + 1), AccountID: 1})
+	if got := receive(t, events, 1); got[0] != int64(total+1) {
+		t.Errorf("events = %v, want [%d]", got, total+1)
+	}
+}
@@ -2,47 +2,292 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
+	"github.com/InternalTransfer/internal/accountid"
 	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/auth"
+	"github.com/InternalTransfer/internal/currency"
 	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/pagination"
 	"github.com/shopspring/decimal"
 )
 
 type AccountService struct {
-	accountRepo AccountRepo
-	logger      *slog.Logger
+	accountRepo     AccountRepo
+	transactionRepo TransactionRepo
+	ledgerRepo      LedgerRepo
+	outboxRepo      OutboxRepo
+	limitRepo       AccountLimitRepo
+	ledger          *ledger
+	txBeginner      TxBeginner
+	logger          *slog.Logger
+	// checkDigit requires account IDs to end in a Luhn check digit.
+	checkDigit bool
 }
 
-func NewAccountService(accountRepo AccountRepo, logger *slog.Logger) *AccountService {
+func NewAccountService(
+	accountRepo AccountRepo,
+	transactionRepo TransactionRepo,
+	ledgerRepo LedgerRepo,
+	outboxRepo OutboxRepo,
+	limitRepo AccountLimitRepo,
+	txBeginner TxBeginner,
+	logger *slog.Logger,
+	checkDigit bool,
+) *AccountService {
 	return &AccountService{
-		accountRepo: accountRepo,
-		logger:      logger,
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		ledgerRepo:      ledgerRepo,
+		outboxRepo:      outboxRepo,
+		limitRepo:       limitRepo,
+		ledger:          newLedger(accountRepo, transactionRepo, ledgerRepo, outboxRepo),
+		txBeginner:      txBeginner,
+		logger:          logger,
+		checkDigit:      checkDigit,
 	}
 }
 
-func (s *AccountService) Create(ctx context.Context, accountID int64, initialBalance decimal.Decimal) error {
-	if accountID <= 0 {
-		return &apperror.ErrValidation{Message: "Please provide a valid account number"}
+// Create opens account a, funded with initialBalance from the equity account.
+// When a.AccountID is zero the next free ID is allocated from a sequence.
+func (s *AccountService) Create(ctx context.Context, a model.Account, initialBalance decimal.Decimal) (*model.Account, error) {
+	if err := authorize(ctx, auth.ActionWriteAccounts); err != nil {
+		return nil, err
+	}
+	a.Currency = currency.Normalize(a.Currency)
+	if a.Currency == "" {
+		a.Currency = currency.Default
+	}
+	if a.AccountType == "" {
+		a.AccountType = model.DefaultAccountType
+	}
+
+	generateID := a.AccountID == 0
+	if !generateID && !validAccountID(a.AccountID, s.checkDigit) {
+		return nil, &apperror.ErrValidation{Message: "Please provide a valid account number"}
+	}
+	if !currency.IsSupported(a.Currency) {
+		return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Currency %q is not supported", a.Currency)}
 	}
 	if initialBalance.IsNegative() {
-		return &apperror.ErrValidation{Message: "Initial balance cannot be negative"}
+		return nil, &apperror.ErrValidation{Message: "Initial balance cannot be negative"}
+	}
+	if !currency.HasValidPrecision(initialBalance, a.Currency) {
+		return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Initial balance can only have up to %d decimal places in %s", currency.MinorUnits(a.Currency), a.Currency)}
+	}
+	a.OwnerID = trimOptional(a.OwnerID)
+	a.Name = trimOptional(a.Name)
+	if owner := callerOwner(ctx); owner != "" && a.OwnerID == nil {
+		a.OwnerID = &owner
+	}
+	if err := validateAccountDetails(&a); err != nil {
+		return nil, err
+	}
+	if err := checkOwner(ctx, &a); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// a generated ID can collide with one a client picked earlier; such IDs
+	// are skipped
+	for attempt := 1; ; attempt++ {
+		if generateID {
+			if a.AccountID, err = s.accountRepo.NextID(ctx, tx); err != nil {
+				return nil, err
+			}
+			if s.checkDigit {
+				a.AccountID = accountid.Append(a.AccountID)
+			}
+		}
+		err = s.accountRepo.Create(ctx, tx, &a)
+		var conflict *apperror.ErrConflict
+		if generateID && errors.As(err, &conflict) && attempt < maxRetries {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("creating account: %w", err)
+		}
+		break
+	}
+
+	// fund the opening balance from the equity account so that the new
+	// account's balance is explained by its ledger entries
+	if initialBalance.IsPositive() {
+		equity, err := s.accountRepo.GetSystemAccountForUpdate(ctx, tx, model.SystemRoleEquity, a.Currency)
+		if err != nil {
+			return nil, err
+		}
+		opening := &model.Transaction{Kind: model.TransactionKindOpening, Amount: initialBalance}
+		if err = s.ledger.post(ctx, tx, equity, &a, opening); err != nil {
+			return nil, fmt.Errorf("posting opening balance: %w", err)
+		}
 	}
-	if initialBalance.Exponent() < -2 {
-		return &apperror.ErrValidation{Message: "Initial balance can only have up to 2 decimal places (e.g., 100.50)"}
+	if err = recordEvent(ctx, tx, s.outboxRepo, EventAccountCreated, []int64{a.AccountID}, a); err != nil {
+		return nil, err
 	}
 
-	if err := s.accountRepo.Create(ctx, accountID, initialBalance); err != nil {
-		return fmt.Errorf("creating account: %w", err)
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing account: %w", err)
 	}
 
-	s.logger.Info("account created", "account_id", accountID)
+	s.logger.Info("account created", "account_id", a.AccountID, "currency", a.Currency, "account_type", a.AccountType, "actor", actor(ctx))
+	return &a, nil
+}
+
+// Update applies patch to the descriptive fields of an account. Balances and
+// status are changed through transfers and ChangeStatus instead.
+func (s *AccountService) Update(ctx context.Context, accountID int64, patch model.AccountPatch) (*model.Account, error) {
+	if err := authorize(ctx, auth.ActionWriteAccounts); err != nil {
+		return nil, err
+	}
+	if !validAccountID(accountID, s.checkDigit) {
+		return nil, &apperror.ErrValidation{Message: "Please provide a valid account number"}
+	}
+
+	tx, err := s.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	account, err := s.accountRepo.GetByIDForUpdate(ctx, tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkOwner(ctx, account); err != nil {
+		return nil, err
+	}
+	if patch.OwnerID != nil {
+		account.OwnerID = trimOptional(patch.OwnerID)
+	}
+	if patch.Name != nil {
+		account.Name = trimOptional(patch.Name)
+	}
+	if patch.AccountType != nil {
+		account.AccountType = *patch.AccountType
+	}
+	if patch.Metadata != nil {
+		account.Metadata = mergeMetadata(account.Metadata, patch.Metadata)
+	}
+	if err = validateAccountDetails(account); err != nil {
+		return nil, err
+	}
+	// an end user cannot give their account away
+	if err = checkOwner(ctx, account); err != nil {
+		return nil, err
+	}
+
+	if err = s.accountRepo.UpdateDetails(ctx, tx, account); err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing account: %w", err)
+	}
+
+	s.logger.Info("account updated", "account_id", accountID, "actor", actor(ctx))
+	return account, nil
+}
+
+const (
+	maxOwnerIDLength     = 128
+	maxAccountNameLength = 200
+	maxMetadataBytes     = 8 << 10
+)
+
+// validateAccountDetails checks the descriptive fields shared by Create and
+// Update.
+func validateAccountDetails(a *model.Account) error {
+	if !validAccountType(a.AccountType) {
+		return &apperror.ErrValidation{Message: "Account type must be 1-32 lowercase letters, digits, '-' or '_'"}
+	}
+	if a.OwnerID != nil && len(*a.OwnerID) > maxOwnerIDLength {
+		return &apperror.ErrValidation{Message: fmt.Sprintf("Owner ID cannot be longer than %d characters", maxOwnerIDLength)}
+	}
+	if a.Name != nil && len(*a.Name) > maxAccountNameLength {
+		return &apperror.ErrValidation{Message: fmt.Sprintf("Account name cannot be longer than %d characters", maxAccountNameLength)}
+	}
+	if len(a.Metadata) > 0 {
+		b, err := json.Marshal(a.Metadata)
+		if err != nil || len(b) > maxMetadataBytes {
+			return &apperror.ErrValidation{Message: fmt.Sprintf("Metadata must be a JSON object of at most %d bytes", maxMetadataBytes)}
+		}
+	}
 	return nil
 }
 
+// mergeMetadata applies patch to metadata the way a JSON merge patch does:
+// each key replaces the stored value, and a null removes it.
+func mergeMetadata(metadata, patch map[string]any) map[string]any {
+	merged := make(map[string]any, len(metadata)+len(patch))
+	maps.Copy(merged, metadata)
+	for k, v := range patch {
+		if v == nil {
+			delete(merged, k)
+			continue
+		}
+		merged[k] = v
+	}
+	return merged
+}
+
+// trimOptional trims s, turning an empty value into nil.
+func trimOptional(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	t := strings.TrimSpace(*s)
+	if t == "" {
+		return nil
+	}
+	return &t
+}
+
+// validAccountID reports whether id can name a customer account. With
+// checkDigit set the ID must also end in its Luhn check digit, which catches
+// mistyped account numbers before they reach the database.
+func validAccountID(id int64, checkDigit bool) bool {
+	return id > 0 && (!checkDigit || accountid.Valid(id))
+}
+
+// validAccountType reports whether t is a short lowercase identifier such as
+// "standard" or "merchant_escrow".
+func validAccountType(t string) bool {
+	if len(t) == 0 || len(t) > 32 {
+		return false
+	}
+	for _, c := range t {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
 func (s *AccountService) GetByID(ctx context.Context, accountID int64) (*model.Account, error) {
-	if accountID <= 0 {
+	if err := authorize(ctx, auth.ActionReadAccounts); err != nil {
+		return nil, err
+	}
+	return s.getAccount(ctx, accountID)
+}
+
+// getAccount returns an account the caller owns, without checking that the
+// caller may read accounts; callers that change an account check their own
+// action instead.
+func (s *AccountService) getAccount(ctx context.Context, accountID int64) (*model.Account, error) {
+	if !validAccountID(accountID, s.checkDigit) {
 		return nil, &apperror.ErrValidation{Message: "Please provide a valid account number"}
 	}
 
@@ -50,6 +295,369 @@ func (s *AccountService) GetByID(ctx context.Context, accountID int64) (*model.A
 	if err != nil {
 		return nil, fmt.Errorf("fetching account: %w", err)
 	}
+	if err = checkOwner(ctx, account); err != nil {
+		return nil, err
+	}
 
 	return account, nil
 }
+
+// BalanceAt returns an account's balance as of asOf, including every ledger
+// entry created at or before it. A zero asOf means now.
+func (s *AccountService) BalanceAt(ctx context.Context, accountID int64, asOf time.Time) (*model.AccountBalance, error) {
+	if err := authorize(ctx, auth.ActionReadAccounts); err != nil {
+		return nil, err
+	}
+	account, err := s.getAccount(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if asOf.IsZero() {
+		return &model.AccountBalance{AccountID: account.AccountID, Currency: account.Currency, Balance: account.Balance, AsOf: now}, nil
+	}
+	if asOf.After(now) {
+		return nil, &apperror.ErrValidation{Message: "The 'as_of' time cannot be in the future"}
+	}
+
+	balance, err := s.ledgerRepo.BalanceAt(ctx, accountID, asOf)
+	if err != nil {
+		return nil, err
+	}
+	return &model.AccountBalance{AccountID: account.AccountID, Currency: account.Currency, Balance: balance, AsOf: asOf}, nil
+}
+
+// Statement writes an account's statement for [from, to) to w: the balance
+// before from, every ledger entry in the period with the running balance,
+// and the balance at the end. Entries are streamed, so w sees them as they
+// are read.
+func (s *AccountService) Statement(ctx context.Context, accountID int64, from, to time.Time, w StatementWriter) error {
+	if err := authorize(ctx, auth.ActionReadAccounts); err != nil {
+		return err
+	}
+	if from.IsZero() || to.IsZero() {
+		return &apperror.ErrValidation{Message: "Please provide both 'from' and 'to' for the statement period"}
+	}
+	if !from.Before(to) {
+		return &apperror.ErrValidation{Message: "The 'from' date must be before the 'to' date"}
+	}
+	account, err := s.getAccount(ctx, accountID)
+	if err != nil {
+		return err
+	}
+
+	// timestamps are stored with microsecond precision, so this is the
+	// balance just before from
+	opening, err := s.ledgerRepo.BalanceAt(ctx, accountID, from.Add(-time.Microsecond))
+	if err != nil {
+		return err
+	}
+
+	st := &model.Statement{
+		AccountID:      account.AccountID,
+		Currency:       account.Currency,
+		From:           from,
+		To:             to,
+		OpeningBalance: opening,
+	}
+	if err = w.Opening(st); err != nil {
+		return err
+	}
+
+	running := opening
+	err = s.transactionRepo.StreamStatement(ctx, accountID, from, to, func(l *model.StatementLine) error {
+		if l.Direction == model.EntryCredit {
+			running = running.Add(l.Amount)
+		} else {
+			running = running.Sub(l.Amount)
+		}
+		l.Balance = running
+		return w.Line(l)
+	})
+	if err != nil {
+		return err
+	}
+
+	st.ClosingBalance = running
+	return w.Closing(st)
+}
+
+// snapshotSettleTime is how long after midnight UTC the day's snapshot is
+// taken, so that transactions still in flight at midnight have committed.
+const snapshotSettleTime = time.Hour
+
+// SnapshotBalances records every account's balance as of the latest UTC
+// midnight at least snapshotSettleTime before now. Snapshots already taken
+// are kept, so running it repeatedly is harmless.
+func (s *AccountService) SnapshotBalances(ctx context.Context, now time.Time) (int64, error) {
+	takenAt := now.UTC().Add(-snapshotSettleTime).Truncate(24 * time.Hour)
+	return s.ledgerRepo.CreateSnapshots(ctx, takenAt)
+}
+
+// RunSnapshotter takes balance snapshots every interval until ctx is
+// cancelled.
+func (s *AccountService) RunSnapshotter(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.SnapshotBalances(ctx, time.Now())
+			if err != nil {
+				s.logger.Error("taking balance snapshots", "error", err)
+				continue
+			}
+			if n > 0 {
+				s.logger.Info("balance snapshots taken", "count", n)
+			}
+		}
+	}
+}
+
+// List returns one page of accounts together with the cursor for the next
+// page, which is nil on the last page.
+func (s *AccountService) List(ctx context.Context, f model.AccountFilter) ([]model.Account, *model.AccountCursor, error) {
+	if err := authorize(ctx, auth.ActionReadAccounts); err != nil {
+		return nil, nil, err
+	}
+	switch f.Status {
+	case "", model.AccountActive, model.AccountFrozen, model.AccountClosed:
+	default:
+		return nil, nil, &apperror.ErrValidation{Message: "Status must be one of: active, frozen, closed"}
+	}
+	switch f.Sort {
+	case "":
+		f.Sort = model.AccountSortID
+	case model.AccountSortID, model.AccountSortBalance, model.AccountSortCreatedAt:
+	default:
+		return nil, nil, &apperror.ErrValidation{Message: "Sort must be one of: account_id, balance, created_at, optionally prefixed with '-' for descending order"}
+	}
+	if f.MinBalance != nil && f.MaxBalance != nil && f.MinBalance.GreaterThan(*f.MaxBalance) {
+		return nil, nil, &apperror.ErrValidation{Message: "Minimum balance cannot be greater than maximum balance"}
+	}
+	if f.CreatedFrom != nil && f.CreatedTo != nil && !f.CreatedFrom.Before(*f.CreatedTo) {
+		return nil, nil, &apperror.ErrValidation{Message: "The 'created_from' date must be before the 'created_to' date"}
+	}
+	if f.Limit <= 0 {
+		f.Limit = pagination.DefaultLimit
+	}
+	if f.Limit > pagination.MaxLimit {
+		return nil, nil, &apperror.ErrValidation{Message: fmt.Sprintf("Limit cannot exceed %d", pagination.MaxLimit)}
+	}
+	// end users only see their own accounts
+	if owner := callerOwner(ctx); owner != "" {
+		if f.OwnerID != "" && f.OwnerID != owner {
+			return nil, nil, nil
+		}
+		f.OwnerID = owner
+	}
+
+	// fetch one extra row to find out whether another page exists
+	pageSize := f.Limit
+	f.Limit++
+	accounts, err := s.accountRepo.List(ctx, f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing accounts: %w", err)
+	}
+
+	if len(accounts) <= pageSize {
+		return accounts, nil, nil
+	}
+	accounts = accounts[:pageSize]
+	last := accounts[pageSize-1]
+	return accounts, &model.AccountCursor{Balance: last.Balance, CreatedAt: last.CreatedAt, AccountID: last.AccountID}, nil
+}
+
+// accountTransitions lists the statuses each status may move to.
+var accountTransitions = map[model.AccountStatus][]model.AccountStatus{
+	model.AccountActive: {model.AccountFrozen, model.AccountClosed},
+	model.AccountFrozen: {model.AccountActive, model.AccountClosed},
+}
+
+// ChangeStatus moves an account to a new status and records who did it and
+// why. An account can only be closed once it holds no funds: any remaining
+// balance is swept to upd.SweepToAccountID first, while funds reserved by
+// active holds must be captured or released beforehand.
+func (s *AccountService) ChangeStatus(ctx context.Context, accountID int64, upd model.AccountStatusUpdate) (*model.Account, *model.AccountStatusChange, error) {
+	if err := authorize(ctx, auth.ActionChangeStatus); err != nil {
+		return nil, nil, err
+	}
+	if !validAccountID(accountID, s.checkDigit) {
+		return nil, nil, &apperror.ErrValidation{Message: "Please provide a valid account number"}
+	}
+	switch upd.Status {
+	case model.AccountActive, model.AccountFrozen, model.AccountClosed:
+	default:
+		return nil, nil, &apperror.ErrValidation{Message: "Status must be one of: active, frozen, closed"}
+	}
+	// the audit trail names the authenticated caller; changed_by from the
+	// client is only used when authentication is disabled
+	if p := auth.FromContext(ctx); p != nil && p != auth.Anonymous {
+		upd.ChangedBy = p.Name
+	}
+	upd.ChangedBy = strings.TrimSpace(upd.ChangedBy)
+	upd.Reason = strings.TrimSpace(upd.Reason)
+	if upd.ChangedBy == "" || upd.Reason == "" {
+		return nil, nil, &apperror.ErrValidation{Message: "Please say who is changing the status and why"}
+	}
+	if upd.SweepToAccountID != nil {
+		if upd.Status != model.AccountClosed {
+			return nil, nil, &apperror.ErrValidation{Message: "A sweep account can only be given when closing an account"}
+		}
+		if !validAccountID(*upd.SweepToAccountID, s.checkDigit) || *upd.SweepToAccountID == accountID {
+			return nil, nil, &apperror.ErrValidation{Message: "Please provide a different, valid account to sweep the balance to"}
+		}
+	}
+
+	tx, err := s.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	ids := []int64{accountID}
+	if upd.SweepToAccountID != nil {
+		ids = append(ids, *upd.SweepToAccountID)
+	}
+	accounts, err := s.ledger.lockAccounts(ctx, tx, ids...)
+	if err != nil {
+		return nil, nil, err
+	}
+	account := accounts[accountID]
+	if err = checkOwner(ctx, account); err != nil {
+		return nil, nil, err
+	}
+	if !slices.Contains(accountTransitions[account.Status], upd.Status) {
+		return nil, nil, &apperror.ErrValidation{Message: fmt.Sprintf("An account that is %s cannot become %s", account.Status, upd.Status)}
+	}
+
+	change := &model.AccountStatusChange{
+		AccountID:  accountID,
+		FromStatus: account.Status,
+		ToStatus:   upd.Status,
+		ChangedBy:  upd.ChangedBy,
+		Reason:     upd.Reason,
+	}
+
+	if upd.Status == model.AccountClosed {
+		if account.HeldBalance.IsPositive() {
+			return nil, nil, &apperror.ErrValidation{Message: "This account has reserved funds. Please capture or release its holds and decide its pending approvals before closing it"}
+		}
+		if account.Balance.IsPositive() {
+			if upd.SweepToAccountID == nil {
+				return nil, nil, &apperror.ErrValidation{Message: "Only accounts with a zero balance can be closed. Please provide an account to sweep the balance to"}
+			}
+			dest := accounts[*upd.SweepToAccountID]
+			if err = requireActive(dest); err != nil {
+				return nil, nil, err
+			}
+			if dest.Currency != account.Currency {
+				return nil, nil, &apperror.ErrCurrencyMismatch{SourceCurrency: account.Currency, DestinationCurrency: dest.Currency}
+			}
+			sweep := &model.Transaction{Kind: model.TransactionKindSweep, Amount: account.Balance}
+			if err = s.ledger.post(ctx, tx, account, dest, sweep); err != nil {
+				return nil, nil, err
+			}
+			change.SweepTransactionID = &sweep.ID
+		}
+	}
+
+	if err = s.accountRepo.UpdateStatus(ctx, tx, accountID, upd.Status); err != nil {
+		return nil, nil, err
+	}
+	if err = s.accountRepo.CreateStatusChange(ctx, tx, change); err != nil {
+		return nil, nil, err
+	}
+	if err = recordEvent(ctx, tx, s.outboxRepo, EventAccountStatusChanged, []int64{accountID}, change); err != nil {
+		return nil, nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("committing status change: %w", err)
+	}
+
+	account.Status = upd.Status
+	s.logger.Info("account status changed", "account_id", accountID, "from", change.FromStatus, "to", change.ToStatus,
+		"changed_by", change.ChangedBy, "actor", actor(ctx))
+	return account, change, nil
+}
+
+func (s *AccountService) StatusHistory(ctx context.Context, accountID int64) ([]model.AccountStatusChange, error) {
+	if err := authorize(ctx, auth.ActionReadAccounts); err != nil {
+		return nil, err
+	}
+	if _, err := s.getAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	history, err := s.accountRepo.ListStatusChanges(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching account status history: %w", err)
+	}
+	return history, nil
+}
+
+// GetLimits returns the outgoing limits of an account. An account without
+// limits gets an empty set.
+func (s *AccountService) GetLimits(ctx context.Context, accountID int64) (*model.AccountLimits, error) {
+	if err := authorize(ctx, auth.ActionReadAccounts); err != nil {
+		return nil, err
+	}
+	if _, err := s.getAccount(ctx, accountID); err != nil {
+		return nil, err
+	}
+
+	limits, err := s.limitRepo.Get(ctx, accountID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching account limits: %w", err)
+	}
+	if limits == nil {
+		limits = &model.AccountLimits{AccountID: accountID}
+	}
+	return limits, nil
+}
+
+// SetLimits replaces every limit of limits.AccountID.
+func (s *AccountService) SetLimits(ctx context.Context, limits model.AccountLimits) (*model.AccountLimits, error) {
+	if err := authorize(ctx, auth.ActionSetLimits); err != nil {
+		return nil, err
+	}
+	account, err := s.getAccount(ctx, limits.AccountID)
+	if err != nil {
+		return nil, err
+	}
+
+	amounts := []struct {
+		name  string
+		value decimal.NullDecimal
+	}{
+		{"Single transfer limit", limits.MaxSingleAmount},
+		{"Daily limit", limits.DailyAmount},
+		{"Monthly limit", limits.MonthlyAmount},
+	}
+	for _, a := range amounts {
+		if !a.value.Valid {
+			continue
+		}
+		if a.value.Decimal.IsNegative() {
+			return nil, &apperror.ErrValidation{Message: fmt.Sprintf("%s cannot be negative", a.name)}
+		}
+		if !currency.HasValidPrecision(a.value.Decimal, account.Currency) {
+			return nil, &apperror.ErrValidation{Message: fmt.Sprintf("%s can only have up to %d decimal places in %s", a.name, currency.MinorUnits(account.Currency), account.Currency)}
+		}
+	}
+	if limits.HourlyCount != nil && *limits.HourlyCount < 0 {
+		return nil, &apperror.ErrValidation{Message: "Hourly transfer count cannot be negative"}
+	}
+
+	if err = s.limitRepo.Upsert(ctx, &limits); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("account limits updated", "account_id", limits.AccountID, "actor", actor(ctx))
+	return &limits, nil
+}
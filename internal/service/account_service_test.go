@@ -0,0 +1,385 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/auth"
+	"github.com/InternalTransfer/internal/model"
+)
+
+func TestValidAccountID(t *testing.T) {
+	tests := []struct {
+		name       string
+		id         int64
+		checkDigit bool
+		want       bool
+	}{
+		{"plain", 12345, false, true},
+		{"plain zero", 0, false, false},
+		{"plain negative", -5, false, false},
+		{"valid check digit", 79927398713, true, true},
+		{"valid check digit not required", 79927398713, false, true},
+		{"invalid check digit", 79927398712, true, false},
+		{"invalid check digit not required", 79927398712, false, true},
+		{"single digit", 8, true, false},
+		{"zero with check digit", 0, true, false},
+		{"negative with check digit", -18, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validAccountID(tt.id, tt.checkDigit); got != tt.want {
+				t.Errorf("validAccountID(%d, %v) = %v, want %v", tt.id, tt.checkDigit, got, tt.want)
+			}
+		})
+	}
+}
+
+func newTestAccountService(bank *fakeBank) *AccountService {
+	return NewAccountService(fakeAccountRepo{bank}, fakeTransactionRepo{bank}, fakeLedgerRepo{bank}, fakeOutboxRepo{bank},
+		fakeLimitRepo{bank}, bank, slog.New(slog.NewTextHandler(io.Discard, nil)), false)
+}
+
+func TestAccountReadsRequireReadScope(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "100")
+	s := newTestAccountService(bank)
+	// may change limits but not read accounts
+	ctx := auth.WithPrincipal(context.Background(),
+		&auth.Principal{ID: "apikey:1", Name: "apikey:limits", Role: auth.RoleApprover, Scopes: []auth.Scope{auth.ScopeAccountsWrite}})
+
+	reads := []struct {
+		name string
+		call func() error
+	}{
+		{"get", func() error {
+			_, err := s.GetByID(ctx, 1)
+			return err
+		}},
+		{"list", func() error {
+			_, _, err := s.List(ctx, model.AccountFilter{})
+			return err
+		}},
+		{"balance", func() error {
+			_, err := s.BalanceAt(ctx, 1, time.Time{})
+			return err
+		}},
+		{"status history", func() error {
+			_, err := s.StatusHistory(ctx, 1)
+			return err
+		}},
+		{"limits", func() error {
+			_, err := s.GetLimits(ctx, 1)
+			return err
+		}},
+	}
+
+	for _, tt := range reads {
+		t.Run(tt.name, func(t *testing.T) {
+			var forbidden *apperror.ErrForbidden
+			if err := tt.call(); !errors.As(err, &forbidden) {
+				t.Errorf("error = %v, want ErrForbidden", err)
+			}
+		})
+	}
+
+	limits := model.AccountLimits{AccountID: 1, DailyAmount: decimal.NewNullDecimal(dec("50"))}
+	if _, err := s.SetLimits(ctx, limits); err != nil {
+		t.Errorf("SetLimits without accounts:read = %v", err)
+	}
+}
+
+func TestChangeStatusTransitions(t *testing.T) {
+	statuses := []model.AccountStatus{model.AccountActive, model.AccountFrozen, model.AccountClosed}
+	allowed := map[[2]model.AccountStatus]bool{
+		{model.AccountActive, model.AccountFrozen}: true,
+		{model.AccountActive, model.AccountClosed}: true,
+		{model.AccountFrozen, model.AccountActive}: true,
+		{model.AccountFrozen, model.AccountClosed}: true,
+	}
+
+	for _, from := range statuses {
+		for _, to := range statuses {
+			t.Run(string(from)+" to "+string(to), func(t *testing.T) {
+				bank := newFakeBank()
+				bank.addAccount(1, "USD", "0")
+				a := bank.accounts[1]
+				a.Status = from
+				bank.accounts[1] = a
+				s := newTestAccountService(bank)
+
+				_, change, err := s.ChangeStatus(context.Background(), 1,
+					model.AccountStatusUpdate{Status: to, ChangedBy: "ops", Reason: "review"})
+				if !allowed[[2]model.AccountStatus{from, to}] {
+					var validation *apperror.ErrValidation
+					if !errors.As(err, &validation) {
+						t.Fatalf("error = %v, want a validation error", err)
+					}
+					if bank.accounts[1].Status != from || len(bank.statusChanges) != 0 {
+						t.Errorf("refused change left status %s and %d changes", bank.accounts[1].Status, len(bank.statusChanges))
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("ChangeStatus: %v", err)
+				}
+				if bank.accounts[1].Status != to || change.FromStatus != from || change.ToStatus != to {
+					t.Errorf("status = %s with change %+v, want %s to %s", bank.accounts[1].Status, change, from, to)
+				}
+				if len(bank.events) != 1 || bank.events[0].Type != EventAccountStatusChanged {
+					t.Errorf("events = %+v, want one status change", bank.events)
+				}
+			})
+		}
+	}
+}
+
+func TestChangeStatusClose(t *testing.T) {
+	sweepTo := func(id int64) *int64 { return &id }
+	tests := []struct {
+		name        string
+		balance     string
+		held        string
+		sweepTo     *int64
+		destStatus  model.AccountStatus
+		destCcy     string
+		wantCode    string
+		wantBalance string // of account 2 afterwards
+	}{
+		{"empty", "0", "0", nil, model.AccountActive, "USD", "", "0"},
+		{"balance swept", "75", "0", sweepTo(2), model.AccountActive, "USD", "", "75"},
+		{"balance without a sweep account", "75", "0", nil, model.AccountActive, "USD", apperror.CodeValidation, "0"},
+		{"funds held", "75", "10", sweepTo(2), model.AccountActive, "USD", apperror.CodeValidation, "0"},
+		{"sweep to a frozen account", "75", "0", sweepTo(2), model.AccountFrozen, "USD", apperror.CodeAccountInactive, "0"},
+		{"sweep across currencies", "75", "0", sweepTo(2), model.AccountActive, "EUR", apperror.CodeCurrencyMismatch, "0"},
+		{"sweep to itself", "75", "0", sweepTo(1), model.AccountActive, "USD", apperror.CodeValidation, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bank := newFakeBank()
+			bank.addAccount(1, "USD", tt.balance)
+			a := bank.accounts[1]
+			a.HeldBalance = dec(tt.held)
+			bank.accounts[1] = a
+			bank.addAccount(2, tt.destCcy, "0")
+			d := bank.accounts[2]
+			d.Status = tt.destStatus
+			bank.accounts[2] = d
+			s := newTestAccountService(bank)
+
+			_, change, err := s.ChangeStatus(context.Background(), 1, model.AccountStatusUpdate{
+				Status: model.AccountClosed, ChangedBy: "ops", Reason: "customer left", SweepToAccountID: tt.sweepTo,
+			})
+			if tt.wantCode != "" {
+				var appErr apperror.AppError
+				if !errors.As(err, &appErr) || appErr.Code() != tt.wantCode {
+					t.Fatalf("error = %v, want %s", err, tt.wantCode)
+				}
+				if bank.accounts[1].Status != model.AccountActive || bank.balance(1) != tt.balance || len(bank.transactions) != 0 {
+					t.Errorf("refused close left account 1 %s with %s and %d transactions",
+						bank.accounts[1].Status, bank.balance(1), len(bank.transactions))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ChangeStatus: %v", err)
+			}
+			if bank.accounts[1].Status != model.AccountClosed || bank.balance(1) != "0" || bank.balance(2) != tt.wantBalance {
+				t.Errorf("after close: account 1 %s with %s, account 2 has %s; want closed with 0 and %s",
+					bank.accounts[1].Status, bank.balance(1), bank.balance(2), tt.wantBalance)
+			}
+			if swept := change.SweepTransactionID != nil; swept != (tt.balance != "0") {
+				t.Fatalf("sweep transaction = %v, want one only when there was a balance", change.SweepTransactionID)
+			}
+			if change.SweepTransactionID != nil {
+				sweep := bank.transactions[*change.SweepTransactionID-1]
+				if sweep.Kind != model.TransactionKindSweep || sweep.Amount.String() != tt.balance || sweep.DestinationAccountID != 2 {
+					t.Errorf("sweep = %+v, want %s to account 2", sweep, tt.balance)
+				}
+			}
+		})
+	}
+}
+
+func TestChangeStatusChangedBy(t *testing.T) {
+	ops := auth.WithPrincipal(context.Background(),
+		&auth.Principal{ID: "apikey:1", Name: "apikey:ops", Role: auth.RoleApprover, Scopes: []auth.Scope{auth.ScopeAccountsWrite}})
+	anonymous := auth.WithPrincipal(context.Background(), auth.Anonymous)
+	tests := []struct {
+		name      string
+		ctx       context.Context
+		changedBy string
+		want      string // empty when the change is refused
+	}{
+		{"principal overrides the client", ops, "someone else", "apikey:ops"},
+		{"principal without a client value", ops, "", "apikey:ops"},
+		{"client value without authentication", anonymous, " alice ", "alice"},
+		{"missing without authentication", anonymous, " ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bank := newFakeBank()
+			bank.addAccount(1, "USD", "0")
+			s := newTestAccountService(bank)
+
+			_, change, err := s.ChangeStatus(tt.ctx, 1, model.AccountStatusUpdate{Status: model.AccountFrozen, ChangedBy: tt.changedBy, Reason: "review"})
+			if tt.want == "" {
+				var validation *apperror.ErrValidation
+				if !errors.As(err, &validation) {
+					t.Errorf("error = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ChangeStatus: %v", err)
+			}
+			if change.ChangedBy != tt.want || len(bank.statusChanges) != 1 || bank.statusChanges[0].ChangedBy != tt.want {
+				t.Errorf("changed_by = %q, stored %+v; want %q", change.ChangedBy, bank.statusChanges, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateMergesMetadata(t *testing.T) {
+	stored := map[string]any{"region": "eu", "cost_center": "1200", "tier": float64(2)}
+	tests := []struct {
+		name    string
+		patch   map[string]any
+		want    map[string]any
+		wantErr bool
+	}{
+		{"absent", nil, stored, false},
+		{"empty", map[string]any{}, stored, false},
+		{"adds a key", map[string]any{"order_id": "991"},
+			map[string]any{"region": "eu", "cost_center": "1200", "tier": float64(2), "order_id": "991"}, false},
+		{"replaces a key", map[string]any{"region": "us", "tier": map[string]any{"level": "gold"}},
+			map[string]any{"region": "us", "cost_center": "1200", "tier": map[string]any{"level": "gold"}}, false},
+		{"null removes a key", map[string]any{"cost_center": nil, "missing": nil},
+			map[string]any{"region": "eu", "tier": float64(2)}, false},
+		{"removes every key", map[string]any{"region": nil, "cost_center": nil, "tier": nil}, map[string]any{}, false},
+		{"merged result too large", map[string]any{"blob": strings.Repeat("x", maxMetadataBytes)}, nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bank := newFakeBank()
+			bank.addAccount(1, "USD", "0")
+			a := bank.accounts[1]
+			a.Metadata = maps.Clone(stored)
+			bank.accounts[1] = a
+			s := newTestAccountService(bank)
+
+			got, err := s.Update(context.Background(), 1, model.AccountPatch{Metadata: tt.patch})
+			if tt.wantErr {
+				var validation *apperror.ErrValidation
+				if !errors.As(err, &validation) {
+					t.Fatalf("error = %v, want a validation error", err)
+				}
+				if !reflect.DeepEqual(bank.accounts[1].Metadata, stored) {
+					t.Errorf("stored metadata = %v, want it unchanged", bank.accounts[1].Metadata)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Update: %v", err)
+			}
+			if !reflect.DeepEqual(got.Metadata, tt.want) || !reflect.DeepEqual(bank.accounts[1].Metadata, tt.want) {
+				t.Errorf("metadata = %v, stored %v; want %v", got.Metadata, bank.accounts[1].Metadata, tt.want)
+			}
+		})
+	}
+}
+
+func TestBalanceAt(t *testing.T) {
+	bank := newFakeBank()
+	bank.now = time.Date(2025, 1, 15, 22, 0, 0, 0, time.UTC)
+	bank.addAccount(1, "USD", "1000")
+	bank.addAccount(2, "USD", "0")
+	// the opening balance of account 1 has no ledger entries behind it
+	bank.snapshots = append(bank.snapshots, balanceSnapshot{accountID: 1, takenAt: bank.now, balance: dec("1000")})
+	opened := bank.now
+	transfers := newTestTransferService(bank, ApprovalPolicy{})
+	accounts := newTestAccountService(bank)
+	ctx := context.Background()
+
+	bank.now = time.Date(2025, 1, 15, 23, 0, 0, 0, time.UTC)
+	if _, err := transfers.Transfer(ctx, transfer(1, 2, "100"), ""); err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+	first := bank.entries[len(bank.entries)-1].CreatedAt
+
+	// snapshots are taken for midnight once it has settled, and only once
+	for _, run := range []struct {
+		at   time.Time
+		want int64
+	}{
+		{time.Date(2025, 1, 16, 0, 30, 0, 0, time.UTC), 0},
+		{time.Date(2025, 1, 16, 1, 30, 0, 0, time.UTC), 2},
+		{time.Date(2025, 1, 16, 2, 30, 0, 0, time.UTC), 0},
+	} {
+		n, err := accounts.SnapshotBalances(ctx, run.at)
+		if err != nil {
+			t.Fatalf("SnapshotBalances: %v", err)
+		}
+		if n != run.want {
+			t.Errorf("SnapshotBalances at %s took %d snapshots, want %d", run.at.Format(time.Kitchen), n, run.want)
+		}
+	}
+	midnight := time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC)
+	for _, snap := range bank.snapshots[1:] {
+		if want := map[int64]string{1: "900", 2: "100"}[snap.accountID]; !snap.takenAt.Equal(midnight) || snap.balance.String() != want {
+			t.Errorf("snapshot = %+v, want %s at midnight", snap, want)
+		}
+	}
+
+	bank.now = time.Date(2025, 1, 16, 9, 0, 0, 0, time.UTC)
+	if _, err := transfers.Transfer(ctx, transfer(1, 2, "50"), ""); err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+	second := bank.entries[len(bank.entries)-1].CreatedAt
+
+	tests := []struct {
+		name string
+		asOf time.Time
+		want map[int64]string
+	}{
+		{"when opened", opened, map[int64]string{1: "1000", 2: "0"}},
+		{"just before the first transfer", first.Add(-time.Microsecond), map[int64]string{1: "1000", 2: "0"}},
+		{"at the first transfer", first, map[int64]string{1: "900", 2: "100"}},
+		{"at the snapshot", midnight, map[int64]string{1: "900", 2: "100"}},
+		{"just before the second transfer", second.Add(-time.Microsecond), map[int64]string{1: "900", 2: "100"}},
+		{"at the second transfer", second, map[int64]string{1: "850", 2: "150"}},
+		{"now", time.Time{}, map[int64]string{1: "850", 2: "150"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for id, want := range tt.want {
+				got, err := accounts.BalanceAt(ctx, id, tt.asOf)
+				if err != nil {
+					t.Fatalf("BalanceAt(%d): %v", id, err)
+				}
+				if got.Balance.String() != want || got.Currency != "USD" {
+					t.Errorf("account %d balance = %s %s, want %s USD", id, got.Balance, got.Currency, want)
+				}
+			}
+		})
+	}
+
+	var validation *apperror.ErrValidation
+	if _, err := accounts.BalanceAt(ctx, 1, time.Now().Add(time.Minute)); !errors.As(err, &validation) {
+		t.Errorf("BalanceAt in the future: error = %v, want a validation error", err)
+	}
+}
@@ -0,0 +1,109 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/auth"
+	"github.com/InternalTransfer/internal/model"
+)
+
+const maxAPIKeyNameLength = 100
+
+// APIKeyService mints, revokes and checks the API keys of service clients.
+type APIKeyService struct {
+	apiKeyRepo APIKeyRepo
+	logger     *slog.Logger
+}
+
+func NewAPIKeyService(apiKeyRepo APIKeyRepo, logger *slog.Logger) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo: apiKeyRepo,
+		logger:     logger,
+	}
+}
+
+// Create mints a key with the given role and scopes and returns it along
+// with the plain key, which cannot be recovered later.
+func (s *APIKeyService) Create(ctx context.Context, name string, role auth.Role, scopes []auth.Scope) (*model.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxAPIKeyNameLength {
+		return nil, "", &apperror.ErrValidation{Message: fmt.Sprintf("Please name the key, in at most %d characters", maxAPIKeyNameLength)}
+	}
+	if !auth.ValidRole(role) {
+		return nil, "", &apperror.ErrValidation{Message: "Role must be one of: viewer, operator, approver, admin"}
+	}
+	if len(scopes) == 0 {
+		return nil, "", &apperror.ErrValidation{Message: "Please grant the key at least one scope"}
+	}
+	k := &model.APIKey{Name: name, Role: string(role), Scopes: make([]string, 0, len(scopes))}
+	for _, sc := range scopes {
+		if !auth.ValidScope(sc) {
+			return nil, "", &apperror.ErrValidation{Message: fmt.Sprintf("Unknown scope %q", sc)}
+		}
+		k.Scopes = append(k.Scopes, string(sc))
+	}
+
+	key, prefix, err := auth.NewAPIKey()
+	if err != nil {
+		return nil, "", err
+	}
+	k.Prefix = prefix
+	k.KeyHash = auth.HashAPIKey(key)
+	if err = s.apiKeyRepo.Create(ctx, k); err != nil {
+		return nil, "", err
+	}
+
+	s.logger.Info("API key created", "api_key_id", k.ID, "name", k.Name, "role", k.Role, "scopes", k.Scopes)
+	return k, key, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context) ([]model.APIKey, error) {
+	keys, err := s.apiKeyRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing API keys: %w", err)
+	}
+	return keys, nil
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, id int64) (*model.APIKey, error) {
+	if id <= 0 {
+		return nil, &apperror.ErrValidation{Message: "Please provide a valid API key ID"}
+	}
+	k, err := s.apiKeyRepo.Revoke(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("API key revoked", "api_key_id", id, "name", k.Name)
+	return k, nil
+}
+
+// Authenticate returns the principal owning key.
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (*auth.Principal, error) {
+	if key == "" {
+		return nil, &apperror.ErrUnauthenticated{}
+	}
+	k, err := s.apiKeyRepo.GetActiveByHash(ctx, auth.HashAPIKey(key))
+	if err != nil {
+		return nil, err
+	}
+	if k == nil {
+		return nil, &apperror.ErrUnauthenticated{}
+	}
+
+	p := &auth.Principal{
+		ID:     "apikey:" + strconv.FormatInt(k.ID, 10),
+		Name:   "apikey:" + k.Name,
+		Role:   auth.Role(k.Role),
+		Scopes: make([]auth.Scope, 0, len(k.Scopes)),
+	}
+	for _, sc := range k.Scopes {
+		p.Scopes = append(p.Scopes, auth.Scope(sc))
+	}
+	return p, nil
+}
@@ -0,0 +1,458 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/auth"
+	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/pagination"
+)
+
+const expiredApprovalBatchSize = 100
+
+// ApprovalPolicy decides which transfers need the approval of a second
+// principal before they are posted. A zero threshold disables approvals.
+type ApprovalPolicy struct {
+	threshold decimal.Decimal
+	ttl       time.Duration
+	holdFunds bool
+}
+
+// NewApprovalPolicy requires approval for transfers above threshold. They
+// expire unless decided within ttl; with holdFunds their amount is reserved
+// on the source account meanwhile.
+func NewApprovalPolicy(threshold int64, ttl time.Duration, holdFunds bool) ApprovalPolicy {
+	return ApprovalPolicy{
+		threshold: decimal.NewFromInt(threshold),
+		ttl:       ttl,
+		holdFunds: holdFunds,
+	}
+}
+
+// requires reports whether a transfer of amount must be approved.
+func (p ApprovalPolicy) requires(amount decimal.Decimal) bool {
+	return p.threshold.IsPositive() && amount.GreaterThan(p.threshold)
+}
+
+// checkUnapproved rejects an amount that needs approval on a path that moves
+// money without one, such as batches, schedules and holds.
+func (p ApprovalPolicy) checkUnapproved(what string, amount decimal.Decimal, currencyCode string) error {
+	if p.requires(amount) {
+		return &apperror.ErrValidation{Message: fmt.Sprintf("%s amount cannot exceed %s %s without approval. Please submit larger amounts as a single transfer", what, p.threshold, currencyCode)}
+	}
+	return nil
+}
+
+// Submit executes req like Transfer when its amount does not need approval.
+// Otherwise it records req as a pending approval for another principal to
+// decide and returns that instead of a transaction.
+func (s *TransferService) Submit(ctx context.Context, req model.TransferRequest, idempotencyKey string) (*model.Transaction, *model.PendingApproval, error) {
+	if err := s.checkTransfer(ctx, &req, idempotencyKey); err != nil {
+		return nil, nil, err
+	}
+
+	var txn *model.Transaction
+	var approval *model.PendingApproval
+	if !s.approvals.requires(req.Amount) {
+		err := s.withRetry(func() error {
+			var err error
+			txn, err = s.executeTransfer(ctx, req, idempotencyKey)
+			return err
+		})
+		return txn, nil, err
+	}
+
+	if req.FXQuoteID != nil {
+		return nil, nil, &apperror.ErrValidation{Message: "FX quotes expire before a transfer can be approved. Please provide an FX rate instead"}
+	}
+	err := s.withRetry(func() error {
+		var err error
+		txn, approval, err = s.executeSubmission(ctx, req, idempotencyKey)
+		return err
+	})
+	return txn, approval, err
+}
+
+func (s *TransferService) executeSubmission(ctx context.Context, req model.TransferRequest, idempotencyKey string) (*model.Transaction, *model.PendingApproval, error) {
+	tx, err := s.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if idempotencyKey != "" {
+		existing, err := s.claimKey(ctx, tx, idempotencyKey, transferFingerprint(req))
+		if err != nil {
+			return nil, nil, err
+		}
+		if existing != nil {
+			return s.replaySubmission(ctx, existing)
+		}
+	}
+
+	accounts, err := s.ledger.lockAccounts(ctx, tx, req.SourceAccountID, req.DestinationAccountID)
+	if err != nil {
+		return nil, nil, err
+	}
+	source, dest := accounts[req.SourceAccountID], accounts[req.DestinationAccountID]
+	if err = requireActive(source, dest); err != nil {
+		return nil, nil, err
+	}
+	// reject what could never be posted now rather than at approval
+	if _, err = newTransferTransaction(req, source, dest, nil); err != nil {
+		return nil, nil, err
+	}
+
+	initiatorID := actorID(ctx)
+	approval := &model.PendingApproval{
+		SourceAccountID:      req.SourceAccountID,
+		DestinationAccountID: req.DestinationAccountID,
+		Amount:               req.Amount,
+		Currency:             req.Currency,
+		FundsHeld:            s.approvals.holdFunds,
+		InitiatedBy:          actor(ctx),
+		InitiatedByID:        &initiatorID,
+		ExpiresAt:            time.Now().Add(s.approvals.ttl),
+	}
+	if req.FXRate != nil {
+		approval.FXRate = decimal.NewNullDecimal(*req.FXRate)
+	}
+	if approval.FundsHeld {
+		if source.AvailableBalance().LessThan(req.Amount) {
+			return nil, nil, &apperror.ErrInsufficientBalance{AccountID: req.SourceAccountID}
+		}
+		if err = s.accountRepo.UpdateHeldBalance(ctx, tx, source.AccountID, source.HeldBalance.Add(req.Amount)); err != nil {
+			return nil, nil, err
+		}
+	}
+	if err = s.approvalRepo.Create(ctx, tx, approval); err != nil {
+		return nil, nil, err
+	}
+
+	if idempotencyKey != "" {
+		if err = s.idempotencyRepo.CompleteApproval(ctx, tx, actorID(ctx), idempotencyKey, approval.ID); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("committing submission: %w", err)
+	}
+
+	s.logger.Info("transfer awaiting approval", "approval_id", approval.ID, "source", req.SourceAccountID, "destination", req.DestinationAccountID,
+		"amount", req.Amount.String(), "currency", req.Currency, "funds_held", approval.FundsHeld, "actor", actor(ctx))
+	return nil, approval, nil
+}
+
+// replaySubmission returns what an earlier submission under the same key
+// produced: the transfer once it has been approved, the approval until then.
+func (s *TransferService) replaySubmission(ctx context.Context, key *model.IdempotencyKey) (*model.Transaction, *model.PendingApproval, error) {
+	switch {
+	case key.TransactionID != nil:
+		txn, err := s.replayTransaction(ctx, *key.TransactionID)
+		return txn, nil, err
+	case key.ApprovalID != nil:
+		approval, err := s.approvalRepo.GetByID(ctx, *key.ApprovalID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if approval.Status != model.ApprovalApproved {
+			return nil, approval, nil
+		}
+		txn, err := s.replayTransaction(ctx, approval.ID)
+		return txn, nil, err
+	}
+	return nil, nil, fmt.Errorf("idempotency key %q has no transaction", key.Key)
+}
+
+// Approve posts a pending transfer. The approver must be someone other than
+// the submitter, and the transfer is checked again in full, so it fails if
+// for example the source can no longer cover it or its fee.
+func (s *TransferService) Approve(ctx context.Context, id int64) (*model.Transaction, error) {
+	if err := authorize(ctx, auth.ActionApprove); err != nil {
+		return nil, err
+	}
+	if id <= 0 {
+		return nil, &apperror.ErrValidation{Message: "Please provide a valid transaction ID"}
+	}
+
+	var txn *model.Transaction
+	err := s.withRetry(func() error {
+		var err error
+		txn, err = s.executeApproval(ctx, id)
+		return err
+	})
+	return txn, err
+}
+
+func (s *TransferService) executeApproval(ctx context.Context, id int64) (*model.Transaction, error) {
+	tx, err := s.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// lock the approval before the accounts, matching the sweeper's lock order
+	approval, err := s.lockPendingApproval(ctx, tx, id, "approve")
+	if err != nil {
+		return nil, err
+	}
+	if err = s.checkDecider(ctx, approval, "approved"); err != nil {
+		return nil, err
+	}
+
+	txn, fee, err := s.postTransfer(ctx, tx, approvalRequest(approval), approval)
+	if err != nil {
+		return nil, err
+	}
+
+	decider := actor(ctx)
+	approval.Status, approval.DecidedBy = model.ApprovalApproved, &decider
+	if err = s.approvalRepo.Decide(ctx, tx, approval); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing approval: %w", err)
+	}
+
+	s.logger.Info("transfer approved", "transaction_id", txn.ID, "initiated_by", approval.InitiatedBy,
+		"amount", approval.Amount.String(), "currency", approval.Currency, "fee", fee.String(), "actor", decider)
+	return txn, nil
+}
+
+// Reject turns down a pending transfer and releases any funds held for it.
+// Like approval, it must come from someone other than the submitter.
+func (s *TransferService) Reject(ctx context.Context, id int64, reason string) (*model.PendingApproval, error) {
+	if err := authorize(ctx, auth.ActionApprove); err != nil {
+		return nil, err
+	}
+	if id <= 0 {
+		return nil, &apperror.ErrValidation{Message: "Please provide a valid transaction ID"}
+	}
+
+	tx, err := s.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	approval, err := s.lockPendingApproval(ctx, tx, id, "reject")
+	if err != nil {
+		return nil, err
+	}
+	if err = s.checkDecider(ctx, approval, "rejected"); err != nil {
+		return nil, err
+	}
+	if err = s.releaseApprovalFunds(ctx, tx, approval); err != nil {
+		return nil, err
+	}
+
+	decider := actor(ctx)
+	approval.Status, approval.DecidedBy = model.ApprovalRejected, &decider
+	if reason = strings.TrimSpace(reason); reason != "" {
+		approval.Reason = &reason
+	}
+	if err = s.approvalRepo.Decide(ctx, tx, approval); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing rejection: %w", err)
+	}
+
+	s.logger.Info("transfer rejected", "approval_id", approval.ID, "initiated_by", approval.InitiatedBy, "actor", decider)
+	return approval, nil
+}
+
+func (s *TransferService) GetApproval(ctx context.Context, id int64) (*model.PendingApproval, error) {
+	if err := authorize(ctx, auth.ActionReadAccounts); err != nil {
+		return nil, err
+	}
+	if id <= 0 {
+		return nil, &apperror.ErrValidation{Message: "Please provide a valid transaction ID"}
+	}
+
+	approval, err := s.approvalRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching approval: %w", err)
+	}
+	// end users can see approvals on either side of their accounts
+	if err = requireOwner(ctx, s.accountRepo, approval.SourceAccountID); err != nil {
+		if requireOwner(ctx, s.accountRepo, approval.DestinationAccountID) != nil {
+			return nil, err
+		}
+	}
+	return approval, nil
+}
+
+// ListApprovals returns one page of approvals together with the ID to
+// continue after, which is zero on the last page.
+func (s *TransferService) ListApprovals(ctx context.Context, f model.ApprovalFilter) ([]model.PendingApproval, int64, error) {
+	if err := authorize(ctx, auth.ActionReadAccounts); err != nil {
+		return nil, 0, err
+	}
+	switch f.Status {
+	case "", model.ApprovalPending, model.ApprovalApproved, model.ApprovalRejected, model.ApprovalExpired:
+	default:
+		return nil, 0, &apperror.ErrValidation{Message: "Status must be one of: pending, approved, rejected, expired"}
+	}
+	if f.AccountID < 0 {
+		return nil, 0, &apperror.ErrValidation{Message: "Please provide a valid account number"}
+	}
+	if f.Limit <= 0 {
+		f.Limit = pagination.DefaultLimit
+	}
+	if f.Limit > pagination.MaxLimit {
+		return nil, 0, &apperror.ErrValidation{Message: fmt.Sprintf("Limit cannot exceed %d", pagination.MaxLimit)}
+	}
+	if callerOwner(ctx) != "" {
+		if f.AccountID == 0 {
+			return nil, 0, &apperror.ErrValidation{Message: "Please provide the account_id to list approvals for"}
+		}
+		if err := requireOwner(ctx, s.accountRepo, f.AccountID); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	pageSize := f.Limit
+	f.Limit++
+	list, err := s.approvalRepo.List(ctx, f)
+	if err != nil {
+		return nil, 0, fmt.Errorf("listing approvals: %w", err)
+	}
+	if len(list) <= pageSize {
+		return list, 0, nil
+	}
+	list = list[:pageSize]
+	return list, list[pageSize-1].ID, nil
+}
+
+// ExpireApprovals expires pending transfers nobody decided on in time,
+// releasing their held funds, and returns how many were expired.
+func (s *TransferService) ExpireApprovals(ctx context.Context) (int, error) {
+	tx, err := s.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	approvals, err := s.approvalRepo.ClaimExpired(ctx, tx, expiredApprovalBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	// release in account order so the sweeper locks accounts in the same
+	// order as transfers do
+	slices.SortFunc(approvals, func(a, b model.PendingApproval) int { return cmp.Compare(a.SourceAccountID, b.SourceAccountID) })
+	for i := range approvals {
+		a := &approvals[i]
+		if err = s.releaseApprovalFunds(ctx, tx, a); err != nil {
+			return 0, err
+		}
+		a.Status = model.ApprovalExpired
+		if err = s.approvalRepo.Decide(ctx, tx, a); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("committing expired approvals: %w", err)
+	}
+	return len(approvals), nil
+}
+
+// RunApprovalExpiry expires undecided transfers every interval until ctx is
+// cancelled.
+func (s *TransferService) RunApprovalExpiry(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.ExpireApprovals(ctx)
+			if err != nil {
+				s.logger.Error("expiring approvals", "error", err)
+				continue
+			}
+			if n > 0 {
+				s.logger.Info("approvals expired", "count", n)
+			}
+		}
+	}
+}
+
+// lockPendingApproval locks an approval and makes sure it can still be
+// decided. One past its expiry that the sweeper has not reached yet is
+// treated as expired.
+func (s *TransferService) lockPendingApproval(ctx context.Context, tx pgx.Tx, id int64, action string) (*model.PendingApproval, error) {
+	approval, err := s.approvalRepo.GetByIDForUpdate(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if approval.Status == model.ApprovalPending && !approval.ExpiresAt.After(time.Now()) {
+		return nil, &apperror.ErrApprovalState{ApprovalID: id, Status: string(model.ApprovalExpired), Action: action}
+	}
+	if approval.Status != model.ApprovalPending {
+		return nil, &apperror.ErrApprovalState{ApprovalID: id, Status: string(approval.Status), Action: action}
+	}
+	return approval, nil
+}
+
+// checkDecider makes sure the caller may decide on approval: end users only
+// on transfers out of their own accounts, and nobody on their own
+// submissions.
+func (s *TransferService) checkDecider(ctx context.Context, approval *model.PendingApproval, action string) error {
+	if err := requireOwner(ctx, s.accountRepo, approval.SourceAccountID); err != nil {
+		return err
+	}
+	// approvals submitted before principals had stable IDs fall back to
+	// comparing names
+	self := actor(ctx) == approval.InitiatedBy
+	if approval.InitiatedByID != nil {
+		self = actorID(ctx) == *approval.InitiatedByID
+	}
+	if self {
+		return &apperror.ErrSelfApproval{ApprovalID: approval.ID, Action: action}
+	}
+	return nil
+}
+
+// releaseApprovalFunds returns the funds held for approval, if any, to the
+// source account's available balance.
+func (s *TransferService) releaseApprovalFunds(ctx context.Context, tx pgx.Tx, approval *model.PendingApproval) error {
+	if !approval.FundsHeld {
+		return nil
+	}
+	account, err := s.accountRepo.GetByIDForUpdate(ctx, tx, approval.SourceAccountID)
+	if err != nil {
+		return err
+	}
+	return s.accountRepo.UpdateHeldBalance(ctx, tx, account.AccountID, account.HeldBalance.Sub(approval.Amount))
+}
+
+// approvalRequest rebuilds the transfer request an approval was submitted
+// with.
+func approvalRequest(a *model.PendingApproval) model.TransferRequest {
+	req := model.TransferRequest{
+		SourceAccountID:      a.SourceAccountID,
+		DestinationAccountID: a.DestinationAccountID,
+		Amount:               a.Amount,
+		Currency:             a.Currency,
+	}
+	if a.FXRate.Valid {
+		req.FXRate = &a.FXRate.Decimal
+	}
+	return req
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/auth"
+	"github.com/InternalTransfer/internal/model"
+)
+
+func TestCheckDecider(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+	bot := &auth.Principal{ID: "apikey:7", Name: "apikey:treasury-bot", Role: auth.RoleAdmin}
+	// a different key that was given the bot's old name
+	renamed := &auth.Principal{ID: "apikey:9", Name: "apikey:treasury-bot", Role: auth.RoleAdmin}
+	sameSubject := &auth.Principal{ID: "jwt:https://a.example customer-1", Name: "user:customer-1", Role: auth.RoleApprover}
+	otherIssuer := &auth.Principal{ID: "jwt:https://b.example customer-1", Name: "user:customer-1", Role: auth.RoleApprover}
+
+	tests := []struct {
+		name     string
+		caller   *auth.Principal
+		approval model.PendingApproval
+		wantSelf bool
+	}{
+		{"submitter", bot, model.PendingApproval{InitiatedBy: bot.Name, InitiatedByID: strPtr(bot.ID)}, true},
+		{"another key under the same name", renamed, model.PendingApproval{InitiatedBy: bot.Name, InitiatedByID: strPtr(bot.ID)}, false},
+		{"same subject and issuer", sameSubject, model.PendingApproval{InitiatedBy: sameSubject.Name, InitiatedByID: strPtr(sameSubject.ID)}, true},
+		{"same subject from another issuer", otherIssuer, model.PendingApproval{InitiatedBy: sameSubject.Name, InitiatedByID: strPtr(sameSubject.ID)}, false},
+		{"legacy approval by name", renamed, model.PendingApproval{InitiatedBy: bot.Name}, true},
+		{"legacy approval by another name", sameSubject, model.PendingApproval{InitiatedBy: bot.Name}, false},
+	}
+
+	s := &TransferService{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := auth.WithPrincipal(context.Background(), tt.caller)
+			err := s.checkDecider(ctx, &tt.approval, "approve")
+			var selfErr *apperror.ErrSelfApproval
+			if got := errors.As(err, &selfErr); got != tt.wantSelf {
+				t.Errorf("checkDecider error = %v, want self-approval %v", err, tt.wantSelf)
+			}
+		})
+	}
+}
+
+// fakeApprovalRepo numbers approvals from firstApprovalID, clear of the
+// transaction IDs the fake bank hands out, as they share a sequence.
+type fakeApprovalRepo struct {
+	ApprovalRepo
+	approvals []model.PendingApproval
+}
+
+const firstApprovalID = 1000
+
+func (r *fakeApprovalRepo) Create(_ context.Context, _ pgx.Tx, a *model.PendingApproval) error {
+	a.ID, a.Status = int64(firstApprovalID+len(r.approvals)), model.ApprovalPending
+	r.approvals = append(r.approvals, *a)
+	return nil
+}
+
+func (r *fakeApprovalRepo) GetByID(_ context.Context, id int64) (*model.PendingApproval, error) {
+	i := id - firstApprovalID
+	if i < 0 || i >= int64(len(r.approvals)) {
+		return nil, &apperror.ErrNotFound{Entity: "approval", ID: id}
+	}
+	a := r.approvals[i]
+	return &a, nil
+}
+
+func (r *fakeApprovalRepo) GetByIDForUpdate(ctx context.Context, _ pgx.Tx, id int64) (*model.PendingApproval, error) {
+	return r.GetByID(ctx, id)
+}
+
+// ClaimExpired returns pending approvals past their expiry, like the
+// repository.
+func (r *fakeApprovalRepo) ClaimExpired(_ context.Context, _ pgx.Tx, limit int) ([]model.PendingApproval, error) {
+	var out []model.PendingApproval
+	for _, a := range r.approvals {
+		if a.Status == model.ApprovalPending && !a.ExpiresAt.After(time.Now()) && len(out) < limit {
+			out = append(out, a)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeApprovalRepo) Decide(_ context.Context, _ pgx.Tx, a *model.PendingApproval) error {
+	now := time.Now()
+	a.DecidedAt = &now
+	r.approvals[a.ID-firstApprovalID] = *a
+	return nil
+}
+
+// newTestApprovalService requires approval above 100.
+func newTestApprovalService(bank *fakeBank, approvals *fakeApprovalRepo, holdFunds bool) *TransferService {
+	return NewTransferService(fakeAccountRepo{bank}, fakeTransactionRepo{bank}, fakeLedgerRepo{bank}, fakeOutboxRepo{bank},
+		fakeIdempotencyRepo{bank}, nil, fakeFeePolicyRepo{bank}, fakeLimitRepo{bank}, approvals, bank,
+		slog.New(slog.NewTextHandler(io.Discard, nil)), 1_000_000, feeAccountID, false,
+		NewApprovalPolicy(100, time.Hour, holdFunds))
+}
+
+var (
+	maker   = &auth.Principal{ID: "apikey:7", Name: "apikey:maker", Role: auth.RoleApprover, Scopes: []auth.Scope{auth.ScopeTransfersWrite}}
+	checker = &auth.Principal{ID: "apikey:8", Name: "apikey:checker", Role: auth.RoleApprover, Scopes: []auth.Scope{auth.ScopeTransfersWrite}}
+)
+
+func submit(t *testing.T, s *TransferService, principal *auth.Principal, req model.TransferRequest, key string) *model.PendingApproval {
+	t.Helper()
+	txn, approval, err := s.Submit(auth.WithPrincipal(context.Background(), principal), req, key)
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if txn != nil || approval == nil || approval.Status != model.ApprovalPending {
+		t.Fatalf("Submit = %+v, %+v; want a pending approval", txn, approval)
+	}
+	return approval
+}
+
+func TestSubmitHoldsFunds(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "1000")
+	bank.addAccount(2, "USD", "0")
+	approvals := &fakeApprovalRepo{}
+	s := newTestApprovalService(bank, approvals, true)
+	checkerCtx := auth.WithPrincipal(context.Background(), checker)
+	held := func() string { return bank.accounts[1].HeldBalance.String() }
+
+	// small amounts are posted straight away
+	txn, approval, err := s.Submit(auth.WithPrincipal(context.Background(), maker), transfer(1, 2, "100"), "")
+	if err != nil || txn == nil || approval != nil {
+		t.Fatalf("Submit below the threshold = %+v, %+v, %v; want a transaction", txn, approval, err)
+	}
+
+	rejected := submit(t, s, maker, transfer(1, 2, "500"), "")
+	if !rejected.FundsHeld || held() != "500" || bank.balance(1) != "900" {
+		t.Fatalf("after submitting: held %s of %s, want 500 of 900", held(), bank.balance(1))
+	}
+	// the held funds are no longer available to other transfers
+	var insufficient *apperror.ErrInsufficientBalance
+	if _, _, err := s.Submit(auth.WithPrincipal(context.Background(), maker), transfer(1, 2, "401"), ""); !errors.As(err, &insufficient) {
+		t.Errorf("Submit beyond the available balance: error = %v, want insufficient balance", err)
+	}
+
+	got, err := s.Reject(checkerCtx, rejected.ID, " not this month ")
+	if err != nil {
+		t.Fatalf("Reject: %v", err)
+	}
+	if got.Status != model.ApprovalRejected || *got.DecidedBy != checker.Name || *got.Reason != "not this month" || held() != "0" {
+		t.Errorf("after rejecting: %+v with %s held, want rejected by the checker and nothing held", got, held())
+	}
+
+	expired := submit(t, s, maker, transfer(1, 2, "300"), "")
+	approvals.approvals[expired.ID-firstApprovalID].ExpiresAt = time.Now().Add(-time.Second)
+	// deciding an approval past its expiry fails even before the sweeper runs
+	var state *apperror.ErrApprovalState
+	if _, err := s.Approve(checkerCtx, expired.ID); !errors.As(err, &state) {
+		t.Errorf("Approve after expiry: error = %v, want an approval state error", err)
+	}
+	if n, err := s.ExpireApprovals(context.Background()); err != nil || n != 1 {
+		t.Fatalf("ExpireApprovals = %d, %v; want 1", n, err)
+	}
+	if a := approvals.approvals[expired.ID-firstApprovalID]; a.Status != model.ApprovalExpired || held() != "0" {
+		t.Errorf("after expiry: status %s with %s held, want expired and nothing held", a.Status, held())
+	}
+	if bank.balance(1) != "900" || bank.balance(2) != "100" {
+		t.Errorf("balances = %s/%s, want only the small transfer posted", bank.balance(1), bank.balance(2))
+	}
+
+	// without holding, submitting leaves the available balance alone
+	s = newTestApprovalService(bank, approvals, false)
+	if a := submit(t, s, maker, transfer(1, 2, "500"), ""); a.FundsHeld || held() != "0" {
+		t.Errorf("approval %+v with %s held, want no funds held", a, held())
+	}
+}
+
+func TestApprovePostsTransfer(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "1000")
+	bank.addAccount(2, "USD", "0")
+	approvals := &fakeApprovalRepo{}
+	s := newTestApprovalService(bank, approvals, true)
+	checkerCtx := auth.WithPrincipal(context.Background(), checker)
+
+	approval := submit(t, s, maker, transfer(1, 2, "500"), "")
+	txn, err := s.Approve(checkerCtx, approval.ID)
+	if err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	// the transfer is posted under the approval's ID, so clients can look it
+	// up by the ID they were given on submission
+	if txn.ID != approval.ID || txn.Amount.String() != "500" {
+		t.Errorf("transaction = %+v, want 500 posted as %d", txn, approval.ID)
+	}
+	if stored, err := s.GetTransaction(context.Background(), approval.ID); err != nil || stored.ID != approval.ID {
+		t.Errorf("GetTransaction(%d) = %+v, %v", approval.ID, stored, err)
+	}
+	if bank.balance(1) != "500" || bank.balance(2) != "500" || bank.accounts[1].HeldBalance.String() != "0" {
+		t.Errorf("after approval: balances %s/%s with %s held, want 500/500 and nothing held",
+			bank.balance(1), bank.balance(2), bank.accounts[1].HeldBalance)
+	}
+	if a := approvals.approvals[0]; a.Status != model.ApprovalApproved || *a.DecidedBy != checker.Name {
+		t.Errorf("approval = %+v, want approved by the checker", a)
+	}
+
+	var state *apperror.ErrApprovalState
+	if _, err := s.Approve(checkerCtx, approval.ID); !errors.As(err, &state) {
+		t.Errorf("second Approve: error = %v, want an approval state error", err)
+	}
+	if _, err := s.Reject(checkerCtx, approval.ID, ""); !errors.As(err, &state) {
+		t.Errorf("Reject after approval: error = %v, want an approval state error", err)
+	}
+}
+
+func TestSubmitReplay(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "1000")
+	bank.addAccount(2, "USD", "0")
+	approvals := &fakeApprovalRepo{}
+	s := newTestApprovalService(bank, approvals, true)
+	makerCtx := auth.WithPrincipal(context.Background(), maker)
+
+	first := submit(t, s, maker, transfer(1, 2, "500"), "key-1")
+	replayed := submit(t, s, maker, transfer(1, 2, "500"), "key-1")
+	if replayed.ID != first.ID || len(approvals.approvals) != 1 || bank.accounts[1].HeldBalance.String() != "500" {
+		t.Errorf("replay = %d with %d approvals and %s held, want %d, 1 and 500",
+			replayed.ID, len(approvals.approvals), bank.accounts[1].HeldBalance, first.ID)
+	}
+
+	var mismatch *apperror.ErrIdempotencyMismatch
+	if _, _, err := s.Submit(makerCtx, transfer(1, 2, "600"), "key-1"); !errors.As(err, &mismatch) {
+		t.Errorf("Submit with another body: error = %v, want an idempotency mismatch", err)
+	}
+
+	if _, err := s.Approve(auth.WithPrincipal(context.Background(), checker), first.ID); err != nil {
+		t.Fatalf("Approve: %v", err)
+	}
+	txn, approval, err := s.Submit(makerCtx, transfer(1, 2, "500"), "key-1")
+	if err != nil || approval != nil || txn == nil || txn.ID != first.ID {
+		t.Errorf("replay after approval = %+v, %+v, %v; want transaction %d", txn, approval, err, first.ID)
+	}
+	if bank.balance(1) != "500" || len(bank.transactions) != 1 {
+		t.Errorf("balance %s after %d transactions, want the transfer posted once", bank.balance(1), len(bank.transactions))
+	}
+}
+
+func TestApproveRejectsSelfApprovalAfterRename(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "1000")
+	bank.addAccount(2, "USD", "0")
+	approvals := &fakeApprovalRepo{}
+	s := newTestApprovalService(bank, approvals, true)
+
+	approval := submit(t, s, maker, transfer(1, 2, "500"), "")
+	// the maker's key was renamed after submitting
+	renamed := &auth.Principal{ID: maker.ID, Name: "apikey:treasury", Role: maker.Role, Scopes: maker.Scopes}
+	ctx := auth.WithPrincipal(context.Background(), renamed)
+
+	var self *apperror.ErrSelfApproval
+	if _, err := s.Approve(ctx, approval.ID); !errors.As(err, &self) {
+		t.Errorf("Approve: error = %v, want a self-approval error", err)
+	}
+	if _, err := s.Reject(ctx, approval.ID, ""); !errors.As(err, &self) {
+		t.Errorf("Reject: error = %v, want a self-approval error", err)
+	}
+	if a := approvals.approvals[0]; a.Status != model.ApprovalPending || bank.accounts[1].HeldBalance.String() != "500" {
+		t.Errorf("approval %s with %s held, want it still pending", a.Status, bank.accounts[1].HeldBalance)
+	}
+
+	// another key that took over the maker's old name may decide
+	impostor := &auth.Principal{ID: "apikey:9", Name: maker.Name, Role: auth.RoleApprover, Scopes: maker.Scopes}
+	if _, err := s.Approve(auth.WithPrincipal(context.Background(), impostor), approval.ID); err != nil {
+		t.Errorf("Approve by another key: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/auth"
+)
+
+// Authenticator accepts the API keys of service clients and, when a JWT
+// verifier is configured, the bearer tokens of end-user apps. Token holders
+// act as the owner named by ownerClaim, with userRole and userScopes.
+type Authenticator struct {
+	apiKeys    *APIKeyService
+	jwt        *auth.JWTVerifier
+	loadJWKS   func(ctx context.Context) ([]byte, error)
+	logger     *slog.Logger
+	ownerClaim string
+	userRole   auth.Role
+	userScopes []auth.Scope
+}
+
+// NewAuthenticator returns an authenticator. jwt may be nil to accept API
+// keys only; otherwise loadJWKS provides its keys.
+func NewAuthenticator(
+	apiKeys *APIKeyService,
+	jwt *auth.JWTVerifier,
+	loadJWKS func(ctx context.Context) ([]byte, error),
+	logger *slog.Logger,
+	ownerClaim string,
+	userRole auth.Role,
+	userScopes []auth.Scope,
+) *Authenticator {
+	return &Authenticator{
+		apiKeys:    apiKeys,
+		jwt:        jwt,
+		loadJWKS:   loadJWKS,
+		logger:     logger,
+		ownerClaim: ownerClaim,
+		userRole:   userRole,
+		userScopes: userScopes,
+	}
+}
+
+func (a *Authenticator) Authenticate(ctx context.Context, credential string) (*auth.Principal, error) {
+	if a.jwt == nil || auth.IsAPIKey(credential) {
+		return a.apiKeys.Authenticate(ctx, credential)
+	}
+
+	claims, err := a.jwt.Verify(credential, time.Now())
+	if err != nil {
+		a.logger.Debug("rejected bearer token", "error", err)
+		return nil, &apperror.ErrUnauthenticated{}
+	}
+	owner, _ := claims[a.ownerClaim].(string)
+	subject, _ := claims["sub"].(string)
+	if strings.TrimSpace(owner) == "" || subject == "" {
+		return nil, &apperror.ErrUnauthenticated{}
+	}
+	// an issuer URL cannot contain a space, so the ID cannot be forged by
+	// another issuer's subject
+	issuer, _ := claims["iss"].(string)
+	return &auth.Principal{
+		ID:      "jwt:" + issuer + " " + subject,
+		Name:    "user:" + owner,
+		Role:    a.userRole,
+		Scopes:  a.userScopes,
+		OwnerID: owner,
+	}, nil
+}
+
+// RefreshKeys reloads the JWKS, keeping the current keys if that fails.
+func (a *Authenticator) RefreshKeys(ctx context.Context) error {
+	if a.jwt == nil {
+		return nil
+	}
+	b, err := a.loadJWKS(ctx)
+	if err != nil {
+		return err
+	}
+	if err = a.jwt.SetKeys(b); err != nil {
+		return fmt.Errorf("loading JWKS: %w", err)
+	}
+	return nil
+}
+
+// RunKeyRefresh reloads the JWKS every interval until ctx is cancelled, so
+// that keys rotated by the issuer are picked up.
+func (a *Authenticator) RunKeyRefresh(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.RefreshKeys(ctx); err != nil && ctx.Err() == nil {
+				a.logger.Error("refreshing JWKS", "error", err)
+			}
+		}
+	}
+}
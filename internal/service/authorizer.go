@@ -0,0 +1,36 @@
+package service
+
+import (
+	"context"
+
+	"github.com/InternalTransfer/internal/auth"
+)
+
+// authorize rejects a caller whose role or scopes do not allow action. It
+// repeats the router's check so that the rule holds however the service is
+// reached. Calls made outside a request, such as by background workers, are
+// trusted.
+func authorize(ctx context.Context, action auth.Action) error {
+	p := auth.FromContext(ctx)
+	if p == nil {
+		return nil
+	}
+	return auth.Authorize(p, action)
+}
+
+// actor names the caller for logs and audit records: the principal of the
+// request, or "system" for background work.
+func actor(ctx context.Context) string {
+	if p := auth.FromContext(ctx); p != nil {
+		return p.Name
+	}
+	return "system"
+}
+
+// actorID is the stable counterpart of actor, for comparing callers.
+func actorID(ctx context.Context) string {
+	if p := auth.FromContext(ctx); p != nil {
+		return p.ID
+	}
+	return "system"
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/auth"
+	"github.com/InternalTransfer/internal/currency"
+	"github.com/InternalTransfer/internal/model"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+// FeeService manages the fee policies applied by TransferService.
+type FeeService struct {
+	feePolicyRepo FeePolicyRepo
+	accountRepo   AccountRepo
+	logger        *slog.Logger
+	feeAccountID  int64
+	// checkDigit requires account IDs to end in a Luhn check digit.
+	checkDigit bool
+}
+
+func NewFeeService(feePolicyRepo FeePolicyRepo, accountRepo AccountRepo, logger *slog.Logger, feeAccountID int64, checkDigit bool) *FeeService {
+	return &FeeService{
+		feePolicyRepo: feePolicyRepo,
+		accountRepo:   accountRepo,
+		logger:        logger,
+		feeAccountID:  feeAccountID,
+		checkDigit:    checkDigit,
+	}
+}
+
+func (s *FeeService) Create(ctx context.Context, p model.FeePolicy) (*model.FeePolicy, error) {
+	if err := authorize(ctx, auth.ActionManageFees); err != nil {
+		return nil, err
+	}
+	if s.feeAccountID == 0 {
+		return nil, &apperror.ErrValidation{Message: "Fees are disabled because no fee collection account is configured"}
+	}
+
+	p.Currency = currency.Normalize(p.Currency)
+	if p.AccountType != nil && (p.SourceAccountID != nil || p.DestinationAccountID != nil) {
+		return nil, &apperror.ErrValidation{Message: "A fee policy applies either to an account type or to an account pair, not both"}
+	}
+	switch {
+	case p.AccountType != nil:
+		if !validAccountType(*p.AccountType) {
+			return nil, &apperror.ErrValidation{Message: "Account type must be 1-32 lowercase letters, digits, '-' or '_'"}
+		}
+	case p.SourceAccountID != nil && p.DestinationAccountID != nil:
+		if !validAccountID(*p.SourceAccountID, s.checkDigit) || !validAccountID(*p.DestinationAccountID, s.checkDigit) ||
+			*p.SourceAccountID == *p.DestinationAccountID {
+			return nil, &apperror.ErrValidation{Message: "Please provide two different valid account numbers"}
+		}
+		if _, err := s.accountRepo.GetByID(ctx, *p.DestinationAccountID); err != nil {
+			return nil, fmt.Errorf("fetching account: %w", err)
+		}
+		source, err := s.accountRepo.GetByID(ctx, *p.SourceAccountID)
+		if err != nil {
+			return nil, fmt.Errorf("fetching account: %w", err)
+		}
+		if p.Currency == "" {
+			p.Currency = source.Currency
+		}
+		if source.Currency != p.Currency {
+			return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Fees on account %d must be charged in %s", source.AccountID, source.Currency)}
+		}
+	default:
+		return nil, &apperror.ErrValidation{Message: "Please provide an account type or a source and destination account"}
+	}
+
+	if p.Currency == "" {
+		return nil, &apperror.ErrValidation{Message: "Please specify the fee currency (e.g., USD)"}
+	}
+	if !currency.IsSupported(p.Currency) {
+		return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Currency %q is not supported", p.Currency)}
+	}
+	feeAccount, err := s.accountRepo.GetByID(ctx, s.feeAccountID)
+	if err != nil {
+		return nil, fmt.Errorf("fetching fee collection account: %w", err)
+	}
+	if feeAccount.Currency != p.Currency {
+		return nil, &apperror.ErrValidation{Message: fmt.Sprintf("The fee collection account holds %s, so fees must be charged in %s", feeAccount.Currency, feeAccount.Currency)}
+	}
+	if err = validateFeePricing(&p); err != nil {
+		return nil, err
+	}
+
+	if err = s.feePolicyRepo.Create(ctx, &p); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("fee policy created", "fee_policy_id", p.ID, "fee_type", p.FeeType, "currency", p.Currency, "actor", actor(ctx))
+	return &p, nil
+}
+
+// validateFeePricing checks that p carries exactly the fields its fee type
+// needs and that amounts fit its currency.
+func validateFeePricing(p *model.FeePolicy) error {
+	nonNegative := func(name string, d decimal.Decimal) error {
+		if d.IsNegative() {
+			return &apperror.ErrValidation{Message: fmt.Sprintf("%s cannot be negative", name)}
+		}
+		if !currency.HasValidPrecision(d, p.Currency) {
+			return &apperror.ErrValidation{Message: fmt.Sprintf("%s can only have up to %d decimal places in %s", name, currency.MinorUnits(p.Currency), p.Currency)}
+		}
+		return nil
+	}
+	validPercentage := func(d decimal.Decimal) error {
+		if d.IsNegative() || d.GreaterThan(hundred) {
+			return &apperror.ErrValidation{Message: "Percentage must be between 0 and 100"}
+		}
+		return nil
+	}
+
+	switch p.FeeType {
+	case model.FeeFlat:
+		if !p.FlatAmount.Valid || p.Percentage.Valid || len(p.Tiers) > 0 {
+			return &apperror.ErrValidation{Message: "A flat fee needs flat_amount only"}
+		}
+		if err := nonNegative("Flat amount", p.FlatAmount.Decimal); err != nil {
+			return err
+		}
+	case model.FeePercentage:
+		if !p.Percentage.Valid || p.FlatAmount.Valid || len(p.Tiers) > 0 {
+			return &apperror.ErrValidation{Message: "A percentage fee needs percentage only"}
+		}
+		if err := validPercentage(p.Percentage.Decimal); err != nil {
+			return err
+		}
+	case model.FeeTiered:
+		if len(p.Tiers) == 0 || p.FlatAmount.Valid || p.Percentage.Valid {
+			return &apperror.ErrValidation{Message: "A tiered fee needs tiers only"}
+		}
+		for i, tier := range p.Tiers {
+			last := i == len(p.Tiers)-1
+			if last != (tier.UpTo == nil) {
+				return &apperror.ErrValidation{Message: "Every tier except the last needs up_to, and the last tier must not have one"}
+			}
+			if i > 0 && !last && !tier.UpTo.GreaterThan(*p.Tiers[i-1].UpTo) {
+				return &apperror.ErrValidation{Message: "Tier up_to values must be increasing"}
+			}
+			if err := nonNegative("Tier flat amount", tier.FlatAmount); err != nil {
+				return err
+			}
+			if err := validPercentage(tier.Percentage); err != nil {
+				return err
+			}
+		}
+	default:
+		return &apperror.ErrValidation{Message: "Fee type must be one of: flat, percentage, tiered"}
+	}
+
+	if p.MinFee.Valid {
+		if err := nonNegative("Minimum fee", p.MinFee.Decimal); err != nil {
+			return err
+		}
+	}
+	if p.MaxFee.Valid {
+		if err := nonNegative("Maximum fee", p.MaxFee.Decimal); err != nil {
+			return err
+		}
+	}
+	if p.MinFee.Valid && p.MaxFee.Valid && p.MinFee.Decimal.GreaterThan(p.MaxFee.Decimal) {
+		return &apperror.ErrValidation{Message: "Minimum fee cannot be greater than maximum fee"}
+	}
+	return nil
+}
+
+func (s *FeeService) GetByID(ctx context.Context, id int64) (*model.FeePolicy, error) {
+	if err := authorize(ctx, auth.ActionManageFees); err != nil {
+		return nil, err
+	}
+	if id <= 0 {
+		return nil, &apperror.ErrValidation{Message: "Please provide a valid fee policy ID"}
+	}
+
+	p, err := s.feePolicyRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching fee policy: %w", err)
+	}
+	return p, nil
+}
+
+func (s *FeeService) List(ctx context.Context) ([]model.FeePolicy, error) {
+	if err := authorize(ctx, auth.ActionManageFees); err != nil {
+		return nil, err
+	}
+	policies, err := s.feePolicyRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing fee policies: %w", err)
+	}
+	return policies, nil
+}
+
+// Update replaces the pricing of a policy with that of p. The scope and
+// currency of a policy are fixed; create a new policy to change them.
+func (s *FeeService) Update(ctx context.Context, id int64, p model.FeePolicy) (*model.FeePolicy, error) {
+	if err := authorize(ctx, auth.ActionManageFees); err != nil {
+		return nil, err
+	}
+	existing, err := s.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	existing.FeeType = p.FeeType
+	existing.FlatAmount = p.FlatAmount
+	existing.Percentage = p.Percentage
+	existing.Tiers = p.Tiers
+	existing.MinFee = p.MinFee
+	existing.MaxFee = p.MaxFee
+	if err = validateFeePricing(existing); err != nil {
+		return nil, err
+	}
+
+	if err = s.feePolicyRepo.Update(ctx, existing); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("fee policy updated", "fee_policy_id", id, "fee_type", existing.FeeType, "actor", actor(ctx))
+	return existing, nil
+}
+
+func (s *FeeService) Delete(ctx context.Context, id int64) error {
+	if err := authorize(ctx, auth.ActionManageFees); err != nil {
+		return err
+	}
+	if id <= 0 {
+		return &apperror.ErrValidation{Message: "Please provide a valid fee policy ID"}
+	}
+	if err := s.feePolicyRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.logger.Info("fee policy deleted", "fee_policy_id", id, "actor", actor(ctx))
+	return nil
+}
+
+// feeFor prices a transfer of amount under p, rounded to the minor units of
+// the policy's currency.
+func feeFor(p *model.FeePolicy, amount decimal.Decimal) decimal.Decimal {
+	percentOf := func(pct decimal.Decimal) decimal.Decimal {
+		return amount.Mul(pct).Div(hundred)
+	}
+
+	var fee decimal.Decimal
+	switch p.FeeType {
+	case model.FeeFlat:
+		fee = p.FlatAmount.Decimal
+	case model.FeePercentage:
+		fee = percentOf(p.Percentage.Decimal)
+	case model.FeeTiered:
+		for _, tier := range p.Tiers {
+			if tier.UpTo == nil || amount.LessThanOrEqual(*tier.UpTo) {
+				fee = tier.FlatAmount.Add(percentOf(tier.Percentage))
+				break
+			}
+		}
+	}
+
+	if p.MinFee.Valid && fee.LessThan(p.MinFee.Decimal) {
+		fee = p.MinFee.Decimal
+	}
+	if p.MaxFee.Valid && fee.GreaterThan(p.MaxFee.Decimal) {
+		fee = p.MaxFee.Decimal
+	}
+	return currency.Round(fee, p.Currency)
+}
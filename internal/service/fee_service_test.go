@@ -0,0 +1,336 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/auth"
+	"github.com/InternalTransfer/internal/model"
+)
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func decPtr(s string) *decimal.Decimal {
+	d := dec(s)
+	return &d
+}
+
+func nullDec(s string) decimal.NullDecimal {
+	return decimal.NewNullDecimal(dec(s))
+}
+
+func TestFeeFor(t *testing.T) {
+	tiers := []model.FeeTier{
+		{UpTo: decPtr("100"), FlatAmount: dec("1")},
+		{UpTo: decPtr("1000"), FlatAmount: dec("0.50"), Percentage: dec("1")},
+		{Percentage: dec("0.5")},
+	}
+
+	tests := []struct {
+		name   string
+		policy model.FeePolicy
+		amount string
+		want   string
+	}{
+		{
+			name:   "flat",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeeFlat, FlatAmount: nullDec("2.50")},
+			amount: "1000",
+			want:   "2.50",
+		},
+		{
+			name:   "percentage",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeePercentage, Percentage: nullDec("1.5")},
+			amount: "200",
+			want:   "3",
+		},
+		{
+			name:   "percentage rounds half up to cents",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeePercentage, Percentage: nullDec("1")},
+			amount: "0.5",
+			want:   "0.01",
+		},
+		{
+			name:   "percentage rounds down to cents",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeePercentage, Percentage: nullDec("1")},
+			amount: "12.34",
+			want:   "0.12",
+		},
+		{
+			name:   "percentage rounds to whole yen",
+			policy: model.FeePolicy{Currency: "JPY", FeeType: model.FeePercentage, Percentage: nullDec("1.5")},
+			amount: "1030",
+			want:   "15",
+		},
+		{
+			name:   "percentage rounds to three places in dinar",
+			policy: model.FeePolicy{Currency: "KWD", FeeType: model.FeePercentage, Percentage: nullDec("0.3")},
+			amount: "10.555",
+			want:   "0.032",
+		},
+		{
+			name:   "first tier",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeeTiered, Tiers: tiers},
+			amount: "50",
+			want:   "1",
+		},
+		{
+			name:   "tier bound is inclusive",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeeTiered, Tiers: tiers},
+			amount: "100",
+			want:   "1",
+		},
+		{
+			name:   "middle tier",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeeTiered, Tiers: tiers},
+			amount: "100.01",
+			want:   "1.50",
+		},
+		{
+			name:   "last tier",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeeTiered, Tiers: tiers},
+			amount: "5000",
+			want:   "25",
+		},
+		{
+			name:   "minimum fee",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeePercentage, Percentage: nullDec("1"), MinFee: nullDec("0.25")},
+			amount: "10",
+			want:   "0.25",
+		},
+		{
+			name:   "maximum fee",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeePercentage, Percentage: nullDec("1"), MaxFee: nullDec("5")},
+			amount: "10000",
+			want:   "5",
+		},
+		{
+			name:   "between minimum and maximum",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeePercentage, Percentage: nullDec("1"), MinFee: nullDec("0.25"), MaxFee: nullDec("5")},
+			amount: "100",
+			want:   "1",
+		},
+		{
+			name:   "minimum applies to a tier",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeeTiered, Tiers: tiers, MinFee: nullDec("2")},
+			amount: "50",
+			want:   "2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := feeFor(&tt.policy, dec(tt.amount)); !got.Equal(dec(tt.want)) {
+				t.Errorf("feeFor(%s) = %s, want %s", tt.amount, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateFeePricing(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  model.FeePolicy
+		wantErr bool
+	}{
+		{
+			name:   "flat",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeeFlat, FlatAmount: nullDec("2.50")},
+		},
+		{
+			name:    "flat without amount",
+			policy:  model.FeePolicy{Currency: "USD", FeeType: model.FeeFlat},
+			wantErr: true,
+		},
+		{
+			name:    "flat with percentage",
+			policy:  model.FeePolicy{Currency: "USD", FeeType: model.FeeFlat, FlatAmount: nullDec("1"), Percentage: nullDec("1")},
+			wantErr: true,
+		},
+		{
+			name:    "negative flat amount",
+			policy:  model.FeePolicy{Currency: "USD", FeeType: model.FeeFlat, FlatAmount: nullDec("-1")},
+			wantErr: true,
+		},
+		{
+			name:    "flat amount below minor units",
+			policy:  model.FeePolicy{Currency: "USD", FeeType: model.FeeFlat, FlatAmount: nullDec("0.001")},
+			wantErr: true,
+		},
+		{
+			name:    "fractional yen",
+			policy:  model.FeePolicy{Currency: "JPY", FeeType: model.FeeFlat, FlatAmount: nullDec("1.5")},
+			wantErr: true,
+		},
+		{
+			name:   "percentage",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeePercentage, Percentage: nullDec("1.25")},
+		},
+		{
+			name:   "percentage of 100",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeePercentage, Percentage: nullDec("100")},
+		},
+		{
+			name:    "percentage above 100",
+			policy:  model.FeePolicy{Currency: "USD", FeeType: model.FeePercentage, Percentage: nullDec("100.01")},
+			wantErr: true,
+		},
+		{
+			name:    "negative percentage",
+			policy:  model.FeePolicy{Currency: "USD", FeeType: model.FeePercentage, Percentage: nullDec("-1")},
+			wantErr: true,
+		},
+		{
+			name:    "percentage with tiers",
+			policy:  model.FeePolicy{Currency: "USD", FeeType: model.FeePercentage, Percentage: nullDec("1"), Tiers: []model.FeeTier{{}}},
+			wantErr: true,
+		},
+		{
+			name: "tiered",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeeTiered, Tiers: []model.FeeTier{
+				{UpTo: decPtr("100"), FlatAmount: dec("1")},
+				{UpTo: decPtr("1000"), Percentage: dec("1")},
+				{Percentage: dec("0.5")},
+			}},
+		},
+		{
+			name:   "single open tier",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeeTiered, Tiers: []model.FeeTier{{FlatAmount: dec("1")}}},
+		},
+		{
+			name:    "no tiers",
+			policy:  model.FeePolicy{Currency: "USD", FeeType: model.FeeTiered},
+			wantErr: true,
+		},
+		{
+			name: "last tier with up_to",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeeTiered, Tiers: []model.FeeTier{
+				{UpTo: decPtr("100"), FlatAmount: dec("1")},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "middle tier without up_to",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeeTiered, Tiers: []model.FeeTier{
+				{FlatAmount: dec("1")},
+				{FlatAmount: dec("2")},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "tiers not increasing",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeeTiered, Tiers: []model.FeeTier{
+				{UpTo: decPtr("100"), FlatAmount: dec("1")},
+				{UpTo: decPtr("100"), FlatAmount: dec("2")},
+				{FlatAmount: dec("3")},
+			}},
+			wantErr: true,
+		},
+		{
+			name: "tier percentage above 100",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeeTiered, Tiers: []model.FeeTier{
+				{Percentage: dec("101")},
+			}},
+			wantErr: true,
+		},
+		{
+			name:   "minimum and maximum",
+			policy: model.FeePolicy{Currency: "USD", FeeType: model.FeePercentage, Percentage: nullDec("1"), MinFee: nullDec("1"), MaxFee: nullDec("1")},
+		},
+		{
+			name:    "minimum above maximum",
+			policy:  model.FeePolicy{Currency: "USD", FeeType: model.FeePercentage, Percentage: nullDec("1"), MinFee: nullDec("2"), MaxFee: nullDec("1")},
+			wantErr: true,
+		},
+		{
+			name:    "negative minimum",
+			policy:  model.FeePolicy{Currency: "USD", FeeType: model.FeePercentage, Percentage: nullDec("1"), MinFee: nullDec("-1")},
+			wantErr: true,
+		},
+		{
+			name:    "maximum below minor units",
+			policy:  model.FeePolicy{Currency: "USD", FeeType: model.FeePercentage, Percentage: nullDec("1"), MaxFee: nullDec("5.005")},
+			wantErr: true,
+		},
+		{
+			name:    "unknown fee type",
+			policy:  model.FeePolicy{Currency: "USD", FeeType: "bogus", FlatAmount: nullDec("1")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateFeePricing(&tt.policy)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateFeePricing() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFeeServiceCheckDigit(t *testing.T) {
+	s := NewFeeService(nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)), 1, true)
+	id := func(v int64) *int64 { return &v }
+
+	tests := []struct {
+		name         string
+		source, dest *int64
+	}{
+		{"source", id(badCheckDigitID), id(goodCheckDigitID)},
+		{"destination", id(goodCheckDigitID), id(badCheckDigitID)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Create(context.Background(), model.FeePolicy{
+				SourceAccountID:      tt.source,
+				DestinationAccountID: tt.dest,
+				Currency:             "USD",
+				FeeType:              model.FeeFlat,
+				FlatAmount:           nullDec("1"),
+			})
+			var validationErr *apperror.ErrValidation
+			if !errors.As(err, &validationErr) {
+				t.Errorf("Create error = %v, want a validation error", err)
+			}
+		})
+	}
+}
+
+func TestFeeReadsRequireAdmin(t *testing.T) {
+	s := NewFeeService(nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)), 1, false)
+	ctx := auth.WithPrincipal(context.Background(),
+		&auth.Principal{ID: "apikey:1", Name: "apikey:reporting", Role: auth.RoleViewer, Scopes: []auth.Scope{auth.ScopeAccountsRead}})
+
+	reads := []struct {
+		name string
+		call func() error
+	}{
+		{"get", func() error {
+			_, err := s.GetByID(ctx, 1)
+			return err
+		}},
+		{"list", func() error {
+			_, err := s.List(ctx)
+			return err
+		}},
+	}
+
+	for _, tt := range reads {
+		t.Run(tt.name, func(t *testing.T) {
+			var forbidden *apperror.ErrForbidden
+			if err := tt.call(); !errors.As(err, &forbidden) {
+				t.Errorf("error = %v, want ErrForbidden", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/auth"
+	"github.com/InternalTransfer/internal/currency"
+	"github.com/InternalTransfer/internal/model"
+)
+
+type FXService struct {
+	fxQuoteRepo FXQuoteRepo
+	rates       RateProvider
+	logger      *slog.Logger
+	quoteTTL    time.Duration
+}
+
+func NewFXService(fxQuoteRepo FXQuoteRepo, rates RateProvider, logger *slog.Logger, quoteTTL time.Duration) *FXService {
+	return &FXService{
+		fxQuoteRepo: fxQuoteRepo,
+		rates:       rates,
+		logger:      logger,
+		quoteTTL:    quoteTTL,
+	}
+}
+
+// CreateQuote fetches the current rate from the provider and locks it for
+// the configured quote TTL.
+func (s *FXService) CreateQuote(ctx context.Context, from, to string) (*model.FXQuote, error) {
+	from, to = currency.Normalize(from), currency.Normalize(to)
+	if from == "" || to == "" {
+		return nil, &apperror.ErrValidation{Message: "Please specify both the source and destination currencies"}
+	}
+	for _, code := range []string{from, to} {
+		if !currency.IsSupported(code) {
+			return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Currency %q is not supported", code)}
+		}
+	}
+	if from == to {
+		return nil, &apperror.ErrValidation{Message: "Source and destination currencies must be different"}
+	}
+
+	rate, err := s.rates.Rate(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("fetching rate: %w", err)
+	}
+	if !rate.IsPositive() {
+		return nil, fmt.Errorf("rate provider returned non-positive rate %s for %s/%s", rate, from, to)
+	}
+
+	q := &model.FXQuote{
+		SourceCurrency:      from,
+		DestinationCurrency: to,
+		Rate:                rate,
+		ExpiresAt:           time.Now().Add(s.quoteTTL),
+	}
+	if err := s.fxQuoteRepo.Create(ctx, q); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("fx quote created", "quote_id", q.ID, "from", from, "to", to, "rate", rate.String(), "actor", actor(ctx))
+	return q, nil
+}
+
+func (s *FXService) GetQuote(ctx context.Context, id int64) (*model.FXQuote, error) {
+	if err := authorize(ctx, auth.ActionReadAccounts); err != nil {
+		return nil, err
+	}
+	if id <= 0 {
+		return nil, &apperror.ErrValidation{Message: "Please provide a valid FX quote ID"}
+	}
+
+	q, err := s.fxQuoteRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching fx quote: %w", err)
+	}
+	return q, nil
+}
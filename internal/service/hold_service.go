@@ -0,0 +1,353 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/auth"
+	"github.com/InternalTransfer/internal/currency"
+	"github.com/InternalTransfer/internal/model"
+)
+
+const expiredHoldBatchSize = 100
+
+type HoldService struct {
+	holdRepo          HoldRepo
+	accountRepo       AccountRepo
+	transactionRepo   TransactionRepo
+	limitRepo         AccountLimitRepo
+	ledger            *ledger
+	txBeginner        TxBeginner
+	logger            *slog.Logger
+	maxTransferAmount decimal.Decimal
+	defaultTTL        time.Duration
+	maxTTL            time.Duration
+	// checkDigit requires account IDs to end in a Luhn check digit.
+	checkDigit bool
+	approvals  ApprovalPolicy
+}
+
+func NewHoldService(
+	holdRepo HoldRepo,
+	accountRepo AccountRepo,
+	transactionRepo TransactionRepo,
+	ledgerRepo LedgerRepo,
+	outboxRepo OutboxRepo,
+	limitRepo AccountLimitRepo,
+	txBeginner TxBeginner,
+	logger *slog.Logger,
+	maxTransferAmount int64,
+	defaultTTL, maxTTL time.Duration,
+	checkDigit bool,
+	approvals ApprovalPolicy,
+) *HoldService {
+	return &HoldService{
+		holdRepo:          holdRepo,
+		accountRepo:       accountRepo,
+		transactionRepo:   transactionRepo,
+		limitRepo:         limitRepo,
+		ledger:            newLedger(accountRepo, transactionRepo, ledgerRepo, outboxRepo),
+		txBeginner:        txBeginner,
+		logger:            logger,
+		maxTransferAmount: decimal.NewFromInt(maxTransferAmount),
+		defaultTTL:        defaultTTL,
+		maxTTL:            maxTTL,
+		checkDigit:        checkDigit,
+		approvals:         approvals,
+	}
+}
+
+// Create reserves amount on an account. A zero ttl uses the default expiry.
+func (s *HoldService) Create(ctx context.Context, accountID int64, amount decimal.Decimal, ttl time.Duration) (*model.Hold, error) {
+	if err := authorize(ctx, auth.ActionTransfer); err != nil {
+		return nil, err
+	}
+	if !validAccountID(accountID, s.checkDigit) {
+		return nil, &apperror.ErrValidation{Message: "Please provide a valid account number"}
+	}
+	if !amount.IsPositive() {
+		return nil, &apperror.ErrValidation{Message: "Hold amount must be greater than zero"}
+	}
+	if ttl != 0 && (ttl < time.Second || ttl > s.maxTTL) {
+		return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Hold expiry must be between 1 second and %s", s.maxTTL)}
+	}
+	if ttl == 0 {
+		ttl = s.defaultTTL
+	}
+
+	tx, err := s.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	account, err := s.accountRepo.GetByIDForUpdate(ctx, tx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkOwner(ctx, account); err != nil {
+		return nil, err
+	}
+	if err = requireActive(account); err != nil {
+		return nil, err
+	}
+	if err = validateAmount("Hold", amount, s.maxTransferAmount, account.Currency); err != nil {
+		return nil, err
+	}
+	// a captured hold pays out without approval
+	if err = s.approvals.checkUnapproved("Hold", amount, account.Currency); err != nil {
+		return nil, err
+	}
+	if account.AvailableBalance().LessThan(amount) {
+		return nil, &apperror.ErrInsufficientBalance{AccountID: accountID}
+	}
+
+	if err = s.accountRepo.UpdateHeldBalance(ctx, tx, accountID, account.HeldBalance.Add(amount)); err != nil {
+		return nil, err
+	}
+
+	hold := &model.Hold{AccountID: accountID, Amount: amount, ExpiresAt: time.Now().Add(ttl)}
+	if err = s.holdRepo.Create(ctx, tx, hold); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing hold: %w", err)
+	}
+
+	s.logger.Info("hold created", "hold_id", hold.ID, "account_id", accountID, "amount", amount.String(), "actor", actor(ctx))
+	return hold, nil
+}
+
+func (s *HoldService) GetByID(ctx context.Context, id int64) (*model.Hold, error) {
+	if err := authorize(ctx, auth.ActionReadAccounts); err != nil {
+		return nil, err
+	}
+	if id <= 0 {
+		return nil, &apperror.ErrValidation{Message: "Please provide a valid hold ID"}
+	}
+
+	hold, err := s.holdRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching hold: %w", err)
+	}
+	if err = requireOwner(ctx, s.accountRepo, hold.AccountID); err != nil {
+		return nil, err
+	}
+	return hold, nil
+}
+
+// Capture transfers held funds to destID. A nil amount captures the whole
+// hold; a smaller amount captures part of it and releases the rest.
+func (s *HoldService) Capture(ctx context.Context, id, destID int64, amount *decimal.Decimal) (*model.Hold, *model.Transaction, error) {
+	if err := authorize(ctx, auth.ActionTransfer); err != nil {
+		return nil, nil, err
+	}
+	if id <= 0 {
+		return nil, nil, &apperror.ErrValidation{Message: "Please provide a valid hold ID"}
+	}
+	if !validAccountID(destID, s.checkDigit) {
+		return nil, nil, &apperror.ErrValidation{Message: "Please provide a valid destination account number"}
+	}
+	if amount != nil && !amount.IsPositive() {
+		return nil, nil, &apperror.ErrValidation{Message: "Capture amount must be greater than zero"}
+	}
+
+	tx, err := s.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// lock the hold before the accounts, matching the sweeper's lock order
+	hold, err := s.lockActiveHold(ctx, tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if hold.AccountID == destID {
+		return nil, nil, &apperror.ErrValidation{Message: "Cannot capture a hold to the account it was placed on"}
+	}
+
+	captureAmount := hold.Amount
+	if amount != nil {
+		if amount.GreaterThan(hold.Amount) {
+			return nil, nil, &apperror.ErrValidation{Message: fmt.Sprintf("Capture amount cannot exceed the held amount of %s", hold.Amount)}
+		}
+		captureAmount = *amount
+	}
+
+	accounts, err := s.ledger.lockAccounts(ctx, tx, hold.AccountID, destID)
+	if err != nil {
+		return nil, nil, err
+	}
+	source, dest := accounts[hold.AccountID], accounts[destID]
+	if err = checkOwner(ctx, source); err != nil {
+		return nil, nil, err
+	}
+	if err = requireActive(source, dest); err != nil {
+		return nil, nil, err
+	}
+	if source.Currency != dest.Currency {
+		return nil, nil, &apperror.ErrCurrencyMismatch{SourceCurrency: source.Currency, DestinationCurrency: dest.Currency}
+	}
+	if !currency.HasValidPrecision(captureAmount, source.Currency) {
+		return nil, nil, &apperror.ErrValidation{Message: fmt.Sprintf("Capture amount can only have up to %d decimal places in %s", currency.MinorUnits(source.Currency), source.Currency)}
+	}
+	// a capture pays out like a transfer, so it counts against the same limits
+	limits, err := loadLimits(ctx, tx, s.limitRepo, s.transactionRepo, source.AccountID, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+	if limits != nil {
+		if err = limits.check(captureAmount); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// drop the whole reservation first; the captured part is then taken
+	// from the ledger balance and any remainder becomes available again
+	source.HeldBalance = source.HeldBalance.Sub(hold.Amount)
+	if err = s.accountRepo.UpdateHeldBalance(ctx, tx, source.AccountID, source.HeldBalance); err != nil {
+		return nil, nil, err
+	}
+
+	txn := &model.Transaction{Kind: model.TransactionKindCapture, Amount: captureAmount}
+	if err = s.ledger.post(ctx, tx, source, dest, txn); err != nil {
+		return nil, nil, err
+	}
+
+	hold.Status = model.HoldCaptured
+	hold.CapturedAmount = decimal.NewNullDecimal(captureAmount)
+	hold.TransactionID = &txn.ID
+	if err = s.holdRepo.UpdateStatus(ctx, tx, hold); err != nil {
+		return nil, nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, nil, fmt.Errorf("committing capture: %w", err)
+	}
+
+	s.logger.Info("hold captured", "hold_id", hold.ID, "transaction_id", txn.ID, "amount", captureAmount.String(), "actor", actor(ctx))
+	return hold, txn, nil
+}
+
+func (s *HoldService) Release(ctx context.Context, id int64) (*model.Hold, error) {
+	if err := authorize(ctx, auth.ActionTransfer); err != nil {
+		return nil, err
+	}
+	if id <= 0 {
+		return nil, &apperror.ErrValidation{Message: "Please provide a valid hold ID"}
+	}
+
+	tx, err := s.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	hold, err := s.lockActiveHold(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = requireOwner(ctx, s.accountRepo, hold.AccountID); err != nil {
+		return nil, err
+	}
+	if err = s.releaseHold(ctx, tx, hold, model.HoldReleased); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing release: %w", err)
+	}
+
+	s.logger.Info("hold released", "hold_id", hold.ID, "actor", actor(ctx))
+	return hold, nil
+}
+
+// ExpireStale releases holds whose expiry has passed and returns how many
+// were expired.
+func (s *HoldService) ExpireStale(ctx context.Context) (int, error) {
+	tx, err := s.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	holds, err := s.holdRepo.ClaimExpired(ctx, tx, expiredHoldBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	// release in account order so the sweeper locks accounts in the same
+	// order as transfers do
+	slices.SortFunc(holds, func(a, b model.Hold) int { return cmp.Compare(a.AccountID, b.AccountID) })
+	for i := range holds {
+		if err = s.releaseHold(ctx, tx, &holds[i], model.HoldExpired); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("committing expired holds: %w", err)
+	}
+	return len(holds), nil
+}
+
+// RunSweeper expires stale holds every interval until ctx is cancelled.
+func (s *HoldService) RunSweeper(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.ExpireStale(ctx)
+			if err != nil {
+				s.logger.Error("expiring holds", "error", err)
+				continue
+			}
+			if n > 0 {
+				s.logger.Info("holds expired", "count", n)
+			}
+		}
+	}
+}
+
+// lockActiveHold locks a hold and makes sure it can still be acted on. A hold
+// past its expiry that the sweeper has not reached yet is treated as expired.
+func (s *HoldService) lockActiveHold(ctx context.Context, tx pgx.Tx, id int64) (*model.Hold, error) {
+	hold, err := s.holdRepo.GetByIDForUpdate(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if hold.Status == model.HoldActive && !hold.ExpiresAt.After(time.Now()) {
+		return nil, &apperror.ErrHoldNotActive{HoldID: id, Status: string(model.HoldExpired)}
+	}
+	if hold.Status != model.HoldActive {
+		return nil, &apperror.ErrHoldNotActive{HoldID: id, Status: string(hold.Status)}
+	}
+	return hold, nil
+}
+
+// releaseHold returns the held funds to the account's available balance.
+func (s *HoldService) releaseHold(ctx context.Context, tx pgx.Tx, hold *model.Hold, status model.HoldStatus) error {
+	account, err := s.accountRepo.GetByIDForUpdate(ctx, tx, hold.AccountID)
+	if err != nil {
+		return err
+	}
+	if err = s.accountRepo.UpdateHeldBalance(ctx, tx, account.AccountID, account.HeldBalance.Sub(hold.Amount)); err != nil {
+		return err
+	}
+
+	hold.Status = status
+	return s.holdRepo.UpdateStatus(ctx, tx, hold)
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/model"
+)
+
+// Account IDs with a valid and an invalid Luhn check digit.
+const (
+	goodCheckDigitID = 79927398713
+	badCheckDigitID  = 79927398712
+)
+
+func TestHoldServiceCheckDigit(t *testing.T) {
+	s := NewHoldService(nil, nil, nil, nil, nil, nil, nil, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		1_000_000, time.Hour, 24*time.Hour, true, ApprovalPolicy{})
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"create", func() error {
+			_, err := s.Create(context.Background(), badCheckDigitID, dec("10"), 0)
+			return err
+		}},
+		{"capture destination", func() error {
+			_, _, err := s.Capture(context.Background(), 1, badCheckDigitID, nil)
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var validationErr *apperror.ErrValidation
+			if err := tt.call(); !errors.As(err, &validationErr) {
+				t.Errorf("error = %v, want a validation error", err)
+			}
+		})
+	}
+}
+
+// fakeHoldRepo keeps holds in memory, outside the fakeBank's transactions.
+type fakeHoldRepo struct {
+	holds []model.Hold
+}
+
+func (r *fakeHoldRepo) Create(_ context.Context, _ pgx.Tx, h *model.Hold) error {
+	h.ID, h.Status = int64(len(r.holds)+1), model.HoldActive
+	r.holds = append(r.holds, *h)
+	return nil
+}
+
+func (r *fakeHoldRepo) GetByID(_ context.Context, id int64) (*model.Hold, error) {
+	if id < 1 || id > int64(len(r.holds)) {
+		return nil, &apperror.ErrNotFound{Entity: "hold", ID: id}
+	}
+	h := r.holds[id-1]
+	return &h, nil
+}
+
+func (r *fakeHoldRepo) GetByIDForUpdate(ctx context.Context, _ pgx.Tx, id int64) (*model.Hold, error) {
+	return r.GetByID(ctx, id)
+}
+
+// ClaimExpired returns active holds past their expiry, like the repository.
+func (r *fakeHoldRepo) ClaimExpired(_ context.Context, _ pgx.Tx, limit int) ([]model.Hold, error) {
+	var out []model.Hold
+	for _, h := range r.holds {
+		if h.Status == model.HoldActive && !h.ExpiresAt.After(time.Now()) && len(out) < limit {
+			out = append(out, h)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeHoldRepo) UpdateStatus(_ context.Context, _ pgx.Tx, h *model.Hold) error {
+	r.holds[h.ID-1] = *h
+	return nil
+}
+
+func newTestHoldService(bank *fakeBank, holds *fakeHoldRepo) *HoldService {
+	return NewHoldService(holds, fakeAccountRepo{bank}, fakeTransactionRepo{bank}, fakeLedgerRepo{bank},
+		fakeOutboxRepo{bank}, fakeLimitRepo{bank}, bank, slog.New(slog.NewTextHandler(io.Discard, nil)),
+		1_000_000, time.Hour, 24*time.Hour, false, ApprovalPolicy{})
+}
+
+func TestHoldServiceCreateTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		ttl     time.Duration
+		wantErr bool
+		wantTTL time.Duration
+	}{
+		{"default", 0, false, time.Hour},
+		{"one second", time.Second, false, time.Second},
+		{"maximum", 24 * time.Hour, false, 24 * time.Hour},
+		{"sub-second", 500 * time.Millisecond, true, 0},
+		{"negative", -time.Second, true, 0},
+		{"above the maximum", 24*time.Hour + time.Second, true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bank := newFakeBank()
+			bank.addAccount(1, "USD", "100")
+			s := newTestHoldService(bank, &fakeHoldRepo{})
+
+			before := time.Now()
+			hold, err := s.Create(context.Background(), 1, dec("10"), tt.ttl)
+			if tt.wantErr {
+				var validationErr *apperror.ErrValidation
+				if !errors.As(err, &validationErr) {
+					t.Errorf("Create error = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+			if ttl := hold.ExpiresAt.Sub(before); ttl < tt.wantTTL || ttl > tt.wantTTL+time.Minute {
+				t.Errorf("hold expires in %s, want %s", ttl, tt.wantTTL)
+			}
+			if got := bank.accounts[1].HeldBalance.String(); got != "10" {
+				t.Errorf("held balance = %s, want 10", got)
+			}
+		})
+	}
+}
+
+func TestHoldCapture(t *testing.T) {
+	tests := []struct {
+		name       string
+		amount     *decimal.Decimal // nil captures the whole hold
+		limits     *model.AccountLimits
+		wantCode   string // of the error, if any
+		wantSource string // balance of the held account afterwards
+		wantHeld   string
+		wantStatus model.HoldStatus
+	}{
+		{"whole hold", nil, nil, "", "60", "0", model.HoldCaptured},
+		{"part releases the rest", decPtr("15"), nil, "", "85", "0", model.HoldCaptured},
+		{"more than held", decPtr("41"), nil, apperror.CodeValidation, "100", "40", model.HoldActive},
+		{"over the single transfer limit", nil, &model.AccountLimits{AccountID: 1, MaxSingleAmount: nullDec("30")},
+			apperror.CodeLimitExceeded, "100", "40", model.HoldActive},
+		{"within the limit", decPtr("30"), &model.AccountLimits{AccountID: 1, MaxSingleAmount: nullDec("30")},
+			"", "70", "0", model.HoldCaptured},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bank := newFakeBank()
+			bank.addAccount(1, "USD", "100")
+			bank.addAccount(2, "USD", "0")
+			if tt.limits != nil {
+				bank.limits[1] = tt.limits
+			}
+			holds := &fakeHoldRepo{}
+			s := newTestHoldService(bank, holds)
+			hold, err := s.Create(context.Background(), 1, dec("40"), 0)
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			captured, txn, err := s.Capture(context.Background(), hold.ID, 2, tt.amount)
+			if tt.wantCode != "" {
+				var appErr apperror.AppError
+				if !errors.As(err, &appErr) || appErr.Code() != tt.wantCode {
+					t.Fatalf("Capture error = %v, want %s", err, tt.wantCode)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("Capture: %v", err)
+				}
+				if txn.Kind != model.TransactionKindCapture || *captured.TransactionID != txn.ID || !captured.CapturedAmount.Decimal.Equal(txn.Amount) {
+					t.Errorf("capture = %+v with %+v, want them linked", captured, txn)
+				}
+			}
+			if got := bank.balance(1); got != tt.wantSource {
+				t.Errorf("balance = %s, want %s", got, tt.wantSource)
+			}
+			if got := bank.accounts[1].HeldBalance.String(); got != tt.wantHeld {
+				t.Errorf("held balance = %s, want %s", got, tt.wantHeld)
+			}
+			if got := holds.holds[0].Status; got != tt.wantStatus {
+				t.Errorf("hold status = %s, want %s", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestHoldSettledOnce(t *testing.T) {
+	capture := func(s *HoldService, id int64) error {
+		_, _, err := s.Capture(context.Background(), id, 2, nil)
+		return err
+	}
+	release := func(s *HoldService, id int64) error {
+		_, err := s.Release(context.Background(), id)
+		return err
+	}
+	tests := []struct {
+		name          string
+		first, second func(*HoldService, int64) error
+		wantBalance   string
+	}{
+		{"capture twice", capture, capture, "60"},
+		{"release twice", release, release, "100"},
+		{"capture after release", release, capture, "100"},
+		{"release after capture", capture, release, "60"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bank := newFakeBank()
+			bank.addAccount(1, "USD", "100")
+			bank.addAccount(2, "USD", "0")
+			s := newTestHoldService(bank, &fakeHoldRepo{})
+			hold, err := s.Create(context.Background(), 1, dec("40"), 0)
+			if err != nil {
+				t.Fatalf("Create: %v", err)
+			}
+
+			if err = tt.first(s, hold.ID); err != nil {
+				t.Fatalf("first: %v", err)
+			}
+			var notActive *apperror.ErrHoldNotActive
+			if err = tt.second(s, hold.ID); !errors.As(err, &notActive) {
+				t.Errorf("second error = %v, want ErrHoldNotActive", err)
+			}
+			if got := bank.balance(1); got != tt.wantBalance {
+				t.Errorf("balance = %s, want %s", got, tt.wantBalance)
+			}
+			if got := bank.accounts[1].HeldBalance.String(); got != "0" {
+				t.Errorf("held balance = %s, want 0", got)
+			}
+		})
+	}
+}
+
+func TestHoldExpireStale(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "100")
+	bank.addAccount(2, "USD", "100")
+	holds := &fakeHoldRepo{}
+	s := newTestHoldService(bank, holds)
+	for _, h := range []struct {
+		account int64
+		amount  string
+	}{{2, "5"}, {1, "10"}, {1, "20"}, {2, "30"}} {
+		if _, err := s.Create(context.Background(), h.account, dec(h.amount), 0); err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+	}
+	if _, err := s.Release(context.Background(), 3); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	// the first three are past their expiry, but the third is released
+	for i := range 3 {
+		holds.holds[i].ExpiresAt = time.Now().Add(-time.Minute)
+	}
+
+	n, err := s.ExpireStale(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("ExpireStale = %d, %v; want 2", n, err)
+	}
+	wantStatus := []model.HoldStatus{model.HoldExpired, model.HoldExpired, model.HoldReleased, model.HoldActive}
+	for i, h := range holds.holds {
+		if h.Status != wantStatus[i] {
+			t.Errorf("hold %d status = %s, want %s", h.ID, h.Status, wantStatus[i])
+		}
+	}
+	if held1, held2 := bank.accounts[1].HeldBalance.String(), bank.accounts[2].HeldBalance.String(); held1 != "0" || held2 != "30" {
+		t.Errorf("held balances = %s, %s; want 0, 30", held1, held2)
+	}
+
+	if n, err = s.ExpireStale(context.Background()); err != nil || n != 0 {
+		t.Errorf("second sweep = %d, %v; want nothing left to expire", n, err)
+	}
+}
@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/InternalTransfer/internal/model"
 	"github.com/jackc/pgx/v5"
@@ -9,14 +10,152 @@ import (
 )
 
 type AccountRepo interface {
-	Create(ctx context.Context, accountID int64, initialBalance decimal.Decimal) error
+	NextID(ctx context.Context, tx pgx.Tx) (int64, error)
+	Create(ctx context.Context, tx pgx.Tx, a *model.Account) error
 	GetByID(ctx context.Context, accountID int64) (*model.Account, error)
 	GetByIDForUpdate(ctx context.Context, tx pgx.Tx, accountID int64) (*model.Account, error)
+	List(ctx context.Context, f model.AccountFilter) ([]model.Account, error)
+	GetSystemAccountForUpdate(ctx context.Context, tx pgx.Tx, role, currency string) (*model.Account, error)
 	UpdateBalance(ctx context.Context, tx pgx.Tx, accountID int64, newBalance decimal.Decimal) error
+	UpdateHeldBalance(ctx context.Context, tx pgx.Tx, accountID int64, newHeld decimal.Decimal) error
+	UpdateDetails(ctx context.Context, tx pgx.Tx, a *model.Account) error
+	UpdateStatus(ctx context.Context, tx pgx.Tx, accountID int64, status model.AccountStatus) error
+	CreateStatusChange(ctx context.Context, tx pgx.Tx, c *model.AccountStatusChange) error
+	ListStatusChanges(ctx context.Context, accountID int64) ([]model.AccountStatusChange, error)
 }
 
 type TransactionRepo interface {
-	Create(ctx context.Context, tx pgx.Tx, sourceID, destID int64, amount decimal.Decimal) error
+	Create(ctx context.Context, tx pgx.Tx, t *model.Transaction) error
+	GetByID(ctx context.Context, id int64) (*model.Transaction, error)
+	GetFee(ctx context.Context, id int64) (*model.Transaction, error)
+	GetByIDForUpdate(ctx context.Context, tx pgx.Tx, id int64) (*model.Transaction, error)
+	SumReversals(ctx context.Context, tx pgx.Tx, id int64) (debited, credited decimal.Decimal, err error)
+	ListByAccount(ctx context.Context, f model.TransactionFilter) ([]model.Transaction, error)
+	OutgoingUsage(ctx context.Context, tx pgx.Tx, accountID int64, dayStart, monthStart, hourStart time.Time) (model.LimitUsage, error)
+	StreamStatement(ctx context.Context, accountID int64, from, to time.Time, fn func(*model.StatementLine) error) error
+}
+
+type AccountLimitRepo interface {
+	Get(ctx context.Context, accountID int64) (*model.AccountLimits, error)
+	GetForTransfer(ctx context.Context, tx pgx.Tx, accountID int64) (*model.AccountLimits, error)
+	Upsert(ctx context.Context, l *model.AccountLimits) error
+}
+
+type LedgerRepo interface {
+	CreateEntries(ctx context.Context, tx pgx.Tx, entries []model.LedgerEntry) error
+	BalanceAt(ctx context.Context, accountID int64, asOf time.Time) (decimal.Decimal, error)
+	CreateSnapshots(ctx context.Context, takenAt time.Time) (int64, error)
+	ListEventsAfter(ctx context.Context, accountID, afterID int64, limit int) ([]model.AccountEvent, error)
+}
+
+type HoldRepo interface {
+	Create(ctx context.Context, tx pgx.Tx, h *model.Hold) error
+	GetByID(ctx context.Context, id int64) (*model.Hold, error)
+	GetByIDForUpdate(ctx context.Context, tx pgx.Tx, id int64) (*model.Hold, error)
+	ClaimExpired(ctx context.Context, tx pgx.Tx, limit int) ([]model.Hold, error)
+	UpdateStatus(ctx context.Context, tx pgx.Tx, h *model.Hold) error
+}
+
+type FeePolicyRepo interface {
+	Create(ctx context.Context, p *model.FeePolicy) error
+	GetByID(ctx context.Context, id int64) (*model.FeePolicy, error)
+	List(ctx context.Context) ([]model.FeePolicy, error)
+	Update(ctx context.Context, p *model.FeePolicy) error
+	Delete(ctx context.Context, id int64) error
+	FindForTransfer(ctx context.Context, tx pgx.Tx, sourceID, destID int64, currency string) (*model.FeePolicy, error)
+}
+
+type ReconcileRepo interface {
+	StreamAccountTotals(ctx context.Context, fn func(*model.AccountTotals) error) error
+	StreamTransactionEntries(ctx context.Context, fn func(*model.TransactionEntries) error) error
+	CurrencyTotals(ctx context.Context) ([]model.CurrencyTotal, error)
+}
+
+type OutboxRepo interface {
+	Create(ctx context.Context, tx pgx.Tx, e *model.OutboxEvent) error
+	ClaimDue(ctx context.Context, tx pgx.Tx, limit int, lease time.Duration) ([]model.OutboxEvent, error)
+	MarkDelivered(ctx context.Context, tx pgx.Tx, e *model.OutboxEvent) (bool, error)
+	MarkFailed(ctx context.Context, tx pgx.Tx, e *model.OutboxEvent, lastError string, nextAttemptAt time.Time, dead bool) (bool, error)
+}
+
+type WebhookRepo interface {
+	Create(ctx context.Context, s *model.WebhookSubscription) error
+	GetByID(ctx context.Context, id int64) (*model.WebhookSubscription, error)
+	List(ctx context.Context) ([]model.WebhookSubscription, error)
+	Delete(ctx context.Context, id int64) error
+	Enable(ctx context.Context, id int64) (*model.WebhookSubscription, error)
+	EnqueueDeliveries(ctx context.Context, e *model.OutboxEvent) (int64, error)
+	ClaimDueDeliveries(ctx context.Context, tx pgx.Tx, limit int, lease time.Duration) ([]model.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, tx pgx.Tx, d *model.WebhookDelivery, leasedUntil time.Time) (bool, error)
+	RecordResult(ctx context.Context, tx pgx.Tx, id int64, success bool, disableAfter int) (model.WebhookStatus, error)
+	ListDeliveries(ctx context.Context, subscriptionID, beforeID int64, limit int) ([]model.WebhookDelivery, error)
+}
+
+type APIKeyRepo interface {
+	Create(ctx context.Context, k *model.APIKey) error
+	GetActiveByHash(ctx context.Context, hash string) (*model.APIKey, error)
+	List(ctx context.Context) ([]model.APIKey, error)
+	Revoke(ctx context.Context, id int64) (*model.APIKey, error)
+}
+
+type IdempotencyRepo interface {
+	Claim(ctx context.Context, tx pgx.Tx, principal, key, requestHash string) (*model.IdempotencyKey, error)
+	Complete(ctx context.Context, tx pgx.Tx, principal, key string, transactionID int64) error
+	CompleteApproval(ctx context.Context, tx pgx.Tx, principal, key string, approvalID int64) error
+}
+
+type ApprovalRepo interface {
+	Create(ctx context.Context, tx pgx.Tx, a *model.PendingApproval) error
+	GetByID(ctx context.Context, id int64) (*model.PendingApproval, error)
+	GetByIDForUpdate(ctx context.Context, tx pgx.Tx, id int64) (*model.PendingApproval, error)
+	List(ctx context.Context, f model.ApprovalFilter) ([]model.PendingApproval, error)
+	ClaimExpired(ctx context.Context, tx pgx.Tx, limit int) ([]model.PendingApproval, error)
+	Decide(ctx context.Context, tx pgx.Tx, a *model.PendingApproval) error
+}
+
+type FXQuoteRepo interface {
+	Create(ctx context.Context, q *model.FXQuote) error
+	GetByID(ctx context.Context, id int64) (*model.FXQuote, error)
+	GetByIDForUpdate(ctx context.Context, tx pgx.Tx, id int64) (*model.FXQuote, error)
+	MarkUsed(ctx context.Context, tx pgx.Tx, id, transactionID int64) error
+}
+
+type ScheduleRepo interface {
+	Create(ctx context.Context, s *model.ScheduledTransfer) error
+	GetByID(ctx context.Context, id int64) (*model.ScheduledTransfer, error)
+	GetByIDForUpdate(ctx context.Context, tx pgx.Tx, id int64) (*model.ScheduledTransfer, error)
+	List(ctx context.Context, f model.ScheduleFilter) ([]model.ScheduledTransfer, error)
+	ClaimDue(ctx context.Context, tx pgx.Tx, limit int) ([]model.ScheduledTransfer, error)
+	Update(ctx context.Context, tx pgx.Tx, s *model.ScheduledTransfer) error
+	CreateRun(ctx context.Context, tx pgx.Tx, run *model.ScheduledTransferRun) error
+	ListRuns(ctx context.Context, scheduleID, beforeID int64, limit int) ([]model.ScheduledTransferRun, error)
+}
+
+// StatementWriter receives a statement while it is read from the database:
+// the opening balance, each line in order, then the closing balance.
+type StatementWriter interface {
+	Opening(s *model.Statement) error
+	Line(l *model.StatementLine) error
+	Closing(s *model.Statement) error
+}
+
+// EventSink receives published outbox events. Publish must return an error
+// unless the event was accepted; it may be called again for the same event.
+type EventSink interface {
+	Name() string
+	Publish(ctx context.Context, e *model.OutboxEvent) error
+}
+
+// RateProvider returns how many units of to one unit of from buys.
+type RateProvider interface {
+	Rate(ctx context.Context, from, to string) (decimal.Decimal, error)
+}
+
+// Listener delivers Postgres notifications on channel to fn, calling ready
+// once listening has started. It returns when ctx is cancelled or the
+// connection fails.
+type Listener interface {
+	Listen(ctx context.Context, channel string, ready func(), fn func(payload string)) error
 }
 
 type TxBeginner interface {
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/model"
+)
+
+// ledger records movements of funds as a transaction plus a balanced pair of
+// ledger entries. It is shared by every service that moves money.
+type ledger struct {
+	accountRepo     AccountRepo
+	transactionRepo TransactionRepo
+	ledgerRepo      LedgerRepo
+	outboxRepo      OutboxRepo
+}
+
+func newLedger(accountRepo AccountRepo, transactionRepo TransactionRepo, ledgerRepo LedgerRepo, outboxRepo OutboxRepo) *ledger {
+	return &ledger{
+		accountRepo:     accountRepo,
+		transactionRepo: transactionRepo,
+		ledgerRepo:      ledgerRepo,
+		outboxRepo:      outboxRepo,
+	}
+}
+
+// lockAccounts locks every account in ascending ID order so that concurrent
+// transfers over overlapping sets of accounts cannot deadlock.
+func (l *ledger) lockAccounts(ctx context.Context, tx pgx.Tx, ids ...int64) (map[int64]*model.Account, error) {
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	sorted = slices.Compact(sorted)
+
+	locked := make(map[int64]*model.Account, len(sorted))
+	for _, id := range sorted {
+		account, err := l.accountRepo.GetByIDForUpdate(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		locked[id] = account
+	}
+	return locked, nil
+}
+
+// requireActive rejects movements touching a frozen or closed account.
+func requireActive(accounts ...*model.Account) error {
+	for _, a := range accounts {
+		if a.Status != model.AccountActive {
+			return &apperror.ErrAccountInactive{AccountID: a.AccountID, Status: string(a.Status)}
+		}
+	}
+	return nil
+}
+
+// post moves txn.Amount from source to dest. Both accounts must already be
+// locked in tx and sufficient funds must have been checked by the caller.
+// Cross-currency movements must set txn.DestinationAmount, which is what the
+// destination is credited. The in-memory balances of source and dest are
+// updated to match, and a transaction.posted event is written to the outbox.
+func (l *ledger) post(ctx context.Context, tx pgx.Tx, source, dest *model.Account, txn *model.Transaction) error {
+	if txn.DestinationAmount.IsZero() {
+		if source.Currency != dest.Currency {
+			return fmt.Errorf("posting %s to %s without a destination amount", source.Currency, dest.Currency)
+		}
+		txn.DestinationAmount = txn.Amount
+	}
+
+	newSourceBal := source.Balance.Sub(txn.Amount)
+	newDestBal := dest.Balance.Add(txn.DestinationAmount)
+
+	if err := l.accountRepo.UpdateBalance(ctx, tx, source.AccountID, newSourceBal); err != nil {
+		return err
+	}
+	if err := l.accountRepo.UpdateBalance(ctx, tx, dest.AccountID, newDestBal); err != nil {
+		return err
+	}
+
+	txn.SourceAccountID = source.AccountID
+	txn.DestinationAccountID = dest.AccountID
+	txn.Currency = source.Currency
+	txn.DestinationCurrency = dest.Currency
+	txn.SourceBalanceAfter = decimal.NewNullDecimal(newSourceBal)
+	if txn.Kind == "" {
+		txn.Kind = model.TransactionKindTransfer
+	}
+	if err := l.transactionRepo.Create(ctx, tx, txn); err != nil {
+		return err
+	}
+
+	entries := []model.LedgerEntry{
+		{AccountID: source.AccountID, TransactionID: txn.ID, Direction: model.EntryDebit, Amount: txn.Amount, BalanceAfter: newSourceBal},
+		{AccountID: dest.AccountID, TransactionID: txn.ID, Direction: model.EntryCredit, Amount: txn.DestinationAmount, BalanceAfter: newDestBal},
+	}
+	if err := l.ledgerRepo.CreateEntries(ctx, tx, entries); err != nil {
+		return err
+	}
+	event := transactionEvent{
+		ID:                   txn.ID,
+		Kind:                 txn.Kind,
+		SourceAccountID:      txn.SourceAccountID,
+		DestinationAccountID: txn.DestinationAccountID,
+		Amount:               txn.Amount,
+		Currency:             txn.Currency,
+		DestinationAmount:    txn.DestinationAmount,
+		DestinationCurrency:  txn.DestinationCurrency,
+		FXRate:               txn.FXRate,
+		FeeOf:                txn.FeeOf,
+		ReversalOf:           txn.ReversalOf,
+		CreatedAt:            txn.CreatedAt,
+	}
+	accountIDs := []int64{source.AccountID, dest.AccountID}
+	if err := recordEvent(ctx, tx, l.outboxRepo, EventTransactionPosted, accountIDs, event); err != nil {
+		return err
+	}
+
+	source.Balance = newSourceBal
+	dest.Balance = newDestBal
+	return nil
+}
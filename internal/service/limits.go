@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/model"
+)
+
+// Names of the per-account limits, as reported in LIMIT_EXCEEDED errors.
+const (
+	limitSingleTransfer = "max_single_amount"
+	limitDaily          = "daily_amount"
+	limitMonthly        = "monthly_amount"
+	limitHourlyCount    = "hourly_count"
+)
+
+// outgoingLimits tracks an account's limits against what it has sent in the
+// current windows. Within a batch the same tracker sees every leg from the
+// account, so legs count against each other.
+type outgoingLimits struct {
+	accountID int64
+	limits    *model.AccountLimits
+	usage     model.LimitUsage
+
+	// start of the current UTC day, month and hour, and of the next ones
+	day, month, hour             time.Time
+	nextDay, nextMonth, nextHour time.Time
+}
+
+// loadLimits reads the limits and current usage of accountID. It returns nil
+// when the account has no limits. The account must be locked in tx so that
+// concurrent transfers cannot both pass the check.
+func loadLimits(ctx context.Context, tx pgx.Tx, limitRepo AccountLimitRepo, transactionRepo TransactionRepo, accountID int64, now time.Time) (*outgoingLimits, error) {
+	limits, err := limitRepo.GetForTransfer(ctx, tx, accountID)
+	if err != nil || limits == nil {
+		return nil, err
+	}
+
+	l := newOutgoingLimits(accountID, limits, now)
+	l.usage, err = transactionRepo.OutgoingUsage(ctx, tx, accountID, l.day, l.month, l.hour)
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// newOutgoingLimits tracks limits over the windows containing now, with no
+// usage recorded yet.
+func newOutgoingLimits(accountID int64, limits *model.AccountLimits, now time.Time) *outgoingLimits {
+	now = now.UTC()
+	l := &outgoingLimits{
+		accountID: accountID,
+		limits:    limits,
+		day:       time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		month:     time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC),
+		hour:      now.Truncate(time.Hour),
+	}
+	l.nextDay = l.day.AddDate(0, 0, 1)
+	l.nextMonth = l.month.AddDate(0, 1, 0)
+	l.nextHour = l.hour.Add(time.Hour)
+	return l
+}
+
+// check reports the first limit that sending amount would break.
+func (l *outgoingLimits) check(amount decimal.Decimal) error {
+	exceeded := func(limit string, resetsAt *time.Time) error {
+		return &apperror.ErrLimitExceeded{AccountID: l.accountID, Limit: limit, ResetsAt: resetsAt}
+	}
+
+	if maxAmount := l.limits.MaxSingleAmount; maxAmount.Valid && amount.GreaterThan(maxAmount.Decimal) {
+		return exceeded(limitSingleTransfer, nil)
+	}
+	if c := l.limits.HourlyCount; c != nil && l.usage.HourlyCount+1 > *c {
+		return exceeded(limitHourlyCount, &l.nextHour)
+	}
+	if maxAmount := l.limits.DailyAmount; maxAmount.Valid && l.usage.DailyAmount.Add(amount).GreaterThan(maxAmount.Decimal) {
+		return exceeded(limitDaily, &l.nextDay)
+	}
+	if maxAmount := l.limits.MonthlyAmount; maxAmount.Valid && l.usage.MonthlyAmount.Add(amount).GreaterThan(maxAmount.Decimal) {
+		return exceeded(limitMonthly, &l.nextMonth)
+	}
+	return nil
+}
+
+// record counts a transfer of amount that has passed check.
+func (l *outgoingLimits) record(amount decimal.Decimal) {
+	l.usage.DailyAmount = l.usage.DailyAmount.Add(amount)
+	l.usage.MonthlyAmount = l.usage.MonthlyAmount.Add(amount)
+	l.usage.HourlyCount++
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/model"
+)
+
+func TestOutgoingLimitsCheck(t *testing.T) {
+	now := time.Date(2025, 1, 15, 10, 20, 30, 0, time.UTC)
+	nextHour := time.Date(2025, 1, 15, 11, 0, 0, 0, time.UTC)
+	nextDay := time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC)
+	nextMonth := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+	count := func(n int) *int { return &n }
+
+	limits := &model.AccountLimits{
+		AccountID:       7,
+		MaxSingleAmount: nullDec("500"),
+		DailyAmount:     nullDec("1000"),
+		MonthlyAmount:   nullDec("5000"),
+		HourlyCount:     count(5),
+	}
+
+	tests := []struct {
+		name      string
+		limits    *model.AccountLimits
+		usage     model.LimitUsage
+		amount    string
+		wantLimit string // empty when the amount is allowed
+		wantReset *time.Time
+	}{
+		{
+			name:   "within every limit",
+			limits: limits,
+			usage:  model.LimitUsage{DailyAmount: dec("100"), MonthlyAmount: dec("100"), HourlyCount: 1},
+			amount: "100",
+		},
+		{
+			name:   "single amount at the limit",
+			limits: limits,
+			amount: "500",
+		},
+		{
+			name:      "single amount",
+			limits:    limits,
+			amount:    "500.01",
+			wantLimit: limitSingleTransfer,
+		},
+		{
+			name:      "hourly count",
+			limits:    limits,
+			usage:     model.LimitUsage{HourlyCount: 5},
+			amount:    "1",
+			wantLimit: limitHourlyCount,
+			wantReset: &nextHour,
+		},
+		{
+			name:   "daily amount reaching the limit",
+			limits: limits,
+			usage:  model.LimitUsage{DailyAmount: dec("900"), MonthlyAmount: dec("900")},
+			amount: "100",
+		},
+		{
+			name:      "daily amount",
+			limits:    limits,
+			usage:     model.LimitUsage{DailyAmount: dec("900"), MonthlyAmount: dec("900")},
+			amount:    "100.01",
+			wantLimit: limitDaily,
+			wantReset: &nextDay,
+		},
+		{
+			name:      "monthly amount",
+			limits:    limits,
+			usage:     model.LimitUsage{DailyAmount: dec("0"), MonthlyAmount: dec("4950")},
+			amount:    "50.01",
+			wantLimit: limitMonthly,
+			wantReset: &nextMonth,
+		},
+		{
+			name:      "single amount is reported first",
+			limits:    limits,
+			usage:     model.LimitUsage{DailyAmount: dec("1000"), MonthlyAmount: dec("5000"), HourlyCount: 5},
+			amount:    "600",
+			wantLimit: limitSingleTransfer,
+		},
+		{
+			name:      "hourly count is reported before amounts",
+			limits:    limits,
+			usage:     model.LimitUsage{DailyAmount: dec("1000"), MonthlyAmount: dec("5000"), HourlyCount: 5},
+			amount:    "1",
+			wantLimit: limitHourlyCount,
+			wantReset: &nextHour,
+		},
+		{
+			name:   "unset limits are not enforced",
+			limits: &model.AccountLimits{AccountID: 7, DailyAmount: nullDec("1000")},
+			usage:  model.LimitUsage{MonthlyAmount: dec("1000000"), HourlyCount: 1000},
+			amount: "1000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newOutgoingLimits(7, tt.limits, now)
+			l.usage = tt.usage
+
+			err := l.check(dec(tt.amount))
+			if tt.wantLimit == "" {
+				if err != nil {
+					t.Fatalf("check(%s) = %v, want nil", tt.amount, err)
+				}
+				return
+			}
+
+			var exceeded *apperror.ErrLimitExceeded
+			if !errors.As(err, &exceeded) {
+				t.Fatalf("check(%s) = %v, want ErrLimitExceeded", tt.amount, err)
+			}
+			if exceeded.AccountID != 7 || exceeded.Limit != tt.wantLimit {
+				t.Errorf("exceeded %s on account %d, want %s on account 7", exceeded.Limit, exceeded.AccountID, tt.wantLimit)
+			}
+			switch {
+			case tt.wantReset == nil && exceeded.ResetsAt != nil:
+				t.Errorf("resets at %s, want no reset", exceeded.ResetsAt)
+			case tt.wantReset != nil && (exceeded.ResetsAt == nil || !exceeded.ResetsAt.Equal(*tt.wantReset)):
+				t.Errorf("resets at %v, want %s", exceeded.ResetsAt, tt.wantReset)
+			}
+		})
+	}
+}
+
+func TestOutgoingLimitsWindows(t *testing.T) {
+	tests := []struct {
+		name                         string
+		now                          time.Time
+		nextHour, nextDay, nextMonth time.Time
+	}{
+		{
+			name:      "end of year",
+			now:       time.Date(2025, 12, 31, 23, 59, 59, 0, time.UTC),
+			nextHour:  time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			nextDay:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+			nextMonth: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "windows are UTC",
+			now:       time.Date(2025, 3, 1, 1, 30, 0, 0, time.FixedZone("EET", 2*3600)),
+			nextHour:  time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			nextDay:   time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			nextMonth: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:      "start of a window",
+			now:       time.Date(2025, 2, 28, 0, 0, 0, 0, time.UTC),
+			nextHour:  time.Date(2025, 2, 28, 1, 0, 0, 0, time.UTC),
+			nextDay:   time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+			nextMonth: time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newOutgoingLimits(1, &model.AccountLimits{}, tt.now)
+			if !l.nextHour.Equal(tt.nextHour) || !l.nextDay.Equal(tt.nextDay) || !l.nextMonth.Equal(tt.nextMonth) {
+				t.Errorf("resets at hour %s, day %s, month %s; want %s, %s, %s",
+					l.nextHour, l.nextDay, l.nextMonth, tt.nextHour, tt.nextDay, tt.nextMonth)
+			}
+		})
+	}
+}
+
+func TestOutgoingLimitsRecord(t *testing.T) {
+	n := 2
+	l := newOutgoingLimits(7, &model.AccountLimits{AccountID: 7, DailyAmount: nullDec("300"), HourlyCount: &n}, time.Now())
+
+	for i, amount := range []string{"100", "200"} {
+		if err := l.check(dec(amount)); err != nil {
+			t.Fatalf("leg %d: check(%s) = %v", i, amount, err)
+		}
+		l.record(dec(amount))
+	}
+
+	var exceeded *apperror.ErrLimitExceeded
+	if err := l.check(dec("0.01")); !errors.As(err, &exceeded) || exceeded.Limit != limitHourlyCount {
+		t.Errorf("third leg: check = %v, want %s exceeded", err, limitHourlyCount)
+	}
+	if !l.usage.DailyAmount.Equal(dec("300")) || !l.usage.MonthlyAmount.Equal(dec("300")) {
+		t.Errorf("recorded daily %s and monthly %s, want 300", l.usage.DailyAmount, l.usage.MonthlyAmount)
+	}
+}
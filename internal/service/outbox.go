@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/model"
+)
+
+// Event types written to the outbox.
+const (
+	// EventTransactionPosted is written for every movement of money:
+	// transfers, fees, reversals, captures, opening balances and sweeps.
+	EventTransactionPosted    = "transaction.posted"
+	EventAccountCreated       = "account.created"
+	EventAccountStatusChanged = "account.status_changed"
+)
+
+var eventTypes = []string{EventTransactionPosted, EventAccountCreated, EventAccountStatusChanged}
+
+// transactionEvent is the data of EventTransactionPosted. It leaves out the
+// source balance, which a subscriber watching only the destination account
+// must not see.
+type transactionEvent struct {
+	ID                   int64                 `json:"id"`
+	Kind                 model.TransactionKind `json:"kind"`
+	SourceAccountID      int64                 `json:"source_account_id"`
+	DestinationAccountID int64                 `json:"destination_account_id"`
+	Amount               decimal.Decimal       `json:"amount"`
+	Currency             string                `json:"currency"`
+	DestinationAmount    decimal.Decimal       `json:"destination_amount"`
+	DestinationCurrency  string                `json:"destination_currency"`
+	FXRate               decimal.NullDecimal   `json:"fx_rate"`
+	FeeOf                *int64                `json:"fee_of,omitempty"`
+	ReversalOf           *int64                `json:"reversal_of,omitempty"`
+	CreatedAt            time.Time             `json:"created_at"`
+}
+
+// recordEvent writes an event to the outbox in tx, so that it is published
+// if and only if tx commits.
+func recordEvent(ctx context.Context, tx pgx.Tx, outboxRepo OutboxRepo, eventType string, accountIDs []int64, data any) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encoding %s event: %w", eventType, err)
+	}
+	if accountIDs == nil {
+		accountIDs = []int64{}
+	}
+	return outboxRepo.Create(ctx, tx, &model.OutboxEvent{Type: eventType, AccountIDs: accountIDs, Data: b})
+}
+
+// backoff returns the delay before retry number attempt (from 1), doubling
+// from base up to limit.
+func backoff(attempt int, base, limit time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempt && d < limit; i++ {
+		d *= 2
+	}
+	return min(d, limit)
+}
+
+const (
+	relayBatchSize   = 100
+	relayBackoffBase = time.Second
+	relayBackoffMax  = 5 * time.Minute
+	// relayLease hides claimed events from other relays while they are
+	// published. An event whose outcome is not recorded by then, e.g.
+	// because the relay stopped, is published again.
+	relayLease = 5 * time.Minute
+)
+
+// OutboxRelay publishes outbox events to every configured sink. An event is
+// marked delivered once all sinks accept it; otherwise it is retried with
+// exponential backoff, so a sink may see an event more than once. After
+// maxAttempts failures the event is dead-lettered.
+type OutboxRelay struct {
+	outboxRepo  OutboxRepo
+	sinks       []EventSink
+	txBeginner  TxBeginner
+	logger      *slog.Logger
+	maxAttempts int
+}
+
+func NewOutboxRelay(outboxRepo OutboxRepo, sinks []EventSink, txBeginner TxBeginner, logger *slog.Logger, maxAttempts int) *OutboxRelay {
+	return &OutboxRelay{
+		outboxRepo:  outboxRepo,
+		sinks:       sinks,
+		txBeginner:  txBeginner,
+		logger:      logger,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// RelayDue publishes the events that are due and returns how many were
+// attempted. Events are leased in one short transaction and published
+// outside of any, and each outcome is recorded in its own transaction, so
+// several relays can run side by side without holding locks across sends.
+func (r *OutboxRelay) RelayDue(ctx context.Context) (int, error) {
+	events, err := r.claim(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range events {
+		e := &events[i]
+		pubErr := r.publish(ctx, e)
+		if ctx.Err() != nil {
+			// the rest are published again once their lease expires
+			return i, ctx.Err()
+		}
+		if err := r.record(ctx, e, pubErr); err != nil {
+			return i, err
+		}
+	}
+	return len(events), nil
+}
+
+func (r *OutboxRelay) claim(ctx context.Context) ([]model.OutboxEvent, error) {
+	tx, err := r.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	events, err := r.outboxRepo.ClaimDue(ctx, tx, relayBatchSize, relayLease)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing outbox claim: %w", err)
+	}
+	return events, nil
+}
+
+// record saves the outcome of publishing e, which failed if pubErr is set.
+func (r *OutboxRelay) record(ctx context.Context, e *model.OutboxEvent, pubErr error) error {
+	tx, err := r.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	attempt := e.Attempts + 1
+	dead := attempt >= r.maxAttempts
+	var recorded bool
+	if pubErr == nil {
+		recorded, err = r.outboxRepo.MarkDelivered(ctx, tx, e)
+	} else {
+		next := time.Now().Add(backoff(attempt, relayBackoffBase, relayBackoffMax))
+		recorded, err = r.outboxRepo.MarkFailed(ctx, tx, e, pubErr.Error(), next, dead)
+	}
+	if err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing outbox event: %w", err)
+	}
+
+	switch {
+	case !recorded:
+		r.logger.Warn("outbox event lease expired before its outcome was recorded", "event_id", e.ID, "event_type", e.Type)
+	case pubErr == nil:
+	case dead:
+		r.logger.Error("outbox event dead-lettered", "event_id", e.ID, "event_type", e.Type, "attempts", attempt, "error", pubErr)
+	default:
+		r.logger.Warn("outbox event delivery failed", "event_id", e.ID, "event_type", e.Type, "attempt", attempt, "error", pubErr)
+	}
+	return nil
+}
+
+func (r *OutboxRelay) publish(ctx context.Context, e *model.OutboxEvent) error {
+	for _, sink := range r.sinks {
+		if err := sink.Publish(ctx, e); err != nil {
+			return fmt.Errorf("%s: %w", sink.Name(), err)
+		}
+	}
+	return nil
+}
+
+// RunRelay publishes due events every interval until ctx is cancelled.
+func (r *OutboxRelay) RunRelay(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.RelayDue(ctx); err != nil && ctx.Err() == nil {
+				r.logger.Error("relaying outbox events", "error", err)
+			}
+		}
+	}
+}
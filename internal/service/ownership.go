@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/auth"
+	"github.com/InternalTransfer/internal/model"
+)
+
+// callerOwner returns the owner the caller is restricted to, or "" when the
+// caller may act on any account, including outside a request.
+func callerOwner(ctx context.Context) string {
+	if p := auth.FromContext(ctx); p != nil {
+		return p.OwnerID
+	}
+	return ""
+}
+
+// checkOwner rejects a restricted caller acting on an account it does not
+// own.
+func checkOwner(ctx context.Context, a *model.Account) error {
+	owner := callerOwner(ctx)
+	if owner == "" || (a.OwnerID != nil && *a.OwnerID == owner) {
+		return nil
+	}
+	return &apperror.ErrNotOwner{AccountID: a.AccountID}
+}
+
+// requireOwner fetches each account and checks that a restricted caller owns
+// it.
+func requireOwner(ctx context.Context, accountRepo AccountRepo, accountIDs ...int64) error {
+	if callerOwner(ctx) == "" {
+		return nil
+	}
+	for _, id := range accountIDs {
+		a, err := accountRepo.GetByID(ctx, id)
+		if err != nil {
+			return fmt.Errorf("fetching account: %w", err)
+		}
+		if err = checkOwner(ctx, a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
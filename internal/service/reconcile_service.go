@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/InternalTransfer/internal/model"
+)
+
+// Reconciliation checks reported in discrepancies.
+const (
+	// CheckLedgerBalance compares an account's balance with the sum of its
+	// ledger entries: the opening balance plus credits minus debits.
+	CheckLedgerBalance = "ledger_balance"
+	// CheckTransactionBalance compares an account's balance with the sum of
+	// its transactions: the opening balance plus incoming minus outgoing
+	// amounts, independently of the ledger entries.
+	CheckTransactionBalance = "transaction_balance"
+	// CheckDebitEntry and CheckCreditEntry require every transaction to have
+	// exactly one debit of its amount on its source account and one credit
+	// of its destination amount on its destination account.
+	CheckDebitEntry  = "debit_entry"
+	CheckCreditEntry = "credit_entry"
+	// CheckHeldBalance compares an account's held balance with its active
+	// holds and the funds held for its pending approvals.
+	CheckHeldBalance = "held_balance"
+	// CheckCurrencyTotal compares the sum of all balances in a currency with
+	// the net amount cross-currency transfers moved into it.
+	CheckCurrencyTotal = "currency_total"
+)
+
+// ReconcileService verifies that stored balances agree with the ledger and
+// with the transactions, and that every transaction has its two entries.
+type ReconcileService struct {
+	reconcileRepo ReconcileRepo
+	logger        *slog.Logger
+}
+
+func NewReconcileService(reconcileRepo ReconcileRepo, logger *slog.Logger) *ReconcileService {
+	return &ReconcileService{
+		reconcileRepo: reconcileRepo,
+		logger:        logger,
+	}
+}
+
+// Reconcile runs every check and reports what failed. Failed checks are not
+// errors; an error means the checks could not be completed.
+func (s *ReconcileService) Reconcile(ctx context.Context) (*model.ReconciliationReport, error) {
+	report := &model.ReconciliationReport{StartedAt: time.Now(), Discrepancies: []model.Discrepancy{}}
+
+	err := s.reconcileRepo.StreamAccountTotals(ctx, func(t *model.AccountTotals) error {
+		report.AccountsChecked++
+		id := t.AccountID
+		if !t.Balance.Equal(t.LedgerBalance) {
+			report.Discrepancies = append(report.Discrepancies, model.Discrepancy{
+				Check: CheckLedgerBalance, AccountID: &id, Currency: t.Currency, Actual: t.Balance, Expected: t.LedgerBalance,
+			})
+		}
+		if !t.Balance.Equal(t.TransactionBalance) {
+			report.Discrepancies = append(report.Discrepancies, model.Discrepancy{
+				Check: CheckTransactionBalance, AccountID: &id, Currency: t.Currency, Actual: t.Balance, Expected: t.TransactionBalance,
+			})
+		}
+		if !t.HeldBalance.Equal(t.ActiveHolds) {
+			report.Discrepancies = append(report.Discrepancies, model.Discrepancy{
+				Check: CheckHeldBalance, AccountID: &id, Currency: t.Currency, Actual: t.HeldBalance, Expected: t.ActiveHolds,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reconciling accounts: %w", err)
+	}
+
+	err = s.reconcileRepo.StreamTransactionEntries(ctx, func(t *model.TransactionEntries) error {
+		report.TransactionsChecked++
+		id := t.TransactionID
+		if debits := t.Debits; debits != 1 || !t.DebitAmount.Equal(t.Amount) {
+			report.Discrepancies = append(report.Discrepancies, model.Discrepancy{
+				Check: CheckDebitEntry, TransactionID: &id, Entries: &debits, Currency: t.Currency, Actual: t.DebitAmount, Expected: t.Amount,
+			})
+		}
+		if credits := t.Credits; credits != 1 || !t.CreditAmount.Equal(t.DestinationAmount) {
+			report.Discrepancies = append(report.Discrepancies, model.Discrepancy{
+				Check: CheckCreditEntry, TransactionID: &id, Entries: &credits, Currency: t.DestinationCurrency, Actual: t.CreditAmount, Expected: t.DestinationAmount,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reconciling transactions: %w", err)
+	}
+
+	totals, err := s.reconcileRepo.CurrencyTotals(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reconciling currencies: %w", err)
+	}
+	for _, t := range totals {
+		report.CurrenciesChecked++
+		if !t.Balance.Equal(t.NetFX) {
+			report.Discrepancies = append(report.Discrepancies, model.Discrepancy{
+				Check: CheckCurrencyTotal, Currency: t.Currency, Actual: t.Balance, Expected: t.NetFX,
+			})
+		}
+	}
+
+	report.FinishedAt = time.Now()
+	report.OK = len(report.Discrepancies) == 0
+	s.logger.Info("reconciliation finished", "accounts", report.AccountsChecked, "transactions", report.TransactionsChecked,
+		"currencies", report.CurrenciesChecked, "discrepancies", len(report.Discrepancies))
+	return report, nil
+}
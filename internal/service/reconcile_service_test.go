@@ -0,0 +1,130 @@
+package service
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/InternalTransfer/internal/model"
+)
+
+type fakeReconcileRepo struct {
+	accounts     []model.AccountTotals
+	transactions []model.TransactionEntries
+	currencies   []model.CurrencyTotal
+}
+
+func (r *fakeReconcileRepo) StreamAccountTotals(_ context.Context, fn func(*model.AccountTotals) error) error {
+	for i := range r.accounts {
+		if err := fn(&r.accounts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *fakeReconcileRepo) StreamTransactionEntries(_ context.Context, fn func(*model.TransactionEntries) error) error {
+	for i := range r.transactions {
+		if err := fn(&r.transactions[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *fakeReconcileRepo) CurrencyTotals(context.Context) ([]model.CurrencyTotal, error) {
+	return r.currencies, nil
+}
+
+func TestReconcile(t *testing.T) {
+	account := func(id int64, balance, ledger, transactions string) model.AccountTotals {
+		return model.AccountTotals{
+			AccountID: id, Currency: "USD", Balance: dec(balance), HeldBalance: dec("0"),
+			LedgerBalance: dec(ledger), TransactionBalance: dec(transactions), ActiveHolds: dec("0"),
+		}
+	}
+	entries := func(id int64, debits, credits int, debit, credit string) model.TransactionEntries {
+		return model.TransactionEntries{
+			TransactionID: id, Currency: "USD", DestinationCurrency: "EUR", Amount: dec("20"), DestinationAmount: dec("18"),
+			Debits: debits, Credits: credits, DebitAmount: dec(debit), CreditAmount: dec(credit),
+		}
+	}
+
+	tests := []struct {
+		name        string
+		account     model.AccountTotals
+		transaction model.TransactionEntries
+		wantChecks  []string
+	}{
+		{
+			name:        "consistent",
+			account:     account(1, "100", "100", "100"),
+			transaction: entries(10, 1, 1, "20", "18"),
+		},
+		{
+			name:        "balance disagrees with transactions",
+			account:     account(1, "120", "120", "100"),
+			transaction: entries(10, 1, 1, "20", "18"),
+			wantChecks:  []string{CheckTransactionBalance},
+		},
+		{
+			name:        "balance disagrees with ledger and transactions",
+			account:     account(1, "120", "100", "100"),
+			transaction: entries(10, 1, 1, "20", "18"),
+			wantChecks:  []string{CheckLedgerBalance, CheckTransactionBalance},
+		},
+		{
+			name:        "missing credit",
+			account:     account(1, "100", "100", "100"),
+			transaction: entries(10, 1, 0, "20", "0"),
+			wantChecks:  []string{CheckCreditEntry},
+		},
+		{
+			name:        "debit split in two",
+			account:     account(1, "100", "100", "100"),
+			transaction: entries(10, 2, 1, "20", "18"),
+			wantChecks:  []string{CheckDebitEntry},
+		},
+		{
+			name:        "credit of the wrong amount",
+			account:     account(1, "100", "100", "100"),
+			transaction: entries(10, 1, 1, "20", "20"),
+			wantChecks:  []string{CheckCreditEntry},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &fakeReconcileRepo{
+				accounts:     []model.AccountTotals{tt.account},
+				transactions: []model.TransactionEntries{tt.transaction},
+				currencies:   []model.CurrencyTotal{{Currency: "USD", Balance: dec("0"), NetFX: dec("0")}},
+			}
+			svc := NewReconcileService(repo, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+			report, err := svc.Reconcile(context.Background())
+			if err != nil {
+				t.Fatalf("Reconcile: %v", err)
+			}
+			if report.AccountsChecked != 1 || report.TransactionsChecked != 1 || report.CurrenciesChecked != 1 {
+				t.Errorf("checked %d accounts, %d transactions and %d currencies, want 1 each",
+					report.AccountsChecked, report.TransactionsChecked, report.CurrenciesChecked)
+			}
+			if report.OK != (len(tt.wantChecks) == 0) {
+				t.Errorf("OK = %v with discrepancies %+v", report.OK, report.Discrepancies)
+			}
+			if len(report.Discrepancies) != len(tt.wantChecks) {
+				t.Fatalf("discrepancies = %+v, want checks %v", report.Discrepancies, tt.wantChecks)
+			}
+			for i, d := range report.Discrepancies {
+				if d.Check != tt.wantChecks[i] {
+					t.Errorf("discrepancy %d is %s, want %s", i, d.Check, tt.wantChecks[i])
+				}
+				if (d.Check == CheckDebitEntry || d.Check == CheckCreditEntry) && (d.TransactionID == nil || *d.TransactionID != 10 || d.Entries == nil) {
+					t.Errorf("entry discrepancy %+v does not name transaction 10 and its entries", d)
+				}
+			}
+		})
+	}
+}
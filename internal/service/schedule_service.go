@@ -0,0 +1,485 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/auth"
+	"github.com/InternalTransfer/internal/currency"
+	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/pagination"
+	"github.com/InternalTransfer/internal/schedule"
+)
+
+const (
+	dueScheduleBatchSize = 50
+	minScheduleInterval  = time.Minute
+)
+
+type ScheduleService struct {
+	scheduleRepo      ScheduleRepo
+	accountRepo       AccountRepo
+	transfers         *TransferService
+	txBeginner        TxBeginner
+	logger            *slog.Logger
+	maxTransferAmount decimal.Decimal
+	// checkDigit requires account IDs to end in a Luhn check digit.
+	checkDigit bool
+}
+
+func NewScheduleService(
+	scheduleRepo ScheduleRepo,
+	accountRepo AccountRepo,
+	transfers *TransferService,
+	txBeginner TxBeginner,
+	logger *slog.Logger,
+	maxTransferAmount int64,
+	checkDigit bool,
+) *ScheduleService {
+	return &ScheduleService{
+		scheduleRepo:      scheduleRepo,
+		accountRepo:       accountRepo,
+		transfers:         transfers,
+		txBeginner:        txBeginner,
+		logger:            logger,
+		maxTransferAmount: decimal.NewFromInt(maxTransferAmount),
+		checkDigit:        checkDigit,
+	}
+}
+
+// Create validates st and stores it as an active schedule. A zero StartsAt
+// starts the schedule now.
+func (s *ScheduleService) Create(ctx context.Context, st model.ScheduledTransfer) (*model.ScheduledTransfer, error) {
+	if err := authorize(ctx, auth.ActionTransfer); err != nil {
+		return nil, err
+	}
+	st.Currency = currency.Normalize(st.Currency)
+	if !validAccountID(st.SourceAccountID, s.checkDigit) || !validAccountID(st.DestinationAccountID, s.checkDigit) {
+		return nil, &apperror.ErrValidation{Message: "Please provide valid account numbers"}
+	}
+	if st.SourceAccountID == st.DestinationAccountID {
+		return nil, &apperror.ErrValidation{Message: "Cannot transfer to the same account. Please choose a different destination account"}
+	}
+	if st.Currency == "" {
+		return nil, &apperror.ErrValidation{Message: "Please specify the transfer currency (e.g., USD)"}
+	}
+	if !currency.IsSupported(st.Currency) {
+		return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Currency %q is not supported", st.Currency)}
+	}
+	if err := validateAmount("Transfer", st.Amount, s.maxTransferAmount, st.Currency); err != nil {
+		return nil, err
+	}
+	// nobody is around to approve a run
+	if err := s.transfers.approvals.checkUnapproved("Scheduled transfer", st.Amount, st.Currency); err != nil {
+		return nil, err
+	}
+	if st.StartsAt.IsZero() {
+		st.StartsAt = time.Now()
+	}
+
+	// scheduled runs cannot carry an FX rate, so both accounts must already
+	// hold the transfer currency
+	for _, id := range []int64{st.SourceAccountID, st.DestinationAccountID} {
+		account, err := s.accountRepo.GetByID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("fetching account: %w", err)
+		}
+		if id == st.SourceAccountID {
+			if err = checkOwner(ctx, account); err != nil {
+				return nil, err
+			}
+		}
+		if account.Currency != st.Currency {
+			return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Scheduled transfers must be in the currency of both accounts; account %d holds %s", id, account.Currency)}
+		}
+	}
+
+	rule, err := scheduleRule(&st)
+	if err != nil {
+		return nil, err
+	}
+	// the first run may fall exactly on StartsAt
+	next := rule.Next(st.StartsAt.Add(-time.Nanosecond))
+	if next.IsZero() || (st.EndsAt != nil && next.After(*st.EndsAt)) {
+		return nil, &apperror.ErrValidation{Message: "This schedule would never run. Please check the rule and end date"}
+	}
+	st.NextRunAt = &next
+
+	if err = s.scheduleRepo.Create(ctx, &st); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("scheduled transfer created", "schedule_id", st.ID, "source", st.SourceAccountID,
+		"destination", st.DestinationAccountID, "next_run_at", next, "actor", actor(ctx))
+	return &st, nil
+}
+
+// scheduleRule validates the rule fields of st and returns the rule.
+func scheduleRule(st *model.ScheduledTransfer) (schedule.Rule, error) {
+	switch {
+	case st.CronExpr != nil && st.IntervalSeconds != nil:
+		return nil, &apperror.ErrValidation{Message: "Please provide either a cron expression or an interval, not both"}
+	case st.CronExpr != nil:
+		c, err := schedule.ParseCron(*st.CronExpr)
+		if err != nil {
+			return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Invalid cron expression: %s", err)}
+		}
+		return c, nil
+	case st.IntervalSeconds != nil:
+		every := time.Duration(*st.IntervalSeconds) * time.Second
+		if every < minScheduleInterval {
+			return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Interval must be at least %d seconds", int(minScheduleInterval.Seconds()))}
+		}
+		return schedule.Interval{Start: st.StartsAt, Every: every}, nil
+	default:
+		return nil, &apperror.ErrValidation{Message: "Please provide a cron expression or an interval"}
+	}
+}
+
+// reschedule moves st to its first run strictly after after, completing it
+// when that run would fall past EndsAt.
+func reschedule(st *model.ScheduledTransfer, rule schedule.Rule, after time.Time) {
+	if after.Before(st.StartsAt) {
+		after = st.StartsAt.Add(-time.Nanosecond)
+	}
+	next := rule.Next(after)
+	if next.IsZero() || (st.EndsAt != nil && next.After(*st.EndsAt)) {
+		st.Status = model.ScheduleCompleted
+		st.NextRunAt = nil
+		return
+	}
+	st.NextRunAt = &next
+}
+
+func (s *ScheduleService) GetByID(ctx context.Context, id int64) (*model.ScheduledTransfer, error) {
+	if err := authorize(ctx, auth.ActionReadAccounts); err != nil {
+		return nil, err
+	}
+	if id <= 0 {
+		return nil, &apperror.ErrValidation{Message: "Please provide a valid scheduled transfer ID"}
+	}
+
+	st, err := s.scheduleRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching scheduled transfer: %w", err)
+	}
+	// end users can see schedules on either side of their accounts
+	if err = requireOwner(ctx, s.accountRepo, st.SourceAccountID); err != nil {
+		if requireOwner(ctx, s.accountRepo, st.DestinationAccountID) != nil {
+			return nil, err
+		}
+	}
+	return st, nil
+}
+
+// List returns one page of schedules and the ID to continue after, which is
+// zero on the last page.
+func (s *ScheduleService) List(ctx context.Context, f model.ScheduleFilter) ([]model.ScheduledTransfer, int64, error) {
+	if err := authorize(ctx, auth.ActionReadAccounts); err != nil {
+		return nil, 0, err
+	}
+	if f.AccountID < 0 {
+		return nil, 0, &apperror.ErrValidation{Message: "Please provide a valid account number"}
+	}
+	if f.Limit <= 0 {
+		f.Limit = pagination.DefaultLimit
+	}
+	if f.Limit > pagination.MaxLimit {
+		return nil, 0, &apperror.ErrValidation{Message: fmt.Sprintf("Limit cannot exceed %d", pagination.MaxLimit)}
+	}
+	if callerOwner(ctx) != "" {
+		if f.AccountID == 0 {
+			return nil, 0, &apperror.ErrValidation{Message: "Please provide the account_id to list scheduled transfers for"}
+		}
+		if err := requireOwner(ctx, s.accountRepo, f.AccountID); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	pageSize := f.Limit
+	f.Limit++
+	list, err := s.scheduleRepo.List(ctx, f)
+	if err != nil {
+		return nil, 0, fmt.Errorf("listing scheduled transfers: %w", err)
+	}
+	if len(list) <= pageSize {
+		return list, 0, nil
+	}
+	list = list[:pageSize]
+	return list, list[pageSize-1].ID, nil
+}
+
+// Update applies patch to an active or paused schedule. Changing the rule or
+// end date recomputes the next run from now.
+func (s *ScheduleService) Update(ctx context.Context, id int64, patch model.SchedulePatch) (*model.ScheduledTransfer, error) {
+	if patch.CronExpr != nil && patch.IntervalSeconds != nil {
+		return nil, &apperror.ErrValidation{Message: "Please provide either a cron expression or an interval, not both"}
+	}
+
+	return s.transition(ctx, id, "update", func(st *model.ScheduledTransfer) error {
+		if st.Status != model.ScheduleActive && st.Status != model.SchedulePaused {
+			return &apperror.ErrScheduleState{ScheduleID: id, Status: string(st.Status), Action: "update"}
+		}
+		if patch.Amount != nil {
+			if err := validateAmount("Transfer", *patch.Amount, s.maxTransferAmount, st.Currency); err != nil {
+				return err
+			}
+			if err := s.transfers.approvals.checkUnapproved("Scheduled transfer", *patch.Amount, st.Currency); err != nil {
+				return err
+			}
+			st.Amount = *patch.Amount
+		}
+		if patch.CronExpr == nil && patch.IntervalSeconds == nil && patch.EndsAt == nil {
+			return nil
+		}
+
+		if patch.CronExpr != nil {
+			st.CronExpr, st.IntervalSeconds = patch.CronExpr, nil
+		}
+		if patch.IntervalSeconds != nil {
+			st.CronExpr, st.IntervalSeconds = nil, patch.IntervalSeconds
+		}
+		if patch.EndsAt != nil {
+			st.EndsAt = patch.EndsAt
+		}
+		rule, err := scheduleRule(st)
+		if err != nil {
+			return err
+		}
+		reschedule(st, rule, time.Now())
+		if st.Status == model.ScheduleCompleted {
+			return &apperror.ErrValidation{Message: "This schedule would never run. Please check the rule and end date"}
+		}
+		return nil
+	})
+}
+
+func (s *ScheduleService) Pause(ctx context.Context, id int64) (*model.ScheduledTransfer, error) {
+	return s.transition(ctx, id, "pause", func(st *model.ScheduledTransfer) error {
+		if st.Status != model.ScheduleActive {
+			return &apperror.ErrScheduleState{ScheduleID: id, Status: string(st.Status), Action: "pause"}
+		}
+		st.Status = model.SchedulePaused
+		return nil
+	})
+}
+
+// Resume reactivates a paused schedule. Runs missed while it was paused are
+// skipped rather than replayed.
+func (s *ScheduleService) Resume(ctx context.Context, id int64) (*model.ScheduledTransfer, error) {
+	return s.transition(ctx, id, "resume", func(st *model.ScheduledTransfer) error {
+		if st.Status != model.SchedulePaused {
+			return &apperror.ErrScheduleState{ScheduleID: id, Status: string(st.Status), Action: "resume"}
+		}
+		rule, err := scheduleRule(st)
+		if err != nil {
+			return err
+		}
+		st.Status = model.ScheduleActive
+		if st.NextRunAt == nil || st.NextRunAt.Before(time.Now()) {
+			reschedule(st, rule, time.Now())
+		}
+		return nil
+	})
+}
+
+func (s *ScheduleService) Cancel(ctx context.Context, id int64) (*model.ScheduledTransfer, error) {
+	return s.transition(ctx, id, "cancel", func(st *model.ScheduledTransfer) error {
+		if st.Status == model.ScheduleCompleted || st.Status == model.ScheduleCancelled {
+			return &apperror.ErrScheduleState{ScheduleID: id, Status: string(st.Status), Action: "cancel"}
+		}
+		st.Status = model.ScheduleCancelled
+		st.NextRunAt = nil
+		return nil
+	})
+}
+
+// transition locks a schedule, applies change and saves the result. Locking
+// waits for a run of the schedule that is in progress to finish.
+func (s *ScheduleService) transition(ctx context.Context, id int64, action string, change func(*model.ScheduledTransfer) error) (*model.ScheduledTransfer, error) {
+	if err := authorize(ctx, auth.ActionTransfer); err != nil {
+		return nil, err
+	}
+	if id <= 0 {
+		return nil, &apperror.ErrValidation{Message: "Please provide a valid scheduled transfer ID"}
+	}
+
+	tx, err := s.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	st, err := s.scheduleRepo.GetByIDForUpdate(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	// only the owner of the paying account may change a schedule
+	if err = requireOwner(ctx, s.accountRepo, st.SourceAccountID); err != nil {
+		return nil, err
+	}
+	if err = change(st); err != nil {
+		return nil, err
+	}
+	if err = s.scheduleRepo.Update(ctx, tx, st); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing scheduled transfer: %w", err)
+	}
+
+	s.logger.Info("scheduled transfer changed", "schedule_id", id, "action", action, "status", st.Status, "actor", actor(ctx))
+	return st, nil
+}
+
+// ListRuns returns one page of a schedule's runs, newest first, and the ID to
+// continue before, which is zero on the last page.
+func (s *ScheduleService) ListRuns(ctx context.Context, id, beforeID int64, limit int) ([]model.ScheduledTransferRun, int64, error) {
+	if id <= 0 {
+		return nil, 0, &apperror.ErrValidation{Message: "Please provide a valid scheduled transfer ID"}
+	}
+	if limit <= 0 {
+		limit = pagination.DefaultLimit
+	}
+	if limit > pagination.MaxLimit {
+		return nil, 0, &apperror.ErrValidation{Message: fmt.Sprintf("Limit cannot exceed %d", pagination.MaxLimit)}
+	}
+
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return nil, 0, err
+	}
+
+	runs, err := s.scheduleRepo.ListRuns(ctx, id, beforeID, limit+1)
+	if err != nil {
+		return nil, 0, fmt.Errorf("listing scheduled transfer runs: %w", err)
+	}
+	if len(runs) <= limit {
+		return runs, 0, nil
+	}
+	runs = runs[:limit]
+	return runs, runs[limit-1].ID, nil
+}
+
+// RunDue executes every schedule that is due and returns how many ran.
+//
+// Claimed schedules stay locked until their runs are recorded. Each transfer
+// commits on its own under an idempotency key derived from the schedule and
+// the run time, so if recording fails the next attempt replays the existing
+// transfer instead of moving money twice.
+func (s *ScheduleService) RunDue(ctx context.Context) (int, error) {
+	tx, err := s.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	due, err := s.scheduleRepo.ClaimDue(ctx, tx, dueScheduleBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range due {
+		st := &due[i]
+		run, err := s.execute(ctx, st)
+		if err != nil {
+			return 0, err
+		}
+		if err = s.scheduleRepo.CreateRun(ctx, tx, run); err != nil {
+			return 0, err
+		}
+		if err = s.scheduleRepo.Update(ctx, tx, st); err != nil {
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("committing scheduled runs: %w", err)
+	}
+	return len(due), nil
+}
+
+// execute performs the run of st that is due, then advances st to its next
+// run. A failed transfer is recorded on the run rather than returned. A
+// schedule whose rule no longer parses cannot be advanced, so it is paused
+// with a failed run instead of holding up the rest of the batch.
+func (s *ScheduleService) execute(ctx context.Context, st *model.ScheduledTransfer) (*model.ScheduledTransferRun, error) {
+	scheduledFor := *st.NextRunAt
+	rule, err := scheduleRule(st)
+	if err != nil {
+		code, msg := runError(err)
+		s.logger.Error("pausing scheduled transfer with an invalid rule", "schedule_id", st.ID, "error", err)
+		st.Status = model.SchedulePaused
+		return &model.ScheduledTransferRun{
+			ScheduleID: st.ID, ScheduledFor: scheduledFor, Status: model.RunFailed, ErrorCode: &code, ErrorMessage: &msg,
+		}, nil
+	}
+	run := &model.ScheduledTransferRun{ScheduleID: st.ID, ScheduledFor: scheduledFor, Status: model.RunSucceeded}
+
+	req := model.TransferRequest{
+		SourceAccountID:      st.SourceAccountID,
+		DestinationAccountID: st.DestinationAccountID,
+		Amount:               st.Amount,
+		Currency:             st.Currency,
+	}
+	key := fmt.Sprintf("schedule:%d:%d", st.ID, scheduledFor.Unix())
+	txn, err := s.transfers.Transfer(ctx, req, key)
+	if ctx.Err() != nil {
+		// shutting down; leave the run to the next scheduler
+		return nil, ctx.Err()
+	}
+	if err != nil {
+		code, msg := runError(err)
+		run.Status, run.ErrorCode, run.ErrorMessage = model.RunFailed, &code, &msg
+		s.logger.Warn("scheduled transfer failed", "schedule_id", st.ID, "scheduled_for", scheduledFor, "error", err)
+	} else {
+		run.TransactionID = &txn.ID
+	}
+
+	// runs missed while the scheduler was down are skipped, not replayed
+	now := time.Now()
+	st.LastRunAt = &now
+	reschedule(st, rule, now)
+	return run, nil
+}
+
+// runError returns the code and message recorded on a failed run. Runs are
+// returned to clients, so only application errors keep their own message;
+// anything else is reported as an internal error and left to the log.
+func runError(err error) (code, msg string) {
+	var appErr apperror.AppError
+	if errors.As(err, &appErr) && appErr.Code() != apperror.CodeInternal {
+		return appErr.Code(), appErr.Error()
+	}
+	return apperror.CodeInternal, "internal server error"
+}
+
+// RunScheduler executes due schedules every interval until ctx is cancelled.
+func (s *ScheduleService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			n, err := s.RunDue(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					s.logger.Error("running scheduled transfers", "error", err)
+				}
+				continue
+			}
+			if n > 0 {
+				s.logger.Info("scheduled transfers run", "count", n)
+			}
+		}
+	}
+}
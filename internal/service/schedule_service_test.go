@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/model"
+)
+
+func TestScheduleServiceCheckDigit(t *testing.T) {
+	s := NewScheduleService(nil, nil, &TransferService{}, nil, slog.New(slog.NewTextHandler(io.Discard, nil)), 1_000_000, true)
+
+	tests := []struct {
+		name         string
+		source, dest int64
+	}{
+		{"source", badCheckDigitID, goodCheckDigitID},
+		{"destination", goodCheckDigitID, badCheckDigitID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Create(context.Background(), model.ScheduledTransfer{
+				SourceAccountID:      tt.source,
+				DestinationAccountID: tt.dest,
+				Amount:               dec("10"),
+				Currency:             "USD",
+			})
+			var validationErr *apperror.ErrValidation
+			if !errors.As(err, &validationErr) {
+				t.Errorf("Create error = %v, want a validation error", err)
+			}
+		})
+	}
+}
+
+func TestRunError(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode string
+		wantMsg  string
+	}{
+		{"app error", fmt.Errorf("transfer: %w", &apperror.ErrInsufficientBalance{AccountID: 1}), apperror.CodeInsufficientBalance, (&apperror.ErrInsufficientBalance{AccountID: 1}).Error()},
+		{"internal", fmt.Errorf("committing transfer: %w", errors.New("conn reset")), apperror.CodeInternal, "internal server error"},
+		{"batch leg wrapping internal", &apperror.ErrBatchLeg{Leg: 0, Err: errors.New("conn reset")}, apperror.CodeInternal, "internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, msg := runError(tt.err)
+			if code != tt.wantCode || msg != tt.wantMsg {
+				t.Errorf("runError = %s %q, want %s %q", code, msg, tt.wantCode, tt.wantMsg)
+			}
+		})
+	}
+}
+
+// fakeScheduleRepo keeps schedules and their runs in memory; only what the
+// scheduler needs is implemented.
+type fakeScheduleRepo struct {
+	ScheduleRepo
+	schedules []model.ScheduledTransfer
+	runs      []model.ScheduledTransferRun
+}
+
+func (r *fakeScheduleRepo) ClaimDue(_ context.Context, _ pgx.Tx, limit int) ([]model.ScheduledTransfer, error) {
+	var out []model.ScheduledTransfer
+	for _, st := range r.schedules {
+		if st.Status == model.ScheduleActive && !st.NextRunAt.After(time.Now()) && len(out) < limit {
+			out = append(out, st)
+		}
+	}
+	return out, nil
+}
+
+func (r *fakeScheduleRepo) Update(_ context.Context, _ pgx.Tx, st *model.ScheduledTransfer) error {
+	r.schedules[st.ID-1] = *st
+	return nil
+}
+
+func (r *fakeScheduleRepo) CreateRun(_ context.Context, _ pgx.Tx, run *model.ScheduledTransferRun) error {
+	run.ID = int64(len(r.runs) + 1)
+	r.runs = append(r.runs, *run)
+	return nil
+}
+
+func TestRunDueInvalidRule(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "100")
+	bank.addAccount(2, "USD", "0")
+	due := time.Now().Add(-time.Minute)
+	broken, every := "* * *", int64(3600)
+	repo := &fakeScheduleRepo{schedules: []model.ScheduledTransfer{
+		{ID: 1, SourceAccountID: 1, DestinationAccountID: 2, Amount: dec("10"), Currency: "USD",
+			CronExpr: &broken, StartsAt: due, Status: model.ScheduleActive, NextRunAt: &due},
+		{ID: 2, SourceAccountID: 1, DestinationAccountID: 2, Amount: dec("10"), Currency: "USD",
+			IntervalSeconds: &every, StartsAt: due, Status: model.ScheduleActive, NextRunAt: &due},
+	}}
+	s := NewScheduleService(repo, fakeAccountRepo{bank}, newTestTransferService(bank, ApprovalPolicy{}), bank,
+		slog.New(slog.NewTextHandler(io.Discard, nil)), 1_000_000, false)
+
+	n, err := s.RunDue(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("RunDue = %d, %v; want both schedules run", n, err)
+	}
+	if len(repo.runs) != 2 {
+		t.Fatalf("runs = %+v, want one per schedule", repo.runs)
+	}
+	if run := repo.runs[0]; run.Status != model.RunFailed || *run.ErrorCode != apperror.CodeValidation {
+		t.Errorf("invalid rule run = %+v, want a failed validation run", run)
+	}
+	if st := repo.schedules[0]; st.Status != model.SchedulePaused {
+		t.Errorf("invalid rule schedule status = %s, want paused", st.Status)
+	}
+	if run := repo.runs[1]; run.Status != model.RunSucceeded || run.TransactionID == nil {
+		t.Errorf("valid run = %+v, want a transfer", run)
+	}
+	if bank.balance(2) != "10" {
+		t.Errorf("destination balance = %s, want 10", bank.balance(2))
+	}
+
+	if n, err = s.RunDue(context.Background()); err != nil || n != 0 {
+		t.Errorf("second RunDue = %d, %v; want the paused schedule skipped", n, err)
+	}
+}
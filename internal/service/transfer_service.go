@@ -2,129 +2,829 @@ package service
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
+	"time"
 
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/shopspring/decimal"
 
 	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/auth"
+	"github.com/InternalTransfer/internal/currency"
+	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/pagination"
 )
 
 type TransferService struct {
 	accountRepo       AccountRepo
 	transactionRepo   TransactionRepo
+	idempotencyRepo   IdempotencyRepo
+	fxQuoteRepo       FXQuoteRepo
+	feePolicyRepo     FeePolicyRepo
+	limitRepo         AccountLimitRepo
+	approvalRepo      ApprovalRepo
+	ledger            *ledger
 	txBeginner        TxBeginner
 	logger            *slog.Logger
 	maxTransferAmount decimal.Decimal
+	feeAccountID      int64
+	// checkDigit requires account IDs to end in a Luhn check digit.
+	checkDigit bool
+	approvals  ApprovalPolicy
 }
 
 var minTransferAmount = decimal.NewFromInt(1)
 
 const maxRetries = 3
 
+const maxIdempotencyKeyLength = 255
+
+const maxBatchLegs = 100
+
 func NewTransferService(
 	accountRepo AccountRepo,
 	transactionRepo TransactionRepo,
+	ledgerRepo LedgerRepo,
+	outboxRepo OutboxRepo,
+	idempotencyRepo IdempotencyRepo,
+	fxQuoteRepo FXQuoteRepo,
+	feePolicyRepo FeePolicyRepo,
+	limitRepo AccountLimitRepo,
+	approvalRepo ApprovalRepo,
 	txBeginner TxBeginner,
 	logger *slog.Logger,
 	maxTransferAmount int64,
+	feeAccountID int64,
+	checkDigit bool,
+	approvals ApprovalPolicy,
 ) *TransferService {
 	return &TransferService{
 		accountRepo:       accountRepo,
 		transactionRepo:   transactionRepo,
+		idempotencyRepo:   idempotencyRepo,
+		fxQuoteRepo:       fxQuoteRepo,
+		feePolicyRepo:     feePolicyRepo,
+		limitRepo:         limitRepo,
+		approvalRepo:      approvalRepo,
+		ledger:            newLedger(accountRepo, transactionRepo, ledgerRepo, outboxRepo),
 		txBeginner:        txBeginner,
 		logger:            logger,
 		maxTransferAmount: decimal.NewFromInt(maxTransferAmount),
+		feeAccountID:      feeAccountID,
+		checkDigit:        checkDigit,
+		approvals:         approvals,
+	}
+}
+
+// Transfer executes req immediately. Amounts that need approval are
+// rejected; Submit routes them to an approver instead.
+func (s *TransferService) Transfer(ctx context.Context, req model.TransferRequest, idempotencyKey string) (*model.Transaction, error) {
+	if err := s.checkTransfer(ctx, &req, idempotencyKey); err != nil {
+		return nil, err
+	}
+	if err := s.approvals.checkUnapproved("Transfer", req.Amount, req.Currency); err != nil {
+		return nil, err
+	}
+
+	var txn *model.Transaction
+	err := s.withRetry(func() error {
+		var err error
+		txn, err = s.executeTransfer(ctx, req, idempotencyKey)
+		return err
+	})
+	return txn, err
+}
+
+// checkTransfer runs the checks shared by Transfer and Submit, normalizing
+// the currency of req.
+func (s *TransferService) checkTransfer(ctx context.Context, req *model.TransferRequest, idempotencyKey string) error {
+	if err := authorize(ctx, auth.ActionTransfer); err != nil {
+		return err
 	}
+	req.Currency = currency.Normalize(req.Currency)
+	if err := s.validateTransfer(*req); err != nil {
+		return err
+	}
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		return &apperror.ErrValidation{Message: fmt.Sprintf("Idempotency-Key cannot be longer than %d characters", maxIdempotencyKeyLength)}
+	}
+	// end users can only debit their own accounts
+	return requireOwner(ctx, s.accountRepo, req.SourceAccountID)
 }
 
-func (s *TransferService) Transfer(ctx context.Context, sourceID, destID int64, amount decimal.Decimal) error {
-	if sourceID <= 0 || destID <= 0 {
+func (s *TransferService) validateTransfer(req model.TransferRequest) error {
+	if !validAccountID(req.SourceAccountID, s.checkDigit) || !validAccountID(req.DestinationAccountID, s.checkDigit) {
 		return &apperror.ErrValidation{Message: "Please provide valid account numbers"}
 	}
-	if sourceID == destID {
+	if req.SourceAccountID == req.DestinationAccountID {
 		return &apperror.ErrValidation{Message: "Cannot transfer to the same account. Please choose a different destination account"}
 	}
+	if req.Currency == "" {
+		return &apperror.ErrValidation{Message: "Please specify the transfer currency (e.g., USD)"}
+	}
+	if !currency.IsSupported(req.Currency) {
+		return &apperror.ErrValidation{Message: fmt.Sprintf("Currency %q is not supported", req.Currency)}
+	}
+	if req.FXRate != nil && !req.FXRate.IsPositive() {
+		return &apperror.ErrValidation{Message: "FX rate must be greater than zero"}
+	}
+	if req.FXQuoteID != nil {
+		if req.FXRate != nil {
+			return &apperror.ErrValidation{Message: "Please provide either an FX rate or an FX quote, not both"}
+		}
+		if *req.FXQuoteID <= 0 {
+			return &apperror.ErrValidation{Message: "Please provide a valid FX quote ID"}
+		}
+	}
+	return validateAmount("Transfer", req.Amount, s.maxTransferAmount, req.Currency)
+}
+
+// validateAmount applies the amount rules shared by transfers and holds.
+// Precision is checked against the minor units of currencyCode.
+func validateAmount(what string, amount, maxAmount decimal.Decimal, currencyCode string) error {
 	if amount.LessThan(minTransferAmount) {
-		return &apperror.ErrValidation{Message: fmt.Sprintf("Transfer amount must be at least $%s", minTransferAmount)}
+		return &apperror.ErrValidation{Message: fmt.Sprintf("%s amount must be at least %s %s", what, minTransferAmount, currencyCode)}
 	}
-	if amount.GreaterThan(s.maxTransferAmount) {
-		return &apperror.ErrValidation{Message: fmt.Sprintf("Transfer amount cannot exceed $%s", s.maxTransferAmount)}
+	if amount.GreaterThan(maxAmount) {
+		return &apperror.ErrValidation{Message: fmt.Sprintf("%s amount cannot exceed %s %s", what, maxAmount, currencyCode)}
 	}
-	if amount.Exponent() < -2 {
-		return &apperror.ErrValidation{Message: "Transfer amount can only have up to 2 decimal places (e.g., 10.50)"}
+	if !currency.HasValidPrecision(amount, currencyCode) {
+		return &apperror.ErrValidation{Message: fmt.Sprintf("%s amount can only have up to %d decimal places in %s", what, currency.MinorUnits(currencyCode), currencyCode)}
+	}
+	return nil
+}
+
+// newTransferTransaction checks req against the locked accounts' currencies
+// and builds the transaction to post, converting the amount when the
+// destination account holds a different currency. quote is the locked quote
+// named by req.FXQuoteID, if any.
+func newTransferTransaction(req model.TransferRequest, source, dest *model.Account, quote *model.FXQuote) (*model.Transaction, error) {
+	if source.Currency != req.Currency {
+		return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Transfer currency %s does not match the source account currency %s", req.Currency, source.Currency)}
 	}
 
-	var lastErr error
+	txn := &model.Transaction{Kind: model.TransactionKindTransfer, Amount: req.Amount}
+	if dest.Currency == source.Currency {
+		if req.FXRate != nil || quote != nil {
+			return nil, &apperror.ErrValidation{Message: "An FX rate or quote can only be supplied for cross-currency transfers"}
+		}
+		return txn, nil
+	}
+
+	var rate decimal.Decimal
+	switch {
+	case quote != nil:
+		if err := checkQuote(quote, source.Currency, dest.Currency); err != nil {
+			return nil, err
+		}
+		rate = quote.Rate
+		txn.FXQuoteID = &quote.ID
+	case req.FXRate != nil:
+		rate = *req.FXRate
+	default:
+		return nil, &apperror.ErrCurrencyMismatch{SourceCurrency: source.Currency, DestinationCurrency: dest.Currency}
+	}
+
+	txn.DestinationAmount = currency.Round(req.Amount.Mul(rate), dest.Currency)
+	if !txn.DestinationAmount.IsPositive() {
+		return nil, &apperror.ErrValidation{Message: "The converted amount is too small to transfer"}
+	}
+	txn.FXRate = decimal.NewNullDecimal(rate)
+	return txn, nil
+}
+
+// checkQuote reports whether a locked quote can still pay for a conversion
+// between the given currencies.
+func checkQuote(q *model.FXQuote, from, to string) error {
+	if q.TransactionID != nil {
+		return &apperror.ErrQuoteInvalid{QuoteID: q.ID, Reason: "it has already been used"}
+	}
+	if !time.Now().Before(q.ExpiresAt) {
+		return &apperror.ErrQuoteInvalid{QuoteID: q.ID, Reason: "it has expired"}
+	}
+	if q.SourceCurrency != from || q.DestinationCurrency != to {
+		return &apperror.ErrQuoteInvalid{QuoteID: q.ID, Reason: fmt.Sprintf("it converts %s to %s, not %s to %s", q.SourceCurrency, q.DestinationCurrency, from, to)}
+	}
+	return nil
+}
+
+// lockQuotes locks the quotes referenced by reqs in ascending ID order. Quotes
+// are locked before accounts, matching the order used for holds and reversals.
+func (s *TransferService) lockQuotes(ctx context.Context, tx pgx.Tx, reqs ...model.TransferRequest) (map[int64]*model.FXQuote, error) {
+	var ids []int64
+	for _, req := range reqs {
+		if req.FXQuoteID != nil {
+			ids = append(ids, *req.FXQuoteID)
+		}
+	}
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+
+	quotes := make(map[int64]*model.FXQuote, len(ids))
+	for _, id := range ids {
+		q, err := s.fxQuoteRepo.GetByIDForUpdate(ctx, tx, id)
+		if err != nil {
+			return nil, err
+		}
+		quotes[id] = q
+	}
+	return quotes, nil
+}
+
+// useQuote marks the quote applied to txn, if any, as spent so that it cannot
+// be applied again.
+func (s *TransferService) useQuote(ctx context.Context, tx pgx.Tx, quotes map[int64]*model.FXQuote, txn *model.Transaction) error {
+	if txn.FXQuoteID == nil {
+		return nil
+	}
+	if err := s.fxQuoteRepo.MarkUsed(ctx, tx, *txn.FXQuoteID, txn.ID); err != nil {
+		return err
+	}
+	quotes[*txn.FXQuoteID].TransactionID = &txn.ID
+	return nil
+}
+
+func quoteFor(quotes map[int64]*model.FXQuote, req model.TransferRequest) *model.FXQuote {
+	if req.FXQuoteID == nil {
+		return nil
+	}
+	return quotes[*req.FXQuoteID]
+}
+
+// withRetry runs fn again when Postgres reports a serialization failure.
+func (s *TransferService) withRetry(fn func() error) error {
 	for i := 0; i < maxRetries; i++ {
-		lastErr = s.executeTransfer(ctx, sourceID, destID, amount)
-		if lastErr == nil {
+		err := fn()
+		if err == nil {
 			return nil
 		}
 
 		var pgErr *pgconn.PgError
-		if errors.As(lastErr, &pgErr) && pgErr.Code == "40001" {
+		if errors.As(err, &pgErr) && pgErr.Code == "40001" {
 			s.logger.Warn("serialization failure, retrying", "attempt", i+1)
 			continue
 		}
 
-		return lastErr
+		return err
 	}
 
 	return fmt.Errorf("unable to complete transfer due to high system load. Please try again in a few moments")
 }
 
-func (s *TransferService) executeTransfer(ctx context.Context, sourceID, destID int64, amount decimal.Decimal) error {
+// claimIdempotencyKey reserves key inside tx. When the key was used before it
+// returns the original transaction, which the caller should return as-is.
+func (s *TransferService) claimIdempotencyKey(ctx context.Context, tx pgx.Tx, key, requestHash string) (*model.Transaction, error) {
+	existing, err := s.claimKey(ctx, tx, key, requestHash)
+	if err != nil || existing == nil {
+		return nil, err
+	}
+	if existing.TransactionID == nil {
+		return nil, fmt.Errorf("idempotency key %q has no transaction", key)
+	}
+	return s.replayTransaction(ctx, *existing.TransactionID)
+}
+
+// claimKey reserves the caller's key inside tx and returns nil, or the record
+// of the earlier request when the caller used the key before. Keys are scoped
+// to the caller's stable ID, so clients cannot collide with each other or with
+// the keys of scheduled runs, which act as "system".
+func (s *TransferService) claimKey(ctx context.Context, tx pgx.Tx, key, requestHash string) (*model.IdempotencyKey, error) {
+	existing, err := s.idempotencyRepo.Claim(ctx, tx, actorID(ctx), key, requestHash)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return nil, nil
+	}
+	if existing.RequestHash != requestHash {
+		return nil, &apperror.ErrIdempotencyMismatch{Key: key}
+	}
+	s.logger.Info("idempotent replay", "idempotency_key", key)
+	return existing, nil
+}
+
+func (s *TransferService) replayTransaction(ctx context.Context, id int64) (*model.Transaction, error) {
+	txn, err := s.transactionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return txn, s.loadFee(ctx, txn)
+}
+
+// loadFee attaches the fee leg charged on txn, if any.
+func (s *TransferService) loadFee(ctx context.Context, txn *model.Transaction) error {
+	if txn.Kind != model.TransactionKindTransfer {
+		return nil
+	}
+	fee, err := s.transactionRepo.GetFee(ctx, txn.ID)
+	if err != nil {
+		return err
+	}
+	txn.Fee = fee
+	return nil
+}
+
+// findFeePolicy returns the fee policy pricing req, or nil when fees are
+// disabled or none applies. Transfers out of the fee account are never
+// charged.
+func (s *TransferService) findFeePolicy(ctx context.Context, tx pgx.Tx, req model.TransferRequest) (*model.FeePolicy, error) {
+	if s.feeAccountID == 0 || req.SourceAccountID == s.feeAccountID {
+		return nil, nil
+	}
+	return s.feePolicyRepo.FindForTransfer(ctx, tx, req.SourceAccountID, req.DestinationAccountID, req.Currency)
+}
+
+// chargeFee posts the fee priced by policy on txn from source to the fee
+// account as a separate transaction and attaches it to txn. The fee account
+// must be locked in tx and active. The caller must have checked that source
+// can cover both txn and the fee.
+func (s *TransferService) chargeFee(ctx context.Context, tx pgx.Tx, source, feeAccount *model.Account, txn *model.Transaction, fee decimal.Decimal) error {
+	if !fee.IsPositive() {
+		return nil
+	}
+	if feeAccount.Currency != source.Currency {
+		return fmt.Errorf("fee account %d holds %s, cannot collect a %s fee", feeAccount.AccountID, feeAccount.Currency, source.Currency)
+	}
+	// a frozen or closed fee account takes no credits, fees included
+	if err := requireActive(feeAccount); err != nil {
+		return err
+	}
+
+	feeTxn := &model.Transaction{Kind: model.TransactionKindFee, Amount: fee, FeeOf: &txn.ID}
+	if err := s.ledger.post(ctx, tx, source, feeAccount, feeTxn); err != nil {
+		return err
+	}
+	txn.Fee = feeTxn
+	return nil
+}
+
+func (s *TransferService) executeTransfer(ctx context.Context, req model.TransferRequest, idempotencyKey string) (*model.Transaction, error) {
 	tx, err := s.txBeginner.BeginTx(ctx)
 	if err != nil {
-		return fmt.Errorf("starting transaction: %w", err)
+		return nil, fmt.Errorf("starting transaction: %w", err)
 	}
 	defer tx.Rollback(ctx)
 
-	// lock accounts in consistent order to avoid deadlocks
-	firstID, secondID := sourceID, destID
-	if sourceID > destID {
-		firstID, secondID = destID, sourceID
+	// claim the idempotency key before touching any account so that
+	// concurrent duplicates queue up behind the first request
+	if idempotencyKey != "" {
+		replay, err := s.claimIdempotencyKey(ctx, tx, idempotencyKey, transferFingerprint(req))
+		if err != nil || replay != nil {
+			return replay, err
+		}
 	}
 
-	first, err := s.accountRepo.GetByIDForUpdate(ctx, tx, firstID)
+	txn, fee, err := s.postTransfer(ctx, tx, req, nil)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	if idempotencyKey != "" {
+		if err = s.idempotencyRepo.Complete(ctx, tx, actorID(ctx), idempotencyKey, txn.ID); err != nil {
+			return nil, err
+		}
 	}
-	second, err := s.accountRepo.GetByIDForUpdate(ctx, tx, secondID)
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing transfer: %w", err)
+	}
+
+	s.logger.Info("transfer completed", "transaction_id", txn.ID, "source", req.SourceAccountID, "destination", req.DestinationAccountID,
+		"amount", req.Amount.String(), "currency", req.Currency, "fee", fee.String(), "actor", actor(ctx))
+	return txn, nil
+}
+
+// postTransfer checks req against the locked accounts and posts it with any
+// fee, returning the transaction and the fee charged. approval is the
+// approved submission being executed, if any: the transaction takes its ID
+// and the funds it held are released first so that they can be spent.
+func (s *TransferService) postTransfer(ctx context.Context, tx pgx.Tx, req model.TransferRequest, approval *model.PendingApproval) (*model.Transaction, decimal.Decimal, error) {
+	quotes, err := s.lockQuotes(ctx, tx, req)
 	if err != nil {
-		return err
+		return nil, decimal.Zero, err
+	}
+	policy, err := s.findFeePolicy(ctx, tx, req)
+	if err != nil {
+		return nil, decimal.Zero, err
 	}
 
-	sourceAccount, destAccount := first, second
-	if sourceID != firstID {
-		sourceAccount, destAccount = second, first
+	// the fee account is only locked when a fee may be charged, so it does
+	// not serialize every transfer
+	ids := []int64{req.SourceAccountID, req.DestinationAccountID}
+	if policy != nil {
+		ids = append(ids, s.feeAccountID)
+	}
+	accounts, err := s.ledger.lockAccounts(ctx, tx, ids...)
+	if err != nil {
+		return nil, decimal.Zero, err
+	}
+	sourceAccount, destAccount := accounts[req.SourceAccountID], accounts[req.DestinationAccountID]
+	if err = requireActive(sourceAccount, destAccount); err != nil {
+		return nil, decimal.Zero, err
 	}
 
-	if sourceAccount.Balance.LessThan(amount) {
-		return &apperror.ErrInsufficientBalance{AccountID: sourceID}
+	txn, err := newTransferTransaction(req, sourceAccount, destAccount, quoteFor(quotes, req))
+	if err != nil {
+		return nil, decimal.Zero, err
+	}
+	limits, err := loadLimits(ctx, tx, s.limitRepo, s.transactionRepo, req.SourceAccountID, time.Now())
+	if err != nil {
+		return nil, decimal.Zero, err
+	}
+	if limits != nil {
+		if err = limits.check(req.Amount); err != nil {
+			return nil, decimal.Zero, err
+		}
+	}
+	if approval != nil {
+		txn.ID = approval.ID
+		if approval.FundsHeld {
+			sourceAccount.HeldBalance = sourceAccount.HeldBalance.Sub(approval.Amount)
+			if err = s.accountRepo.UpdateHeldBalance(ctx, tx, sourceAccount.AccountID, sourceAccount.HeldBalance); err != nil {
+				return nil, decimal.Zero, err
+			}
+		}
+	}
+	fee := decimal.Zero
+	if policy != nil {
+		fee = feeFor(policy, req.Amount)
+	}
+	if sourceAccount.AvailableBalance().LessThan(req.Amount.Add(fee)) {
+		return nil, decimal.Zero, &apperror.ErrInsufficientBalance{AccountID: req.SourceAccountID}
 	}
 
-	newSourceBal := sourceAccount.Balance.Sub(amount)
-	newDestBal := destAccount.Balance.Add(amount)
+	if err = s.ledger.post(ctx, tx, sourceAccount, destAccount, txn); err != nil {
+		return nil, decimal.Zero, err
+	}
+	if policy != nil {
+		if err = s.chargeFee(ctx, tx, sourceAccount, accounts[s.feeAccountID], txn, fee); err != nil {
+			return nil, decimal.Zero, err
+		}
+	}
+	if err = s.useQuote(ctx, tx, quotes, txn); err != nil {
+		return nil, decimal.Zero, err
+	}
+	return txn, fee, nil
+}
 
-	if err = s.accountRepo.UpdateBalance(ctx, tx, sourceID, newSourceBal); err != nil {
-		return err
+// transferFingerprint identifies the body of a transfer request so that a
+// replayed Idempotency-Key can be matched against the original request.
+func transferFingerprint(req model.TransferRequest) string {
+	fxRate, fxQuote := "", ""
+	if req.FXRate != nil {
+		fxRate = req.FXRate.String()
+	}
+	if req.FXQuoteID != nil {
+		fxQuote = fmt.Sprint(*req.FXQuoteID)
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d|%d|%s|%s|%s|%s",
+		req.SourceAccountID, req.DestinationAccountID, req.Amount.String(), req.Currency, fxRate, fxQuote)))
+	return hex.EncodeToString(sum[:])
+}
+
+func reversalFingerprint(transactionID int64, amount *decimal.Decimal) string {
+	amountStr := "remaining"
+	if amount != nil {
+		amountStr = amount.String()
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("reverse|%d|%s", transactionID, amountStr)))
+	return hex.EncodeToString(sum[:])
+}
+
+// TransferBatch executes every leg in a single database transaction: either
+// all legs are committed or none are. Results are returned in leg order.
+func (s *TransferService) TransferBatch(ctx context.Context, legs []model.TransferRequest) ([]model.Transaction, error) {
+	if err := authorize(ctx, auth.ActionTransfer); err != nil {
+		return nil, err
+	}
+	if len(legs) == 0 {
+		return nil, &apperror.ErrValidation{Message: "A batch must contain at least one transfer"}
+	}
+	if len(legs) > maxBatchLegs {
+		return nil, &apperror.ErrValidation{Message: fmt.Sprintf("A batch cannot contain more than %d transfers", maxBatchLegs)}
+	}
+	for i := range legs {
+		legs[i].Currency = currency.Normalize(legs[i].Currency)
+		if err := s.validateTransfer(legs[i]); err != nil {
+			return nil, batchLegError(i, err)
+		}
+		if err := s.approvals.checkUnapproved("Transfer", legs[i].Amount, legs[i].Currency); err != nil {
+			return nil, batchLegError(i, err)
+		}
+		if err := requireOwner(ctx, s.accountRepo, legs[i].SourceAccountID); err != nil {
+			return nil, batchLegError(i, err)
+		}
 	}
-	if err = s.accountRepo.UpdateBalance(ctx, tx, destID, newDestBal); err != nil {
+
+	var txns []model.Transaction
+	err := s.withRetry(func() error {
+		var err error
+		txns, err = s.executeBatch(ctx, legs)
+		return err
+	})
+	return txns, err
+}
+
+// batchLegError attributes err to leg i of a batch. Only application errors
+// are attributed; anything else is internal, its text must not reach the
+// client, and it is returned as is.
+func batchLegError(i int, err error) error {
+	var appErr apperror.AppError
+	if !errors.As(err, &appErr) {
 		return err
 	}
+	return &apperror.ErrBatchLeg{Leg: i, Err: err}
+}
 
-	if err = s.transactionRepo.Create(ctx, tx, sourceID, destID, amount); err != nil {
+func (s *TransferService) executeBatch(ctx context.Context, legs []model.TransferRequest) ([]model.Transaction, error) {
+	tx, err := s.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	quotes, err := s.lockQuotes(ctx, tx, legs...)
+	if err != nil {
+		return nil, err
+	}
+	policies := make([]*model.FeePolicy, len(legs))
+	chargesFees := false
+	ids := make([]int64, 0, 2*len(legs)+1)
+	for i, leg := range legs {
+		if policies[i], err = s.findFeePolicy(ctx, tx, leg); err != nil {
+			return nil, err
+		}
+		chargesFees = chargesFees || policies[i] != nil
+		ids = append(ids, leg.SourceAccountID, leg.DestinationAccountID)
+	}
+	if chargesFees {
+		ids = append(ids, s.feeAccountID)
+	}
+	accounts, err := s.ledger.lockAccounts(ctx, tx, ids...)
+	if err != nil {
+		return nil, err
+	}
+
+	// legs are applied in order, so a later leg may spend funds credited by an earlier one
+	now := time.Now()
+	limits := make(map[int64]*outgoingLimits)
+	txns := make([]model.Transaction, len(legs))
+	for i, leg := range legs {
+		source, dest := accounts[leg.SourceAccountID], accounts[leg.DestinationAccountID]
+		if err = requireActive(source, dest); err != nil {
+			return nil, batchLegError(i, err)
+		}
+		// a quote used by an earlier leg is rejected as already used
+		txn, err := newTransferTransaction(leg, source, dest, quoteFor(quotes, leg))
+		if err != nil {
+			return nil, batchLegError(i, err)
+		}
+		sourceLimits, loaded := limits[source.AccountID]
+		if !loaded {
+			if sourceLimits, err = loadLimits(ctx, tx, s.limitRepo, s.transactionRepo, source.AccountID, now); err != nil {
+				return nil, err
+			}
+			limits[source.AccountID] = sourceLimits
+		}
+		if sourceLimits != nil {
+			if err = sourceLimits.check(leg.Amount); err != nil {
+				return nil, batchLegError(i, err)
+			}
+			sourceLimits.record(leg.Amount)
+		}
+		fee := decimal.Zero
+		if policies[i] != nil {
+			fee = feeFor(policies[i], leg.Amount)
+		}
+		if source.AvailableBalance().LessThan(leg.Amount.Add(fee)) {
+			return nil, &apperror.ErrBatchLeg{Leg: i, Err: &apperror.ErrInsufficientBalance{AccountID: source.AccountID}}
+		}
+
+		if err = s.ledger.post(ctx, tx, source, dest, txn); err != nil {
+			return nil, err
+		}
+		if policies[i] != nil {
+			if err = s.chargeFee(ctx, tx, source, accounts[s.feeAccountID], txn, fee); err != nil {
+				return nil, err
+			}
+		}
+		if err = s.useQuote(ctx, tx, quotes, txn); err != nil {
+			return nil, err
+		}
+		txns[i] = *txn
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing batch: %w", err)
+	}
+
+	s.logger.Info("batch transfer completed", "legs", len(legs), "first_transaction_id", txns[0].ID, "actor", actor(ctx))
+	return txns, nil
+}
+
+// Reverse moves money back from the destination of a transfer to its source.
+// A nil amount reverses whatever has not been reversed yet.
+func (s *TransferService) Reverse(ctx context.Context, transactionID int64, amount *decimal.Decimal, idempotencyKey string) (*model.Transaction, error) {
+	if err := authorize(ctx, auth.ActionReverse); err != nil {
+		return nil, err
+	}
+	if transactionID <= 0 {
+		return nil, &apperror.ErrValidation{Message: "Please provide a valid transaction ID"}
+	}
+	if amount != nil && !amount.IsPositive() {
+		return nil, &apperror.ErrValidation{Message: "Reversal amount must be greater than zero"}
+	}
+	if len(idempotencyKey) > maxIdempotencyKeyLength {
+		return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Idempotency-Key cannot be longer than %d characters", maxIdempotencyKeyLength)}
+	}
+
+	var txn *model.Transaction
+	err := s.withRetry(func() error {
+		var err error
+		txn, err = s.executeReversal(ctx, transactionID, amount, idempotencyKey)
 		return err
+	})
+	return txn, err
+}
+
+func (s *TransferService) executeReversal(ctx context.Context, transactionID int64, amount *decimal.Decimal, idempotencyKey string) (*model.Transaction, error) {
+	tx, err := s.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if idempotencyKey != "" {
+		replay, err := s.claimIdempotencyKey(ctx, tx, idempotencyKey, reversalFingerprint(transactionID, amount))
+		if err != nil || replay != nil {
+			return replay, err
+		}
+	}
+
+	// locking the original serializes concurrent reversals of it
+	original, err := s.transactionRepo.GetByIDForUpdate(ctx, tx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	if original.Kind != model.TransactionKindTransfer && original.Kind != model.TransactionKindCapture {
+		return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Only transfers and captures can be reversed; this is a %s transaction", original.Kind)}
+	}
+
+	// amount is expressed in the original source currency, i.e. what the
+	// original source gets back
+	debited, credited, err := s.transactionRepo.SumReversals(ctx, tx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+	remaining := original.Amount.Sub(credited)
+	if !remaining.IsPositive() {
+		return nil, &apperror.ErrValidation{Message: "This transfer has already been fully reversed"}
+	}
+
+	refund := remaining
+	if amount != nil {
+		if !currency.HasValidPrecision(*amount, original.Currency) {
+			return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Reversal amount can only have up to %d decimal places in %s", currency.MinorUnits(original.Currency), original.Currency)}
+		}
+		if amount.GreaterThan(remaining) {
+			return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Reversal amount cannot exceed the remaining reversible amount of %s %s", remaining, original.Currency)}
+		}
+		refund = *amount
+	}
+
+	// what the original destination gives back, in its own currency; a full
+	// reversal returns exactly what is left so rounding never drifts
+	takeBack := original.DestinationAmount.Sub(debited)
+	if refund.LessThan(remaining) {
+		takeBack = currency.Round(refund.Mul(original.DestinationAmount).Div(original.Amount), original.DestinationCurrency)
+		if !takeBack.IsPositive() {
+			return nil, &apperror.ErrValidation{Message: "Reversal amount is too small to convert back"}
+		}
+	}
+
+	// money flows back from the original destination to the original source
+	accounts, err := s.ledger.lockAccounts(ctx, tx, original.DestinationAccountID, original.SourceAccountID)
+	if err != nil {
+		return nil, err
+	}
+	sourceAccount, destAccount := accounts[original.DestinationAccountID], accounts[original.SourceAccountID]
+	// only the recipient of a transfer may give the money back
+	if err = checkOwner(ctx, sourceAccount); err != nil {
+		return nil, err
+	}
+	if err = requireActive(sourceAccount, destAccount); err != nil {
+		return nil, err
+	}
+	if sourceAccount.AvailableBalance().LessThan(takeBack) {
+		return nil, &apperror.ErrReversalInsufficientFunds{TransactionID: transactionID, AccountID: sourceAccount.AccountID}
+	}
+
+	txn := &model.Transaction{
+		Kind:              model.TransactionKindReversal,
+		Amount:            takeBack,
+		DestinationAmount: refund,
+		ReversalOf:        &original.ID,
+	}
+	if original.FXRate.Valid {
+		txn.FXRate = decimal.NewNullDecimal(refund.Div(takeBack))
+	}
+	if err = s.ledger.post(ctx, tx, sourceAccount, destAccount, txn); err != nil {
+		return nil, err
+	}
+
+	if idempotencyKey != "" {
+		if err = s.idempotencyRepo.Complete(ctx, tx, actorID(ctx), idempotencyKey, txn.ID); err != nil {
+			return nil, err
+		}
 	}
 
 	if err = tx.Commit(ctx); err != nil {
-		return fmt.Errorf("committing transfer: %w", err)
+		return nil, fmt.Errorf("committing reversal: %w", err)
 	}
 
-	s.logger.Info("transfer completed", "source", sourceID, "destination", destID, "amount", amount.String())
-	return nil
+	s.logger.Info("transfer reversed", "transaction_id", txn.ID, "reversal_of", original.ID, "amount", refund.String(), "actor", actor(ctx))
+	return txn, nil
+}
+
+func (s *TransferService) GetTransaction(ctx context.Context, id int64) (*model.Transaction, error) {
+	if err := authorize(ctx, auth.ActionReadAccounts); err != nil {
+		return nil, err
+	}
+	if id <= 0 {
+		return nil, &apperror.ErrValidation{Message: "Please provide a valid transaction ID"}
+	}
+
+	txn, err := s.transactionRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching transaction: %w", err)
+	}
+	// end users can see transactions on either side of their accounts
+	if err = requireOwner(ctx, s.accountRepo, txn.SourceAccountID); err != nil {
+		if requireOwner(ctx, s.accountRepo, txn.DestinationAccountID) != nil {
+			return nil, err
+		}
+	}
+	if err = s.loadFee(ctx, txn); err != nil {
+		return nil, fmt.Errorf("fetching fee: %w", err)
+	}
+	return txn, nil
+}
+
+// ListAccountTransactions returns one page of an account's history together
+// with the cursor for the next page, which is nil on the last page.
+func (s *TransferService) ListAccountTransactions(ctx context.Context, f model.TransactionFilter) ([]model.Transaction, *model.TransactionCursor, error) {
+	if err := authorize(ctx, auth.ActionReadAccounts); err != nil {
+		return nil, nil, err
+	}
+	if !validAccountID(f.AccountID, s.checkDigit) {
+		return nil, nil, &apperror.ErrValidation{Message: "Please provide a valid account number"}
+	}
+	switch f.Direction {
+	case "":
+		f.Direction = model.DirectionBoth
+	case model.DirectionIncoming, model.DirectionOutgoing, model.DirectionBoth:
+	default:
+		return nil, nil, &apperror.ErrValidation{Message: "Direction must be one of: incoming, outgoing, both"}
+	}
+	if f.From != nil && f.To != nil && !f.From.Before(*f.To) {
+		return nil, nil, &apperror.ErrValidation{Message: "The 'from' date must be before the 'to' date"}
+	}
+	if f.MinAmount != nil && f.MaxAmount != nil && f.MinAmount.GreaterThan(*f.MaxAmount) {
+		return nil, nil, &apperror.ErrValidation{Message: "Minimum amount cannot be greater than maximum amount"}
+	}
+	if f.Limit <= 0 {
+		f.Limit = pagination.DefaultLimit
+	}
+	if f.Limit > pagination.MaxLimit {
+		return nil, nil, &apperror.ErrValidation{Message: fmt.Sprintf("Limit cannot exceed %d", pagination.MaxLimit)}
+	}
+
+	account, err := s.accountRepo.GetByID(ctx, f.AccountID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetching account: %w", err)
+	}
+	if err = checkOwner(ctx, account); err != nil {
+		return nil, nil, err
+	}
+
+	// fetch one extra row to find out whether another page exists
+	pageSize := f.Limit
+	f.Limit++
+	txns, err := s.transactionRepo.ListByAccount(ctx, f)
+	if err != nil {
+		return nil, nil, fmt.Errorf("listing transactions: %w", err)
+	}
+
+	if len(txns) <= pageSize {
+		return txns, nil, nil
+	}
+	txns = txns[:pageSize]
+	last := txns[pageSize-1]
+	return txns, &model.TransactionCursor{CreatedAt: last.CreatedAt, ID: last.ID}, nil
 }
@@ -0,0 +1,990 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"maps"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/auth"
+	"github.com/InternalTransfer/internal/model"
+)
+
+// bankState is everything a fakeBank stores; it is copied when a
+// transaction begins so that a rollback can restore it.
+type bankState struct {
+	accounts      map[int64]model.Account
+	transactions  []model.Transaction
+	entries       []model.LedgerEntry
+	events        []model.OutboxEvent
+	keys          map[[2]string]model.IdempotencyKey
+	statusChanges []model.AccountStatusChange
+	snapshots     []balanceSnapshot
+}
+
+type balanceSnapshot struct {
+	accountID int64
+	takenAt   time.Time
+	balance   decimal.Decimal
+}
+
+func (s bankState) clone() bankState {
+	c := s
+	c.accounts = maps.Clone(s.accounts)
+	c.keys = maps.Clone(s.keys)
+	c.transactions = slices.Clone(s.transactions)
+	c.entries = slices.Clone(s.entries)
+	c.events = slices.Clone(s.events)
+	c.statusChanges = slices.Clone(s.statusChanges)
+	c.snapshots = slices.Clone(s.snapshots)
+	return c
+}
+
+// fakeBank keeps accounts, transactions, ledger entries, outbox events and
+// idempotency keys in memory, following the semantics of the Postgres
+// repositories. Writes apply straight away and are undone when a
+// transaction is rolled back without being committed. Repository methods
+// that no test reaches yet return an error.
+type fakeBank struct {
+	bankState
+	now time.Time
+	// feePolicy prices every transfer when set.
+	feePolicy *model.FeePolicy
+	limits    map[int64]*model.AccountLimits
+	begun     int
+}
+
+func newFakeBank() *fakeBank {
+	return &fakeBank{
+		bankState: bankState{accounts: map[int64]model.Account{}, keys: map[[2]string]model.IdempotencyKey{}},
+		now:       time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC),
+		limits:    map[int64]*model.AccountLimits{},
+	}
+}
+
+func (b *fakeBank) addAccount(id int64, currency, balance string) {
+	b.accounts[id] = model.Account{
+		AccountID: id, Currency: currency, AccountType: model.DefaultAccountType, Status: model.AccountActive,
+		Balance: dec(balance), CreatedAt: b.now, UpdatedAt: b.now,
+	}
+}
+
+func (b *fakeBank) balance(id int64) string {
+	return b.accounts[id].Balance.String()
+}
+
+// tick returns a later timestamp on every call, so that rows have distinct
+// creation times.
+func (b *fakeBank) tick() time.Time {
+	b.now = b.now.Add(time.Second)
+	return b.now
+}
+
+type fakeBankTx struct {
+	pgx.Tx
+	bank  *fakeBank
+	saved bankState
+	done  bool
+}
+
+func (tx *fakeBankTx) Commit(context.Context) error {
+	tx.done = true
+	return nil
+}
+
+func (tx *fakeBankTx) Rollback(context.Context) error {
+	if !tx.done {
+		tx.bank.bankState = tx.saved
+		tx.done = true
+	}
+	return nil
+}
+
+func (b *fakeBank) BeginTx(context.Context) (pgx.Tx, error) {
+	b.begun++
+	return &fakeBankTx{bank: b, saved: b.bankState.clone()}, nil
+}
+
+type fakeAccountRepo struct{ *fakeBank }
+
+func (r fakeAccountRepo) NextID(context.Context, pgx.Tx) (int64, error) {
+	return 0, errors.New("not implemented")
+}
+
+func (r fakeAccountRepo) Create(context.Context, pgx.Tx, *model.Account) error {
+	return errors.New("not implemented")
+}
+
+func (r fakeAccountRepo) GetByID(_ context.Context, id int64) (*model.Account, error) {
+	a, ok := r.accounts[id]
+	if !ok {
+		return nil, &apperror.ErrNotFound{Entity: "account", ID: id}
+	}
+	return &a, nil
+}
+
+func (r fakeAccountRepo) GetByIDForUpdate(ctx context.Context, _ pgx.Tx, id int64) (*model.Account, error) {
+	return r.GetByID(ctx, id)
+}
+
+func (r fakeAccountRepo) List(context.Context, model.AccountFilter) ([]model.Account, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r fakeAccountRepo) GetSystemAccountForUpdate(context.Context, pgx.Tx, string, string) (*model.Account, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (r fakeAccountRepo) update(id int64, fn func(a *model.Account)) error {
+	a, ok := r.accounts[id]
+	if !ok {
+		return &apperror.ErrNotFound{Entity: "account", ID: id}
+	}
+	fn(&a)
+	a.UpdatedAt = r.tick()
+	r.accounts[id] = a
+	return nil
+}
+
+func (r fakeAccountRepo) UpdateBalance(_ context.Context, _ pgx.Tx, id int64, balance decimal.Decimal) error {
+	return r.update(id, func(a *model.Account) { a.Balance = balance })
+}
+
+func (r fakeAccountRepo) UpdateHeldBalance(_ context.Context, _ pgx.Tx, id int64, held decimal.Decimal) error {
+	return r.update(id, func(a *model.Account) { a.HeldBalance = held })
+}
+
+func (r fakeAccountRepo) UpdateDetails(_ context.Context, _ pgx.Tx, a *model.Account) error {
+	return r.update(a.AccountID, func(stored *model.Account) {
+		stored.OwnerID, stored.Name, stored.AccountType, stored.Metadata = a.OwnerID, a.Name, a.AccountType, a.Metadata
+	})
+}
+
+func (r fakeAccountRepo) UpdateStatus(_ context.Context, _ pgx.Tx, id int64, status model.AccountStatus) error {
+	return r.update(id, func(a *model.Account) { a.Status = status })
+}
+
+func (r fakeAccountRepo) CreateStatusChange(_ context.Context, _ pgx.Tx, c *model.AccountStatusChange) error {
+	c.ID, c.CreatedAt = int64(len(r.statusChanges)+1), r.tick()
+	r.statusChanges = append(r.statusChanges, *c)
+	return nil
+}
+
+func (r fakeAccountRepo) ListStatusChanges(_ context.Context, id int64) ([]model.AccountStatusChange, error) {
+	var out []model.AccountStatusChange
+	for _, c := range r.statusChanges {
+		if c.AccountID == id {
+			out = append(out, c)
+		}
+	}
+	return out, nil
+}
+
+type fakeTransactionRepo struct{ *fakeBank }
+
+func (r fakeTransactionRepo) Create(_ context.Context, _ pgx.Tx, t *model.Transaction) error {
+	if t.ID == 0 {
+		t.ID = int64(len(r.transactions) + 1)
+	}
+	t.CreatedAt = r.tick()
+	stored := *t
+	stored.Fee = nil
+	r.transactions = append(r.transactions, stored)
+	return nil
+}
+
+func (r fakeTransactionRepo) GetByID(_ context.Context, id int64) (*model.Transaction, error) {
+	for _, t := range r.transactions {
+		if t.ID == id {
+			return &t, nil
+		}
+	}
+	return nil, &apperror.ErrNotFound{Entity: "transaction", ID: id}
+}
+
+func (r fakeTransactionRepo) GetFee(_ context.Context, id int64) (*model.Transaction, error) {
+	for _, t := range r.transactions {
+		if t.FeeOf != nil && *t.FeeOf == id {
+			return &t, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r fakeTransactionRepo) GetByIDForUpdate(ctx context.Context, _ pgx.Tx, id int64) (*model.Transaction, error) {
+	return r.GetByID(ctx, id)
+}
+
+func (r fakeTransactionRepo) SumReversals(_ context.Context, _ pgx.Tx, id int64) (debited, credited decimal.Decimal, err error) {
+	for _, t := range r.transactions {
+		if t.ReversalOf != nil && *t.ReversalOf == id {
+			debited, credited = debited.Add(t.Amount), credited.Add(t.DestinationAmount)
+		}
+	}
+	return debited, credited, nil
+}
+
+// ListByAccount pages newest first by (created_at, id), like the repository.
+func (r fakeTransactionRepo) ListByAccount(_ context.Context, f model.TransactionFilter) ([]model.Transaction, error) {
+	var out []model.Transaction
+	for _, t := range slices.Backward(r.transactions) {
+		incoming, outgoing := t.DestinationAccountID == f.AccountID, t.SourceAccountID == f.AccountID
+		switch {
+		case f.Direction == model.DirectionIncoming && !incoming,
+			f.Direction == model.DirectionOutgoing && !outgoing,
+			!incoming && !outgoing:
+			continue
+		}
+		if f.After != nil && (t.CreatedAt.After(f.After.CreatedAt) ||
+			t.CreatedAt.Equal(f.After.CreatedAt) && t.ID >= f.After.ID) {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out[:min(len(out), f.Limit)], nil
+}
+
+func (r fakeTransactionRepo) OutgoingUsage(_ context.Context, _ pgx.Tx, accountID int64, dayStart, monthStart, hourStart time.Time) (model.LimitUsage, error) {
+	var u model.LimitUsage
+	for _, t := range r.transactions {
+		if t.SourceAccountID != accountID || (t.Kind != model.TransactionKindTransfer && t.Kind != model.TransactionKindCapture) {
+			continue
+		}
+		if !t.CreatedAt.Before(dayStart) {
+			u.DailyAmount = u.DailyAmount.Add(t.Amount)
+		}
+		if !t.CreatedAt.Before(monthStart) {
+			u.MonthlyAmount = u.MonthlyAmount.Add(t.Amount)
+		}
+		if !t.CreatedAt.Before(hourStart) {
+			u.HourlyCount++
+		}
+	}
+	return u, nil
+}
+
+func (r fakeTransactionRepo) StreamStatement(context.Context, int64, time.Time, time.Time, func(*model.StatementLine) error) error {
+	return errors.New("not implemented")
+}
+
+type fakeLedgerRepo struct{ *fakeBank }
+
+func (r fakeLedgerRepo) CreateEntries(_ context.Context, _ pgx.Tx, entries []model.LedgerEntry) error {
+	for _, e := range entries {
+		e.ID, e.CreatedAt = int64(len(r.entries)+1), r.now
+		r.entries = append(r.entries, e)
+	}
+	return nil
+}
+
+func (r fakeLedgerRepo) BalanceAt(_ context.Context, accountID int64, asOf time.Time) (decimal.Decimal, error) {
+	return r.balanceAt(accountID, asOf, true), nil
+}
+
+// balanceAt adds the entries up to asOf to the latest snapshot taken at or,
+// unless inclusive is false, before it.
+func (r fakeLedgerRepo) balanceAt(accountID int64, asOf time.Time, inclusive bool) decimal.Decimal {
+	var from time.Time
+	balance := decimal.Zero
+	for _, snap := range r.snapshots {
+		if snap.accountID == accountID && snap.takenAt.After(from) &&
+			(snap.takenAt.Before(asOf) || inclusive && snap.takenAt.Equal(asOf)) {
+			from, balance = snap.takenAt, snap.balance
+		}
+	}
+	for _, e := range r.entries {
+		if e.AccountID != accountID || !e.CreatedAt.After(from) || e.CreatedAt.After(asOf) {
+			continue
+		}
+		if e.Direction == model.EntryCredit {
+			balance = balance.Add(e.Amount)
+		} else {
+			balance = balance.Sub(e.Amount)
+		}
+	}
+	return balance
+}
+
+func (r fakeLedgerRepo) CreateSnapshots(_ context.Context, takenAt time.Time) (int64, error) {
+	var n int64
+	for _, id := range slices.Sorted(maps.Keys(r.accounts)) {
+		taken := slices.ContainsFunc(r.snapshots, func(s balanceSnapshot) bool {
+			return s.accountID == id && s.takenAt.Equal(takenAt)
+		})
+		if taken || r.accounts[id].CreatedAt.After(takenAt) {
+			continue
+		}
+		r.snapshots = append(r.snapshots, balanceSnapshot{accountID: id, takenAt: takenAt, balance: r.balanceAt(id, takenAt, false)})
+		n++
+	}
+	return n, nil
+}
+
+func (r fakeLedgerRepo) ListEventsAfter(_ context.Context, accountID, afterID int64, limit int) ([]model.AccountEvent, error) {
+	var out []model.AccountEvent
+	for _, e := range r.entries {
+		if e.AccountID != accountID || e.ID <= afterID || len(out) == limit {
+			continue
+		}
+		t := r.transactions[e.TransactionID-1]
+		event := model.AccountEvent{ID: e.ID, AccountID: e.AccountID, TransactionID: e.TransactionID, Kind: t.Kind,
+			Direction: e.Direction, Amount: e.Amount, Currency: t.DestinationCurrency, CounterpartyAccountID: t.SourceAccountID,
+			Balance: e.BalanceAfter, CreatedAt: e.CreatedAt}
+		if e.Direction == model.EntryDebit {
+			event.Currency, event.CounterpartyAccountID = t.Currency, t.DestinationAccountID
+		}
+		out = append(out, event)
+	}
+	return out, nil
+}
+
+type fakeOutboxRepo struct{ *fakeBank }
+
+func (r fakeOutboxRepo) Create(_ context.Context, _ pgx.Tx, e *model.OutboxEvent) error {
+	e.ID, e.CreatedAt = int64(len(r.events)+1), r.now
+	r.events = append(r.events, *e)
+	return nil
+}
+
+func (r fakeOutboxRepo) ClaimDue(context.Context, pgx.Tx, int, time.Duration) ([]model.OutboxEvent, error) {
+	return nil, nil
+}
+
+func (r fakeOutboxRepo) MarkDelivered(context.Context, pgx.Tx, *model.OutboxEvent) (bool, error) {
+	return false, nil
+}
+
+func (r fakeOutboxRepo) MarkFailed(context.Context, pgx.Tx, *model.OutboxEvent, string, time.Time, bool) (bool, error) {
+	return false, nil
+}
+
+type fakeIdempotencyRepo struct{ *fakeBank }
+
+func (r fakeIdempotencyRepo) Claim(_ context.Context, _ pgx.Tx, principal, key, requestHash string) (*model.IdempotencyKey, error) {
+	if k, ok := r.keys[[2]string{principal, key}]; ok {
+		return &k, nil
+	}
+	r.keys[[2]string{principal, key}] = model.IdempotencyKey{Principal: principal, Key: key, RequestHash: requestHash, CreatedAt: r.now}
+	return nil, nil
+}
+
+func (r fakeIdempotencyRepo) Complete(_ context.Context, _ pgx.Tx, principal, key string, transactionID int64) error {
+	k := r.keys[[2]string{principal, key}]
+	k.TransactionID = &transactionID
+	r.keys[[2]string{principal, key}] = k
+	return nil
+}
+
+func (r fakeIdempotencyRepo) CompleteApproval(_ context.Context, _ pgx.Tx, principal, key string, approvalID int64) error {
+	k := r.keys[[2]string{principal, key}]
+	k.ApprovalID = &approvalID
+	r.keys[[2]string{principal, key}] = k
+	return nil
+}
+
+type fakeFeePolicyRepo struct{ *fakeBank }
+
+func (r fakeFeePolicyRepo) Create(context.Context, *model.FeePolicy) error { return nil }
+
+func (r fakeFeePolicyRepo) GetByID(_ context.Context, id int64) (*model.FeePolicy, error) {
+	return nil, &apperror.ErrNotFound{Entity: "fee policy", ID: id}
+}
+
+func (r fakeFeePolicyRepo) List(context.Context) ([]model.FeePolicy, error) { return nil, nil }
+
+func (r fakeFeePolicyRepo) Update(context.Context, *model.FeePolicy) error { return nil }
+
+func (r fakeFeePolicyRepo) Delete(context.Context, int64) error { return nil }
+
+func (r fakeFeePolicyRepo) FindForTransfer(context.Context, pgx.Tx, int64, int64, string) (*model.FeePolicy, error) {
+	return r.feePolicy, nil
+}
+
+type fakeLimitRepo struct{ *fakeBank }
+
+func (r fakeLimitRepo) Get(_ context.Context, id int64) (*model.AccountLimits, error) {
+	return r.limits[id], nil
+}
+
+func (r fakeLimitRepo) GetForTransfer(ctx context.Context, _ pgx.Tx, id int64) (*model.AccountLimits, error) {
+	return r.Get(ctx, id)
+}
+
+func (r fakeLimitRepo) Upsert(_ context.Context, l *model.AccountLimits) error {
+	r.limits[l.AccountID] = l
+	return nil
+}
+
+// feeAccountID is the fee collection account of services built by
+// newTestTransferService.
+const feeAccountID = 900
+
+func newTestTransferService(bank *fakeBank, approvals ApprovalPolicy) *TransferService {
+	return NewTransferService(fakeAccountRepo{bank}, fakeTransactionRepo{bank}, fakeLedgerRepo{bank}, fakeOutboxRepo{bank},
+		fakeIdempotencyRepo{bank}, nil, fakeFeePolicyRepo{bank}, fakeLimitRepo{bank}, nil, bank,
+		slog.New(slog.NewTextHandler(io.Discard, nil)), 1_000_000, feeAccountID, false, approvals)
+}
+
+func transfer(source, dest int64, amount string) model.TransferRequest {
+	return model.TransferRequest{SourceAccountID: source, DestinationAccountID: dest, Amount: dec(amount), Currency: "USD"}
+}
+
+func asPrincipal(id string) context.Context {
+	return auth.WithPrincipal(context.Background(), &auth.Principal{
+		ID: id, Name: id, Role: auth.RoleAdmin, Scopes: []auth.Scope{auth.ScopeAdmin},
+	})
+}
+
+func TestTransferIdempotency(t *testing.T) {
+	tests := []struct {
+		name string
+		// second is sent after first, under the same Idempotency-Key
+		firstCtx, secondCtx context.Context
+		first, second       model.TransferRequest
+		wantReplay          bool
+		wantMismatch        bool
+		wantBalance         string // of account 1 afterwards
+	}{
+		{
+			name:     "same request is replayed",
+			firstCtx: asPrincipal("apikey:1"), secondCtx: asPrincipal("apikey:1"),
+			first: transfer(1, 2, "10"), second: transfer(1, 2, "10"),
+			wantReplay: true, wantBalance: "90",
+		},
+		{
+			name:     "currency case does not change the fingerprint",
+			firstCtx: asPrincipal("apikey:1"), secondCtx: asPrincipal("apikey:1"),
+			first: transfer(1, 2, "10"), second: model.TransferRequest{SourceAccountID: 1, DestinationAccountID: 2, Amount: dec("10"), Currency: "usd"},
+			wantReplay: true, wantBalance: "90",
+		},
+		{
+			name:     "different amount is rejected",
+			firstCtx: asPrincipal("apikey:1"), secondCtx: asPrincipal("apikey:1"),
+			first: transfer(1, 2, "10"), second: transfer(1, 2, "11"),
+			wantMismatch: true, wantBalance: "90",
+		},
+		{
+			name:     "different destination is rejected",
+			firstCtx: asPrincipal("apikey:1"), secondCtx: asPrincipal("apikey:1"),
+			first: transfer(1, 2, "10"), second: transfer(1, 3, "10"),
+			wantMismatch: true, wantBalance: "90",
+		},
+		{
+			name:     "another principal's key does not collide",
+			firstCtx: asPrincipal("apikey:1"), secondCtx: asPrincipal("apikey:2"),
+			first: transfer(1, 2, "10"), second: transfer(1, 3, "25"),
+			wantBalance: "65",
+		},
+		{
+			name:     "a client cannot replay a scheduled run",
+			firstCtx: context.Background(), secondCtx: asPrincipal("apikey:1"),
+			first: transfer(1, 2, "10"), second: transfer(1, 2, "10"),
+			wantBalance: "80",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bank := newFakeBank()
+			bank.addAccount(1, "USD", "100")
+			bank.addAccount(2, "USD", "0")
+			bank.addAccount(3, "USD", "0")
+			s := newTestTransferService(bank, ApprovalPolicy{})
+
+			first, err := s.Transfer(tt.firstCtx, tt.first, "key-1")
+			if err != nil {
+				t.Fatalf("first Transfer: %v", err)
+			}
+			second, err := s.Transfer(tt.secondCtx, tt.second, "key-1")
+
+			var mismatch *apperror.ErrIdempotencyMismatch
+			if got := errors.As(err, &mismatch); got != tt.wantMismatch {
+				t.Fatalf("second Transfer error = %v, want mismatch %v", err, tt.wantMismatch)
+			}
+			if !tt.wantMismatch {
+				if err != nil {
+					t.Fatalf("second Transfer: %v", err)
+				}
+				if replayed := second.ID == first.ID; replayed != tt.wantReplay {
+					t.Errorf("second transaction %d, first %d; want replay %v", second.ID, first.ID, tt.wantReplay)
+				}
+			}
+			if got := bank.balance(1); got != tt.wantBalance {
+				t.Errorf("source balance = %s, want %s", got, tt.wantBalance)
+			}
+		})
+	}
+}
+
+func TestTransferIdempotencyKeyReleasedOnFailure(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "5")
+	bank.addAccount(2, "USD", "0")
+	s := newTestTransferService(bank, ApprovalPolicy{})
+	ctx := asPrincipal("apikey:1")
+
+	var insufficient *apperror.ErrInsufficientBalance
+	if _, err := s.Transfer(ctx, transfer(1, 2, "10"), "key-1"); !errors.As(err, &insufficient) {
+		t.Fatalf("Transfer error = %v, want ErrInsufficientBalance", err)
+	}
+	if len(bank.keys) != 0 {
+		t.Fatalf("failed transfer kept its idempotency key: %v", bank.keys)
+	}
+
+	// the rolled-back claim does not block a corrected retry
+	if _, err := s.Transfer(ctx, transfer(1, 2, "5"), "key-1"); err != nil {
+		t.Fatalf("retry: %v", err)
+	}
+	if got := bank.balance(2); got != "5" {
+		t.Errorf("destination balance = %s, want 5", got)
+	}
+}
+
+func TestTransferRejectsLongIdempotencyKey(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "100")
+	bank.addAccount(2, "USD", "0")
+	s := newTestTransferService(bank, ApprovalPolicy{})
+
+	key := string(make([]byte, maxIdempotencyKeyLength+1))
+	var validation *apperror.ErrValidation
+	if _, err := s.Transfer(context.Background(), transfer(1, 2, "10"), key); !errors.As(err, &validation) {
+		t.Errorf("Transfer error = %v, want a validation error", err)
+	}
+	if bank.begun != 0 {
+		t.Errorf("began %d transactions for an invalid request", bank.begun)
+	}
+}
+
+func TestTransferFeeAccountStatus(t *testing.T) {
+	tests := []struct {
+		status      model.AccountStatus
+		wantErr     bool
+		wantBalance string // of the source afterwards
+	}{
+		{model.AccountActive, false, "89"},
+		{model.AccountFrozen, true, "100"},
+		{model.AccountClosed, true, "100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.status), func(t *testing.T) {
+			bank := newFakeBank()
+			bank.addAccount(1, "USD", "100")
+			bank.addAccount(2, "USD", "0")
+			bank.addAccount(feeAccountID, "USD", "0")
+			fees := bank.accounts[feeAccountID]
+			fees.Status = tt.status
+			bank.accounts[feeAccountID] = fees
+			bank.feePolicy = &model.FeePolicy{ID: 1, Currency: "USD", FeeType: model.FeeFlat, FlatAmount: nullDec("1")}
+			s := newTestTransferService(bank, ApprovalPolicy{})
+
+			txn, err := s.Transfer(context.Background(), transfer(1, 2, "10"), "")
+			var inactive *apperror.ErrAccountInactive
+			if tt.wantErr {
+				if !errors.As(err, &inactive) || inactive.AccountID != feeAccountID {
+					t.Fatalf("Transfer error = %v, want the fee account to be inactive", err)
+				}
+			} else if err != nil || txn.Fee == nil || !txn.Fee.Amount.Equal(dec("1")) {
+				t.Fatalf("Transfer = %+v, %v; want a fee of 1", txn, err)
+			}
+			if got := bank.balance(1); got != tt.wantBalance {
+				t.Errorf("source balance = %s, want %s", got, tt.wantBalance)
+			}
+		})
+	}
+}
+
+func TestListAccountTransactionsPages(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "100")
+	bank.addAccount(2, "USD", "100")
+	s := newTestTransferService(bank, ApprovalPolicy{})
+	for i, req := range []model.TransferRequest{
+		transfer(1, 2, "1"), transfer(2, 1, "2"), transfer(1, 2, "3"), transfer(2, 1, "4"), transfer(1, 2, "5"),
+	} {
+		if _, err := s.Transfer(context.Background(), req, ""); err != nil {
+			t.Fatalf("transfer %d: %v", i, err)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		direction model.TransactionDirection
+		limit     int
+		wantPages [][]int64
+	}{
+		{"both directions", "", 2, [][]int64{{5, 4}, {3, 2}, {1}}},
+		{"exact pages", model.DirectionBoth, 5, [][]int64{{5, 4, 3, 2, 1}}},
+		{"outgoing", model.DirectionOutgoing, 2, [][]int64{{5, 3}, {1}}},
+		{"incoming", model.DirectionIncoming, 2, [][]int64{{4, 2}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := model.TransactionFilter{AccountID: 1, Direction: tt.direction, Limit: tt.limit}
+			var pages [][]int64
+			for {
+				txns, next, err := s.ListAccountTransactions(context.Background(), f)
+				if err != nil {
+					t.Fatalf("ListAccountTransactions: %v", err)
+				}
+				var ids []int64
+				for _, txn := range txns {
+					ids = append(ids, txn.ID)
+				}
+				pages = append(pages, ids)
+				if next == nil {
+					break
+				}
+				if len(pages) > len(tt.wantPages) {
+					t.Fatalf("pages = %v and counting, want %v", pages, tt.wantPages)
+				}
+				f.After = next
+			}
+			if !slices.EqualFunc(pages, tt.wantPages, slices.Equal) {
+				t.Errorf("pages = %v, want %v", pages, tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestListAccountTransactionsValidation(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "100")
+	s := newTestTransferService(bank, ApprovalPolicy{})
+	from := bank.now
+	to := from.Add(-time.Hour)
+
+	tests := []struct {
+		name string
+		f    model.TransactionFilter
+	}{
+		{"invalid account", model.TransactionFilter{AccountID: 0}},
+		{"unknown direction", model.TransactionFilter{AccountID: 1, Direction: "sideways"}},
+		{"from after to", model.TransactionFilter{AccountID: 1, From: &from, To: &to}},
+		{"from equals to", model.TransactionFilter{AccountID: 1, From: &from, To: &from}},
+		{"min above max", model.TransactionFilter{AccountID: 1, MinAmount: decPtr("10"), MaxAmount: decPtr("5")}},
+		{"limit too large", model.TransactionFilter{AccountID: 1, Limit: 201}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var validation *apperror.ErrValidation
+			if _, _, err := s.ListAccountTransactions(context.Background(), tt.f); !errors.As(err, &validation) {
+				t.Errorf("error = %v, want a validation error", err)
+			}
+		})
+	}
+}
+
+func TestGetTransaction(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "100")
+	bank.addAccount(2, "USD", "0")
+	bank.addAccount(feeAccountID, "USD", "0")
+	bank.feePolicy = &model.FeePolicy{ID: 1, Currency: "USD", FeeType: model.FeeFlat, FlatAmount: nullDec("1")}
+	s := newTestTransferService(bank, ApprovalPolicy{})
+	posted, err := s.Transfer(context.Background(), transfer(1, 2, "10"), "")
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		id      int64
+		wantErr any
+		wantFee bool
+	}{
+		{"transfer with its fee", posted.ID, nil, true},
+		{"fee leg", posted.Fee.ID, nil, false},
+		{"unknown", 99, &apperror.ErrNotFound{}, false},
+		{"invalid", 0, &apperror.ErrValidation{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txn, err := s.GetTransaction(context.Background(), tt.id)
+			switch want := tt.wantErr.(type) {
+			case *apperror.ErrNotFound:
+				if !errors.As(err, &want) {
+					t.Fatalf("error = %v, want ErrNotFound", err)
+				}
+				return
+			case *apperror.ErrValidation:
+				if !errors.As(err, &want) {
+					t.Fatalf("error = %v, want ErrValidation", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("GetTransaction: %v", err)
+			}
+			if txn.ID != tt.id {
+				t.Errorf("ID = %d, want %d", txn.ID, tt.id)
+			}
+			if gotFee := txn.Fee != nil; gotFee != tt.wantFee {
+				t.Errorf("fee attached = %v, want %v", gotFee, tt.wantFee)
+			}
+		})
+	}
+}
+
+func TestTransactionReadsRequireReadScope(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "100")
+	s := newTestTransferService(bank, ApprovalPolicy{})
+	ctx := auth.WithPrincipal(context.Background(),
+		&auth.Principal{ID: "apikey:1", Name: "apikey:payouts", Role: auth.RoleOperator, Scopes: []auth.Scope{auth.ScopeTransfersWrite}})
+
+	reads := []struct {
+		name string
+		call func() error
+	}{
+		{"get", func() error {
+			_, err := s.GetTransaction(ctx, 1)
+			return err
+		}},
+		{"list by account", func() error {
+			_, _, err := s.ListAccountTransactions(ctx, model.TransactionFilter{AccountID: 1})
+			return err
+		}},
+		{"get approval", func() error {
+			_, err := s.GetApproval(ctx, 1)
+			return err
+		}},
+		{"list approvals", func() error {
+			_, _, err := s.ListApprovals(ctx, model.ApprovalFilter{})
+			return err
+		}},
+	}
+
+	for _, tt := range reads {
+		t.Run(tt.name, func(t *testing.T) {
+			var forbidden *apperror.ErrForbidden
+			if err := tt.call(); !errors.As(err, &forbidden) {
+				t.Errorf("error = %v, want ErrForbidden", err)
+			}
+		})
+	}
+}
+
+func TestReverse(t *testing.T) {
+	type step struct {
+		amount  *decimal.Decimal // nil reverses the rest
+		wantErr bool
+		want    string // amount given back
+	}
+	tests := []struct {
+		name                 string
+		steps                []step
+		wantSource, wantDest string
+	}{
+		{"full", []step{{nil, false, "100"}, {nil, true, ""}}, "1000", "0"},
+		{"partial then the rest", []step{{decPtr("30"), false, "30"}, {nil, false, "70"}}, "1000", "0"},
+		{"partials capped at the original", []step{
+			{decPtr("60"), false, "60"},
+			{decPtr("50"), true, ""},
+			{decPtr("40"), false, "40"},
+			{decPtr("0.01"), true, ""},
+		}, "1000", "0"},
+		{"partial only", []step{{decPtr("25.50"), false, "25.5"}}, "925.5", "74.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bank := newFakeBank()
+			bank.addAccount(1, "USD", "1000")
+			bank.addAccount(2, "USD", "0")
+			s := newTestTransferService(bank, ApprovalPolicy{})
+			original, err := s.Transfer(context.Background(), transfer(1, 2, "100"), "")
+			if err != nil {
+				t.Fatalf("Transfer: %v", err)
+			}
+
+			for i, st := range tt.steps {
+				txn, err := s.Reverse(context.Background(), original.ID, st.amount, "")
+				if st.wantErr {
+					var validation *apperror.ErrValidation
+					if !errors.As(err, &validation) {
+						t.Fatalf("step %d: error = %v, want a validation error", i, err)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("step %d: Reverse: %v", i, err)
+				}
+				if txn.Kind != model.TransactionKindReversal || *txn.ReversalOf != original.ID ||
+					txn.SourceAccountID != 2 || txn.DestinationAccountID != 1 || txn.Amount.String() != st.want {
+					t.Errorf("step %d: reversal = %+v, want %s from 2 back to 1", i, txn, st.want)
+				}
+			}
+			if got := bank.balance(1); got != tt.wantSource {
+				t.Errorf("source balance = %s, want %s", got, tt.wantSource)
+			}
+			if got := bank.balance(2); got != tt.wantDest {
+				t.Errorf("destination balance = %s, want %s", got, tt.wantDest)
+			}
+		})
+	}
+}
+
+func TestReverseFX(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "900")
+	bank.addAccount(2, "EUR", "92.35")
+	bank.transactions = append(bank.transactions, model.Transaction{
+		ID: 1, Kind: model.TransactionKindTransfer, SourceAccountID: 1, DestinationAccountID: 2,
+		Amount: dec("100"), Currency: "USD", DestinationAmount: dec("92.35"), DestinationCurrency: "EUR",
+		FXRate: nullDec("0.9235"), CreatedAt: bank.tick(),
+	})
+	s := newTestTransferService(bank, ApprovalPolicy{})
+
+	// 33.33 USD is 30.780255 EUR at the original rate, which rounds down
+	partial, err := s.Reverse(context.Background(), 1, decPtr("33.33"), "")
+	if err != nil {
+		t.Fatalf("partial Reverse: %v", err)
+	}
+	if partial.Amount.String() != "30.78" || partial.DestinationAmount.String() != "33.33" || !partial.FXRate.Valid {
+		t.Errorf("partial reversal = %s EUR for %s USD (rate %v), want 30.78 for 33.33", partial.Amount, partial.DestinationAmount, partial.FXRate)
+	}
+
+	// the rest takes back exactly what is left, so rounding never strands cents
+	rest, err := s.Reverse(context.Background(), 1, nil, "")
+	if err != nil {
+		t.Fatalf("Reverse of the rest: %v", err)
+	}
+	if rest.Amount.String() != "61.57" || rest.DestinationAmount.String() != "66.67" {
+		t.Errorf("final reversal = %s EUR for %s USD, want 61.57 for 66.67", rest.Amount, rest.DestinationAmount)
+	}
+	if bank.balance(1) != "1000" || bank.balance(2) != "0" {
+		t.Errorf("balances = %s USD, %s EUR; want 1000 and 0", bank.balance(1), bank.balance(2))
+	}
+}
+
+func TestReverseSpentFunds(t *testing.T) {
+	bank := newFakeBank()
+	bank.addAccount(1, "USD", "100")
+	bank.addAccount(2, "USD", "0")
+	bank.addAccount(3, "USD", "0")
+	s := newTestTransferService(bank, ApprovalPolicy{})
+	original, err := s.Transfer(context.Background(), transfer(1, 2, "100"), "")
+	if err != nil {
+		t.Fatalf("Transfer: %v", err)
+	}
+	if _, err = s.Transfer(context.Background(), transfer(2, 3, "80"), ""); err != nil {
+		t.Fatalf("spending Transfer: %v", err)
+	}
+
+	var insufficient *apperror.ErrReversalInsufficientFunds
+	if _, err = s.Reverse(context.Background(), original.ID, nil, ""); !errors.As(err, &insufficient) || insufficient.AccountID != 2 {
+		t.Fatalf("Reverse error = %v, want ErrReversalInsufficientFunds on account 2", err)
+	}
+	if bank.balance(1) != "0" || bank.balance(2) != "20" {
+		t.Errorf("balances after refusal = %s, %s; want 0, 20", bank.balance(1), bank.balance(2))
+	}
+
+	// what is left can still be given back
+	if _, err = s.Reverse(context.Background(), original.ID, decPtr("20"), ""); err != nil {
+		t.Fatalf("Reverse of the unspent part: %v", err)
+	}
+	if bank.balance(1) != "20" || bank.balance(2) != "0" {
+		t.Errorf("balances = %s, %s; want 20, 0", bank.balance(1), bank.balance(2))
+	}
+}
+
+func TestTransferBatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		legs    []model.TransferRequest
+		limits  *model.AccountLimits
+		wantLeg int // that fails, or -1
+		wantErr any
+		want    map[int64]string // balances afterwards
+	}{
+		{
+			name:    "all legs post",
+			legs:    []model.TransferRequest{transfer(1, 2, "30"), transfer(1, 3, "20")},
+			wantLeg: -1,
+			want:    map[int64]string{1: "50", 2: "30", 3: "20"},
+		},
+		{
+			name:    "a later leg spends an earlier credit",
+			legs:    []model.TransferRequest{transfer(1, 2, "100"), transfer(2, 3, "100")},
+			wantLeg: -1,
+			want:    map[int64]string{1: "0", 2: "0", 3: "100"},
+		},
+		{
+			name:    "a failing later leg rolls back the batch",
+			legs:    []model.TransferRequest{transfer(1, 2, "60"), transfer(1, 3, "60")},
+			wantLeg: 1,
+			wantErr: &apperror.ErrInsufficientBalance{},
+			want:    map[int64]string{1: "100", 2: "0", 3: "0"},
+		},
+		{
+			name:    "limits add up across legs",
+			legs:    []model.TransferRequest{transfer(1, 2, "30"), transfer(1, 3, "30")},
+			limits:  &model.AccountLimits{AccountID: 1, DailyAmount: nullDec("50")},
+			wantLeg: 1,
+			wantErr: &apperror.ErrLimitExceeded{},
+			want:    map[int64]string{1: "100", 2: "0", 3: "0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bank := newFakeBank()
+			bank.addAccount(1, "USD", "100")
+			bank.addAccount(2, "USD", "0")
+			bank.addAccount(3, "USD", "0")
+			if tt.limits != nil {
+				bank.limits[1] = tt.limits
+			}
+			s := newTestTransferService(bank, ApprovalPolicy{})
+
+			txns, err := s.TransferBatch(context.Background(), tt.legs)
+			if tt.wantLeg < 0 {
+				if err != nil || len(txns) != len(tt.legs) {
+					t.Fatalf("TransferBatch = %d transactions, %v; want %d", len(txns), err, len(tt.legs))
+				}
+			} else {
+				var legErr *apperror.ErrBatchLeg
+				if !errors.As(err, &legErr) || legErr.Leg != tt.wantLeg {
+					t.Fatalf("TransferBatch error = %v, want leg %d to fail", err, tt.wantLeg)
+				}
+				if reflect.TypeOf(legErr.Err) != reflect.TypeOf(tt.wantErr) {
+					t.Errorf("leg error = %T, want %T", legErr.Err, tt.wantErr)
+				}
+				if len(bank.transactions) != 0 || len(bank.entries) != 0 || len(bank.events) != 0 {
+					t.Errorf("rolled back batch left %d transactions, %d entries, %d events",
+						len(bank.transactions), len(bank.entries), len(bank.events))
+				}
+			}
+			for id, want := range tt.want {
+				if got := bank.balance(id); got != want {
+					t.Errorf("balance of %d = %s, want %s", id, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestBatchLegError(t *testing.T) {
+	internal := errors.New("conn reset")
+	if err := batchLegError(2, internal); err != internal {
+		t.Errorf("internal error = %v, want it returned unwrapped", err)
+	}
+	var legErr *apperror.ErrBatchLeg
+	if err := batchLegError(2, &apperror.ErrInsufficientBalance{AccountID: 1}); !errors.As(err, &legErr) || legErr.Leg != 2 {
+		t.Errorf("application error = %v, want it attributed to leg 2", err)
+	}
+}
@@ -0,0 +1,366 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/auth"
+	"github.com/InternalTransfer/internal/eventsink"
+	"github.com/InternalTransfer/internal/model"
+	"github.com/InternalTransfer/internal/pagination"
+)
+
+const (
+	webhookBatchSize   = 50
+	webhookBackoffBase = 10 * time.Second
+	webhookBackoffMax  = time.Hour
+	// webhookLease hides claimed deliveries from other workers while a batch
+	// is sent; see relayLease.
+	webhookLease = 15 * time.Minute
+	// maxWebhookErrorLength bounds the response text kept in the delivery log.
+	maxWebhookErrorLength = 500
+)
+
+// WebhookService manages webhook subscriptions and delivers events to them.
+// It is also an EventSink: publishing an event queues a delivery for every
+// matching subscription, which RunDeliveries then sends with its own retries.
+type WebhookService struct {
+	webhookRepo  WebhookRepo
+	txBeginner   TxBeginner
+	client       *http.Client
+	logger       *slog.Logger
+	maxAttempts  int
+	disableAfter int
+}
+
+func NewWebhookService(
+	webhookRepo WebhookRepo,
+	txBeginner TxBeginner,
+	client *http.Client,
+	logger *slog.Logger,
+	maxAttempts, disableAfter int,
+) *WebhookService {
+	return &WebhookService{
+		webhookRepo:  webhookRepo,
+		txBeginner:   txBeginner,
+		client:       client,
+		logger:       logger,
+		maxAttempts:  maxAttempts,
+		disableAfter: disableAfter,
+	}
+}
+
+// Create registers a subscription. Empty event types or account IDs match
+// every event or account. The signing secret is generated here and only
+// returned by this call.
+func (s *WebhookService) Create(ctx context.Context, sub model.WebhookSubscription) (*model.WebhookSubscription, error) {
+	if err := authorize(ctx, auth.ActionManageWebhooks); err != nil {
+		return nil, err
+	}
+	sub.URL = strings.TrimSpace(sub.URL)
+	u, err := url.Parse(sub.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, &apperror.ErrValidation{Message: "Please provide an absolute http or https URL"}
+	}
+	for _, t := range sub.EventTypes {
+		if !slices.Contains(eventTypes, t) {
+			return nil, &apperror.ErrValidation{Message: fmt.Sprintf("Event type must be one of: %s", strings.Join(eventTypes, ", "))}
+		}
+	}
+	for _, id := range sub.AccountIDs {
+		if id <= 0 {
+			return nil, &apperror.ErrValidation{Message: "Please provide valid account numbers"}
+		}
+	}
+	// the columns are NOT NULL, and a nil slice is sent as NULL
+	if sub.EventTypes == nil {
+		sub.EventTypes = []string{}
+	}
+	if sub.AccountIDs == nil {
+		sub.AccountIDs = []int64{}
+	}
+
+	secret := make([]byte, 32)
+	if _, err = rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("generating webhook secret: %w", err)
+	}
+	sub.Secret = "whsec_" + hex.EncodeToString(secret)
+
+	if err = s.webhookRepo.Create(ctx, &sub); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("webhook subscription created", "webhook_id", sub.ID, "url", sub.URL, "actor", actor(ctx))
+	return &sub, nil
+}
+
+func (s *WebhookService) GetByID(ctx context.Context, id int64) (*model.WebhookSubscription, error) {
+	if err := authorize(ctx, auth.ActionManageWebhooks); err != nil {
+		return nil, err
+	}
+	if id <= 0 {
+		return nil, &apperror.ErrValidation{Message: "Please provide a valid webhook ID"}
+	}
+
+	sub, err := s.webhookRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("fetching webhook subscription: %w", err)
+	}
+	return sub, nil
+}
+
+func (s *WebhookService) List(ctx context.Context) ([]model.WebhookSubscription, error) {
+	if err := authorize(ctx, auth.ActionManageWebhooks); err != nil {
+		return nil, err
+	}
+	subs, err := s.webhookRepo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing webhook subscriptions: %w", err)
+	}
+	return subs, nil
+}
+
+func (s *WebhookService) Delete(ctx context.Context, id int64) error {
+	if err := authorize(ctx, auth.ActionManageWebhooks); err != nil {
+		return err
+	}
+	if id <= 0 {
+		return &apperror.ErrValidation{Message: "Please provide a valid webhook ID"}
+	}
+	if err := s.webhookRepo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	s.logger.Info("webhook subscription deleted", "webhook_id", id, "actor", actor(ctx))
+	return nil
+}
+
+// Enable reactivates a subscription, typically one disabled after repeated
+// failures. Deliveries still pending are retried; those that were already
+// dead-lettered are not.
+func (s *WebhookService) Enable(ctx context.Context, id int64) (*model.WebhookSubscription, error) {
+	if err := authorize(ctx, auth.ActionManageWebhooks); err != nil {
+		return nil, err
+	}
+	if id <= 0 {
+		return nil, &apperror.ErrValidation{Message: "Please provide a valid webhook ID"}
+	}
+
+	sub, err := s.webhookRepo.Enable(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("webhook subscription enabled", "webhook_id", id, "actor", actor(ctx))
+	return sub, nil
+}
+
+// ListDeliveries returns one page of a subscription's delivery log, newest
+// first, and the ID to continue before, which is zero on the last page.
+func (s *WebhookService) ListDeliveries(ctx context.Context, id, beforeID int64, limit int) ([]model.WebhookDelivery, int64, error) {
+	if err := authorize(ctx, auth.ActionManageWebhooks); err != nil {
+		return nil, 0, err
+	}
+	if limit <= 0 {
+		limit = pagination.DefaultLimit
+	}
+	if limit > pagination.MaxLimit {
+		return nil, 0, &apperror.ErrValidation{Message: fmt.Sprintf("Limit cannot exceed %d", pagination.MaxLimit)}
+	}
+	if _, err := s.GetByID(ctx, id); err != nil {
+		return nil, 0, err
+	}
+
+	deliveries, err := s.webhookRepo.ListDeliveries(ctx, id, beforeID, limit+1)
+	if err != nil {
+		return nil, 0, fmt.Errorf("listing webhook deliveries: %w", err)
+	}
+	if len(deliveries) <= limit {
+		return deliveries, 0, nil
+	}
+	deliveries = deliveries[:limit]
+	return deliveries, deliveries[limit-1].ID, nil
+}
+
+func (s *WebhookService) Name() string {
+	return "webhooks"
+}
+
+// Publish queues e for every matching subscription.
+func (s *WebhookService) Publish(ctx context.Context, e *model.OutboxEvent) error {
+	_, err := s.webhookRepo.EnqueueDeliveries(ctx, e)
+	return err
+}
+
+// DeliverDue sends the deliveries that are due and returns how many were
+// attempted. Deliveries are leased in one short transaction and sent outside
+// of any, and each outcome is recorded in its own transaction.
+func (s *WebhookService) DeliverDue(ctx context.Context) (int, error) {
+	deliveries, err := s.claimDeliveries(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	// a subscription disabled part way through the batch gets no further
+	// attempts in it; its remaining deliveries stay pending
+	disabled := make(map[int64]bool)
+	attempted := 0
+	for i := range deliveries {
+		d := &deliveries[i]
+		if disabled[d.SubscriptionID] {
+			continue
+		}
+		attempted++
+
+		status, sendErr := s.send(ctx, d)
+		if ctx.Err() != nil {
+			// the rest are sent again once their lease expires
+			return attempted, ctx.Err()
+		}
+		subStatus, err := s.recordAttempt(ctx, d, status, sendErr)
+		if err != nil {
+			return attempted, err
+		}
+		if subStatus == model.WebhookDisabled {
+			disabled[d.SubscriptionID] = true
+			s.logger.Warn("webhook subscription disabled after repeated failures", "webhook_id", d.SubscriptionID)
+		}
+	}
+	return attempted, nil
+}
+
+func (s *WebhookService) claimDeliveries(ctx context.Context) ([]model.WebhookDelivery, error) {
+	tx, err := s.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	deliveries, err := s.webhookRepo.ClaimDueDeliveries(ctx, tx, webhookBatchSize, webhookLease)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("committing webhook claim: %w", err)
+	}
+	return deliveries, nil
+}
+
+// recordAttempt saves the outcome of sending d to the delivery log and to the
+// failure count of its subscription, and returns the subscription's status.
+func (s *WebhookService) recordAttempt(ctx context.Context, d *model.WebhookDelivery, status *int, sendErr error) (model.WebhookStatus, error) {
+	leasedUntil := d.NextAttemptAt
+	now := time.Now()
+	d.Attempts++
+	d.ResponseStatus = status
+	if sendErr == nil {
+		d.Status = model.DeliverySucceeded
+		d.LastError = nil
+		d.DeliveredAt = &now
+	} else {
+		msg := sendErr.Error()
+		if len(msg) > maxWebhookErrorLength {
+			msg = msg[:maxWebhookErrorLength]
+		}
+		d.LastError = &msg
+		d.NextAttemptAt = now.Add(backoff(d.Attempts, webhookBackoffBase, webhookBackoffMax))
+		if d.Attempts >= s.maxAttempts {
+			d.Status = model.DeliveryDead
+		}
+	}
+
+	tx, err := s.txBeginner.BeginTx(ctx)
+	if err != nil {
+		return "", fmt.Errorf("starting transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	recorded, err := s.webhookRepo.UpdateDelivery(ctx, tx, d, leasedUntil)
+	if err != nil {
+		return "", err
+	}
+	if !recorded {
+		s.logger.Warn("webhook delivery lease expired before its outcome was recorded", "webhook_id", d.SubscriptionID, "delivery_id", d.ID)
+		return model.WebhookActive, nil
+	}
+	subStatus, err := s.webhookRepo.RecordResult(ctx, tx, d.SubscriptionID, sendErr == nil, s.disableAfter)
+	if err != nil {
+		return "", err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("committing webhook delivery: %w", err)
+	}
+
+	if sendErr != nil {
+		s.logger.Warn("webhook delivery failed", "webhook_id", d.SubscriptionID, "delivery_id", d.ID,
+			"attempt", d.Attempts, "status", d.Status, "error", sendErr)
+	}
+	return subStatus, nil
+}
+
+// send POSTs the event of d to its subscription, signed with the
+// subscription secret. It returns the response status, if there was one,
+// and an error unless the status was 2xx.
+func (s *WebhookService) send(ctx context.Context, d *model.WebhookDelivery) (*int, error) {
+	body, err := json.Marshal(d.Event)
+	if err != nil {
+		return nil, fmt.Errorf("encoding event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Subscription.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("building webhook request: %w", err)
+	}
+	now := time.Now()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(eventsink.HeaderEventID, strconv.FormatInt(d.EventID, 10))
+	req.Header.Set(eventsink.HeaderDeliveryID, strconv.FormatInt(d.ID, 10))
+	req.Header.Set(eventsink.HeaderEventType, d.EventType)
+	req.Header.Set(eventsink.HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(eventsink.HeaderSignature, eventsink.Sign(d.Subscription.Secret, now, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	status := resp.StatusCode
+
+	if status < 200 || status > 299 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxWebhookErrorLength))
+		return &status, fmt.Errorf("endpoint returned status %d: %s", status, strings.TrimSpace(string(snippet)))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return &status, nil
+}
+
+// RunDeliveries sends due webhook deliveries every interval until ctx is
+// cancelled.
+func (s *WebhookService) RunDeliveries(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.DeliverDue(ctx); err != nil && ctx.Err() == nil {
+				s.logger.Error("delivering webhooks", "error", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,457 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/InternalTransfer/internal/apperror"
+	"github.com/InternalTransfer/internal/auth"
+	"github.com/InternalTransfer/internal/eventsink"
+	"github.com/InternalTransfer/internal/model"
+)
+
+// fakeTx stands in for a database transaction; the fake repositories apply
+// writes straight away, so only the calls are tracked.
+type fakeTx struct {
+	pgx.Tx
+	committed bool
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	tx.committed = true
+	return nil
+}
+
+func (tx *fakeTx) Rollback(context.Context) error {
+	return nil
+}
+
+type fakeTxBeginner struct {
+	begun int
+}
+
+func (b *fakeTxBeginner) BeginTx(context.Context) (pgx.Tx, error) {
+	b.begun++
+	return &fakeTx{}, nil
+}
+
+// fakeWebhookRepo keeps subscriptions and deliveries in memory, following
+// the semantics of WebhookRepository.
+type fakeWebhookRepo struct {
+	subs       map[int64]*model.WebhookSubscription
+	events     map[int64]*model.OutboxEvent
+	deliveries []*model.WebhookDelivery
+	// attempts logs every saved delivery outcome, oldest first
+	attempts []model.WebhookDelivery
+}
+
+func newFakeWebhookRepo() *fakeWebhookRepo {
+	return &fakeWebhookRepo{subs: map[int64]*model.WebhookSubscription{}, events: map[int64]*model.OutboxEvent{}}
+}
+
+func (r *fakeWebhookRepo) addSubscription(id int64, url string) *model.WebhookSubscription {
+	s := &model.WebhookSubscription{ID: id, URL: url, Secret: "whsec_" + strconv.FormatInt(id, 10), Status: model.WebhookActive}
+	r.subs[id] = s
+	return s
+}
+
+func (r *fakeWebhookRepo) addDelivery(subscriptionID, eventID int64) *model.WebhookDelivery {
+	if _, ok := r.events[eventID]; !ok {
+		data, _ := json.Marshal(map[string]int64{"id": eventID})
+		r.events[eventID] = &model.OutboxEvent{
+			ID: eventID, Type: EventTransactionPosted, AccountIDs: []int64{1, 2}, Data: data, CreatedAt: time.Now(),
+		}
+	}
+	d := &model.WebhookDelivery{
+		ID:             int64(len(r.deliveries) + 1),
+		SubscriptionID: subscriptionID,
+		EventID:        eventID,
+		EventType:      EventTransactionPosted,
+		Status:         model.DeliveryPending,
+		NextAttemptAt:  time.Now().Add(-time.Second),
+		CreatedAt:      time.Now(),
+	}
+	r.deliveries = append(r.deliveries, d)
+	return d
+}
+
+func (r *fakeWebhookRepo) Create(context.Context, *model.WebhookSubscription) error { return nil }
+
+func (r *fakeWebhookRepo) GetByID(_ context.Context, id int64) (*model.WebhookSubscription, error) {
+	s, ok := r.subs[id]
+	if !ok {
+		return nil, &apperror.ErrNotFound{Entity: "webhook", ID: id}
+	}
+	return s, nil
+}
+
+func (r *fakeWebhookRepo) List(context.Context) ([]model.WebhookSubscription, error) { return nil, nil }
+
+func (r *fakeWebhookRepo) Delete(context.Context, int64) error { return nil }
+
+func (r *fakeWebhookRepo) Enable(context.Context, int64) (*model.WebhookSubscription, error) {
+	return nil, nil
+}
+
+func (r *fakeWebhookRepo) EnqueueDeliveries(context.Context, *model.OutboxEvent) (int64, error) {
+	return 0, nil
+}
+
+func (r *fakeWebhookRepo) ClaimDueDeliveries(_ context.Context, _ pgx.Tx, limit int, lease time.Duration) ([]model.WebhookDelivery, error) {
+	now := time.Now()
+	var out []model.WebhookDelivery
+	for _, d := range r.deliveries {
+		s := r.subs[d.SubscriptionID]
+		if len(out) == limit || d.Status != model.DeliveryPending || d.NextAttemptAt.After(now) || s.Status != model.WebhookActive {
+			continue
+		}
+		d.NextAttemptAt = now.Add(lease)
+		claimed := *d
+		sub := *s
+		claimed.Event, claimed.Subscription = r.events[d.EventID], &sub
+		out = append(out, claimed)
+	}
+	return out, nil
+}
+
+func (r *fakeWebhookRepo) UpdateDelivery(_ context.Context, _ pgx.Tx, d *model.WebhookDelivery, leasedUntil time.Time) (bool, error) {
+	for _, stored := range r.deliveries {
+		if stored.ID != d.ID {
+			continue
+		}
+		if stored.Status != model.DeliveryPending || !stored.NextAttemptAt.Equal(leasedUntil) {
+			return false, nil
+		}
+		stored.Status, stored.Attempts, stored.NextAttemptAt = d.Status, d.Attempts, d.NextAttemptAt
+		stored.ResponseStatus, stored.LastError, stored.DeliveredAt = d.ResponseStatus, d.LastError, d.DeliveredAt
+		r.attempts = append(r.attempts, *stored)
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *fakeWebhookRepo) RecordResult(_ context.Context, _ pgx.Tx, id int64, success bool, disableAfter int) (model.WebhookStatus, error) {
+	s := r.subs[id]
+	if success {
+		s.ConsecutiveFailures = 0
+		return s.Status, nil
+	}
+	s.ConsecutiveFailures++
+	if s.ConsecutiveFailures >= disableAfter {
+		now := time.Now()
+		s.Status, s.DisabledAt = model.WebhookDisabled, &now
+	}
+	return s.Status, nil
+}
+
+func (r *fakeWebhookRepo) ListDeliveries(_ context.Context, subscriptionID, beforeID int64, limit int) ([]model.WebhookDelivery, error) {
+	var out []model.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.SubscriptionID == subscriptionID && (beforeID == 0 || d.ID < beforeID) {
+			out = append(out, *d)
+		}
+	}
+	slices.SortFunc(out, func(a, b model.WebhookDelivery) int { return cmp.Compare(b.ID, a.ID) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// receivedWebhook is a request seen by webhookServer.
+type receivedWebhook struct {
+	header   http.Header
+	body     []byte
+	verified bool
+}
+
+// webhookServer answers each request with the next of statuses, repeating the
+// last one, and verifies its signature against secret.
+type webhookServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	secret   string
+	statuses []int
+	received []receivedWebhook
+}
+
+func newWebhookServer(t *testing.T, secret string, statuses ...int) *webhookServer {
+	s := &webhookServer{secret: secret, statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.received = append(s.received, receivedWebhook{
+			header: r.Header.Clone(),
+			body:   body,
+			verified: eventsink.Verify(s.secret, r.Header.Get(eventsink.HeaderTimestamp),
+				r.Header.Get(eventsink.HeaderSignature), body, time.Minute),
+		})
+		status := s.statuses[min(len(s.received), len(s.statuses))-1]
+		w.WriteHeader(status)
+		if status >= 300 {
+			io.WriteString(w, "receiver unavailable")
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func newTestWebhookService(repo *fakeWebhookRepo, txBeginner TxBeginner, maxAttempts, disableAfter int) *WebhookService {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewWebhookService(repo, txBeginner, &http.Client{Timeout: 5 * time.Second}, logger, maxAttempts, disableAfter)
+}
+
+func TestDeliverDueSignsRequests(t *testing.T) {
+	repo := newFakeWebhookRepo()
+	sub := repo.addSubscription(1, "")
+	server := newWebhookServer(t, sub.Secret, http.StatusNoContent)
+	sub.URL = server.URL
+	sub.ConsecutiveFailures = 3
+	d := repo.addDelivery(1, 42)
+	txBeginner := &fakeTxBeginner{}
+
+	n, err := newTestWebhookService(repo, txBeginner, 8, 20).DeliverDue(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("DeliverDue = %d, %v; want 1 attempt", n, err)
+	}
+
+	if len(server.received) != 1 {
+		t.Fatalf("server received %d requests, want 1", len(server.received))
+	}
+	req := server.received[0]
+	if !req.verified {
+		t.Error("signature and timestamp headers do not pass Verify")
+	}
+	wantHeaders := map[string]string{
+		eventsink.HeaderEventID:    "42",
+		eventsink.HeaderDeliveryID: strconv.FormatInt(d.ID, 10),
+		eventsink.HeaderEventType:  EventTransactionPosted,
+		"Content-Type":             "application/json",
+	}
+	for name, want := range wantHeaders {
+		if got := req.header.Get(name); got != want {
+			t.Errorf("header %s = %q, want %q", name, got, want)
+		}
+	}
+	var event model.OutboxEvent
+	if err := json.Unmarshal(req.body, &event); err != nil || event.ID != 42 || event.Type != EventTransactionPosted {
+		t.Errorf("body = %s, want event 42", req.body)
+	}
+
+	if d.Status != model.DeliverySucceeded || d.Attempts != 1 || d.DeliveredAt == nil || d.LastError != nil {
+		t.Errorf("delivery = %+v, want succeeded after 1 attempt", d)
+	}
+	if d.ResponseStatus == nil || *d.ResponseStatus != http.StatusNoContent {
+		t.Errorf("response status = %v, want 204", d.ResponseStatus)
+	}
+	if sub.ConsecutiveFailures != 0 {
+		t.Errorf("consecutive failures = %d, want reset to 0", sub.ConsecutiveFailures)
+	}
+	// one transaction claims the batch and one records the attempt
+	if txBeginner.begun != 2 {
+		t.Errorf("began %d transactions, want 2", txBeginner.begun)
+	}
+}
+
+func TestDeliverDueRetriesWithBackoff(t *testing.T) {
+	repo := newFakeWebhookRepo()
+	sub := repo.addSubscription(1, "")
+	server := newWebhookServer(t, sub.Secret, http.StatusInternalServerError)
+	sub.URL = server.URL
+	d := repo.addDelivery(1, 42)
+	svc := newTestWebhookService(repo, &fakeTxBeginner{}, 3, 20)
+
+	for attempt, wantDelay := range []time.Duration{webhookBackoffBase, 2 * webhookBackoffBase} {
+		before := time.Now()
+		if _, err := svc.DeliverDue(context.Background()); err != nil {
+			t.Fatalf("attempt %d: DeliverDue: %v", attempt+1, err)
+		}
+		if d.Status != model.DeliveryPending || d.Attempts != attempt+1 {
+			t.Fatalf("attempt %d: delivery is %s after %d attempts, want pending after %d", attempt+1, d.Status, d.Attempts, attempt+1)
+		}
+		if delay := d.NextAttemptAt.Sub(before); delay < wantDelay || delay > wantDelay+time.Second {
+			t.Errorf("attempt %d: retry in %s, want %s", attempt+1, delay, wantDelay)
+		}
+		if d.ResponseStatus == nil || *d.ResponseStatus != http.StatusInternalServerError {
+			t.Errorf("attempt %d: response status = %v, want 500", attempt+1, d.ResponseStatus)
+		}
+		if d.LastError == nil || !strings.Contains(*d.LastError, "500") || !strings.Contains(*d.LastError, "receiver unavailable") {
+			t.Errorf("attempt %d: last error = %v, want the status and response text", attempt+1, d.LastError)
+		}
+
+		// not due yet, so the next run leaves it alone
+		if n, _ := svc.DeliverDue(context.Background()); n != 0 {
+			t.Fatalf("attempt %d: redelivered %d before the backoff elapsed", attempt+1, n)
+		}
+		d.NextAttemptAt = time.Now().Add(-time.Second)
+	}
+
+	if _, err := svc.DeliverDue(context.Background()); err != nil {
+		t.Fatalf("last attempt: DeliverDue: %v", err)
+	}
+	if d.Status != model.DeliveryDead || d.Attempts != 3 {
+		t.Errorf("delivery is %s after %d attempts, want dead after 3", d.Status, d.Attempts)
+	}
+	if len(server.received) != 3 {
+		t.Errorf("server received %d requests, want 3", len(server.received))
+	}
+}
+
+func TestDeliverDueDisablesFailingSubscription(t *testing.T) {
+	repo := newFakeWebhookRepo()
+	failing := repo.addSubscription(1, "")
+	healthy := repo.addSubscription(2, "")
+	failingServer := newWebhookServer(t, failing.Secret, http.StatusBadGateway)
+	healthyServer := newWebhookServer(t, healthy.Secret, http.StatusOK)
+	failing.URL, healthy.URL = failingServer.URL, healthyServer.URL
+
+	var failingDeliveries []*model.WebhookDelivery
+	for event := int64(1); event <= 3; event++ {
+		failingDeliveries = append(failingDeliveries, repo.addDelivery(1, event))
+	}
+	healthyDelivery := repo.addDelivery(2, 1)
+
+	n, err := newTestWebhookService(repo, &fakeTxBeginner{}, 8, 2).DeliverDue(context.Background())
+	if err != nil {
+		t.Fatalf("DeliverDue: %v", err)
+	}
+	if n != 3 {
+		t.Errorf("attempted %d deliveries, want 3", n)
+	}
+
+	if failing.Status != model.WebhookDisabled || failing.DisabledAt == nil || failing.ConsecutiveFailures != 2 {
+		t.Errorf("failing subscription = %+v, want disabled after 2 failures", failing)
+	}
+	if len(failingServer.received) != 2 {
+		t.Errorf("failing endpoint received %d requests, want 2", len(failingServer.received))
+	}
+	if last := failingDeliveries[2]; last.Attempts != 0 || last.Status != model.DeliveryPending {
+		t.Errorf("delivery after the subscription was disabled = %+v, want pending and not attempted", last)
+	}
+	if healthy.Status != model.WebhookActive || healthyDelivery.Status != model.DeliverySucceeded {
+		t.Errorf("healthy subscription is %s with delivery %s, want active and succeeded", healthy.Status, healthyDelivery.Status)
+	}
+
+	// a disabled subscription is not claimed again
+	failingDeliveries[2].NextAttemptAt = time.Now().Add(-time.Second)
+	if n, _ := newTestWebhookService(repo, &fakeTxBeginner{}, 8, 2).DeliverDue(context.Background()); n != 0 {
+		t.Errorf("attempted %d deliveries to a disabled subscription", n)
+	}
+}
+
+func TestDeliveryLogRecordsEachAttempt(t *testing.T) {
+	repo := newFakeWebhookRepo()
+	sub := repo.addSubscription(1, "")
+	server := newWebhookServer(t, sub.Secret, http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK)
+	sub.URL = server.URL
+	d := repo.addDelivery(1, 42)
+	svc := newTestWebhookService(repo, &fakeTxBeginner{}, 8, 20)
+
+	for range 3 {
+		if _, err := svc.DeliverDue(context.Background()); err != nil {
+			t.Fatalf("DeliverDue: %v", err)
+		}
+		d.NextAttemptAt = time.Now().Add(-time.Second)
+	}
+
+	wantStatuses := []int{http.StatusServiceUnavailable, http.StatusTooManyRequests, http.StatusOK}
+	if len(repo.attempts) != len(wantStatuses) {
+		t.Fatalf("recorded %d attempts, want %d", len(repo.attempts), len(wantStatuses))
+	}
+	for i, a := range repo.attempts {
+		if a.Attempts != i+1 || a.ResponseStatus == nil || *a.ResponseStatus != wantStatuses[i] {
+			t.Errorf("attempt %d recorded as number %d with status %v, want %d", i+1, a.Attempts, a.ResponseStatus, wantStatuses[i])
+		}
+		if succeeded := i == len(wantStatuses)-1; succeeded != (a.Status == model.DeliverySucceeded) || succeeded != (a.LastError == nil) {
+			t.Errorf("attempt %d recorded as %s with error %v", i+1, a.Status, a.LastError)
+		}
+	}
+
+	log, next, err := svc.ListDeliveries(context.Background(), 1, 0, 10)
+	if err != nil || next != 0 {
+		t.Fatalf("ListDeliveries = %d entries, next %d, %v", len(log), next, err)
+	}
+	if len(log) != 1 || log[0].ID != d.ID || log[0].Attempts != 3 || log[0].Status != model.DeliverySucceeded {
+		t.Errorf("delivery log = %+v, want delivery %d succeeded after 3 attempts", log, d.ID)
+	}
+}
+
+func TestDeliverDueDropsOutcomeAfterLeaseLoss(t *testing.T) {
+	repo := newFakeWebhookRepo()
+	sub := repo.addSubscription(1, "")
+	var d *model.WebhookDelivery
+	// another worker claims the delivery while this one is sending it
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d.NextAttemptAt = time.Now().Add(webhookLease + time.Minute)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+	sub.URL = server.URL
+	d = repo.addDelivery(1, 42)
+
+	if _, err := newTestWebhookService(repo, &fakeTxBeginner{}, 8, 1).DeliverDue(context.Background()); err != nil {
+		t.Fatalf("DeliverDue: %v", err)
+	}
+	if len(repo.attempts) != 0 || d.Attempts != 0 {
+		t.Errorf("recorded %d attempts after losing the lease, want none", len(repo.attempts))
+	}
+	if sub.Status != model.WebhookActive || sub.ConsecutiveFailures != 0 {
+		t.Errorf("subscription = %+v, want its failure count untouched", sub)
+	}
+}
+
+func TestWebhookReadsRequireAdmin(t *testing.T) {
+	repo := newFakeWebhookRepo()
+	repo.addSubscription(1, "https://example.com/hook")
+	s := newTestWebhookService(repo, &fakeTxBeginner{}, 8, 20)
+
+	reads := []struct {
+		name string
+		call func(ctx context.Context) error
+	}{
+		{"get", func(ctx context.Context) error {
+			_, err := s.GetByID(ctx, 1)
+			return err
+		}},
+		{"list", func(ctx context.Context) error {
+			_, err := s.List(ctx)
+			return err
+		}},
+		{"list deliveries", func(ctx context.Context) error {
+			_, _, err := s.ListDeliveries(ctx, 1, 0, 10)
+			return err
+		}},
+	}
+
+	viewer := auth.WithPrincipal(context.Background(),
+		&auth.Principal{ID: "apikey:1", Name: "apikey:reporting", Role: auth.RoleViewer, Scopes: []auth.Scope{auth.ScopeAccountsRead}})
+	admin := auth.WithPrincipal(context.Background(),
+		&auth.Principal{ID: "apikey:2", Name: "apikey:ops", Role: auth.RoleAdmin, Scopes: []auth.Scope{auth.ScopeAdmin}})
+
+	for _, tt := range reads {
+		t.Run(tt.name, func(t *testing.T) {
+			var forbidden *apperror.ErrForbidden
+			if err := tt.call(viewer); !errors.As(err, &forbidden) {
+				t.Errorf("viewer error = %v, want ErrForbidden", err)
+			}
+			if err := tt.call(admin); err != nil {
+				t.Errorf("admin error = %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,10 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key            TEXT        PRIMARY KEY,
+    request_hash   TEXT        NOT NULL,
+    transaction_id BIGINT      REFERENCES transactions(id),
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMIT;
@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS source_balance_after NUMERIC(20, 2);
+
+COMMIT;
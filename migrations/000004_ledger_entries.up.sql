@@ -0,0 +1,83 @@
+BEGIN;
+
+-- System accounts (e.g. equity) are not customer accounts and may go negative.
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS system_role TEXT;
+ALTER TABLE accounts DROP CONSTRAINT IF EXISTS accounts_balance_non_negative;
+ALTER TABLE accounts ADD CONSTRAINT accounts_balance_non_negative CHECK (balance >= 0 OR system_role IS NOT NULL);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_system_role ON accounts(system_role) WHERE system_role IS NOT NULL;
+
+-- Opening balances are funded from the equity account.
+INSERT INTO accounts (account_id, balance, system_role) VALUES (-1, 0, 'equity') ON CONFLICT DO NOTHING;
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'transfer';
+
+-- One debit (money out) and one credit (money in) per transaction.
+CREATE TABLE IF NOT EXISTS ledger_entries (
+    id             BIGSERIAL      PRIMARY KEY,
+    account_id     BIGINT         NOT NULL REFERENCES accounts(account_id),
+    transaction_id BIGINT         NOT NULL REFERENCES transactions(id),
+    direction      TEXT           NOT NULL,
+    amount         NUMERIC(20, 2) NOT NULL,
+    balance_after  NUMERIC(20, 2) NOT NULL,
+    created_at     TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT ledger_entries_direction CHECK (direction IN ('debit', 'credit')),
+    CONSTRAINT ledger_entries_amount_positive CHECK (amount > 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account ON ledger_entries(account_id, id);
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction ON ledger_entries(transaction_id);
+
+-- Backfill: open every existing account at its current balance so that the
+-- ledger explains today's balances. Earlier transfers are not replayed.
+INSERT INTO transactions (source_account_id, destination_account_id, amount, source_balance_after, kind)
+SELECT -1, account_id, balance, -SUM(balance) OVER (ORDER BY account_id), 'opening'
+FROM accounts
+WHERE system_role IS NULL AND balance > 0
+ORDER BY account_id;
+
+INSERT INTO ledger_entries (account_id, transaction_id, direction, amount, balance_after, created_at)
+SELECT source_account_id, id, 'debit', amount, source_balance_after, created_at
+FROM transactions WHERE kind = 'opening'
+ORDER BY id;
+
+INSERT INTO ledger_entries (account_id, transaction_id, direction, amount, balance_after, created_at)
+SELECT destination_account_id, id, 'credit', amount, amount, created_at
+FROM transactions WHERE kind = 'opening'
+ORDER BY id;
+
+UPDATE accounts
+SET balance = (SELECT COALESCE(-SUM(amount), 0) FROM transactions WHERE kind = 'opening'), updated_at = NOW()
+WHERE account_id = -1;
+
+-- Invariant: an account's balance always equals the balance after its latest
+-- ledger entry. Checked at commit so multi-step transfers are validated once.
+CREATE OR REPLACE FUNCTION check_account_ledger_balance() RETURNS trigger AS $$
+DECLARE
+    current_balance NUMERIC(20, 2);
+    ledger_balance  NUMERIC(20, 2);
+BEGIN
+    SELECT balance INTO current_balance FROM accounts WHERE account_id = NEW.account_id;
+
+    SELECT balance_after INTO ledger_balance
+    FROM ledger_entries
+    WHERE account_id = NEW.account_id
+    ORDER BY id DESC
+    LIMIT 1;
+
+    IF current_balance IS DISTINCT FROM COALESCE(ledger_balance, 0) THEN
+        RAISE EXCEPTION 'account % balance % does not match ledger balance %',
+            NEW.account_id, current_balance, COALESCE(ledger_balance, 0)
+            USING ERRCODE = 'check_violation';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS accounts_balance_matches_ledger ON accounts;
+CREATE CONSTRAINT TRIGGER accounts_balance_matches_ledger
+    AFTER INSERT OR UPDATE OF balance ON accounts
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION check_account_ledger_balance();
+
+COMMIT;
@@ -0,0 +1,27 @@
+BEGIN;
+
+-- held_balance is the part of balance reserved by active holds;
+-- available balance = balance - held_balance.
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS held_balance NUMERIC(20, 2) NOT NULL DEFAULT 0;
+ALTER TABLE accounts ADD CONSTRAINT accounts_held_balance_valid
+    CHECK (held_balance >= 0 AND (held_balance <= balance OR system_role IS NOT NULL));
+
+CREATE TABLE IF NOT EXISTS holds (
+    id              BIGSERIAL      PRIMARY KEY,
+    account_id      BIGINT         NOT NULL REFERENCES accounts(account_id),
+    amount          NUMERIC(20, 2) NOT NULL,
+    status          TEXT           NOT NULL DEFAULT 'active',
+    captured_amount NUMERIC(20, 2),
+    transaction_id  BIGINT         REFERENCES transactions(id),
+    expires_at      TIMESTAMPTZ    NOT NULL,
+    created_at      TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT holds_amount_positive CHECK (amount > 0),
+    CONSTRAINT holds_status CHECK (status IN ('active', 'captured', 'released', 'expired'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_holds_account ON holds(account_id);
+CREATE INDEX IF NOT EXISTS idx_holds_active_expiry ON holds(expires_at) WHERE status = 'active';
+
+COMMIT;
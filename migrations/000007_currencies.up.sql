@@ -0,0 +1,64 @@
+BEGIN;
+
+-- Widen money columns so currencies with three minor units fit. The
+-- balance trigger depends on accounts.balance and must be recreated.
+DROP TRIGGER IF EXISTS accounts_balance_matches_ledger ON accounts;
+
+ALTER TABLE accounts
+    ALTER COLUMN balance TYPE NUMERIC(24, 4),
+    ALTER COLUMN held_balance TYPE NUMERIC(24, 4);
+ALTER TABLE transactions
+    ALTER COLUMN amount TYPE NUMERIC(24, 4),
+    ALTER COLUMN source_balance_after TYPE NUMERIC(24, 4);
+ALTER TABLE ledger_entries
+    ALTER COLUMN amount TYPE NUMERIC(24, 4),
+    ALTER COLUMN balance_after TYPE NUMERIC(24, 4);
+ALTER TABLE holds
+    ALTER COLUMN amount TYPE NUMERIC(24, 4),
+    ALTER COLUMN captured_amount TYPE NUMERIC(24, 4);
+
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+
+-- amount is in the source currency and destination_amount in the destination
+-- currency; fx_rate is set only for cross-currency transfers.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS currency CHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS destination_currency CHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS destination_amount NUMERIC(24, 4);
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fx_rate NUMERIC(24, 10);
+UPDATE transactions SET destination_amount = amount WHERE destination_amount IS NULL;
+ALTER TABLE transactions ALTER COLUMN destination_amount SET NOT NULL;
+
+-- One system account per role and currency; new ones take negative IDs.
+DROP INDEX IF EXISTS idx_accounts_system_role;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_accounts_system_role_currency
+    ON accounts(system_role, currency) WHERE system_role IS NOT NULL;
+CREATE SEQUENCE IF NOT EXISTS system_account_id_seq INCREMENT BY -1 START WITH -2 MAXVALUE -2;
+
+CREATE OR REPLACE FUNCTION check_account_ledger_balance() RETURNS trigger AS $$
+DECLARE
+    current_balance NUMERIC;
+    ledger_balance  NUMERIC;
+BEGIN
+    SELECT balance INTO current_balance FROM accounts WHERE account_id = NEW.account_id;
+
+    SELECT balance_after INTO ledger_balance
+    FROM ledger_entries
+    WHERE account_id = NEW.account_id
+    ORDER BY id DESC
+    LIMIT 1;
+
+    IF current_balance IS DISTINCT FROM COALESCE(ledger_balance, 0) THEN
+        RAISE EXCEPTION 'account % balance % does not match ledger balance %',
+            NEW.account_id, current_balance, COALESCE(ledger_balance, 0)
+            USING ERRCODE = 'check_violation';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE CONSTRAINT TRIGGER accounts_balance_matches_ledger
+    AFTER INSERT OR UPDATE OF balance ON accounts
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION check_account_ledger_balance();
+
+COMMIT;
@@ -0,0 +1,19 @@
+BEGIN;
+
+-- A quote locks a rate for a short time and can be used by one transfer.
+CREATE TABLE IF NOT EXISTS fx_quotes (
+    id                   BIGSERIAL      PRIMARY KEY,
+    source_currency      CHAR(3)        NOT NULL,
+    destination_currency CHAR(3)        NOT NULL,
+    rate                 NUMERIC(24, 10) NOT NULL,
+    expires_at           TIMESTAMPTZ    NOT NULL,
+    transaction_id       BIGINT         REFERENCES transactions(id),
+    created_at           TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fx_quotes_rate_positive CHECK (rate > 0),
+    CONSTRAINT fx_quotes_different_currencies CHECK (source_currency <> destination_currency)
+);
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fx_quote_id BIGINT REFERENCES fx_quotes(id);
+
+COMMIT;
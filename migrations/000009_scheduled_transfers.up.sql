@@ -0,0 +1,46 @@
+BEGIN;
+
+-- A schedule repeats a transfer on either a cron expression or a fixed
+-- interval counted from starts_at. next_run_at is NULL once a schedule is
+-- completed or cancelled.
+CREATE TABLE IF NOT EXISTS scheduled_transfers (
+    id                     BIGSERIAL      PRIMARY KEY,
+    source_account_id      BIGINT         NOT NULL REFERENCES accounts(account_id),
+    destination_account_id BIGINT         NOT NULL REFERENCES accounts(account_id),
+    amount                 NUMERIC(24, 4) NOT NULL,
+    currency               CHAR(3)        NOT NULL,
+    cron_expr              TEXT,
+    interval_seconds       BIGINT,
+    starts_at              TIMESTAMPTZ    NOT NULL,
+    ends_at                TIMESTAMPTZ,
+    status                 TEXT           NOT NULL DEFAULT 'active',
+    next_run_at            TIMESTAMPTZ,
+    last_run_at            TIMESTAMPTZ,
+    created_at             TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    updated_at             TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT scheduled_transfers_amount_positive CHECK (amount > 0),
+    CONSTRAINT scheduled_transfers_different_accounts CHECK (source_account_id <> destination_account_id),
+    CONSTRAINT scheduled_transfers_one_rule CHECK ((cron_expr IS NULL) <> (interval_seconds IS NULL)),
+    CONSTRAINT scheduled_transfers_interval_positive CHECK (interval_seconds > 0),
+    CONSTRAINT scheduled_transfers_status CHECK (status IN ('active', 'paused', 'completed', 'cancelled'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_scheduled_transfers_due ON scheduled_transfers(next_run_at) WHERE status = 'active';
+CREATE INDEX IF NOT EXISTS idx_scheduled_transfers_source ON scheduled_transfers(source_account_id);
+
+CREATE TABLE IF NOT EXISTS scheduled_transfer_runs (
+    id             BIGSERIAL   PRIMARY KEY,
+    schedule_id    BIGINT      NOT NULL REFERENCES scheduled_transfers(id),
+    scheduled_for  TIMESTAMPTZ NOT NULL,
+    status         TEXT        NOT NULL,
+    transaction_id BIGINT      REFERENCES transactions(id),
+    error_code     TEXT,
+    error_message  TEXT,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT scheduled_transfer_runs_status CHECK (status IN ('succeeded', 'failed')),
+    CONSTRAINT scheduled_transfer_runs_once UNIQUE (schedule_id, scheduled_for)
+);
+
+COMMIT;
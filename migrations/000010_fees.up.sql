@@ -0,0 +1,44 @@
+BEGIN;
+
+-- account_type groups accounts for fee policies.
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS account_type TEXT NOT NULL DEFAULT 'standard';
+
+-- A fee policy applies either to transfers out of accounts of a given type
+-- or to transfers between one specific pair of accounts; pair policies win.
+-- Fees are charged in the policy's currency, which must be the source
+-- account's currency.
+CREATE TABLE IF NOT EXISTS fee_policies (
+    id                     BIGSERIAL      PRIMARY KEY,
+    account_type           TEXT,
+    source_account_id      BIGINT         REFERENCES accounts(account_id),
+    destination_account_id BIGINT         REFERENCES accounts(account_id),
+    currency               CHAR(3)        NOT NULL,
+    fee_type               TEXT           NOT NULL,
+    flat_amount            NUMERIC(24, 4),
+    percentage             NUMERIC(9, 6),
+    tiers                  JSONB,
+    min_fee                NUMERIC(24, 4),
+    max_fee                NUMERIC(24, 4),
+    created_at             TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+    updated_at             TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT fee_policies_scope CHECK (
+        (account_type IS NOT NULL AND source_account_id IS NULL AND destination_account_id IS NULL)
+        OR (account_type IS NULL AND source_account_id IS NOT NULL AND destination_account_id IS NOT NULL)
+    ),
+    CONSTRAINT fee_policies_fee_type CHECK (fee_type IN ('flat', 'percentage', 'tiered')),
+    CONSTRAINT fee_policies_caps CHECK (min_fee IS NULL OR max_fee IS NULL OR min_fee <= max_fee)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fee_policies_type ON fee_policies(account_type, currency)
+    WHERE account_type IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fee_policies_pair ON fee_policies(source_account_id, destination_account_id, currency)
+    WHERE source_account_id IS NOT NULL;
+
+-- Fees are posted as their own transaction linked to the transfer they were
+-- charged on.
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS fee_of BIGINT REFERENCES transactions(id);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_fee_of ON transactions(fee_of) WHERE fee_of IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,23 @@
+BEGIN;
+
+-- Outgoing limits per account, in the account's currency. A NULL column
+-- means that limit is not enforced. Daily, monthly and hourly windows are
+-- fixed UTC calendar periods.
+CREATE TABLE IF NOT EXISTS account_limits (
+    account_id        BIGINT         PRIMARY KEY REFERENCES accounts(account_id),
+    max_single_amount NUMERIC(24, 4),
+    daily_amount      NUMERIC(24, 4),
+    monthly_amount    NUMERIC(24, 4),
+    hourly_count      INTEGER,
+    updated_at        TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT account_limits_non_negative CHECK (
+        COALESCE(max_single_amount, 0) >= 0 AND COALESCE(daily_amount, 0) >= 0
+        AND COALESCE(monthly_amount, 0) >= 0 AND COALESCE(hourly_count, 0) >= 0
+    )
+);
+
+-- usage is aggregated from an account's recent outgoing transfers
+CREATE INDEX IF NOT EXISTS idx_transactions_source_created ON transactions(source_account_id, created_at);
+
+COMMIT;
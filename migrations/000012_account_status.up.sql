@@ -0,0 +1,21 @@
+BEGIN;
+
+-- Frozen and closed accounts cannot send or receive transfers; closed is
+-- final.
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'active';
+ALTER TABLE accounts ADD CONSTRAINT accounts_status CHECK (status IN ('active', 'frozen', 'closed'));
+
+CREATE TABLE IF NOT EXISTS account_status_history (
+    id                   BIGSERIAL   PRIMARY KEY,
+    account_id           BIGINT      NOT NULL REFERENCES accounts(account_id),
+    from_status          TEXT        NOT NULL,
+    to_status            TEXT        NOT NULL,
+    changed_by           TEXT        NOT NULL,
+    reason               TEXT        NOT NULL,
+    sweep_transaction_id BIGINT      REFERENCES transactions(id),
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_status_history_account ON account_status_history(account_id, id);
+
+COMMIT;
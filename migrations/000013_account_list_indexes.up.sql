@@ -0,0 +1,8 @@
+BEGIN;
+
+-- Keyset pagination for GET /accounts sorts by one of these columns with
+-- account_id as the tie-breaker.
+CREATE INDEX IF NOT EXISTS idx_accounts_balance ON accounts(balance, account_id) WHERE system_role IS NULL;
+CREATE INDEX IF NOT EXISTS idx_accounts_created_at ON accounts(created_at, account_id) WHERE system_role IS NULL;
+
+COMMIT;
@@ -0,0 +1,12 @@
+BEGIN;
+
+-- Optional references from a ledger account to the business entity it
+-- belongs to. account_type (added with fees) serves as the account's type.
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS owner_id TEXT;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS name TEXT;
+ALTER TABLE accounts ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS idx_accounts_owner ON accounts(owner_id, account_id) WHERE owner_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_accounts_metadata ON accounts USING GIN (metadata jsonb_path_ops);
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+-- Account IDs allocated by the server when a client omits account_id. With
+-- ACCOUNT_ID_CHECK_DIGIT enabled a Luhn digit is appended to each value, so
+-- MAXVALUE keeps value*10+9 within BIGINT. Collisions with client-chosen IDs
+-- are skipped by the service.
+CREATE SEQUENCE IF NOT EXISTS account_id_seq START WITH 100000000 MAXVALUE 922337203685477579;
+
+COMMIT;
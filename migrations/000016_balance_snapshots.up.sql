@@ -0,0 +1,17 @@
+BEGIN;
+
+-- An account's balance as of taken_at, i.e. including every ledger entry
+-- created at or before it. Point-in-time queries start from the latest
+-- snapshot and add the entries after it.
+CREATE TABLE IF NOT EXISTS balance_snapshots (
+    account_id BIGINT         NOT NULL REFERENCES accounts(account_id),
+    taken_at   TIMESTAMPTZ    NOT NULL,
+    balance    NUMERIC(24, 4) NOT NULL,
+    created_at TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    PRIMARY KEY (account_id, taken_at)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account_created ON ledger_entries(account_id, created_at);
+
+COMMIT;
@@ -0,0 +1,60 @@
+BEGIN;
+
+-- Events written in the same database transaction as the change they
+-- describe, then published by the relay. Delivery is at least once: an event
+-- stays pending until every sink accepted it, and is dead-lettered after too
+-- many attempts.
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id              BIGSERIAL   PRIMARY KEY,
+    event_type      TEXT        NOT NULL,
+    account_ids     BIGINT[]    NOT NULL DEFAULT '{}',
+    payload         JSONB       NOT NULL,
+    status          TEXT        NOT NULL DEFAULT 'pending',
+    attempts        INT         NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at    TIMESTAMPTZ,
+
+    CONSTRAINT outbox_events_status CHECK (status IN ('pending', 'delivered', 'dead'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_due ON outbox_events(next_attempt_at) WHERE status = 'pending';
+
+-- Empty event_types or account_ids match every event or account.
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id                   BIGSERIAL   PRIMARY KEY,
+    url                  TEXT        NOT NULL,
+    secret               TEXT        NOT NULL,
+    event_types          TEXT[]      NOT NULL DEFAULT '{}',
+    account_ids          BIGINT[]    NOT NULL DEFAULT '{}',
+    status               TEXT        NOT NULL DEFAULT 'active',
+    consecutive_failures INT         NOT NULL DEFAULT 0,
+    disabled_at          TIMESTAMPTZ,
+    created_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at           TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT webhook_subscriptions_status CHECK (status IN ('active', 'disabled'))
+);
+
+-- One delivery per subscription and event, retried in place.
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id              BIGSERIAL   PRIMARY KEY,
+    subscription_id BIGINT      NOT NULL REFERENCES webhook_subscriptions(id) ON DELETE CASCADE,
+    event_id        BIGINT      NOT NULL REFERENCES outbox_events(id),
+    status          TEXT        NOT NULL DEFAULT 'pending',
+    attempts        INT         NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    response_status INT,
+    last_error      TEXT,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at    TIMESTAMPTZ,
+
+    CONSTRAINT webhook_deliveries_status CHECK (status IN ('pending', 'succeeded', 'dead')),
+    CONSTRAINT webhook_deliveries_unique UNIQUE (subscription_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries(subscription_id, id);
+
+COMMIT;
@@ -0,0 +1,32 @@
+BEGIN;
+
+-- Announce every ledger entry on the account_activity channel. Notifications
+-- are sent when the inserting transaction commits and never for one that
+-- rolls back, so listeners only see movements that happened. The payload
+-- matches the rows LedgerRepository.ListEventsAfter returns.
+CREATE OR REPLACE FUNCTION notify_account_activity() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('account_activity', json_build_object(
+        'id', NEW.id,
+        'account_id', NEW.account_id,
+        'transaction_id', NEW.transaction_id,
+        'kind', t.kind,
+        'direction', NEW.direction,
+        'amount', NEW.amount,
+        'currency', CASE WHEN NEW.direction = 'debit' THEN t.currency ELSE t.destination_currency END,
+        'counterparty_account_id', CASE WHEN NEW.direction = 'debit' THEN t.destination_account_id ELSE t.source_account_id END,
+        'balance', NEW.balance_after,
+        'created_at', NEW.created_at
+    )::text)
+    FROM transactions t
+    WHERE t.id = NEW.transaction_id;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_entries_notify ON ledger_entries;
+CREATE TRIGGER ledger_entries_notify
+    AFTER INSERT ON ledger_entries
+    FOR EACH ROW EXECUTE FUNCTION notify_account_activity();
+
+COMMIT;
@@ -0,0 +1,15 @@
+BEGIN;
+
+-- API keys are stored as SHA-256 hashes; prefix keeps the first characters in
+-- clear so that keys can be told apart. Revoked keys are kept for auditing.
+CREATE TABLE IF NOT EXISTS api_keys (
+    id         BIGSERIAL   PRIMARY KEY,
+    name       TEXT        NOT NULL,
+    prefix     TEXT        NOT NULL,
+    key_hash   TEXT        NOT NULL UNIQUE,
+    scopes     TEXT[]      NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    revoked_at TIMESTAMPTZ
+);
+
+COMMIT;
@@ -0,0 +1,18 @@
+BEGIN;
+
+-- Roles decide what a key's holder may do; scopes can only narrow that.
+-- Existing keys get the least role that keeps what their scopes reached
+-- before: admin only with the admin scope, approver with a write scope
+-- (status changes, limits and reversals were open to those), viewer
+-- otherwise.
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS role TEXT
+    CHECK (role IN ('viewer', 'operator', 'approver', 'admin'));
+UPDATE api_keys SET role = CASE
+        WHEN 'admin' = ANY (scopes) THEN 'admin'
+        WHEN scopes && ARRAY['accounts:write', 'transfers:write'] THEN 'approver'
+        ELSE 'viewer'
+    END
+WHERE role IS NULL;
+ALTER TABLE api_keys ALTER COLUMN role SET NOT NULL;
+
+COMMIT;
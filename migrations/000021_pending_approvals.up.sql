@@ -0,0 +1,35 @@
+BEGIN;
+
+-- Transfers above the approval threshold wait here for a second principal.
+-- Their IDs come from the transaction sequence so that an approved transfer
+-- is posted under the ID it was submitted with. When funds_held is set the
+-- amount is counted in the source account's held_balance until a decision.
+CREATE TABLE IF NOT EXISTS pending_approvals (
+    id                     BIGINT         PRIMARY KEY DEFAULT nextval('transactions_id_seq'),
+    source_account_id      BIGINT         NOT NULL REFERENCES accounts(account_id),
+    destination_account_id BIGINT         NOT NULL REFERENCES accounts(account_id),
+    amount                 NUMERIC(24, 4) NOT NULL,
+    currency               CHAR(3)        NOT NULL,
+    fx_rate                NUMERIC(24, 10),
+    funds_held             BOOLEAN        NOT NULL,
+    status                 TEXT           NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'approved', 'rejected', 'expired')),
+    initiated_by           TEXT           NOT NULL,
+    decided_by             TEXT,
+    reason                 TEXT,
+    expires_at             TIMESTAMPTZ    NOT NULL,
+    decided_at             TIMESTAMPTZ,
+    created_at             TIMESTAMPTZ    NOT NULL DEFAULT NOW(),
+
+    CONSTRAINT pending_approvals_amount_positive CHECK (amount > 0),
+    CONSTRAINT pending_approvals_different_accounts CHECK (source_account_id <> destination_account_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_pending_approvals_expiry ON pending_approvals(expires_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_pending_approvals_source ON pending_approvals(source_account_id);
+CREATE INDEX IF NOT EXISTS idx_pending_approvals_destination ON pending_approvals(destination_account_id);
+
+-- A submission replays as its approval until the transfer is posted.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS approval_id BIGINT REFERENCES pending_approvals(id);
+
+COMMIT;
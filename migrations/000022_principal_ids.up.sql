@@ -0,0 +1,16 @@
+BEGIN;
+
+-- Approvals record the stable ID of the principal that submitted them, so
+-- that the submitter cannot approve under another display name. Approvals
+-- submitted before this migration keep a NULL ID and compare by name.
+ALTER TABLE pending_approvals ADD COLUMN IF NOT EXISTS initiated_by_id TEXT;
+
+-- Key names appear in audit records, so two keys may not share one. Existing
+-- duplicates keep the oldest key's name and suffix the others with their ID.
+UPDATE api_keys k SET name = k.name || ' #' || k.id
+ WHERE EXISTS (SELECT 1 FROM api_keys o WHERE o.name = k.name AND o.id < k.id);
+
+ALTER TABLE api_keys DROP CONSTRAINT IF EXISTS api_keys_name_key;
+ALTER TABLE api_keys ADD CONSTRAINT api_keys_name_key UNIQUE (name);
+
+COMMIT;
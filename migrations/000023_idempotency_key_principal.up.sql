@@ -0,0 +1,16 @@
+BEGIN;
+
+-- Idempotency keys are scoped to the principal that used them, so that one
+-- client's key cannot replay or block another's. Keys stored before this
+-- migration were used by scheduled runs, which act as "system", or by
+-- clients, which are attributed to the anonymous principal that serves
+-- every request while AUTH_ENABLED is false.
+ALTER TABLE idempotency_keys ADD COLUMN IF NOT EXISTS principal TEXT;
+UPDATE idempotency_keys SET principal = CASE WHEN key LIKE 'schedule:%' THEN 'system' ELSE 'anonymous' END
+ WHERE principal IS NULL;
+ALTER TABLE idempotency_keys ALTER COLUMN principal SET NOT NULL;
+
+ALTER TABLE idempotency_keys DROP CONSTRAINT IF EXISTS idempotency_keys_pkey;
+ALTER TABLE idempotency_keys ADD CONSTRAINT idempotency_keys_pkey PRIMARY KEY (principal, key);
+
+COMMIT;
@@ -0,0 +1,35 @@
+BEGIN;
+
+-- Invariant: an account's balance always equals the sum of its ledger
+-- entries, credits minus debits. Comparing against the latest entry's
+-- balance_after trusted that chain, and checking only when the balance
+-- changed let an entry inserted on its own go unnoticed, so the check now
+-- sums the entries and also runs when one is written. Checked at commit so
+-- multi-step transfers are validated once.
+CREATE OR REPLACE FUNCTION check_account_ledger_balance() RETURNS trigger AS $$
+DECLARE
+    current_balance NUMERIC;
+    ledger_balance  NUMERIC;
+BEGIN
+    SELECT balance INTO current_balance FROM accounts WHERE account_id = NEW.account_id;
+
+    SELECT COALESCE(SUM(CASE direction WHEN 'credit' THEN amount ELSE -amount END), 0) INTO ledger_balance
+    FROM ledger_entries
+    WHERE account_id = NEW.account_id;
+
+    IF current_balance IS DISTINCT FROM ledger_balance THEN
+        RAISE EXCEPTION 'account % balance % does not match ledger balance %',
+            NEW.account_id, current_balance, ledger_balance
+            USING ERRCODE = 'check_violation';
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS ledger_entries_match_balance ON ledger_entries;
+CREATE CONSTRAINT TRIGGER ledger_entries_match_balance
+    AFTER INSERT ON ledger_entries
+    DEFERRABLE INITIALLY DEFERRED
+    FOR EACH ROW EXECUTE FUNCTION check_account_ledger_balance();
+
+COMMIT;